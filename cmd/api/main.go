@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"net/http"
 	"os"
@@ -18,6 +20,8 @@ import (
 	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
 	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/feed-system/feed-system/pkg/retry"
+	"github.com/feed-system/feed-system/pkg/shutdown"
 	"github.com/gin-gonic/gin"
 )
 
@@ -32,44 +36,68 @@ func main() {
 	logger := logger.NewLogger()
 	logger.Info("Starting Feed System API server...")
 
-	// 初始化数据库
-	db, err := repository.NewDatabase(&cfg.Database)
+	// 初始化数据库（编排部署时依赖可能晚于本服务就绪，按配置退避重试）
+	ctx := context.Background()
+	var db *repository.Database
+	err = retry.Do(ctx, cfg.Server.StartupBackoff(), func(context.Context) error {
+		var err error
+		db, err = repository.NewDatabase(&cfg.Database)
+		return err
+	}, func(attempt int, wait time.Duration, err error) {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warn("Database not ready, retrying")
+	})
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to connect to database")
 	}
-	defer db.Close()
 
-	// 自动迁移数据库表
-	if err := db.AutoMigrate(); err != nil {
+	// 执行版本化数据库迁移
+	applied, err := db.Migrate(ctx)
+	if err != nil {
 		logger.WithError(err).Fatal("Failed to migrate database")
 	}
+	if version, err := db.SchemaVersion(ctx); err == nil {
+		logger.WithFields(map[string]interface{}{
+			"applied": applied,
+			"version": version,
+		}).Info("Database migrations complete")
+	}
+	if cfg.Database.AutoMigrate {
+		if err := db.AutoMigrate(); err != nil {
+			logger.WithError(err).Fatal("Failed to auto migrate database")
+		}
+	}
 
 	// 初始化Redis缓存
-	redisClient := cache.NewRedisClient(
-		cfg.Redis.Addr(),
-		cfg.Redis.Password,
-		cfg.Redis.DB,
-		cfg.Redis.PoolSize,
-		cfg.Redis.MinIdleConns,
-	)
-	defer redisClient.Close()
+	redisClient, err := cache.New(cfg.Redis.ClientOptions())
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to create Redis client")
+	}
 
 	// 检查Redis连接
-	ctx := context.Background()
-	if err := redisClient.Ping(ctx); err != nil {
+	if err := retry.Do(ctx, cfg.Server.StartupBackoff(), redisClient.Ping, func(attempt int, wait time.Duration, err error) {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warn("Redis not ready, retrying")
+	}); err != nil {
 		logger.WithError(err).Fatal("Failed to connect to Redis")
 	}
 
 	// 初始化Kafka生产者
 	feedEventsProducer := queue.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents)
-	defer feedEventsProducer.Close()
 
 	userEventsProducer := queue.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.Topics.UserEvents)
-	defer userEventsProducer.Close()
 
-	// 初始化Kafka消费者
-	feedEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents, "feed-worker-group")
-	defer feedEventsConsumer.Close()
+	// 初始化Kafka消费者，v1和优化版worker各自使用独立的消费组，每个事件两边都会处理一次
+	feedEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents, cfg.Kafka.ConsumerGroups.FeedWorker)
+	optimizedFeedEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents, cfg.Kafka.ConsumerGroups.OptimizedFeedWorker)
+
+	// 配置热更新（Feed阈值等）
+	configWatcher := config.NewConfigWatcher(&cfg.Feed, logger)
+	configWatcher.Watch()
 
 	// 初始化仓库
 	userRepo := repository.NewUserRepository(db.DB)
@@ -79,45 +107,121 @@ func main() {
 	likeRepo := repository.NewLikeRepository(db.DB)
 	commentRepo := repository.NewCommentRepository(db.DB)
 
+	// 内容审核
+	moderator, err := services.NewKeywordModerator(cfg.Moderation)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize content moderator")
+	}
+
 	// 初始化服务
-	userService := services.NewUserService(userRepo, followRepo, userEventsProducer, logger)
-	feedService := services.NewFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, &cfg.Feed, logger)
-	likeService := services.NewLikeService(postRepo, likeRepo, userRepo, feedEventsProducer, logger)
-	commentService := services.NewCommentService(postRepo, commentRepo, userRepo, feedEventsProducer, logger)
+	userService := services.NewUserService(userRepo, followRepo, redisClient, userEventsProducer, cfg.User.ProfileCacheTTL, logger)
+	activityService := services.NewActivityService(userRepo, followRepo, redisClient, configWatcher, logger)
+	timelineCacheService := services.NewTimelineCacheService(redisClient, configWatcher, logger)
+	cacheStrategyService := services.NewCacheStrategyService(redisClient, configWatcher, logger, activityService, timelineCacheService)
+	timelineCacheService.SetCapResolver(cacheStrategyService.TimelineCaps)
+	timelineCacheService.SetPageLimits(cfg.Server.DefaultPageSize, cfg.Server.MaxPageSize)
+	feedService := services.NewFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, configWatcher, logger, moderator, cacheStrategyService)
+
+	// 点赞/评论是高频写路径，可选择缓冲后批量发布事件
+	var engagementPublisher queue.Publisher = feedEventsProducer
+	var bufferedProducer *queue.BufferedProducer
+	if cfg.Kafka.Buffer.Enabled {
+		bufferedProducer = queue.NewBufferedProducer(feedEventsProducer, cfg.Kafka.Buffer.FlushInterval, cfg.Kafka.Buffer.FlushSize)
+		engagementPublisher = bufferedProducer
+	}
+	likeService := services.NewLikeService(postRepo, likeRepo, userRepo, followRepo, redisClient, engagementPublisher, logger)
+	commentService := services.NewCommentService(postRepo, commentRepo, userRepo, followRepo, engagementPublisher, logger, moderator)
 
 	// 初始化优化版服务（新增）
-	activityService := services.NewActivityService(userRepo, redisClient, logger)
-	timelineCacheService := services.NewTimelineCacheService(redisClient, logger)
-	cacheStrategyService := services.NewCacheStrategyService(redisClient, &cfg.Feed, logger, activityService, timelineCacheService)
-	recoveryService := services.NewRecoveryService(postRepo, userRepo, followRepo, redisClient, logger, activityService, timelineCacheService)
-	optimizedFeedService := services.NewOptimizedFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, &cfg.Feed, logger, activityService, timelineCacheService)
+	recoveryService := services.NewRecoveryService(postRepo, userRepo, followRepo, redisClient, configWatcher, logger, activityService, timelineCacheService)
+	optimizedFeedService := services.NewOptimizedFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, configWatcher, logger, activityService, timelineCacheService, moderator)
+
+	// 后台任务和worker使用可取消的context，关闭时先停止它们
+	workerCtx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+
+	// Feed延迟SLO评估
+	sloService := services.NewSLOService(cfg.SLO, logger)
+	go sloService.StartEvaluationJob(workerCtx)
+
+	// 按事件类型统计worker处理次数和耗时
+	eventMetrics := services.NewEventMetrics(logger)
 
 	// 初始化工作处理器（原版）
-	feedWorker := workers.NewFeedWorker(feedService, userService, postRepo, timelineRepo, followRepo, userRepo, redisClient, feedEventsConsumer, logger)
+	feedWorker := workers.NewFeedWorker(feedService, userService, postRepo, timelineRepo, followRepo, userRepo, redisClient, feedEventsConsumer, logger, eventMetrics)
 
 	// 初始化优化版工作处理器（新增）
-	optimizedFeedWorker := workers.NewOptimizedFeedWorker(feedEventsConsumer, logger, cfg, activityService, timelineCacheService, cacheStrategyService, recoveryService, optimizedFeedService)
+	optimizedFeedWorker := workers.NewOptimizedFeedWorker(optimizedFeedEventsConsumer, logger, cfg, activityService, timelineCacheService, cacheStrategyService, recoveryService, optimizedFeedService, eventMetrics)
 
 	// 启动工作处理器
 	go func() {
-		if err := feedWorker.Start(ctx); err != nil {
+		if err := feedWorker.Start(workerCtx); err != nil {
 			logger.WithError(err).Error("Feed worker stopped with error")
 		}
 	}()
 
 	// 启动优化版工作处理器（新增）
 	go func() {
-		if err := optimizedFeedWorker.Start(ctx); err != nil {
+		if err := optimizedFeedWorker.Start(workerCtx); err != nil {
 			logger.WithError(err).Error("Optimized feed worker stopped with error")
 		}
 	}()
 
 	// 初始化处理器
+	handlers.SetPaginationLimits(cfg.Server.DefaultPageSize, cfg.Server.MaxPageSize)
 	userHandler := handlers.NewUserHandler(userService, cfg.JWT.Secret)
 	feedHandler := handlers.NewFeedHandler(feedService, likeService, commentService)
 
+	// 运维面板聚合统计
+	adminStats := services.NewAdminStatsService(logger)
+	adminStats.Register("cache", func(ctx context.Context) (interface{}, error) {
+		return cacheStrategyService.GetCacheStats(ctx)
+	})
+	adminStats.Register("distribution", func(ctx context.Context) (interface{}, error) {
+		stats, err := recoveryService.GetDistributionStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"distribution_stats": stats,
+			"fanout_queue":       optimizedFeedService.FanoutStats(),
+		}, nil
+	})
+	adminStats.Register("worker", func(ctx context.Context) (interface{}, error) {
+		return optimizedFeedWorker.GetWorkerStats(ctx)
+	})
+	adminStats.Register("events", func(ctx context.Context) (interface{}, error) {
+		return eventMetrics.Snapshot(), nil
+	})
+	if bufferedProducer != nil {
+		adminStats.Register("event_buffer", func(ctx context.Context) (interface{}, error) {
+			return map[string]interface{}{"dropped": bufferedProducer.Dropped()}, nil
+		})
+	}
+	adminStats.Register("consumer_lag", func(ctx context.Context) (interface{}, error) {
+		return optimizedFeedEventsConsumer.Lag(ctx)
+	})
+	adminStats.Register("jobs", func(ctx context.Context) (interface{}, error) {
+		pending, err := optimizedFeedService.PendingDelayedFanouts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"pending_delayed_fanouts": pending,
+			"slo":                     sloService.Status(ctx),
+		}, nil
+	})
+	adminStats.Register("db_pool", func(ctx context.Context) (interface{}, error) {
+		return db.Stats()
+	})
+	poolMetrics := services.NewPoolMetrics(redisClient, db, cfg.Redis.PoolSize, logger)
+	go poolMetrics.StartSampleJob(workerCtx, 15*time.Second)
+	adminStats.Register("pools", func(ctx context.Context) (interface{}, error) {
+		return poolMetrics.Snapshot()
+	})
+
 	// 初始化优化版处理器（新增）
-	optimizedFeedHandler := handlers.NewOptimizedFeedHandler(optimizedFeedService, activityService, cacheStrategyService, recoveryService, logger)
+	optimizedFeedHandler := handlers.NewOptimizedFeedHandler(optimizedFeedService, activityService, cacheStrategyService, recoveryService, sloService, optimizedFeedEventsConsumer, adminStats, logger)
 
 	// 设置Gin模式
 	if cfg.Server.Mode == "release" {
@@ -127,12 +231,14 @@ func main() {
 	// 创建路由
 	router := gin.New()
 	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
 
 	// 添加CORS中间件
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -159,40 +265,58 @@ func main() {
 			users.POST("/register", userHandler.Register)
 			users.POST("/login", userHandler.Login)
 			users.GET("/search", userHandler.SearchUsers)
-			users.GET("/:id", userHandler.GetProfile)
+			users.GET("/:id", middleware.NewOptionalJWTAuth(&middleware.JWTConfig{Secret: cfg.JWT.Secret, AdminUserIDs: cfg.JWT.AdminUserIDs}), userHandler.GetProfile)
 			users.GET("/:id/followers", userHandler.GetFollowers)
 			users.GET("/:id/following", userHandler.GetFollowing)
 		}
 
 		// 需要认证的路由（原版API）
 		protected := api.Group("")
-		protected.Use(middleware.NewJWTAuth(&middleware.JWTConfig{Secret: cfg.JWT.Secret}))
+		protected.Use(middleware.NewJWTAuth(&middleware.JWTConfig{Secret: cfg.JWT.Secret, AdminUserIDs: cfg.JWT.AdminUserIDs}))
 		{
 			// 用户相关
 			protected.PUT("/users/profile", userHandler.UpdateProfile)
 			protected.POST("/users/follow", userHandler.Follow)
+			protected.POST("/users/follow/batch", userHandler.BatchFollow)
+			protected.POST("/users/unfollow/batch", userHandler.BatchUnfollow)
+			// unfollow-bulk是/users/unfollow/batch的别名，保留给已接入的客户端
+			protected.POST("/users/unfollow-bulk", userHandler.BatchUnfollow)
+			protected.POST("/users/import-following", userHandler.ImportFollowing)
+			protected.POST("/users/:id/follow-back", userHandler.FollowBack)
+			protected.GET("/users/:id/mutuals", userHandler.GetMutuals)
+			protected.GET("/users/suggestions", userHandler.GetFollowSuggestions)
+			protected.GET("/users/me/stats", feedHandler.GetMyStats)
+			protected.GET("/users/me/viewers", userHandler.GetProfileViewers)
+			protected.GET("/users/me/export", feedHandler.ExportMyData)
 			protected.DELETE("/users/unfollow/:id", userHandler.Unfollow)
+			protected.PUT("/follows/:id/preferences", userHandler.UpdateFollowPreferences)
 
 			// Feed相关（原版）
 			protected.POST("/posts", feedHandler.CreatePost)
 			protected.GET("/feed", feedHandler.GetFeed)
 			protected.GET("/users/:id/posts", feedHandler.GetUserPosts)
+			protected.GET("/users/:id/likes", feedHandler.GetLikedPosts)
 			protected.GET("/posts/:id", feedHandler.GetPost)
 			protected.DELETE("/posts/:id", feedHandler.DeletePost)
+			protected.POST("/posts/:id/restore", feedHandler.RestorePost)
 			protected.POST("/posts/:id/like", feedHandler.LikePost)
 			protected.DELETE("/posts/:id/like", feedHandler.UnlikePost)
+			protected.POST("/posts/:id/react", feedHandler.ReactToPost)
 			protected.GET("/posts/:id/likes", feedHandler.GetPostLikes)
 			protected.POST("/posts/:id/comments", feedHandler.CreateComment)
 			protected.GET("/posts/:id/comments", feedHandler.GetPostComments)
 			protected.DELETE("/comments/:id", feedHandler.DeleteComment)
 			protected.GET("/posts/search", feedHandler.SearchPosts)
+
+			// 管理相关
+			protected.POST("/admin/feed-cache/version", middleware.RequireAdmin(), feedHandler.BumpFeedCacheVersion)
 		}
 	}
 
 	// 优化版API路由（新增）
 	apiV2 := router.Group("/api/v2")
 	{
-		jwtConfig := &middleware.JWTConfig{Secret: cfg.JWT.Secret}
+		jwtConfig := &middleware.JWTConfig{Secret: cfg.JWT.Secret, AdminUserIDs: cfg.JWT.AdminUserIDs}
 		optimizedFeedHandler.RegisterRoutes(apiV2, jwtConfig)
 	}
 
@@ -219,21 +343,36 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
-	// 优雅关闭
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// 优雅关闭，整体受shutdown_timeout约束；某一步卡住时不再等待，继续关闭后面的资源
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.WithError(err).Error("Server forced to shutdown")
+	steps := []shutdown.Step{
+		// 1. 停止接收新请求，等待进行中的请求结束
+		{Name: "stop HTTP server", Stop: srv.Shutdown},
+		// 2. 停止消费消息和后台任务
+		{Name: "cancel workers", Stop: func(context.Context) error {
+			cancelWorkers()
+			return nil
+		}},
+		{Name: "stop feed worker", Stop: feedWorker.Stop},
+		{Name: "stop optimized feed worker", Stop: optimizedFeedWorker.Stop},
+		// 3. 等待进行中的帖子分发和Timeline重建完成
+		{Name: "drain feed distributions", Stop: optimizedFeedService.Shutdown},
 	}
-
-	if err := feedWorker.Stop(); err != nil {
-		logger.WithError(err).Error("Failed to stop feed worker")
-	}
-
-	if err := optimizedFeedWorker.Stop(ctx); err != nil {
-		logger.WithError(err).Error("Failed to stop optimized feed worker")
+	// 4. 关闭外部连接：Kafka -> Redis -> DB，先发送缓冲中的事件
+	if bufferedProducer != nil {
+		steps = append(steps, shutdown.Step{Name: "flush buffered events", Stop: bufferedProducer.Close})
 	}
+	steps = append(steps,
+		shutdown.Close("close feed events producer", feedEventsProducer.Close),
+		shutdown.Close("close user events producer", userEventsProducer.Close),
+		shutdown.Close("close Redis client", redisClient.Close),
+		shutdown.Close("close database", db.Close),
+	)
+	shutdown.Run(shutdownCtx, steps, func(step string, err error) {
+		logger.WithError(err).WithField("step", step).Error("Shutdown step failed")
+	})
 
 	logger.Info("Server exited")
 }
@@ -256,12 +395,19 @@ func init() {
 	}
 }
 
+// createDefaultConfig 生成本地开发用的配置文件，JWT密钥随机生成
 func createDefaultConfig(path string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
 	defaultConfig := `server:
   port: ":8080"
   mode: "debug"
   read_timeout: 30s
   write_timeout: 30s
+  shutdown_timeout: 30s
 
 database:
   host: "localhost"
@@ -290,7 +436,7 @@ kafka:
     feed_updates: "feed-updates"
 
 jwt:
-  secret: "your-secret-key-change-in-production"
+  secret: "` + hex.EncodeToString(secret) + `"
   expire_time: 24h
 
 feed:
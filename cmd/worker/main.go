@@ -15,6 +15,8 @@ import (
 	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
 	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/feed-system/feed-system/pkg/retry"
+	"github.com/feed-system/feed-system/pkg/shutdown"
 )
 
 func main() {
@@ -28,36 +30,49 @@ func main() {
 	logger := logger.NewLogger()
 	logger.Info("Starting Feed System Worker...")
 
-	// 初始化数据库
-	db, err := repository.NewDatabase(&cfg.Database)
+	// 初始化数据库（编排部署时依赖可能晚于本服务就绪，按配置退避重试）
+	ctx := context.Background()
+	var db *repository.Database
+	err = retry.Do(ctx, cfg.Server.StartupBackoff(), func(context.Context) error {
+		var err error
+		db, err = repository.NewDatabase(&cfg.Database)
+		return err
+	}, func(attempt int, wait time.Duration, err error) {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warn("Database not ready, retrying")
+	})
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to connect to database")
 	}
-	defer db.Close()
 
 	// 初始化Redis缓存
-	redisClient := cache.NewRedisClient(
-		cfg.Redis.Addr(),
-		cfg.Redis.Password,
-		cfg.Redis.DB,
-		cfg.Redis.PoolSize,
-		cfg.Redis.MinIdleConns,
-	)
-	defer redisClient.Close()
+	redisClient, err := cache.New(cfg.Redis.ClientOptions())
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to create Redis client")
+	}
 
 	// 检查Redis连接
-	ctx := context.Background()
-	if err := redisClient.Ping(ctx); err != nil {
+	if err := retry.Do(ctx, cfg.Server.StartupBackoff(), redisClient.Ping, func(attempt int, wait time.Duration, err error) {
+		logger.WithError(err).WithFields(map[string]interface{}{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warn("Redis not ready, retrying")
+	}); err != nil {
 		logger.WithError(err).Fatal("Failed to connect to Redis")
 	}
 
 	// 初始化Kafka消费者
-	feedEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents, "feed-worker-group")
-	defer feedEventsConsumer.Close()
+	feedEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents, cfg.Kafka.ConsumerGroups.FeedWorker)
+	userEventsConsumer := queue.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.Topics.UserEvents, cfg.Kafka.ConsumerGroups.UserEventWorker)
 
 	// 初始化Kafka生产者（用于处理过程中的事件发布）
 	feedEventsProducer := queue.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.Topics.FeedEvents)
-	defer feedEventsProducer.Close()
+
+	// 配置热更新（Feed阈值等）
+	configWatcher := config.NewConfigWatcher(&cfg.Feed, logger)
+	configWatcher.Watch()
 
 	// 初始化仓库
 	userRepo := repository.NewUserRepository(db.DB)
@@ -67,21 +82,55 @@ func main() {
 	likeRepo := repository.NewLikeRepository(db.DB)
 	commentRepo := repository.NewCommentRepository(db.DB)
 
+	// 内容审核
+	moderator, err := services.NewKeywordModerator(cfg.Moderation)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize content moderator")
+	}
+
 	// 初始化服务
-	userService := services.NewUserService(userRepo, followRepo, feedEventsProducer, logger)
-	feedService := services.NewFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, &cfg.Feed, logger)
+	userService := services.NewUserService(userRepo, followRepo, redisClient, feedEventsProducer, cfg.User.ProfileCacheTTL, logger)
+	activityService := services.NewActivityService(userRepo, followRepo, redisClient, configWatcher, logger)
+	timelineCacheService := services.NewTimelineCacheService(redisClient, configWatcher, logger)
+	cacheStrategyService := services.NewCacheStrategyService(redisClient, configWatcher, logger, activityService, timelineCacheService)
+	timelineCacheService.SetCapResolver(cacheStrategyService.TimelineCaps)
+	feedService := services.NewFeedService(postRepo, timelineRepo, userRepo, followRepo, likeRepo, commentRepo, redisClient, feedEventsProducer, configWatcher, logger, moderator, cacheStrategyService)
 
 	// 初始化工作处理器
-	feedWorker := workers.NewFeedWorker(feedService, userService, postRepo, timelineRepo, followRepo, userRepo, redisClient, feedEventsConsumer, logger)
+	eventMetrics := services.NewEventMetrics(logger)
+	feedWorker := workers.NewFeedWorker(feedService, userService, postRepo, timelineRepo, followRepo, userRepo, redisClient, feedEventsConsumer, logger, eventMetrics)
+	userEventWorker := workers.NewUserEventWorker(redisClient, postRepo, timelineCacheService, userEventsConsumer, logger, eventMetrics)
+	timelineWarmer := workers.NewTimelineWarmer(cacheStrategyService, userRepo, timelineRepo, configWatcher, logger)
 
 	// 启动工作处理器
+	workerCtx, cancelWorkers := context.WithCancel(ctx)
+	defer cancelWorkers()
+
 	logger.Info("Starting feed worker...")
 	go func() {
-		if err := feedWorker.Start(ctx); err != nil {
+		if err := feedWorker.Start(workerCtx); err != nil {
 			logger.WithError(err).Error("Feed worker stopped with error")
 		}
 	}()
 
+	logger.Info("Starting user event worker...")
+	go func() {
+		if err := userEventWorker.Start(workerCtx); err != nil {
+			logger.WithError(err).Error("User event worker stopped with error")
+		}
+	}()
+
+	// worker进程没有HTTP接口，定期把事件处理统计写入日志
+	go eventMetrics.StartReportJob(workerCtx, time.Minute)
+	go services.NewPoolMetrics(redisClient, db, cfg.Redis.PoolSize, logger).StartSampleJob(workerCtx, 15*time.Second)
+
+	// 后台预热最活跃用户的Timeline缓存（默认关闭）
+	go func() {
+		if err := timelineWarmer.Run(workerCtx); err != nil {
+			logger.WithError(err).Error("Timeline prewarm stopped with error")
+		}
+	}()
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -89,13 +138,25 @@ func main() {
 
 	logger.Info("Shutting down worker...")
 
-	// 优雅关闭
-	_, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// 优雅关闭，整体受shutdown_timeout约束；某一步卡住时不再等待，继续关闭后面的资源
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	if err := feedWorker.Stop(); err != nil {
-		logger.WithError(err).Error("Failed to stop feed worker")
-	}
+	shutdown.Run(shutdownCtx, []shutdown.Step{
+		// 1. 停止消费消息
+		{Name: "cancel workers", Stop: func(context.Context) error {
+			cancelWorkers()
+			return nil
+		}},
+		{Name: "stop feed worker", Stop: feedWorker.Stop},
+		{Name: "stop user event worker", Stop: userEventWorker.Stop},
+		// 2. 关闭外部连接：Kafka -> Redis -> DB
+		shutdown.Close("close feed events producer", feedEventsProducer.Close),
+		shutdown.Close("close Redis client", redisClient.Close),
+		shutdown.Close("close database", db.Close),
+	}, func(step string, err error) {
+		logger.WithError(err).WithField("step", step).Error("Shutdown step failed")
+	})
 
 	logger.Info("Worker exited")
 }
\ No newline at end of file
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/retry"
 	"github.com/spf13/viper"
 )
 
@@ -15,6 +20,22 @@ type Config struct {
 	Kafka    KafkaConfig    `mapstructure:"kafka"`
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Feed     FeedConfig     `mapstructure:"feed"`
+	SLO      SLOConfig      `mapstructure:"slo"`
+	User     UserConfig     `mapstructure:"user"`
+
+	Moderation ModerationConfig `mapstructure:"moderation"`
+}
+
+// ModerationConfig 发帖/评论内容审核配置
+type ModerationConfig struct {
+	Enabled         bool     `mapstructure:"enabled"`
+	BlockedKeywords []string `mapstructure:"blocked_keywords"` // 不区分大小写
+	BlockedPatterns []string `mapstructure:"blocked_patterns"` // Go正则表达式
+}
+
+// UserConfig 用户相关配置
+type UserConfig struct {
+	ProfileCacheTTL time.Duration `mapstructure:"profile_cache_ttl"` // 用户资料缓存时间
 }
 
 type ServerConfig struct {
@@ -22,6 +43,15 @@ type ServerConfig struct {
 	Mode         string        `mapstructure:"mode"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// 优雅关闭的总超时时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// 启动时连接数据库/Redis的重试：最多尝试次数、首次间隔（之后翻倍）和最大间隔
+	StartupRetryAttempts    int           `mapstructure:"startup_retry_attempts"`
+	StartupRetryInterval    time.Duration `mapstructure:"startup_retry_interval"`
+	StartupRetryMaxInterval time.Duration `mapstructure:"startup_retry_max_interval"`
+	// 列表接口未指定limit时的页大小，以及允许的最大页大小
+	DefaultPageSize int `mapstructure:"default_page_size"`
+	MaxPageSize     int `mapstructure:"max_page_size"`
 }
 
 type DatabaseConfig struct {
@@ -33,20 +63,47 @@ type DatabaseConfig struct {
 	SSLMode      string `mapstructure:"sslmode"`
 	MaxOpenConns int    `mapstructure:"max_open_conns"`
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	// 连接最长存活时间和最长空闲时间，托管Postgres会回收长连接，0表示不限制
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+	// 启动时在版本化迁移之后再按模型AutoMigrate，仅用于本地开发同步未写迁移的字段
+	AutoMigrate bool `mapstructure:"auto_migrate"`
 }
 
 type RedisConfig struct {
-	Host         string `mapstructure:"host"`
-	Port         int    `mapstructure:"port"`
-	Password     string `mapstructure:"password"`
-	DB           int    `mapstructure:"db"`
-	PoolSize     int    `mapstructure:"pool_size"`
-	MinIdleConns int    `mapstructure:"min_idle_conns"`
+	// 部署模式：single（默认，使用host/port）、cluster（addrs为集群节点）、sentinel（addrs为哨兵地址）
+	Mode         string   `mapstructure:"mode"`
+	Addrs        []string `mapstructure:"addrs"`
+	MasterName   string   `mapstructure:"master_name"`
+	Host         string   `mapstructure:"host"`
+	Port         int      `mapstructure:"port"`
+	Password     string   `mapstructure:"password"`
+	DB           int      `mapstructure:"db"`
+	PoolSize     int      `mapstructure:"pool_size"`
+	MinIdleConns int      `mapstructure:"min_idle_conns"`
 }
 
 type KafkaConfig struct {
-	Brokers []string `mapstructure:"brokers"`
-	Topics  Topics   `mapstructure:"topics"`
+	Brokers []string      `mapstructure:"brokers"`
+	Topics  Topics        `mapstructure:"topics"`
+	Buffer  PublishBuffer `mapstructure:"buffer"`
+	// 各Worker的消费组
+	ConsumerGroups ConsumerGroups `mapstructure:"consumer_groups"`
+}
+
+// ConsumerGroups Kafka消费组配置。v1和优化版Feed Worker消费同一个topic，
+// 必须使用不同的消费组才能各自收到每条事件；同一Worker的多个实例共享消费组分摊分区
+type ConsumerGroups struct {
+	FeedWorker          string `mapstructure:"feed_worker"`
+	OptimizedFeedWorker string `mapstructure:"optimized_feed_worker"`
+	UserEventWorker     string `mapstructure:"user_event_worker"`
+}
+
+// PublishBuffer 点赞/评论事件的缓冲批量发布配置
+type PublishBuffer struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // 定时刷新间隔
+	FlushSize     int           `mapstructure:"flush_size"`     // 缓冲达到该条数立即刷新
 }
 
 type Topics struct {
@@ -55,17 +112,52 @@ type Topics struct {
 	FeedUpdates string `mapstructure:"feed_updates"`
 }
 
+// StartupBackoff 启动时连接依赖的重试配置
+func (c *ServerConfig) StartupBackoff() retry.Backoff {
+	return retry.Backoff{
+		Attempts:    c.StartupRetryAttempts,
+		Interval:    c.StartupRetryInterval,
+		MaxInterval: c.StartupRetryMaxInterval,
+	}
+}
+
+// SLOConfig Feed延迟SLO配置
+type SLOConfig struct {
+	FeedP99Target      time.Duration `mapstructure:"feed_p99_target"`     // p99目标延迟
+	Window             time.Duration `mapstructure:"window"`              // 统计窗口
+	EvaluationInterval time.Duration `mapstructure:"evaluation_interval"` // 评估间隔
+	WebhookURL         string        `mapstructure:"webhook_url"`         // 状态变化时回调（可选）
+}
+
 type JWTConfig struct {
-	Secret     string        `mapstructure:"secret"`
-	ExpireTime time.Duration `mapstructure:"expire_time"`
+	Secret       string        `mapstructure:"secret"`
+	ExpireTime   time.Duration `mapstructure:"expire_time"`
+	AdminUserIDs []string      `mapstructure:"admin_user_ids"` // 拥有管理员权限的用户ID
 }
 
 type FeedConfig struct {
 	PushThreshold      int                `mapstructure:"push_threshold"` // 推模式阈值
 	CacheTTL           time.Duration      `mapstructure:"cache_ttl"`
+	CacheVersion       string             `mapstructure:"cache_version"` // Feed缓存key版本，修改后所有已缓存Feed失效
 	MaxFeedSize        int                `mapstructure:"max_feed_size"`
 	RankUpdateInterval time.Duration      `mapstructure:"rank_update_interval"`
-	Optimization       OptimizationConfig `mapstructure:"optimization"` // 优化配置
+	MaxPushAge         time.Duration      `mapstructure:"max_push_age"`         // 只推送该时间窗口内的帖子（扇出/恢复/回填），0表示不限制
+	PullMergeMode      string             `mapstructure:"pull_merge_mode"`      // 拉模式合并方式: global | kway
+	KWayMinFollowing   int                `mapstructure:"kway_min_following"`   // 关注数达到该值才使用多路归并
+	PullMaxFollowing   int                `mapstructure:"pull_max_following"`   // 拉模式最多合并的关注数，超出时按活跃度采样
+	PullOverfetch      int                `mapstructure:"pull_overfetch"`       // 拉模式每轮按页大小的倍数取候选帖子，给过滤留出余量
+	PullMaxRounds      int                `mapstructure:"pull_max_rounds"`      // 过滤后仍不足一页时最多补取的轮数
+	HydrateViewerState bool               `mapstructure:"hydrate_viewer_state"` // 单帖查询时是否默认填充查看者的点赞状态
+	RestoreGraceWindow time.Duration      `mapstructure:"restore_grace_window"` // 删除后允许作者恢复帖子的时间窗口，0表示不允许恢复
+	ContentSanitize    string             `mapstructure:"content_sanitize"`     // 帖子内容HTML处理: off（原样保存，纯文本客户端）| escape | strip
+	BackfillCooldown   time.Duration      `mapstructure:"backfill_cooldown"`    // 同一对关注关系在该窗口内只回填一次（防止反复关注刷屏），0表示不限制
+	SnapshotTTL        time.Duration      `mapstructure:"snapshot_ttl"`         // Feed首页快照的保留时间，实时读取失败时返回快照，0表示关闭
+	DegradedFallback   bool               `mapstructure:"degraded_fallback"`    // 拉模式也失败时是否用缓存数据返回部分Feed
+	EngagedRanking     string             `mapstructure:"engaged_ranking"`      // 排序Feed中查看者已点赞/评论过的帖子: off | penalize（降权）| exclude（不展示）
+	EngagedPenalty     float64            `mapstructure:"engaged_penalty"`      // penalize模式下已互动帖子的分数乘数，取值(0, 1]
+	PostCacheTTL       time.Duration      `mapstructure:"post_cache_ttl"`       // 单帖缓存时间，点赞/评论/删除时失效，0表示不缓存
+	AuthorDiversity    int                `mapstructure:"author_diversity"`     // Feed页内同一作者最多连续出现的帖子数，超出时与其他作者穿插，0表示不限制
+	Optimization       OptimizationConfig `mapstructure:"optimization"`         // 优化配置
 }
 
 // OptimizationConfig 优化配置
@@ -77,13 +169,40 @@ type OptimizationConfig struct {
 	CacheCleanup  CleanupConfig   `mapstructure:"cache_cleanup"`
 	ActivityDecay DecayConfig     `mapstructure:"activity_decay"`
 	Timeline      TimelineConfig  `mapstructure:"timeline"`
+	DelayedFanout DelayedFanout   `mapstructure:"delayed_fanout"`
+	Prewarm       PrewarmConfig   `mapstructure:"prewarm"`
+	Distribution  Distribution    `mapstructure:"distribution"`
+}
+
+// Distribution 推拉模式选择配置，在粉丝数阈值之外考虑作者发帖频率和关注者活跃占比
+type Distribution struct {
+	FrequentPosterPerDay int     `mapstructure:"frequent_poster_per_day"` // 作者24小时内发帖数达到该值视为高频发帖者，0表示不考虑发帖频率
+	MinActiveRatio       float64 `mapstructure:"min_active_ratio"`        // 普通作者活跃关注者占比低于该值时只推给活跃关注者，0表示不考虑
+	ActiveSampleSize     int     `mapstructure:"active_sample_size"`      // 估算活跃占比时抽样的关注者数
+}
+
+// PrewarmConfig worker启动时的Timeline缓存预热配置（Redis清空/重启后避免拉模式雪崩）
+type PrewarmConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	MaxUsers int           `mapstructure:"max_users"` // 按活跃度取前N个用户预热
+	Delay    time.Duration `mapstructure:"delay"`     // 每个用户之间的间隔，用于限制预热对数据库的压力
+}
+
+// DelayedFanout 非活跃关注者延迟扇出配置
+type DelayedFanout struct {
+	Enabled          bool `mapstructure:"enabled"`
+	OffPeakStartHour int  `mapstructure:"off_peak_start_hour"` // 低峰时段开始（本地时间小时，含）
+	OffPeakEndHour   int  `mapstructure:"off_peak_end_hour"`   // 低峰时段结束（不含），可小于开始表示跨零点
+	BatchSize        int  `mapstructure:"batch_size"`          // 每轮处理的任务数
 }
 
 // UserCacheConfig 用户缓存配置
 type UserCacheConfig struct {
-	ScoreThreshold   float64 `mapstructure:"score_threshold"`
-	CacheHours       int     `mapstructure:"cache_hours"`
-	MaxTimelineItems int     `mapstructure:"max_timeline_items"`
+	ScoreThreshold   float64       `mapstructure:"score_threshold"`
+	CacheHours       int           `mapstructure:"cache_hours"`
+	MaxTimelineItems int           `mapstructure:"max_timeline_items"`
+	MinRecentScore   float64       `mapstructure:"min_recent_score"` // 仅active_user使用：仅凭最近活跃判定为活跃的用户使用长TTL时还需达到的最低活跃度分数
+	FeedCacheTTL     time.Duration `mapstructure:"feed_cache_ttl"`   // 该档位用户Feed响应的缓存时间，0表示使用feed.cache_ttl
 }
 
 // RecoveryConfig 崩溃恢复配置
@@ -94,8 +213,10 @@ type RecoveryConfig struct {
 
 // CleanupConfig 缓存清理配置
 type CleanupConfig struct {
-	Interval  int `mapstructure:"interval"`
-	BatchSize int `mapstructure:"batch_size"`
+	Interval   int           `mapstructure:"interval"`
+	BatchSize  int           `mapstructure:"batch_size"`  // 每批处理的Timeline数，同时作为SCAN的COUNT
+	BatchDelay time.Duration `mapstructure:"batch_delay"` // 批次之间的间隔，用于限制清理速率
+	MaxPerRun  int           `mapstructure:"max_per_run"` // 每次运行检查的Timeline数上限（在页边界停止，可能超出一页），0表示不限制
 }
 
 // DecayConfig 活跃度衰减配置
@@ -110,6 +231,21 @@ type TimelineConfig struct {
 	DefaultTTL      int `mapstructure:"default_ttl"`
 	MaxItems        int `mapstructure:"max_items"`
 	CleanupInterval int `mapstructure:"cleanup_interval"`
+	FanoutChunkSize int `mapstructure:"fanout_chunk_size"` // 扇出时每个Pipeline包含的关注者数
+	FanoutWorkers   int `mapstructure:"fanout_workers"`    // 扇出并发worker数
+	// 同时进行的扇出任务上限，超过时帖子分发阻塞等待、缓存重建直接跳过
+	MaxInflightFanouts int `mapstructure:"max_inflight_fanouts"`
+	// Timeline缓存的软内存预算（MB），超出时在清理任务中淘汰最不活跃用户的Timeline，0表示不限制
+	MemoryBudgetMB int `mapstructure:"memory_budget_mb"`
+}
+
+// EnvPrefix 环境变量前缀，例如 FEEDSYSTEM_DATABASE_PASSWORD 覆盖 database.password
+const EnvPrefix = "FEEDSYSTEM"
+
+// 默认配置中的JWT占位密钥，生产环境禁止使用
+var placeholderJWTSecrets = []string{
+	"your-secret-key-change-in-production",
+	"your-super-secret-jwt-key-change-in-production",
 }
 
 func LoadConfig() (*Config, error) {
@@ -121,6 +257,13 @@ func LoadConfig() (*Config, error) {
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("yaml")
 
+	// 允许通过环境变量覆盖YAML配置
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	setDefaults()
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -130,9 +273,272 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
+// setDefaults 为可选配置项设置默认值
+func setDefaults() {
+	// 没有默认值的键不会被Unmarshal读取环境变量，jwt.secret需要能只通过FEEDSYSTEM_JWT_SECRET提供
+	viper.SetDefault("jwt.secret", "")
+	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("database.conn_max_lifetime", "30m")
+	viper.SetDefault("database.conn_max_idle_time", "5m")
+	viper.SetDefault("database.auto_migrate", false)
+	viper.SetDefault("server.startup_retry_attempts", 5)
+	viper.SetDefault("server.startup_retry_interval", "1s")
+	viper.SetDefault("server.startup_retry_max_interval", "10s")
+	viper.SetDefault("server.default_page_size", 20)
+	viper.SetDefault("server.max_page_size", 100)
+	viper.SetDefault("redis.mode", "single")
+	viper.SetDefault("kafka.buffer.enabled", false)
+	viper.SetDefault("kafka.buffer.flush_interval", "100ms")
+	viper.SetDefault("kafka.buffer.flush_size", 100)
+	viper.SetDefault("kafka.consumer_groups.feed_worker", "feed-worker-group")
+	viper.SetDefault("kafka.consumer_groups.optimized_feed_worker", "optimized-feed-worker-group")
+	viper.SetDefault("kafka.consumer_groups.user_event_worker", "user-event-worker-group")
+	viper.SetDefault("feed.optimization.timeline.fanout_chunk_size", 500)
+	viper.SetDefault("feed.optimization.timeline.fanout_workers", 4)
+	viper.SetDefault("feed.optimization.timeline.max_inflight_fanouts", 64)
+	viper.SetDefault("user.profile_cache_ttl", "10m")
+	viper.SetDefault("feed.cache_version", "1")
+	viper.SetDefault("feed.optimization.delayed_fanout.enabled", false)
+	viper.SetDefault("feed.optimization.delayed_fanout.off_peak_start_hour", 1)
+	viper.SetDefault("feed.optimization.delayed_fanout.off_peak_end_hour", 6)
+	viper.SetDefault("feed.optimization.delayed_fanout.batch_size", 100)
+	viper.SetDefault("feed.optimization.active_user.min_recent_score", 10.0)
+	viper.SetDefault("feed.optimization.inactive_user.feed_cache_ttl", "10m")
+	viper.SetDefault("feed.optimization.vip_user.feed_cache_ttl", "2h")
+	viper.SetDefault("feed.optimization.cache_cleanup.batch_size", 100)
+	viper.SetDefault("feed.optimization.cache_cleanup.batch_delay", "100ms")
+	viper.SetDefault("feed.optimization.cache_cleanup.max_per_run", 10000)
+	viper.SetDefault("feed.optimization.distribution.frequent_poster_per_day", 0)
+	viper.SetDefault("feed.optimization.distribution.min_active_ratio", 0)
+	viper.SetDefault("feed.optimization.distribution.active_sample_size", 200)
+	viper.SetDefault("feed.optimization.prewarm.enabled", false)
+	viper.SetDefault("feed.optimization.prewarm.max_users", 1000)
+	viper.SetDefault("feed.optimization.prewarm.delay", "20ms")
+	viper.SetDefault("feed.rank_update_interval", "5m")
+	viper.SetDefault("feed.max_push_age", "72h")
+	viper.SetDefault("feed.pull_merge_mode", "global")
+	viper.SetDefault("feed.hydrate_viewer_state", true)
+	viper.SetDefault("feed.restore_grace_window", "5m")
+	viper.SetDefault("feed.content_sanitize", "off")
+	viper.SetDefault("feed.backfill_cooldown", "10m")
+	viper.SetDefault("feed.snapshot_ttl", "24h")
+	viper.SetDefault("feed.post_cache_ttl", "5m")
+	viper.SetDefault("feed.degraded_fallback", true)
+	viper.SetDefault("feed.engaged_ranking", "off")
+	viper.SetDefault("feed.engaged_penalty", 0.5)
+	viper.SetDefault("feed.author_diversity", 0)
+	viper.SetDefault("moderation.enabled", false)
+	viper.SetDefault("feed.kway_min_following", 200)
+	viper.SetDefault("feed.pull_max_following", 1000)
+	viper.SetDefault("feed.pull_overfetch", 2)
+	viper.SetDefault("feed.pull_max_rounds", 3)
+	viper.SetDefault("slo.feed_p99_target", "500ms")
+	viper.SetDefault("slo.window", "5m")
+	viper.SetDefault("slo.evaluation_interval", "30s")
+}
+
+// Validate 校验必填项和取值范围，尽早暴露配置错误
+func (c *Config) Validate() error {
+	if c.Server.ShutdownTimeout <= 0 {
+		return fmt.Errorf("server.shutdown_timeout must be positive, got %s", c.Server.ShutdownTimeout)
+	}
+	if c.Server.StartupRetryAttempts < 1 {
+		return fmt.Errorf("server.startup_retry_attempts must be at least 1, got %d", c.Server.StartupRetryAttempts)
+	}
+	if c.Server.StartupRetryInterval < 0 || c.Server.StartupRetryMaxInterval < 0 {
+		return fmt.Errorf("server.startup_retry_interval and startup_retry_max_interval must not be negative")
+	}
+	if c.Server.MaxPageSize < 1 {
+		return fmt.Errorf("server.max_page_size must be at least 1, got %d", c.Server.MaxPageSize)
+	}
+	if c.Server.DefaultPageSize < 1 || c.Server.DefaultPageSize > c.Server.MaxPageSize {
+		return fmt.Errorf("server.default_page_size must be in [1, %d], got %d", c.Server.MaxPageSize, c.Server.DefaultPageSize)
+	}
+
+	if len(c.Kafka.Brokers) == 0 {
+		return errors.New("kafka.brokers must not be empty")
+	}
+	for _, broker := range c.Kafka.Brokers {
+		if strings.TrimSpace(broker) == "" {
+			return errors.New("kafka.brokers must not contain empty entries")
+		}
+	}
+	if c.Kafka.Topics.FeedEvents == "" || c.Kafka.Topics.UserEvents == "" {
+		return errors.New("kafka.topics.feed_events and kafka.topics.user_events are required")
+	}
+	groups := c.Kafka.ConsumerGroups
+	if groups.FeedWorker == "" || groups.OptimizedFeedWorker == "" || groups.UserEventWorker == "" {
+		return errors.New("kafka.consumer_groups.feed_worker, optimized_feed_worker and user_event_worker are required")
+	}
+	if groups.FeedWorker == groups.OptimizedFeedWorker {
+		return fmt.Errorf("kafka.consumer_groups.feed_worker and optimized_feed_worker must differ, both are %q", groups.FeedWorker)
+	}
+
+	if c.JWT.Secret == "" {
+		return errors.New("jwt.secret is required")
+	}
+	// 任何模式下都不允许使用占位密钥，开发和测试环境也需要显式配置
+	for _, placeholder := range placeholderJWTSecrets {
+		if c.JWT.Secret == placeholder {
+			return errors.New("jwt.secret must be changed from the default placeholder")
+		}
+	}
+
+	if c.Database.Host == "" || c.Database.DBName == "" {
+		return errors.New("database.host and database.dbname are required")
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		return fmt.Errorf("database.max_open_conns must be positive, got %d", c.Database.MaxOpenConns)
+	}
+	if c.Database.MaxIdleConns <= 0 {
+		return fmt.Errorf("database.max_idle_conns must be positive, got %d", c.Database.MaxIdleConns)
+	}
+	if c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		return fmt.Errorf("database.max_idle_conns (%d) must not exceed max_open_conns (%d)",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns)
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		return errors.New("database.conn_max_lifetime and conn_max_idle_time must not be negative")
+	}
+
+	switch c.Redis.Mode {
+	case cache.ModeSingle:
+		if c.Redis.Host == "" {
+			return errors.New("redis.host is required")
+		}
+	case cache.ModeCluster:
+		if len(c.Redis.Addrs) == 0 {
+			return errors.New("redis.addrs is required in cluster mode")
+		}
+		if c.Redis.DB != 0 {
+			return fmt.Errorf("redis.db must be 0 in cluster mode, got %d", c.Redis.DB)
+		}
+	case cache.ModeSentinel:
+		if len(c.Redis.Addrs) == 0 || c.Redis.MasterName == "" {
+			return errors.New("redis.addrs and redis.master_name are required in sentinel mode")
+		}
+	default:
+		return fmt.Errorf("redis.mode must be one of single, cluster, sentinel, got %q", c.Redis.Mode)
+	}
+	if c.Redis.PoolSize <= 0 {
+		return fmt.Errorf("redis.pool_size must be positive, got %d", c.Redis.PoolSize)
+	}
+
+	for _, pattern := range c.Moderation.BlockedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("moderation.blocked_patterns contains invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.User.ProfileCacheTTL <= 0 {
+		return fmt.Errorf("user.profile_cache_ttl must be positive, got %s", c.User.ProfileCacheTTL)
+	}
+
+	if c.Feed.PushThreshold <= 0 {
+		return fmt.Errorf("feed.push_threshold must be positive, got %d", c.Feed.PushThreshold)
+	}
+	if c.Feed.MaxFeedSize <= 0 {
+		return fmt.Errorf("feed.max_feed_size must be positive, got %d", c.Feed.MaxFeedSize)
+	}
+	if c.Feed.Optimization.Timeline.MemoryBudgetMB < 0 {
+		return fmt.Errorf("feed.optimization.timeline.memory_budget_mb must not be negative, got %d", c.Feed.Optimization.Timeline.MemoryBudgetMB)
+	}
+	if df := c.Feed.Optimization.DelayedFanout; df.Enabled {
+		if df.OffPeakStartHour < 0 || df.OffPeakStartHour > 23 || df.OffPeakEndHour < 0 || df.OffPeakEndHour > 23 {
+			return fmt.Errorf("feed.optimization.delayed_fanout off-peak hours must be within 0-23, got %d-%d", df.OffPeakStartHour, df.OffPeakEndHour)
+		}
+		if df.BatchSize <= 0 {
+			return fmt.Errorf("feed.optimization.delayed_fanout.batch_size must be positive, got %d", df.BatchSize)
+		}
+	}
+	for name, tier := range map[string]UserCacheConfig{
+		"active_user":   c.Feed.Optimization.ActiveUser,
+		"inactive_user": c.Feed.Optimization.InactiveUser,
+		"vip_user":      c.Feed.Optimization.VIPUser,
+	} {
+		if tier.FeedCacheTTL < 0 {
+			return fmt.Errorf("feed.optimization.%s.feed_cache_ttl must not be negative, got %s", name, tier.FeedCacheTTL)
+		}
+	}
+	if c.Feed.Optimization.ActiveUser.MinRecentScore < 0 {
+		return fmt.Errorf("feed.optimization.active_user.min_recent_score must not be negative, got %v", c.Feed.Optimization.ActiveUser.MinRecentScore)
+	}
+	if cc := c.Feed.Optimization.CacheCleanup; cc.BatchSize <= 0 || cc.BatchDelay < 0 {
+		return fmt.Errorf("feed.optimization.cache_cleanup requires positive batch_size and non-negative batch_delay, got %d/%s", cc.BatchSize, cc.BatchDelay)
+	}
+	if c.Feed.Optimization.CacheCleanup.MaxPerRun < 0 {
+		return fmt.Errorf("feed.optimization.cache_cleanup.max_per_run must not be negative, got %d", c.Feed.Optimization.CacheCleanup.MaxPerRun)
+	}
+	if dist := c.Feed.Optimization.Distribution; dist.FrequentPosterPerDay < 0 {
+		return fmt.Errorf("feed.optimization.distribution.frequent_poster_per_day must not be negative, got %d", dist.FrequentPosterPerDay)
+	} else if dist.MinActiveRatio < 0 || dist.MinActiveRatio > 1 {
+		return fmt.Errorf("feed.optimization.distribution.min_active_ratio must be in [0, 1], got %v", dist.MinActiveRatio)
+	} else if dist.MinActiveRatio > 0 && dist.ActiveSampleSize <= 0 {
+		return fmt.Errorf("feed.optimization.distribution.active_sample_size must be positive, got %d", dist.ActiveSampleSize)
+	}
+	if c.Feed.Optimization.Prewarm.MaxUsers < 0 {
+		return fmt.Errorf("feed.optimization.prewarm.max_users must not be negative, got %d", c.Feed.Optimization.Prewarm.MaxUsers)
+	}
+	if c.Feed.Optimization.Prewarm.Delay < 0 {
+		return fmt.Errorf("feed.optimization.prewarm.delay must not be negative, got %s", c.Feed.Optimization.Prewarm.Delay)
+	}
+	switch c.Feed.ContentSanitize {
+	case "off", "escape", "strip":
+	default:
+		return fmt.Errorf("feed.content_sanitize must be off, escape or strip, got %q", c.Feed.ContentSanitize)
+	}
+	switch c.Feed.EngagedRanking {
+	case "off", "penalize", "exclude":
+	default:
+		return fmt.Errorf("feed.engaged_ranking must be off, penalize or exclude, got %q", c.Feed.EngagedRanking)
+	}
+	if c.Feed.EngagedPenalty <= 0 || c.Feed.EngagedPenalty > 1 {
+		return fmt.Errorf("feed.engaged_penalty must be in (0, 1], got %v", c.Feed.EngagedPenalty)
+	}
+	if c.Feed.AuthorDiversity < 0 {
+		return fmt.Errorf("feed.author_diversity must not be negative, got %d", c.Feed.AuthorDiversity)
+	}
+	if c.Feed.BackfillCooldown < 0 {
+		return fmt.Errorf("feed.backfill_cooldown must not be negative, got %s", c.Feed.BackfillCooldown)
+	}
+	if c.Feed.SnapshotTTL < 0 {
+		return fmt.Errorf("feed.snapshot_ttl must not be negative, got %s", c.Feed.SnapshotTTL)
+	}
+	if c.Feed.PostCacheTTL < 0 {
+		return fmt.Errorf("feed.post_cache_ttl must not be negative, got %s", c.Feed.PostCacheTTL)
+	}
+	if c.Feed.RestoreGraceWindow < 0 {
+		return fmt.Errorf("feed.restore_grace_window must not be negative, got %s", c.Feed.RestoreGraceWindow)
+	}
+	if c.Feed.MaxPushAge < 0 {
+		return fmt.Errorf("feed.max_push_age must not be negative, got %s", c.Feed.MaxPushAge)
+	}
+	if c.Feed.RankUpdateInterval <= 0 {
+		return fmt.Errorf("feed.rank_update_interval must be positive, got %s", c.Feed.RankUpdateInterval)
+	}
+	if c.Feed.PullMergeMode != "" && c.Feed.PullMergeMode != "global" && c.Feed.PullMergeMode != "kway" {
+		return fmt.Errorf("feed.pull_merge_mode must be \"global\" or \"kway\", got %q", c.Feed.PullMergeMode)
+	}
+	if c.Feed.PullOverfetch < 1 || c.Feed.PullOverfetch > 10 {
+		return fmt.Errorf("feed.pull_overfetch must be between 1 and 10, got %d", c.Feed.PullOverfetch)
+	}
+	if c.Feed.PullMaxRounds < 1 {
+		return fmt.Errorf("feed.pull_max_rounds must be at least 1, got %d", c.Feed.PullMaxRounds)
+	}
+	if c.Feed.PushThreshold > 10000000 {
+		return fmt.Errorf("feed.push_threshold is unreasonably large: %d", c.Feed.PushThreshold)
+	}
+
+	return nil
+}
+
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode)
@@ -141,3 +547,25 @@ func (c *DatabaseConfig) DSN() string {
 func (c *RedisConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
+
+// ClientOptions 按部署模式生成Redis客户端配置，single模式使用host/port
+func (c *RedisConfig) ClientOptions() cache.Options {
+	addrs := c.Addrs
+	if c.Mode == cache.ModeSingle {
+		addrs = []string{c.Addr()}
+	}
+	return cache.Options{
+		Mode:         c.Mode,
+		Addrs:        addrs,
+		MasterName:   c.MasterName,
+		Password:     c.Password,
+		DB:           c.DB,
+		PoolSize:     c.PoolSize,
+		MinIdleConns: c.MinIdleConns,
+	}
+}
+
+// IsPushEligible 判断帖子是否足够新，可以被推送到Timeline
+func (c *FeedConfig) IsPushEligible(createdAt time.Time) bool {
+	return c.MaxPushAge <= 0 || time.Since(createdAt) <= c.MaxPushAge
+}
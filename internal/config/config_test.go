@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// loadDeployedConfig 取出k8s ConfigMap中的config.yaml写入临时文件，并让LoadConfig读取它
+func loadDeployedConfig(t *testing.T) string {
+	t.Helper()
+
+	raw, err := os.ReadFile("../../deployments/k8s/configmap.yaml")
+	if err != nil {
+		t.Fatalf("failed to read configmap: %v", err)
+	}
+	var configMap struct {
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(raw, &configMap); err != nil {
+		t.Fatalf("failed to parse configmap: %v", err)
+	}
+	content, ok := configMap.Data["config.yaml"]
+	if !ok {
+		t.Fatal("configmap has no config.yaml")
+	}
+	return content
+}
+
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+}
+
+// 部署的ConfigMap不带JWT密钥，必须能通过Secret注入的环境变量在release模式下启动
+func TestLoadConfigDeployedConfigMapWithSecretEnv(t *testing.T) {
+	content := loadDeployedConfig(t)
+	if strings.Contains(content, "secret:") {
+		t.Fatal("configmap must not ship a jwt secret")
+	}
+
+	writeConfig(t, content)
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "injected-secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Server.Mode != "release" {
+		t.Fatalf("server.mode = %q, want release", cfg.Server.Mode)
+	}
+	if cfg.JWT.Secret != "injected-secret" {
+		t.Errorf("jwt.secret = %q, want injected-secret", cfg.JWT.Secret)
+	}
+}
+
+func TestLoadConfigRejectsMissingJWTSecret(t *testing.T) {
+	writeConfig(t, loadDeployedConfig(t))
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "")
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "jwt.secret") {
+		t.Errorf("LoadConfig() error = %v, want jwt.secret error", err)
+	}
+}
+
+// 占位密钥在任何模式下都被拒绝，不只是release
+func TestLoadConfigRejectsPlaceholderSecret(t *testing.T) {
+	for _, mode := range []string{"release", "debug", "test"} {
+		for _, placeholder := range placeholderJWTSecrets {
+			t.Run(mode, func(t *testing.T) {
+				writeConfig(t, loadDeployedConfig(t))
+				t.Setenv("FEEDSYSTEM_SERVER_MODE", mode)
+				t.Setenv("FEEDSYSTEM_JWT_SECRET", placeholder)
+
+				if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "placeholder") {
+					t.Errorf("LoadConfig() error = %v, want placeholder error", err)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadConfigEnvOverridesYAML(t *testing.T) {
+	writeConfig(t, loadDeployedConfig(t))
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "injected-secret")
+	t.Setenv("FEEDSYSTEM_DATABASE_PASSWORD", "from-env")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Database.Password != "from-env" {
+		t.Errorf("database.password = %q, want from-env", cfg.Database.Password)
+	}
+}
+
+func TestLoadConfigRankUpdateInterval(t *testing.T) {
+	writeConfig(t, loadDeployedConfig(t))
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "injected-secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Feed.RankUpdateInterval != 5*time.Minute {
+		t.Errorf("feed.rank_update_interval = %s, want 5m", cfg.Feed.RankUpdateInterval)
+	}
+
+	for _, interval := range []string{"0s", "-1m"} {
+		viper.Reset()
+		t.Setenv("FEEDSYSTEM_FEED_RANK_UPDATE_INTERVAL", interval)
+		if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "rank_update_interval") {
+			t.Errorf("LoadConfig() with interval %s error = %v, want rank_update_interval error", interval, err)
+		}
+	}
+}
+
+// v1和优化版Feed Worker消费同一个topic，必须各自使用独立的消费组
+func TestLoadConfigFeedWorkersUseSeparateConsumerGroups(t *testing.T) {
+	writeConfig(t, loadDeployedConfig(t))
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "injected-secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	groups := cfg.Kafka.ConsumerGroups
+	seen := map[string]string{}
+	for worker, group := range map[string]string{
+		"feed_worker":           groups.FeedWorker,
+		"optimized_feed_worker": groups.OptimizedFeedWorker,
+		"user_event_worker":     groups.UserEventWorker,
+	} {
+		if group == "" {
+			t.Errorf("%s has no consumer group", worker)
+		}
+		if other, ok := seen[group]; ok {
+			t.Errorf("%s and %s share consumer group %q", worker, other, group)
+		}
+		seen[group] = worker
+	}
+
+	viper.Reset()
+	t.Setenv("FEEDSYSTEM_KAFKA_CONSUMER_GROUPS_OPTIMIZED_FEED_WORKER", groups.FeedWorker)
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "must differ") {
+		t.Errorf("LoadConfig() with a shared feed consumer group error = %v, want must differ error", err)
+	}
+}
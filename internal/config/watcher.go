@@ -0,0 +1,65 @@
+package config
+
+import (
+	"sync/atomic"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ConfigWatcher 监听配置文件变化，支持不重启热更新Feed配置
+// 服务通过Feed()读取当前配置，不要长期持有返回的指针
+type ConfigWatcher struct {
+	feed   atomic.Value // *FeedConfig
+	logger *logger.Logger
+}
+
+func NewConfigWatcher(feed *FeedConfig, logger *logger.Logger) *ConfigWatcher {
+	w := &ConfigWatcher{logger: logger}
+	w.Store(feed)
+	return w
+}
+
+// Feed 获取当前生效的Feed配置
+func (w *ConfigWatcher) Feed() *FeedConfig {
+	return w.feed.Load().(*FeedConfig)
+}
+
+// Store 原子替换Feed配置
+func (w *ConfigWatcher) Store(feed *FeedConfig) {
+	copied := *feed
+	w.feed.Store(&copied)
+}
+
+// Watch 开始监听配置文件，文件变化时重新加载并校验
+func (w *ConfigWatcher) Watch() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		w.reload(e.Name)
+	})
+	viper.WatchConfig()
+}
+
+// reload 重新解析配置，校验失败时保留旧配置
+func (w *ConfigWatcher) reload(source string) {
+	var cfg Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		w.logger.WithError(err).WithField("file", source).Error("Failed to unmarshal reloaded config")
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		w.logger.WithError(err).WithField("file", source).Error("Reloaded config is invalid, keeping previous config")
+		return
+	}
+
+	old := w.Feed()
+	w.Store(&cfg.Feed)
+
+	w.logger.WithFields(map[string]interface{}{
+		"file":               source,
+		"push_threshold":     cfg.Feed.PushThreshold,
+		"old_push_threshold": old.PushThreshold,
+		"cache_ttl":          cfg.Feed.CacheTTL.String(),
+		"max_feed_size":      cfg.Feed.MaxFeedSize,
+	}).Info("Feed config reloaded")
+}
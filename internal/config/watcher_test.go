@@ -0,0 +1,52 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+func TestConfigWatcherReloadsFeedConfig(t *testing.T) {
+	content := loadDeployedConfig(t)
+	writeConfig(t, content)
+	t.Setenv("FEEDSYSTEM_JWT_SECRET", "injected-secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	watcher := NewConfigWatcher(&cfg.Feed, logger.NewLogger())
+	watcher.Watch()
+
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(os.Getenv("CONFIG_PATH"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(want int) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if watcher.Feed().PushThreshold == want {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	rewrite(strings.Replace(content, "push_threshold: 5000", "push_threshold: 200", 1))
+	if !waitFor(200) {
+		t.Fatalf("push_threshold = %d after reload, want 200", watcher.Feed().PushThreshold)
+	}
+
+	// 校验失败的配置不生效，保留上一次的配置
+	rewrite(strings.Replace(content, "max_feed_size: 1000", "max_feed_size: -1", 1))
+	time.Sleep(200 * time.Millisecond)
+	if got := watcher.Feed(); got.PushThreshold != 200 || got.MaxFeedSize != 1000 {
+		t.Errorf("invalid reload applied: push_threshold = %d, max_feed_size = %d", got.PushThreshold, got.MaxFeedSize)
+	}
+}
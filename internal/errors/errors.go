@@ -0,0 +1,46 @@
+// Package errors 定义服务层返回的错误类别，handler据此映射HTTP状态码
+package errors
+
+import "errors"
+
+// 错误类别，使用errors.Is判断
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrInvalidInput     = errors.New("invalid input")
+)
+
+// Error 带类别的错误，Error()返回具体描述，Unwrap()返回类别
+type Error struct {
+	kind    error
+	message string
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// NotFound 资源不存在
+func NotFound(message string) error {
+	return &Error{kind: ErrNotFound, message: message}
+}
+
+// PermissionDenied 无权操作该资源
+func PermissionDenied(message string) error {
+	return &Error{kind: ErrPermissionDenied, message: message}
+}
+
+// AlreadyExists 资源已存在或操作已执行过
+func AlreadyExists(message string) error {
+	return &Error{kind: ErrAlreadyExists, message: message}
+}
+
+// InvalidInput 请求参数不合法
+func InvalidInput(message string) error {
+	return &Error{kind: ErrInvalidInput, message: message}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// 错误码，客户端据此区分错误类型，message仅供展示
+const (
+	CodeInvalidRequest  = "invalid_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeGone            = "gone"
+	CodeContentRejected = "content_rejected"
+	CodeInternal        = "internal_error"
+)
+
+// respondError 以统一结构返回错误
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, middleware.ErrorBody(c, code, message))
+}
+
+// internalErrorMessage 5xx错误返回给客户端的通用信息
+const internalErrorMessage = "Internal server error"
+
+// respondServiceError 根据服务层错误类别返回错误，未归类的错误使用fallback状态码。
+// 5xx错误不向客户端暴露内部错误信息，原始错误记录在c.Errors中
+func respondServiceError(c *gin.Context, err error, fallback int) {
+	status := errorStatus(err, fallback)
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		_ = c.Error(err)
+		message = internalErrorMessage
+	}
+	respondError(c, status, errorCode(status), message)
+}
+
+// errorStatus 根据服务层错误类别确定HTTP状态码，未归类的错误使用fallback
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrInvalidInput):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
+
+// errorCode HTTP状态码对应的默认错误码
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusUnprocessableEntity:
+		return CodeContentRejected
+	default:
+		return CodeInternal
+	}
+}
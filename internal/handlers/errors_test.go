@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondServiceError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		err         error
+		fallback    int
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"not found keeps message", apperrors.NotFound("post not found"), http.StatusInternalServerError, http.StatusNotFound, CodeNotFound, "post not found"},
+		{"invalid input keeps message", apperrors.InvalidInput("bad cursor"), http.StatusInternalServerError, http.StatusBadRequest, CodeInvalidRequest, "bad cursor"},
+		{"wrapped not found", fmt.Errorf("failed to load feed: %w", apperrors.NotFound("user not found")), http.StatusBadRequest, http.StatusNotFound, CodeNotFound, "failed to load feed: user not found"},
+		{"permission denied", apperrors.PermissionDenied("not the post author"), http.StatusBadRequest, http.StatusForbidden, CodeForbidden, "not the post author"},
+		{"already exists", apperrors.AlreadyExists("already following"), http.StatusBadRequest, http.StatusConflict, CodeConflict, "already following"},
+		{"untyped error uses fallback", errors.New("invalid user ID"), http.StatusBadRequest, http.StatusBadRequest, CodeInvalidRequest, "invalid user ID"},
+		{"internal error hides details", fmt.Errorf("failed to get user: %w", errors.New("dial tcp 10.0.0.5:5432: connection refused")), http.StatusInternalServerError, http.StatusInternalServerError, CodeInternal, internalErrorMessage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			respondServiceError(c, tt.err, tt.fallback)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var body struct {
+				Error struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error.Code != tt.wantCode || body.Error.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want code %q message %q", body.Error, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
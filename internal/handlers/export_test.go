@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestExportRouter 基于sqlmock的导出接口，请求以userID身份发出
+func newTestExportRouter(t *testing.T, userID string) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+
+	feedService := services.NewFeedService(repository.NewPostRepository(db), nil, nil, nil, nil, repository.NewCommentRepository(db),
+		redisClient, nil, nil, logger.NewLogger(), nil, nil)
+	handler := NewFeedHandler(feedService, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	router.GET("/users/me/export", handler.ExportMyData)
+	return router, mock
+}
+
+// readExport 逐行解析NDJSON导出内容
+func readExport(t *testing.T, body string) []services.ExportRecord {
+	t.Helper()
+
+	var records []services.ExportRecord
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var record services.ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid export line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestExportMyDataStreamsPostsThenComments(t *testing.T) {
+	userID := uuid.New()
+	router, mock := newTestExportRouter(t, userID.String())
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// 第一页帖子正好ExportPageSize条，需要再按游标读取下一页
+	postRows := sqlmock.NewRows([]string{"id", "user_id", "content", "like_count", "created_at"})
+	var lastPostID uuid.UUID
+	for i := 0; i < services.ExportPageSize; i++ {
+		lastPostID = uuid.New()
+		postRows.AddRow(lastPostID, userID, "post", i, base.Add(time.Duration(i)*time.Second))
+	}
+	lastPostAt := base.Add(time.Duration(services.ExportPageSize-1) * time.Second)
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(user_id = \$1 AND is_deleted = \$2\) AND "posts"."deleted_at" IS NULL ORDER BY created_at ASC, id ASC LIMIT 500`).
+		WithArgs(userID, false).
+		WillReturnRows(postRows)
+	finalPostID := uuid.New()
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(user_id = \$1 AND is_deleted = \$2\) AND \(created_at, id\) > \(\$3, \$4\)`).
+		WithArgs(userID, false, lastPostAt, lastPostID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "created_at"}).
+			AddRow(finalPostID, userID, "last post", lastPostAt.Add(time.Second)))
+
+	commentID, parentID := uuid.New(), uuid.New()
+	mock.ExpectQuery(`SELECT \* FROM "comments" WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "post_id", "parent_id", "content", "like_count", "created_at"}).
+			AddRow(commentID, userID, finalPostID, parentID, "a reply", 2, base))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export", nil))
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status = %d, content type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, userID.String()) {
+		t.Errorf("Content-Disposition = %q, want the user ID in the file name", got)
+	}
+
+	records := readExport(t, w.Body.String())
+	if len(records) != services.ExportPageSize+2 {
+		t.Fatalf("got %d records, want %d", len(records), services.ExportPageSize+2)
+	}
+	for i, record := range records[:services.ExportPageSize+1] {
+		if record.Type != services.ExportRecordPost || record.Post == nil || record.Comment != nil {
+			t.Fatalf("record %d = %+v, want a post", i, record)
+		}
+	}
+	if records[0].Post.LikeCount != 0 || records[1].Post.LikeCount != 1 || records[services.ExportPageSize].Post.ID != finalPostID {
+		t.Errorf("posts not exported in order")
+	}
+	comment := records[len(records)-1]
+	if comment.Type != services.ExportRecordComment || comment.Comment == nil || comment.Comment.ID != commentID ||
+		comment.Comment.PostID != finalPostID || comment.Comment.ParentID == nil || *comment.Comment.ParentID != parentID ||
+		comment.Comment.Content != "a reply" || comment.Comment.LikeCount != 2 {
+		t.Errorf("last record = %+v, want the comment", comment)
+	}
+}
+
+func TestExportMyDataFailures(t *testing.T) {
+	t.Run("failure after streaming started ends with an error record", func(t *testing.T) {
+		userID := uuid.New()
+		router, mock := newTestExportRouter(t, userID.String())
+		postID := uuid.New()
+		mock.ExpectQuery(`SELECT \* FROM "posts"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content"}).AddRow(postID, userID, "post"))
+		mock.ExpectQuery(`SELECT \* FROM "comments"`).WillReturnError(errors.New("connection reset"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export", nil))
+
+		records := readExport(t, w.Body.String())
+		if w.Code != http.StatusOK || len(records) != 2 {
+			t.Fatalf("status = %d, records = %+v", w.Code, records)
+		}
+		if records[0].Post == nil || records[0].Post.ID != postID {
+			t.Errorf("first record = %+v, want the post", records[0])
+		}
+		if last := records[1]; last.Type != services.ExportRecordError || last.Error == "" || strings.Contains(last.Error, "connection") {
+			t.Errorf("last record = %+v, want a generic error record", last)
+		}
+	})
+
+	t.Run("failure before any data is a 500", func(t *testing.T) {
+		router, mock := newTestExportRouter(t, uuid.NewString())
+		mock.ExpectQuery(`SELECT \* FROM "posts"`).WillReturnError(errors.New("db down"))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export", nil))
+		if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") == "application/x-ndjson" {
+			t.Errorf("status = %d, content type = %q; want a JSON 500", w.Code, w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("user without data gets an empty file", func(t *testing.T) {
+		router, mock := newTestExportRouter(t, uuid.NewString())
+		mock.ExpectQuery(`SELECT \* FROM "posts"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(`SELECT \* FROM "comments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/export", nil))
+		if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("status = %d, body = %q; want an empty NDJSON file", w.Code, w.Body.String())
+		}
+	})
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/middleware"
 	"github.com/feed-system/feed-system/internal/services"
@@ -26,19 +30,23 @@ func NewFeedHandler(feedService *services.FeedService, likeService *services.Lik
 func (h *FeedHandler) CreatePost(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req services.CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	post, err := h.feedService.CreatePost(c.Request.Context(), userID, &req)
+	if errors.Is(err, services.ErrContentRejected) {
+		respondError(c, http.StatusUnprocessableEntity, CodeContentRejected, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -51,54 +59,46 @@ func (h *FeedHandler) CreatePost(c *gin.Context) {
 func (h *FeedHandler) GetFeed(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	cursor := c.Query("cursor")
-	limit := 20
-	if l := c.Query("limit"); l != "" {
-		if parsedLimit, err := strconv.Atoi(l); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
-		}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	cursor, limit := page.Cursor, page.Limit
+
+	// debug模式返回每个帖子的分数组成，仅管理员可用
+	debug := c.Query("debug") == "true"
+	if debug && !middleware.IsAdmin(c) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "Admin privileges required for debug mode")
+		return
 	}
 
 	feed, err := h.feedService.GetFeed(c.Request.Context(), userID, cursor, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	if debug {
+		feed.ScoreBreakdown = h.feedService.ExplainScores(feed.Posts)
+	}
+
 	c.JSON(http.StatusOK, feed)
 }
 
 func (h *FeedHandler) GetUserPosts(c *gin.Context) {
 	targetUserID := c.Param("id")
 	if targetUserID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "User ID is required")
 		return
 	}
 
-	offset := 0
-	limit := 20
-	query := struct {
-		Offset int `form:"offset"`
-		Limit  int `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&query); err == nil {
-		offset = query.Offset
-		limit = query.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
-	}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
 
-	posts, err := h.feedService.GetUserPosts(c.Request.Context(), targetUserID, offset, limit)
+	posts, err := h.feedService.GetUserPosts(c.Request.Context(), middleware.GetUserID(c), targetUserID, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -109,16 +109,48 @@ func (h *FeedHandler) GetUserPosts(c *gin.Context) {
 	})
 }
 
+// GetLikedPosts 获取用户赞过的帖子，按点赞时间倒序游标分页
+func (h *FeedHandler) GetLikedPosts(c *gin.Context) {
+	targetUserID := c.Param("id")
+	if targetUserID == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "User ID is required")
+		return
+	}
+
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+
+	result, err := h.feedService.GetLikedPosts(c.Request.Context(), middleware.GetUserID(c), targetUserID, page.Cursor, page.Limit)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":       result.Posts,
+		"next_cursor": result.NextCursor,
+		"has_more":    result.HasMore,
+		"limit":       page.Limit,
+	})
+}
+
 func (h *FeedHandler) GetPost(c *gin.Context) {
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
-	post, err := h.feedService.GetPostByID(c.Request.Context(), postID)
+	// hydrate参数可覆盖默认配置，流量敏感的客户端可以跳过查看者状态
+	hydrate := h.feedService.ViewerStateEnabled()
+	if v := c.Query("hydrate"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			hydrate = parsed
+		}
+	}
+
+	post, err := h.feedService.GetPostForViewer(c.Request.Context(), postID, middleware.GetUserID(c), hydrate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -128,60 +160,121 @@ func (h *FeedHandler) GetPost(c *gin.Context) {
 func (h *FeedHandler) DeletePost(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
 	if err := h.feedService.DeletePost(c.Request.Context(), userID, postID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
+// RestorePost 在宽限期内恢复自己删除的帖子
+func (h *FeedHandler) RestorePost(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID := c.Param("id")
+	if postID == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
+		return
+	}
+
+	post, err := h.feedService.RestorePost(c.Request.Context(), userID, postID)
+	if errors.Is(err, services.ErrRestoreWindowExpired) {
+		respondError(c, http.StatusGone, CodeGone, err.Error())
+		return
+	}
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post restored successfully",
+		"post":    post,
+	})
+}
+
 func (h *FeedHandler) LikePost(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
 	if err := h.likeService.LikePost(c.Request.Context(), userID, postID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Post liked successfully"})
 }
 
+// ReactToPost 对帖子做出回应（like、love、haha等），已有回应时切换为新的类型
+func (h *FeedHandler) ReactToPost(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	postID := c.Param("id")
+	if postID == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
+		return
+	}
+
+	var req services.ReactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.likeService.React(c.Request.Context(), userID, postID, req.Type); err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Reaction updated successfully",
+		"reaction_type": req.Type,
+	})
+}
+
 func (h *FeedHandler) UnlikePost(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
 	if err := h.likeService.UnlikePost(c.Request.Context(), userID, postID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -191,30 +284,16 @@ func (h *FeedHandler) UnlikePost(c *gin.Context) {
 func (h *FeedHandler) GetPostLikes(c *gin.Context) {
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
-	offset := 0
-	limit := 20
-	query := struct {
-		Offset int `form:"offset"`
-		Limit  int `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&query); err == nil {
-		offset = query.Offset
-		limit = query.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
-	}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
 
-	likes, err := h.likeService.GetPostLikes(c.Request.Context(), postID, offset, limit)
+	likes, err := h.likeService.GetPostLikes(c.Request.Context(), middleware.GetUserID(c), postID, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -228,25 +307,29 @@ func (h *FeedHandler) GetPostLikes(c *gin.Context) {
 func (h *FeedHandler) CreateComment(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
 	var req services.CreateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	comment, err := h.commentService.CreateComment(c.Request.Context(), userID, postID, &req)
+	if errors.Is(err, services.ErrContentRejected) {
+		respondError(c, http.StatusUnprocessableEntity, CodeContentRejected, err.Error())
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -259,55 +342,57 @@ func (h *FeedHandler) CreateComment(c *gin.Context) {
 func (h *FeedHandler) GetPostComments(c *gin.Context) {
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
-	offset := 0
-	limit := 20
-	query := struct {
-		Offset int `form:"offset"`
-		Limit  int `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&query); err == nil {
-		offset = query.Offset
-		limit = query.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+
+	// 兼容旧客户端：只传offset时仍按偏移量分页，新评论插入时可能重复或遗漏
+	if page.Cursor == "" && page.Offset > 0 {
+		comments, err := h.commentService.GetPostComments(c.Request.Context(), middleware.GetUserID(c), postID, page.Offset, page.Limit)
+		if err != nil {
+			respondServiceError(c, err, http.StatusBadRequest)
+			return
 		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"comments": comments,
+			"offset":   page.Offset,
+			"limit":    page.Limit,
+		})
+		return
 	}
 
-	comments, err := h.commentService.GetPostComments(c.Request.Context(), postID, offset, limit)
+	result, err := h.commentService.GetPostCommentsPage(c.Request.Context(), middleware.GetUserID(c), postID, page.Cursor, page.Limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-		"offset":   offset,
-		"limit":    limit,
+		"comments":    result.Comments,
+		"next_cursor": result.NextCursor,
+		"has_more":    result.HasMore,
+		"limit":       page.Limit,
 	})
 }
 
 func (h *FeedHandler) DeleteComment(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	commentID := c.Param("id")
 	if commentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Comment ID is required")
 		return
 	}
 
 	if err := h.commentService.DeleteComment(c.Request.Context(), userID, commentID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -317,32 +402,16 @@ func (h *FeedHandler) DeleteComment(c *gin.Context) {
 func (h *FeedHandler) SearchPosts(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is required"})
-		return
-	}
-
-	offset := 0
-	limit := 20
-	queryParams := struct {
-		Query  string `form:"q"`
-		Offset int    `form:"offset"`
-		Limit  int    `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&queryParams); err == nil {
-		query = queryParams.Query
-		offset = queryParams.Offset
-		limit = queryParams.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Query is required")
+		return
 	}
 
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
+
 	posts, err := h.feedService.SearchPosts(c.Request.Context(), query, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -352,4 +421,89 @@ func (h *FeedHandler) SearchPosts(c *gin.Context) {
 		"offset": offset,
 		"limit":  limit,
 	})
-}
\ No newline at end of file
+}
+
+func (h *FeedHandler) BumpFeedCacheVersion(c *gin.Context) {
+	version, err := h.feedService.BumpFeedCacheVersion(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cache_version": version})
+}
+
+func (h *FeedHandler) GetMyStats(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	days := 30
+	if d := c.Query("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 && parsed <= services.MaxUserStatsWindowDays {
+			days = parsed
+		}
+	}
+
+	stats, err := h.feedService.GetUserStats(c.Request.Context(), userID, days)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stats": stats})
+}
+
+// exportPageWriteTimeout 导出时写出每一页的超时时间
+const exportPageWriteTimeout = 30 * time.Second
+
+// ExportMyData 以NDJSON流式导出当前用户的全部帖子和评论，每行一条记录
+func (h *FeedHandler) ExportMyData(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%s.ndjson"`, userID))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+	}
+
+	// 导出总耗时可能超过服务器的WriteTimeout，每写一页顺延一次写超时
+	rc := http.NewResponseController(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	err := h.feedService.ExportUserData(c.Request.Context(), userID, func(records []services.ExportRecord) error {
+		if !started {
+			start()
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(exportPageWriteTimeout))
+		for i := range records {
+			if err := encoder.Encode(&records[i]); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && !started {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		// 响应头已发送，无法再返回错误状态码，以最后一行错误记录告知客户端导出不完整
+		c.Error(err)
+		encoder.Encode(services.ExportRecord{Type: services.ExportRecordError, Error: "export interrupted"})
+		return
+	}
+	if !started {
+		// 没有任何数据时返回空文件
+		start()
+	}
+}
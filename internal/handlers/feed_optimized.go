@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/middleware"
 	"github.com/feed-system/feed-system/internal/services"
 	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -17,6 +21,9 @@ type OptimizedFeedHandler struct {
 	activityService      *services.ActivityService
 	cacheStrategyService *services.CacheStrategyService
 	recoveryService      *services.RecoveryService
+	sloService           *services.SLOService
+	feedConsumer         *queue.KafkaConsumer
+	adminStats           *services.AdminStatsService
 	logger               *logger.Logger
 }
 
@@ -25,6 +32,9 @@ func NewOptimizedFeedHandler(
 	activityService *services.ActivityService,
 	cacheStrategyService *services.CacheStrategyService,
 	recoveryService *services.RecoveryService,
+	sloService *services.SLOService,
+	feedConsumer *queue.KafkaConsumer,
+	adminStats *services.AdminStatsService,
 	logger *logger.Logger,
 ) *OptimizedFeedHandler {
 	return &OptimizedFeedHandler{
@@ -32,6 +42,9 @@ func NewOptimizedFeedHandler(
 		activityService:      activityService,
 		cacheStrategyService: cacheStrategyService,
 		recoveryService:      recoveryService,
+		sloService:           sloService,
+		feedConsumer:         feedConsumer,
+		adminStats:           adminStats,
 		logger:               logger,
 	}
 }
@@ -45,12 +58,18 @@ func (h *OptimizedFeedHandler) RegisterRoutes(r *gin.RouterGroup, jwtConfig *mid
 		auth.POST("/posts", h.CreatePost)
 		auth.GET("/feed", h.GetFeed)
 		auth.DELETE("/posts/:id", h.DeletePost)
+		auth.GET("/posts/preview-reach", h.PreviewReach)
 
 		// 管理相关路由
 		auth.GET("/admin/cache-stats", h.GetCacheStats)
 		auth.GET("/admin/distribution-stats", h.GetDistributionStats)
 		auth.POST("/admin/recover-distributions", h.RecoverDistributions)
 		auth.POST("/admin/cleanup-cache", h.CleanupCache)
+		auth.GET("/admin/slo", middleware.RequireAdmin(), h.GetSLOStatus)
+		auth.GET("/admin/consumer-lag", middleware.RequireAdmin(), h.GetConsumerLag)
+		auth.GET("/admin/stats", middleware.RequireAdmin(), h.GetAdminStats)
+		auth.POST("/admin/users/:id/cache-strategy-override", middleware.RequireAdmin(), h.SetCacheStrategyOverride)
+		auth.GET("/admin/users/:id/feed", middleware.RequireAdmin(), h.InspectUserFeed)
 
 		// 用户活跃度相关
 		auth.GET("/user/activity-status", h.GetUserActivityStatus)
@@ -62,20 +81,28 @@ func (h *OptimizedFeedHandler) RegisterRoutes(r *gin.RouterGroup, jwtConfig *mid
 func (h *OptimizedFeedHandler) CreatePost(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	var req services.CreatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	post, err := h.feedService.CreatePost(c.Request.Context(), userID, &req)
+	if errors.Is(err, services.ErrContentRejected) {
+		respondError(c, http.StatusUnprocessableEntity, CodeContentRejected, err.Error())
+		return
+	}
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to create post")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to create post")
 		return
 	}
 
@@ -83,42 +110,127 @@ func (h *OptimizedFeedHandler) CreatePost(c *gin.Context) {
 }
 
 // GetFeed 获取Feed（优化版 - 使用游标分页）
+// cursor向更旧的内容翻页，since只返回比它更新的内容（增量轮询），两者不能同时使用
 func (h *OptimizedFeedHandler) GetFeed(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	cursor := c.Query("cursor")
-	limit := 20 // 默认限制
-	if l := c.Query("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	cursor, limit := page.Cursor, page.Limit
+
+	sortMode := c.DefaultQuery("sort", "latest")
+	if sortMode != "latest" && sortMode != "top" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "sort must be latest or top")
+		return
+	}
+
+	if since := c.Query("since"); since != "" {
+		if sortMode == "top" {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "since is not supported with sort=top")
+			return
+		}
+		if cursor != "" {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "cursor and since cannot be used together")
+			return
+		}
+		response, err := h.feedService.GetFeedSince(c.Request.Context(), userID, since, limit)
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid since cursor")
+			return
+		}
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get new feed items")
+			respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get feed")
+			return
 		}
+
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// diversity限制同一作者在页内最多连续出现的条数，0表示关闭，不传时使用配置
+	ctx := c.Request.Context()
+	if v := c.Query("diversity"); v != "" {
+		maxConsecutive, err := strconv.Atoi(v)
+		if err != nil || maxConsecutive < 0 {
+			respondError(c, http.StatusBadRequest, CodeInvalidRequest, "diversity must be a non-negative integer")
+			return
+		}
+		ctx = services.WithAuthorDiversity(ctx, maxConsecutive)
 	}
 
-	response, err := h.feedService.GetFeed(c.Request.Context(), userID, cursor, limit)
+	start := time.Now()
+	var response *services.FeedResponse
+	var err error
+	if sortMode == "top" {
+		response, err = h.feedService.GetTopFeed(ctx, userID, cursor, limit)
+	} else {
+		response, err = h.feedService.GetFeed(ctx, userID, cursor, limit)
+	}
+	h.sloService.Record(time.Since(start))
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get feed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get feed"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get feed")
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// InspectUserFeed 管理员查看指定用户看到的Feed，参数与GetFeed相同（不支持since）
+func (h *OptimizedFeedHandler) InspectUserFeed(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	limit := page.Limit
+
+	sortMode := c.DefaultQuery("sort", "latest")
+	if sortMode != "latest" && sortMode != "top" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "sort must be latest or top")
+		return
+	}
+
+	response, err := h.feedService.InspectFeed(c.Request.Context(), userUUID.String(), page.Cursor, limit, sortMode == "top")
+	if errors.Is(err, apperrors.ErrInvalidInput) {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to inspect user feed")
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get feed")
+		return
+	}
+
+	h.logger.WithFields(map[string]interface{}{
+		"admin_id": c.GetString("user_id"),
+		"user_id":  userUUID.String(),
+	}).Info("Admin inspected user feed")
+
+	c.JSON(http.StatusOK, response)
+}
+
 // DeletePost 删除帖子
 func (h *OptimizedFeedHandler) DeletePost(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Post ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Post ID is required")
 		return
 	}
 
@@ -126,12 +238,30 @@ func (h *OptimizedFeedHandler) DeletePost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
+// PreviewReach 预估发帖的推送范围
+func (h *OptimizedFeedHandler) PreviewReach(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+		return
+	}
+
+	preview, err := h.feedService.PreviewReach(c.Request.Context(), userID, c.Query("visibility"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to preview post reach")
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reach": preview})
+}
+
 // GetCacheStats 获取缓存统计信息
 func (h *OptimizedFeedHandler) GetCacheStats(c *gin.Context) {
 	stats, err := h.cacheStrategyService.GetCacheStats(c.Request.Context())
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get cache stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cache stats"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get cache stats")
 		return
 	}
 
@@ -143,18 +273,76 @@ func (h *OptimizedFeedHandler) GetDistributionStats(c *gin.Context) {
 	stats, err := h.recoveryService.GetDistributionStats(c.Request.Context())
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get distribution stats")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get distribution stats"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get distribution stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"distribution_stats": stats,
+		"fanout_queue":       h.feedService.FanoutStats(),
+	})
+}
+
+// GetSLOStatus 获取Feed延迟SLO状态
+func (h *OptimizedFeedHandler) GetSLOStatus(c *gin.Context) {
+	status := h.sloService.Status(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{"slo": status})
+}
+
+// GetConsumerLag 获取Feed事件消费者的延迟
+func (h *OptimizedFeedHandler) GetConsumerLag(c *gin.Context) {
+	lag, err := h.feedConsumer.Lag(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get consumer lag")
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get consumer lag")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consumer_lag": lag})
+}
+
+// GetAdminStats 获取运维面板的聚合统计，单项失败不影响其他项
+func (h *OptimizedFeedHandler) GetAdminStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.adminStats.Collect(c.Request.Context()))
+}
+
+// SetCacheStrategyOverride 强制指定用户的缓存策略档位，tier为空表示清除覆盖
+func (h *OptimizedFeedHandler) SetCacheStrategyOverride(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		Tier string `json:"tier" binding:"omitempty,oneof=active inactive vip"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.cacheStrategyService.SetCacheStrategyOverride(c.Request.Context(), userUUID, req.Tier); err != nil {
+		h.logger.WithError(err).Error("Failed to set cache strategy override")
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to set cache strategy override")
+		return
+	}
+
+	strategy, err := h.cacheStrategyService.GetUserCacheStrategy(c.Request.Context(), userUUID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cache strategy")
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to get cache strategy")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"distribution_stats": stats})
+	c.JSON(http.StatusOK, gin.H{"cache_strategy": strategy})
 }
 
 // RecoverDistributions 手动触发分发恢复
 func (h *OptimizedFeedHandler) RecoverDistributions(c *gin.Context) {
 	if err := h.recoveryService.RecoverPendingDistributions(c.Request.Context()); err != nil {
 		h.logger.WithError(err).Error("Failed to recover distributions")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recover distributions"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to recover distributions")
 		return
 	}
 
@@ -165,7 +353,7 @@ func (h *OptimizedFeedHandler) RecoverDistributions(c *gin.Context) {
 func (h *OptimizedFeedHandler) CleanupCache(c *gin.Context) {
 	if err := h.cacheStrategyService.CleanupInactiveUserCaches(c.Request.Context()); err != nil {
 		h.logger.WithError(err).Error("Failed to cleanup cache")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cleanup cache"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to cleanup cache")
 		return
 	}
 
@@ -176,20 +364,20 @@ func (h *OptimizedFeedHandler) CleanupCache(c *gin.Context) {
 func (h *OptimizedFeedHandler) GetUserActivityStatus(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	isActive, err := h.activityService.IsUserActive(c.Request.Context(), userUUID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to check user activity")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user activity"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to check user activity")
 		return
 	}
 
@@ -216,7 +404,7 @@ func (h *OptimizedFeedHandler) GetUserActivityStatus(c *gin.Context) {
 func (h *OptimizedFeedHandler) UpdateUserActivity(c *gin.Context) {
 	userID := c.GetString("user_id")
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -225,19 +413,19 @@ func (h *OptimizedFeedHandler) UpdateUserActivity(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
-	if err := h.activityService.UpdateUserActivity(c.Request.Context(), userUUID, req.ActivityType); err != nil {
+	if err := h.feedService.RecordActivity(c.Request.Context(), userUUID, req.ActivityType); err != nil {
 		h.logger.WithError(err).Error("Failed to update user activity")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user activity"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to update user activity")
 		return
 	}
 
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列表接口的默认页大小和最大页大小，启动时可通过SetPaginationLimits按配置覆盖
+var (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// SetPaginationLimits 设置列表接口的默认页大小和最大页大小，需在注册路由前调用
+func SetPaginationLimits(defaultLimit, maxLimit int) {
+	defaultPageLimit = defaultLimit
+	maxPageLimit = maxLimit
+}
+
+// pagination 列表接口的分页参数
+type pagination struct {
+	Offset int
+	Limit  int
+	Cursor string
+}
+
+// parsePagination 解析offset、limit和cursor查询参数。limit缺省或无法解析时使用defaultLimit，
+// 超出范围时截断到[1, maxLimit]；offset无法解析或为负数时为0
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) pagination {
+	page := pagination{
+		Limit:  defaultLimit,
+		Cursor: c.Query("cursor"),
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			page.Limit = parsed
+		}
+	}
+	if page.Limit > maxLimit {
+		page.Limit = maxLimit
+	}
+	if page.Limit < 1 {
+		page.Limit = 1
+	}
+
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed > 0 {
+			page.Offset = parsed
+		}
+	}
+	return page
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		query string
+		want  pagination
+	}{
+		{"defaults", "", pagination{Limit: 20}},
+		{"explicit limit", "limit=50", pagination{Limit: 50}},
+		{"limit above max is capped", "limit=500", pagination{Limit: 200}},
+		{"zero limit raised to one", "limit=0", pagination{Limit: 1}},
+		{"negative limit raised to one", "limit=-5", pagination{Limit: 1}},
+		{"unparsable limit uses default", "limit=abc", pagination{Limit: 20}},
+		{"offset and cursor", "offset=40&cursor=next", pagination{Offset: 40, Limit: 20, Cursor: "next"}},
+		{"negative offset ignored", "offset=-1", pagination{Limit: 20}},
+		{"unparsable offset ignored", "offset=x", pagination{Limit: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+
+			if got := parsePagination(c, 20, 200); got != tt.want {
+				t.Errorf("parsePagination(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// 配置的页大小覆盖包内默认值
+func TestSetPaginationLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer SetPaginationLimits(defaultPageLimit, maxPageLimit)
+
+	SetPaginationLimits(30, 250)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?limit=1000", nil)
+	if got := parsePagination(c, defaultPageLimit, maxPageLimit).Limit; got != 250 {
+		t.Errorf("limit = %d, want configured max 250", got)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if got := parsePagination(c, defaultPageLimit, maxPageLimit).Limit; got != 30 {
+		t.Errorf("limit = %d, want configured default 30", got)
+	}
+}
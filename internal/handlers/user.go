@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/feed-system/feed-system/internal/middleware"
@@ -23,13 +24,13 @@ func NewUserHandler(userService *services.UserService, jwtSecret string) *UserHa
 func (h *UserHandler) Register(c *gin.Context) {
 	var req services.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	user, err := h.userService.Register(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -42,20 +43,20 @@ func (h *UserHandler) Register(c *gin.Context) {
 func (h *UserHandler) Login(c *gin.Context) {
 	var req services.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	user, err := h.userService.Login(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	// 生成JWT token
 	token, err := middleware.GenerateToken(user.ID.String(), user.Username, h.jwtSecret, 86400)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "Failed to generate token")
 		return
 	}
 
@@ -74,29 +75,57 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 	user, err := h.userService.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
+	// 已登录用户查看他人资料时记录访客，失败不影响返回资料
+	if viewerID := middleware.GetUserID(c); viewerID != "" {
+		if err := h.userService.RecordProfileView(c.Request.Context(), viewerID, user.ID.String()); err != nil {
+			c.Error(err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+func (h *UserHandler) GetProfileViewers(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit := parsePagination(c, defaultPageLimit, services.MaxProfileViewers).Limit
+
+	viewers, err := h.userService.GetProfileViewers(c.Request.Context(), userID, limit)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"viewers": viewers,
+		"limit":   limit,
+	})
+}
+
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req services.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	user, err := h.userService.Update(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -109,44 +138,151 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 func (h *UserHandler) Follow(c *gin.Context) {
 	followerID := middleware.GetUserID(c)
 	if followerID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req services.FollowRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return
 	}
 
 	if followerID == req.FollowingID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot follow yourself"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Cannot follow yourself")
 		return
 	}
 
 	if err := h.userService.Follow(c.Request.Context(), followerID, req.FollowingID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Followed successfully"})
 }
 
+func (h *UserHandler) BatchFollow(c *gin.Context) {
+	h.batchFollow(c, h.userService.BatchFollow)
+}
+
+func (h *UserHandler) BatchUnfollow(c *gin.Context) {
+	h.batchFollow(c, h.userService.BatchUnfollow)
+}
+
+// ImportFollowing 批量导入关注（从其他平台迁移），请求中可混用用户名和用户ID
+func (h *UserHandler) ImportFollowing(c *gin.Context) {
+	followerID := middleware.GetUserID(c)
+	if followerID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req services.ImportFollowingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	result, err := h.userService.ImportFollowing(c.Request.Context(), followerID, req.Users)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *UserHandler) batchFollow(c *gin.Context, op func(ctx context.Context, followerID string, followingIDs []string) ([]*services.BatchFollowResult, error)) {
+	followerID := middleware.GetUserID(c)
+	if followerID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req services.BatchFollowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	results, err := op(c.Request.Context(), followerID, req.FollowingIDs)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
+
+func (h *UserHandler) FollowBack(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	followerID := c.Param("id")
+	if followerID == userID {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Cannot follow yourself")
+		return
+	}
+
+	if err := h.userService.FollowBack(c.Request.Context(), userID, followerID); err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Followed back successfully"})
+}
+
+// UpdateFollowPreferences 更新与:id用户之间的关注设置（屏蔽通知、密友）
+func (h *UserHandler) UpdateFollowPreferences(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req services.FollowPreference
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.userService.UpdateFollowPreferences(c.Request.Context(), userID, c.Param("id"), &req); err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Follow preferences updated successfully"})
+}
+
 func (h *UserHandler) Unfollow(c *gin.Context) {
 	followerID := middleware.GetUserID(c)
 	if followerID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	followingID := c.Param("id")
 	if followingID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Following ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "Following ID is required")
 		return
 	}
 
 	if err := h.userService.Unfollow(c.Request.Context(), followerID, followingID); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -156,30 +292,16 @@ func (h *UserHandler) Unfollow(c *gin.Context) {
 func (h *UserHandler) GetFollowers(c *gin.Context) {
 	userID := c.Param("id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "User ID is required")
 		return
 	}
 
-	offset := 0
-	limit := 20
-	query := struct {
-		Offset int `form:"offset"`
-		Limit  int `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&query); err == nil {
-		offset = query.Offset
-		limit = query.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
-	}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
 
 	followers, err := h.userService.GetFollowers(c.Request.Context(), userID, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -193,30 +315,16 @@ func (h *UserHandler) GetFollowers(c *gin.Context) {
 func (h *UserHandler) GetFollowing(c *gin.Context) {
 	userID := c.Param("id")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "User ID is required")
 		return
 	}
 
-	offset := 0
-	limit := 20
-	query := struct {
-		Offset int `form:"offset"`
-		Limit  int `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&query); err == nil {
-		offset = query.Offset
-		limit = query.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
-	}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
 
 	following, err := h.userService.GetFollowing(c.Request.Context(), userID, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -227,31 +335,71 @@ func (h *UserHandler) GetFollowing(c *gin.Context) {
 	})
 }
 
+func (h *UserHandler) GetMutuals(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	otherID := c.Param("id")
+	if otherID == "" {
+		respondError(c, http.StatusBadRequest, CodeInvalidRequest, "User ID is required")
+		return
+	}
+
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
+
+	mutuals, err := h.userService.GetMutuals(c.Request.Context(), userID, otherID, offset, limit)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	isMutual, err := h.userService.IsMutualFollow(c.Request.Context(), userID, otherID)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mutuals":          mutuals,
+		"is_mutual_follow": isMutual,
+		"offset":           offset,
+		"limit":            limit,
+	})
+}
+
+func (h *UserHandler) GetFollowSuggestions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	if userID == "" {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit := parsePagination(c, defaultPageLimit, maxPageLimit).Limit
+
+	suggestions, err := h.userService.GetFollowSuggestions(c.Request.Context(), userID, limit)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+		"limit":       limit,
+	})
+}
+
 func (h *UserHandler) SearchUsers(c *gin.Context) {
 	query := c.Query("q")
-	offset := 0
-	limit := 20
-
-	queryParams := struct {
-		Query  string `form:"q"`
-		Offset int    `form:"offset"`
-		Limit  int    `form:"limit"`
-	}{}
-	if err := c.ShouldBindQuery(&queryParams); err == nil {
-		query = queryParams.Query
-		offset = queryParams.Offset
-		limit = queryParams.Limit
-		if limit > 100 {
-			limit = 100
-		}
-		if limit < 1 {
-			limit = 1
-		}
-	}
+	page := parsePagination(c, defaultPageLimit, maxPageLimit)
+	offset, limit := page.Offset, page.Limit
 
 	users, err := h.userService.Search(c.Request.Context(), query, offset, limit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
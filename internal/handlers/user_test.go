@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestUserHandler 基于sqlmock和miniredis的UserHandler，请求以viewerID身份发出
+func newTestUserHandler(t *testing.T, viewerID string) (*gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+
+	userService := services.NewUserService(repository.NewUserRepository(db), repository.NewFollowRepository(db), redisClient, nil, 0, logger.NewLogger())
+	handler := NewUserHandler(userService, "test-secret")
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if viewerID != "" {
+			c.Set("user_id", viewerID)
+		}
+		c.Next()
+	})
+	router.GET("/users/me/viewers", handler.GetProfileViewers)
+	router.GET("/users/:id", handler.GetProfile)
+	return router, mock
+}
+
+func TestUserHandlerMapsServiceErrors(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("missing user is 404", func(t *testing.T) {
+		router, mock := newTestUserHandler(t, "")
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+userID.String(), nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404: %s", w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("malformed ID is 400", func(t *testing.T) {
+		router, _ := newTestUserHandler(t, "")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/not-a-uuid", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("disabled profile views is 403", func(t *testing.T) {
+		router, mock := newTestUserHandler(t, userID.String())
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "show_profile_views"}).AddRow(userID, false))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/me/viewers", nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403: %s", w.Code, w.Body)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type JWTConfig struct {
-	Secret string
+	Secret       string
+	AdminUserIDs []string
 }
 
 type Claims struct {
@@ -24,36 +25,81 @@ func NewJWTAuth(config *JWTConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.JSON(http.StatusUnauthorized, ErrorBody(c, "unauthorized", "Authorization header required"))
 			c.Abort()
 			return
 		}
 
-		// 检查Bearer token格式
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		claims, err := config.parseAuthHeader(authHeader)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, ErrorBody(c, "unauthorized", err.Error()))
 			c.Abort()
 			return
 		}
 
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
+		// 将用户信息存储到上下文中
+		config.setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// NewOptionalJWTAuth 可选认证：携带有效token时写入用户信息，否则按匿名请求继续处理
+func NewOptionalJWTAuth(config *JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			if claims, err := config.parseAuthHeader(authHeader); err == nil {
+				config.setClaims(c, claims)
 			}
-			return []byte(config.Secret), nil
-		})
+		}
+		c.Next()
+	}
+}
+
+// parseAuthHeader 解析并校验Bearer token
+func (config *JWTConfig) parseAuthHeader(authHeader string) (*Claims, error) {
+	// 检查Bearer token格式
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errors.New("Invalid authorization header format")
+	}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(config.Secret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+	return claims, nil
+}
+
+func (config *JWTConfig) setClaims(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("is_admin", config.isAdmin(claims.UserID))
+}
+
+func (config *JWTConfig) isAdmin(userID string) bool {
+	for _, id := range config.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdmin 仅允许管理员访问，需在JWT认证之后使用
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !IsAdmin(c) {
+			c.JSON(http.StatusForbidden, ErrorBody(c, "forbidden", "Admin privileges required"))
 			c.Abort()
 			return
 		}
-
-		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
 		c.Next()
 	}
 }
@@ -87,4 +133,8 @@ func GetUsername(c *gin.Context) string {
 		return ""
 	}
 	return username.(string)
-}
\ No newline at end of file
+}
+
+func IsAdmin(c *gin.Context) bool {
+	return c.GetBool("is_admin")
+}
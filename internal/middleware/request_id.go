@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID的HTTP头，客户端未提供时由服务端生成
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID 为每个请求分配请求ID，写入上下文和响应头，便于日志和错误排查
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+// ErrorBody 统一的错误响应结构: {"error": {"code", "message", "request_id"}}
+func ErrorBody(c *gin.Context, code, message string) gin.H {
+	return gin.H{
+		"error": gin.H{
+			"code":       code,
+			"message":    message,
+			"request_id": GetRequestID(c),
+		},
+	}
+}
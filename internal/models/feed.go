@@ -12,22 +12,31 @@ type Post struct {
 	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
 	Content     string     `json:"content" gorm:"type:text;not null"`
 	ImageURLs   []string   `json:"image_urls" gorm:"type:text[]"`
-	LikeCount   int64      `json:"like_count" gorm:"default:0"`
+	LikeCount   int64      `json:"like_count" gorm:"default:0"` // 全部回应的总数
+	ReactionCounts ReactionCounts `json:"reaction_counts" gorm:"type:jsonb;not null;default:'{}'"` // 按回应类型的计数
 	CommentCount int64     `json:"comment_count" gorm:"default:0"`
 	ShareCount  int64      `json:"share_count" gorm:"default:0"`
+	ViewCount   int64      `json:"view_count" gorm:"default:0"`
 	Score       float64    `json:"score" gorm:"default:0"` // 用于排序的分数
 	IsDeleted   bool       `json:"is_deleted" gorm:"default:false"`
+	Visibility  string     `json:"visibility" gorm:"type:varchar(20);not null;default:public"` // public | close_friends（只分发给作者标记的密友）
+	RemovedAt   *time.Time `json:"-"` // 用户删除（is_deleted）的时间，用于宽限期内恢复；DeletedAt为gorm软删除字段，未使用
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	User User `json:"user" gorm:"foreignKey:UserID"`
+
+	// 查看者相关的状态，不入库；未填充时不返回
+	IsLiked        *bool   `json:"is_liked,omitempty" gorm:"-"`
+	ViewerReaction *string `json:"viewer_reaction,omitempty" gorm:"-"` // 查看者当前的回应类型，未回应时不返回
 }
 
 type Like struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index:idx_user_post"`
 	PostID    uuid.UUID `json:"post_id" gorm:"type:uuid;not null;index:idx_user_post"`
+	ReactionType string `json:"reaction_type" gorm:"type:varchar(20);not null;default:like"` // 回应类型，见ReactionLike等常量
 	CreatedAt time.Time `json:"created_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
@@ -60,6 +69,17 @@ type Timeline struct {
 	Post Post `json:"post" gorm:"foreignKey:PostID"`
 }
 
+// 帖子可见范围
+const (
+	PostVisibilityPublic       = "public"
+	PostVisibilityCloseFriends = "close_friends"
+)
+
+// IsCloseFriendsOnly 是否仅密友可见
+func (p *Post) IsCloseFriendsOnly() bool {
+	return p.Visibility == PostVisibilityCloseFriends
+}
+
 func (Post) TableName() string {
 	return "posts"
 }
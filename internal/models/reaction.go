@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// 回应类型，点赞是默认的回应
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionHaha  = "haha"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+var reactionTypes = map[string]bool{
+	ReactionLike:  true,
+	ReactionLove:  true,
+	ReactionHaha:  true,
+	ReactionWow:   true,
+	ReactionSad:   true,
+	ReactionAngry: true,
+}
+
+// IsValidReactionType 是否是支持的回应类型
+func IsValidReactionType(reactionType string) bool {
+	return reactionTypes[reactionType]
+}
+
+// ReactionCounts 帖子按回应类型的计数，以JSONB存储
+type ReactionCounts map[string]int64
+
+// Value 实现driver.Valuer
+func (c ReactionCounts) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现sql.Scanner
+func (c *ReactionCounts) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = ReactionCounts{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for ReactionCounts: %T", value)
+	}
+	counts := ReactionCounts{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	*c = counts
+	return nil
+}
@@ -18,6 +18,10 @@ type User struct {
 	Followers   int64     `json:"followers" gorm:"default:0"`
 	Following   int64     `json:"following" gorm:"default:0"`
 	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	// 隐私设置：关闭后既不记录也看不到"谁看过我"
+	ShowProfileViews bool `json:"show_profile_views" gorm:"default:true"`
+	// 隐私设置：关闭后其他用户看不到自己赞过的帖子
+	ShowLikes bool `json:"show_likes" gorm:"default:true"`
 	// 用户活跃度相关字段
 	LastActiveAt  *time.Time     `json:"last_active_at" gorm:"index"`     // 最后活跃时间
 	ActivityScore float64        `json:"activity_score" gorm:"default:0"` // 活跃度分数
@@ -28,11 +32,13 @@ type User struct {
 }
 
 type Follow struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	FollowerID  uuid.UUID      `json:"follower_id" gorm:"type:uuid;not null;index:idx_follower_following"`
-	FollowingID uuid.UUID      `json:"following_id" gorm:"type:uuid;not null;index:idx_follower_following"`
-	CreatedAt   time.Time      `json:"created_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	FollowerID        uuid.UUID      `json:"follower_id" gorm:"type:uuid;not null;index:idx_follower_following"`
+	FollowingID       uuid.UUID      `json:"following_id" gorm:"type:uuid;not null;index:idx_follower_following"`
+	MuteNotifications bool           `json:"mute_notifications" gorm:"not null;default:false"` // 关注者设置：不接收被关注者的通知，帖子仍进入Feed
+	CloseFriend       bool           `json:"close_friend" gorm:"not null;default:false"`       // 被关注者设置：标记为密友，可收到密友可见的帖子
+	CreatedAt         time.Time      `json:"created_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Follower  User `json:"follower" gorm:"foreignKey:FollowerID"`
 	Following User `json:"following" gorm:"foreignKey:FollowingID"`
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// 单条IN查询最多携带的ID数，避免超过Postgres参数上限和执行计划退化
+	MaxIDsPerQuery = 500
+	// 分块查询的最大并发数
+	MaxChunkQueryConcurrency = 4
+)
+
+// chunkUUIDs 按size切分ID列表
+func chunkUUIDs(ids []uuid.UUID, size int) [][]uuid.UUID {
+	if size <= 0 {
+		size = MaxIDsPerQuery
+	}
+
+	chunks := make([][]uuid.UUID, 0, (len(ids)+size-1)/size)
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunks = append(chunks, ids[start:end])
+	}
+	return chunks
+}
+
+// uuidArray 以单个Postgres数组参数绑定的UUID列表，用于unnest(?::uuid[])等需要整个数组的场景；
+// 直接传[]uuid.UUID会被GORM展开成(?,?,...)
+type uuidArray []uuid.UUID
+
+// Value 实现driver.Valuer，输出数组字面量{id1,id2}
+func (a uuidArray) Value() (driver.Value, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, id := range a {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(id.String())
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// queryInChunks 分块并发执行查询，结果按分块顺序返回；任一分块失败则返回第一个错误
+func queryInChunks[T any](ctx context.Context, ids []uuid.UUID, query func(ctx context.Context, chunk []uuid.UUID) ([]T, error)) ([][]T, error) {
+	chunks := chunkUUIDs(ids, MaxIDsPerQuery)
+	if len(chunks) == 1 {
+		rows, err := query(ctx, chunks[0])
+		if err != nil {
+			return nil, err
+		}
+		return [][]T{rows}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]T, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, MaxChunkQueryConcurrency)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []uuid.UUID) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			rows, err := query(ctx, chunk)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			results[i] = rows
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// 优先返回真正的查询错误，而不是因取消产生的ctx错误
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if err != context.Canceled {
+			return nil, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
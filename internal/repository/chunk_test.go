@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func newUUIDs(n int) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	return ids
+}
+
+func TestChunkUUIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		n, size    int
+		wantChunks []int
+	}{
+		{"empty", 0, 3, []int{}},
+		{"exact multiple", 6, 3, []int{3, 3}},
+		{"remainder", 7, 3, []int{3, 3, 1}},
+		{"default size", MaxIDsPerQuery + 1, 0, []int{MaxIDsPerQuery, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkUUIDs(newUUIDs(tt.n), tt.size)
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.wantChunks))
+			}
+			for i, chunk := range chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d has %d ids, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryInChunks(t *testing.T) {
+	ctx := context.Background()
+	ids := newUUIDs(MaxIDsPerQuery*3 + 10)
+
+	t.Run("results keep chunk order", func(t *testing.T) {
+		var active, peak int32
+		chunks, err := queryInChunks(ctx, ids, func(ctx context.Context, chunk []uuid.UUID) ([]uuid.UUID, error) {
+			n := atomic.AddInt32(&active, 1)
+			defer atomic.AddInt32(&active, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			if len(chunk) > MaxIDsPerQuery {
+				t.Errorf("chunk of %d ids exceeds %d", len(chunk), MaxIDsPerQuery)
+			}
+			return chunk, nil
+		})
+		if err != nil {
+			t.Fatalf("queryInChunks() error = %v", err)
+		}
+
+		var got []uuid.UUID
+		for _, chunk := range chunks {
+			got = append(got, chunk...)
+		}
+		if len(got) != len(ids) {
+			t.Fatalf("got %d ids, want %d", len(got), len(ids))
+		}
+		for i := range ids {
+			if got[i] != ids[i] {
+				t.Fatalf("id %d out of order", i)
+			}
+		}
+		if peak > MaxChunkQueryConcurrency {
+			t.Errorf("peak concurrency %d exceeds %d", peak, MaxChunkQueryConcurrency)
+		}
+	})
+
+	t.Run("returns query error", func(t *testing.T) {
+		queryErr := errors.New("statement timeout")
+		_, err := queryInChunks(ctx, ids, func(ctx context.Context, chunk []uuid.UUID) ([]uuid.UUID, error) {
+			if chunk[0] == ids[MaxIDsPerQuery] {
+				return nil, queryErr
+			}
+			return chunk, nil
+		})
+		if !errors.Is(err, queryErr) {
+			t.Errorf("queryInChunks() error = %v, want %v", err, queryErr)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/google/uuid"
@@ -43,7 +44,7 @@ func (r *CommentRepository) GetByPostID(ctx context.Context, postID uuid.UUID, o
 	if err := r.db.WithContext(ctx).
 		Preload("User").
 		Where("post_id = ?", postID).
-		Order("created_at DESC").
+		Order("created_at DESC, id DESC").
 		Offset(offset).
 		Limit(limit).
 		Find(&comments).Error; err != nil {
@@ -52,6 +53,35 @@ func (r *CommentRepository) GetByPostID(ctx context.Context, postID uuid.UUID, o
 	return comments, nil
 }
 
+// GetByPostIDCursor 按(created_at, id)降序以keyset方式分页获取帖子的评论，新评论插入不会使已翻过的页发生偏移。
+// beforeID为uuid.Nil时从最新的评论开始
+func (r *CommentRepository) GetByPostIDCursor(ctx context.Context, postID uuid.UUID, beforeCreatedAt time.Time, beforeID uuid.UUID, limit int) ([]*models.Comment, error) {
+	db := r.db.WithContext(ctx).Preload("User").Where("post_id = ?", postID)
+	if beforeID != uuid.Nil {
+		db = db.Where("(created_at, id) < (?, ?)", beforeCreatedAt, beforeID)
+	}
+
+	var comments []*models.Comment
+	if err := db.Order("created_at DESC, id DESC").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get comments by post: %w", err)
+	}
+	return comments, nil
+}
+
+// ScanByUserID 按(created_at, id)升序以keyset方式分页获取用户发表的评论，afterID为uuid.Nil时从头开始
+func (r *CommentRepository) ScanByUserID(ctx context.Context, userID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int) ([]*models.Comment, error) {
+	db := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if afterID != uuid.Nil {
+		db = db.Where("(created_at, id) > (?, ?)", afterCreatedAt, afterID)
+	}
+
+	var comments []*models.Comment
+	if err := db.Order("created_at ASC, id ASC").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to scan comments by user: %w", err)
+	}
+	return comments, nil
+}
+
 func (r *CommentRepository) Update(ctx context.Context, comment *models.Comment) error {
 	if err := r.db.WithContext(ctx).Save(comment).Error; err != nil {
 		return fmt.Errorf("failed to update comment: %w", err)
@@ -70,7 +100,7 @@ func (r *CommentRepository) Delete(ctx context.Context, id uuid.UUID) error {
 func (r *CommentRepository) UpdateLikeCount(ctx context.Context, commentID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.Comment{}).
 		Where("id = ?", commentID).
-		UpdateColumn("like_count", gorm.Expr("like_count + ?", delta)).Error; err != nil {
+		UpdateColumn("like_count", gorm.Expr("GREATEST(like_count + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update comment like count: %w", err)
 	}
 	return nil
@@ -85,4 +115,72 @@ func (r *CommentRepository) CountByPostID(ctx context.Context, postID uuid.UUID)
 		return 0, fmt.Errorf("failed to count comments: %w", err)
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+
+// GetByIDs 按ID批量获取评论，返回顺序不限，不存在的ID被忽略
+func (r *CommentRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Comment, error) {
+	if len(ids) == 0 {
+		return []*models.Comment{}, nil
+	}
+
+	var comments []*models.Comment
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id IN ?", ids).
+		Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get comments by IDs: %w", err)
+	}
+	return comments, nil
+}
+
+// GetTopCommentsForPosts 一次查询为每个帖子取点赞数最高的perPost条顶层评论（点赞数相同时较新的在前），
+// 用于帖子详情等需要同时展示多个帖子热门评论的场景。没有评论的帖子不在结果中
+func (r *CommentRepository) GetTopCommentsForPosts(ctx context.Context, postIDs []uuid.UUID, perPost int) (map[uuid.UUID][]*models.Comment, error) {
+	result := make(map[uuid.UUID][]*models.Comment)
+	if len(postIDs) == 0 || perPost <= 0 {
+		return result, nil
+	}
+
+	subQuery := r.db.Raw(`SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (
+				PARTITION BY post_id ORDER BY like_count DESC, created_at DESC, id DESC
+			) AS rn
+			FROM comments
+			WHERE post_id IN ? AND parent_id IS NULL AND deleted_at IS NULL
+		) ranked WHERE rn <= ?`, postIDs, perPost)
+
+	var comments []*models.Comment
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id IN (?)", subQuery).
+		Order("like_count DESC, created_at DESC, id DESC").
+		Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get top comments for posts: %w", err)
+	}
+
+	for _, comment := range comments {
+		result[comment.PostID] = append(result[comment.PostID], comment)
+	}
+	return result, nil
+}
+
+// GetCommentedPostIDs 批量查询用户在哪些帖子下发表过评论
+func (r *CommentRepository) GetCommentedPostIDs(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	commented := make(map[uuid.UUID]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return commented, nil
+	}
+
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Comment{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Distinct().
+		Pluck("post_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get commented posts: %w", err)
+	}
+	for _, id := range ids {
+		commented[id] = true
+	}
+	return commented, nil
+}
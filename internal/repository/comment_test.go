@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// keyset条件必须同时比较created_at和id，时间相同的评论才不会在翻页时重复或遗漏
+func TestGetByPostIDCursorKeysetOnCreatedAtAndID(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepository(db)
+	postID, beforeID := uuid.New(), uuid.New()
+	beforeCreatedAt := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "comments" WHERE post_id = $1 AND "comments"."deleted_at" IS NULL ORDER BY created_at DESC, id DESC LIMIT 3`)).
+		WithArgs(postID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "comments" WHERE post_id = $1 AND (created_at, id) < ($2, $3) AND "comments"."deleted_at" IS NULL ORDER BY created_at DESC, id DESC LIMIT 3`)).
+		WithArgs(postID, beforeCreatedAt, beforeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := repo.GetByPostIDCursor(context.Background(), postID, time.Time{}, uuid.Nil, 3); err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if _, err := repo.GetByPostIDCursor(context.Background(), postID, beforeCreatedAt, beforeID, 3); err != nil {
+		t.Fatalf("next page: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetByPostIDCursorEqualTimestampsIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewCommentRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	post := &models.Post{UserID: user.ID, Content: "post"}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	t.Cleanup(func() { db.Unscoped().Where("post_id = ?", post.ID).Delete(&models.Comment{}) })
+
+	// 五条创建时间完全相同的评论
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	want := make(map[uuid.UUID]bool)
+	for i := 0; i < 5; i++ {
+		comment := &models.Comment{UserID: user.ID, PostID: post.ID, Content: "comment", CreatedAt: createdAt}
+		if err := db.Create(comment).Error; err != nil {
+			t.Fatalf("failed to create comment: %v", err)
+		}
+		want[comment.ID] = true
+	}
+
+	// 每页两条逐页读取，翻页期间插入的新评论不影响后面的页
+	var seen []*models.Comment
+	beforeCreatedAt, beforeID := time.Time{}, uuid.Nil
+	for page := 0; page < 5; page++ {
+		comments, err := repo.GetByPostIDCursor(ctx, post.ID, beforeCreatedAt, beforeID, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) == 0 {
+			break
+		}
+		seen = append(seen, comments...)
+		last := comments[len(comments)-1]
+		beforeCreatedAt, beforeID = last.CreatedAt, last.ID
+
+		newer := &models.Comment{UserID: user.ID, PostID: post.ID, Content: "newer", CreatedAt: time.Now()}
+		if err := db.Create(newer).Error; err != nil {
+			t.Fatalf("failed to create comment: %v", err)
+		}
+	}
+
+	if len(seen) != len(want) {
+		t.Fatalf("paged through %d comments, want %d", len(seen), len(want))
+	}
+	for i, comment := range seen {
+		if !want[comment.ID] {
+			t.Errorf("comment %s returned twice or not one of the originals", comment.ID)
+		}
+		delete(want, comment.ID)
+		if i > 0 && seen[i-1].ID.String() <= comment.ID.String() {
+			t.Errorf("comments %d and %d not ordered by id desc", i-1, i)
+		}
+	}
+}
+
+func TestGetTopCommentsForPostsGroupsByPost(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewCommentRepository(db)
+	ctx := context.Background()
+	withComments, withoutComments := uuid.New(), uuid.New()
+	top, second := uuid.New(), uuid.New()
+
+	// 一次查询，每个帖子的条数上限由窗口函数限制
+	mock.ExpectQuery(regexp.QuoteMeta(`PARTITION BY post_id ORDER BY like_count DESC, created_at DESC, id DESC`)+`.*`+
+		regexp.QuoteMeta(`WHERE post_id IN ($1,$2) AND parent_id IS NULL AND deleted_at IS NULL`)+`.*`+
+		regexp.QuoteMeta(`WHERE rn <= $3`)).
+		WithArgs(withComments, withoutComments, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "like_count"}).
+			AddRow(top, withComments, 9).
+			AddRow(second, withComments, 3))
+
+	result, err := repo.GetTopCommentsForPosts(ctx, []uuid.UUID{withComments, withoutComments}, 2)
+	if err != nil {
+		t.Fatalf("GetTopCommentsForPosts() error = %v", err)
+	}
+	if got := result[withComments]; len(got) != 2 || got[0].ID != top || got[1].ID != second {
+		t.Errorf("comments for post = %v, want [%s %s]", got, top, second)
+	}
+	if _, ok := result[withoutComments]; ok || len(result) != 1 {
+		t.Errorf("result = %v, want no entry for the post without comments", result)
+	}
+
+	// 没有帖子或perPost不为正时不查询
+	for _, perPost := range []int{0, -1} {
+		if result, err := repo.GetTopCommentsForPosts(ctx, []uuid.UUID{withComments}, perPost); err != nil || len(result) != 0 {
+			t.Errorf("perPost %d: result = %v, err = %v", perPost, result, err)
+		}
+	}
+	if result, err := repo.GetTopCommentsForPosts(ctx, nil, 2); err != nil || len(result) != 0 {
+		t.Errorf("no posts: result = %v, err = %v", result, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetTopCommentsForPostsIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewCommentRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	var posts []*models.Post
+	for i := 0; i < 3; i++ {
+		post := &models.Post{UserID: user.ID, Content: "post"}
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+		posts = append(posts, post)
+		t.Cleanup(func() { db.Unscoped().Where("post_id = ?", post.ID).Delete(&models.Comment{}) })
+	}
+	busy, quiet, empty := posts[0], posts[1], posts[2]
+
+	createComment := func(post *models.Post, likes int64, parentID *uuid.UUID) *models.Comment {
+		comment := &models.Comment{UserID: user.ID, PostID: post.ID, Content: "comment", LikeCount: likes, ParentID: parentID}
+		if err := db.Create(comment).Error; err != nil {
+			t.Fatalf("failed to create comment: %v", err)
+		}
+		return comment
+	}
+	low := createComment(busy, 1, nil)
+	best := createComment(busy, 10, nil)
+	createComment(busy, 50, &low.ID) // 回复不参与排名
+	next := createComment(busy, 5, nil)
+	createComment(busy, 0, nil)
+	only := createComment(quiet, 0, nil)
+
+	result, err := repo.GetTopCommentsForPosts(ctx, []uuid.UUID{busy.ID, quiet.ID, empty.ID}, 2)
+	if err != nil {
+		t.Fatalf("GetTopCommentsForPosts() error = %v", err)
+	}
+	if got := result[busy.ID]; len(got) != 2 || got[0].ID != best.ID || got[1].ID != next.ID {
+		t.Errorf("busy post comments = %v, want [%s %s]", got, best.ID, next.ID)
+	}
+	if got := result[quiet.ID]; len(got) != 1 || got[0].ID != only.ID {
+		t.Errorf("quiet post comments = %v, want [%s]", got, only.ID)
+	}
+	if _, ok := result[empty.ID]; ok {
+		t.Errorf("post without comments has an entry: %v", result[empty.ID])
+	}
+}
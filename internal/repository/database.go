@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/feed-system/feed-system/internal/config"
@@ -31,12 +32,20 @@ func NewDatabase(cfg *config.DatabaseConfig) (*Database, error) {
 		return nil, fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
-	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	applyPoolSettings(sqlDB, cfg)
 
 	return &Database{db}, nil
 }
 
+// applyPoolSettings 按配置设置连接池大小和连接的最长存活、空闲时间
+func applyPoolSettings(sqlDB *sql.DB, cfg *config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// AutoMigrate 按模型自动同步表结构，只用于开发环境，生产环境的表结构变更走Migrate
 func (db *Database) AutoMigrate() error {
 	return db.DB.AutoMigrate(
 		&models.User{},
@@ -54,4 +63,13 @@ func (db *Database) Close() error {
 		return err
 	}
 	return sqlDB.Close()
-}
\ No newline at end of file
+}
+
+// Stats 获取数据库连接池统计
+func (db *Database) Stats() (sql.DBStats, error) {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+	return sqlDB.Stats(), nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+)
+
+func TestApplyPoolSettings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("max open conns", func(t *testing.T) {
+		sqlDB, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sqlDB.Close()
+		applyPoolSettings(sqlDB, &config.DatabaseConfig{MaxOpenConns: 7, MaxIdleConns: 3})
+		if got := sqlDB.Stats().MaxOpenConnections; got != 7 {
+			t.Errorf("MaxOpenConnections = %d, want 7", got)
+		}
+	})
+
+	t.Run("conn max lifetime", func(t *testing.T) {
+		sqlDB, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sqlDB.Close()
+		applyPoolSettings(sqlDB, &config.DatabaseConfig{MaxOpenConns: 1, MaxIdleConns: 1, ConnMaxLifetime: 10 * time.Millisecond})
+
+		// 超过存活时间的空闲连接在下次取用时被关闭
+		if err := sqlDB.PingContext(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		sqlDB.PingContext(ctx)
+		if got := sqlDB.Stats().MaxLifetimeClosed; got == 0 {
+			t.Error("expired connection was not closed")
+		}
+	})
+
+	t.Run("conn max idle time", func(t *testing.T) {
+		sqlDB, _, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sqlDB.Close()
+		applyPoolSettings(sqlDB, &config.DatabaseConfig{MaxOpenConns: 1, MaxIdleConns: 1, ConnMaxIdleTime: 10 * time.Millisecond})
+
+		// 空闲超时的连接由后台清理，清理间隔最短1秒
+		if err := sqlDB.PingContext(ctx); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(3 * time.Second)
+		for sqlDB.Stats().MaxIdleTimeClosed == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("idle connection was not closed")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if got := sqlDB.Stats().Idle; got != 0 {
+			t.Errorf("Idle = %d after idle timeout, want 0", got)
+		}
+	})
+}
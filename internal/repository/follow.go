@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FollowRepository struct {
@@ -33,6 +35,73 @@ func (r *FollowRepository) Delete(ctx context.Context, followerID, followingID u
 	return nil
 }
 
+// CreateBatch 在一个事务中创建多条关注关系并更新双方的关注数和粉丝数
+func (r *FollowRepository) CreateBatch(ctx context.Context, followerID uuid.UUID, followingIDs []uuid.UUID) ([]*models.Follow, error) {
+	if len(followingIDs) == 0 {
+		return []*models.Follow{}, nil
+	}
+
+	follows := make([]*models.Follow, len(followingIDs))
+	for i, id := range followingIDs {
+		follows[i] = &models.Follow{FollowerID: followerID, FollowingID: id}
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&follows).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", followerID).
+			UpdateColumn("following", gorm.Expr("GREATEST(following + ?, 0)", len(follows))).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id IN ?", followingIDs).
+			UpdateColumn("followers", gorm.Expr("GREATEST(followers + ?, 0)", 1)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create follows in batch: %w", err)
+	}
+	return follows, nil
+}
+
+// DeleteBatch 在一个事务中删除多条关注关系并更新计数，返回实际删除的关注关系
+func (r *FollowRepository) DeleteBatch(ctx context.Context, followerID uuid.UUID, followingIDs []uuid.UUID) ([]*models.Follow, error) {
+	var deleted []*models.Follow
+	if len(followingIDs) == 0 {
+		return deleted, nil
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Returning{}).
+			Where("follower_id = ? AND following_id IN ?", followerID, followingIDs).
+			Delete(&deleted).Error; err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return nil
+		}
+
+		// 只调整实际删除的关注关系对应的计数
+		removedIDs := make([]uuid.UUID, len(deleted))
+		for i, follow := range deleted {
+			removedIDs[i] = follow.FollowingID
+		}
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", followerID).
+			UpdateColumn("following", gorm.Expr("GREATEST(following - ?, 0)", len(deleted))).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id IN ?", removedIDs).
+			UpdateColumn("followers", gorm.Expr("GREATEST(followers - ?, 0)", 1)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete follows in batch: %w", err)
+	}
+	return deleted, nil
+}
+
 func (r *FollowRepository) Get(ctx context.Context, followerID, followingID uuid.UUID) (*models.Follow, error) {
 	var follow models.Follow
 	if err := r.db.WithContext(ctx).
@@ -105,4 +174,217 @@ func (r *FollowRepository) IsFollowing(ctx context.Context, followerID, followin
 		return false, fmt.Errorf("failed to check follow status: %w", err)
 	}
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// CountFollowersSince 统计某时间之后新增的关注者数量
+func (r *FollowRepository) CountFollowersSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("following_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count new followers: %w", err)
+	}
+	return count, nil
+}
+
+// AreFollowing 一次查询返回followingIDs中followerID已关注的子集
+func (r *FollowRepository) AreFollowing(ctx context.Context, followerID uuid.UUID, followingIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	result := make(map[uuid.UUID]bool)
+	if len(followingIDs) == 0 {
+		return result, nil
+	}
+
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id IN ?", followerID, followingIDs).
+		Pluck("following_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to check follow status in batch: %w", err)
+	}
+
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// SetMuteNotifications 设置followerID是否屏蔽来自followingID的通知，关注关系不存在时返回false
+func (r *FollowRepository) SetMuteNotifications(ctx context.Context, followerID, followingID uuid.UUID, muted bool) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id = ?", followerID, followingID).
+		Update("mute_notifications", muted)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update mute notifications: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetCloseFriend 设置followerID是否为followingID的密友，关注关系不存在时返回false
+func (r *FollowRepository) SetCloseFriend(ctx context.Context, followerID, followingID uuid.UUID, closeFriend bool) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id = ?", followerID, followingID).
+		Update("close_friend", closeFriend)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update close friend: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetCloseFriendFollowerIDs 获取被userID标记为密友的关注者ID
+func (r *FollowRepository) GetCloseFriendFollowerIDs(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("following_id = ? AND close_friend = ?", userID, true).
+		Limit(limit).
+		Pluck("follower_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get close friend IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// GetCloseFriendOfIDs 获取把userID标记为密友的作者ID，即userID能看到其密友可见帖子的作者
+func (r *FollowRepository) GetCloseFriendOfIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ? AND close_friend = ?", userID, true).
+		Pluck("following_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get close friend of IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// IsCloseFriend 判断followerID是否关注了followingID且被标记为密友
+func (r *FollowRepository) IsCloseFriend(ctx context.Context, followerID, followingID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ? AND following_id = ? AND close_friend = ?", followerID, followingID, true).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check close friend status: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetFollowerIDs 获取用户的关注者ID列表
+func (r *FollowRepository) GetFollowerIDs(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("following_id = ?", userID).
+		Limit(limit).
+		Pluck("follower_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get follower IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// GetFollowingIDs 获取用户关注的用户ID列表
+func (r *FollowRepository) GetFollowingIDs(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("follower_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("following_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get following IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// GetMostActiveFollowingIDs 按最近活跃程度获取用户关注的用户ID（关注数过多时用于采样）
+func (r *FollowRepository) GetMostActiveFollowingIDs(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Table("follows").
+		Joins("JOIN users ON users.id = follows.following_id").
+		Where("follows.follower_id = ? AND follows.deleted_at IS NULL AND users.deleted_at IS NULL", userID).
+		Order("users.last_active_at DESC NULLS LAST, users.activity_score DESC").
+		Limit(limit).
+		Pluck("follows.following_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get most active following IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// GetMutuals 获取userA和userB共同关注的用户
+func (r *FollowRepository) GetMutuals(ctx context.Context, userA, userB uuid.UUID, offset, limit int) ([]*models.User, error) {
+	var users []*models.User
+	if err := r.db.WithContext(ctx).
+		Table("users").
+		Joins("JOIN follows fa ON fa.following_id = users.id AND fa.follower_id = ? AND fa.deleted_at IS NULL", userA).
+		Joins("JOIN follows fb ON fb.following_id = users.id AND fb.follower_id = ? AND fb.deleted_at IS NULL", userB).
+		Where("users.deleted_at IS NULL").
+		Order("users.username").
+		Offset(offset).
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get mutual follows: %w", err)
+	}
+	return users, nil
+}
+
+// IsMutualFollow 判断两个用户是否互相关注
+func (r *FollowRepository) IsMutualFollow(ctx context.Context, userA, userB uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&models.Follow{}).
+		Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)", userA, userB, userB, userA).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check mutual follow: %w", err)
+	}
+	return count == 2, nil
+}
+
+// FollowSuggestion 二度关注推荐结果
+type FollowSuggestion struct {
+	UserID      uuid.UUID
+	MutualCount int64
+}
+
+// GetSecondDegreeFollowing 获取userID关注的人所关注、但userID尚未关注的用户，按共同关注人数排序
+// 只从最近关注的followingLimit个用户展开，避免大V关注列表导致全表扫描。
+// 屏蔽过的用户不作为展开起点，也不会被推荐（包括屏蔽后又取消关注的）
+func (r *FollowRepository) GetSecondDegreeFollowing(ctx context.Context, userID uuid.UUID, followingLimit, limit int) ([]FollowSuggestion, error) {
+	var suggestions []FollowSuggestion
+
+	followees := r.db.
+		Model(&models.Follow{}).
+		Select("following_id").
+		Where("follower_id = ? AND mute_notifications = ?", userID, false).
+		Order("created_at DESC").
+		Limit(followingLimit)
+
+	alreadyFollowing := r.db.
+		Model(&models.Follow{}).
+		Select("following_id").
+		Where("follower_id = ?", userID)
+
+	muted := r.db.
+		Unscoped().
+		Model(&models.Follow{}).
+		Select("following_id").
+		Where("follower_id = ? AND mute_notifications = ?", userID, true)
+
+	if err := r.db.WithContext(ctx).
+		Table("follows").
+		Select("follows.following_id AS user_id, COUNT(*) AS mutual_count").
+		Joins("JOIN users ON users.id = follows.following_id AND users.deleted_at IS NULL AND users.is_active = ?", true).
+		Where("follows.follower_id IN (?)", followees).
+		Where("follows.following_id <> ?", userID).
+		Where("follows.following_id NOT IN (?)", alreadyFollowing).
+		Where("follows.following_id NOT IN (?)", muted).
+		Where("follows.deleted_at IS NULL").
+		Group("follows.following_id").
+		Order("mutual_count DESC, MAX(users.followers) DESC").
+		Limit(limit).
+		Scan(&suggestions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get follow suggestions: %w", err)
+	}
+	return suggestions, nil
+}
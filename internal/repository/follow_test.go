@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetMutuals两次JOIN follows分别绑定两个用户，结果是两人关注列表的交集
+func TestGetMutualsJoinsBothFollowSets(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewFollowRepository(db)
+	userA, userB, mutual := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN follows fa ON fa.following_id = users.id AND fa.follower_id = $1 AND fa.deleted_at IS NULL JOIN follows fb ON fb.following_id = users.id AND fb.follower_id = $2 AND fb.deleted_at IS NULL`)+
+		`.* ORDER BY users\.username LIMIT 10 OFFSET 20`).
+		WithArgs(userA, userB).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(mutual, "carol"))
+
+	users, err := repo.GetMutuals(context.Background(), userA, userB, 20, 10)
+	if err != nil {
+		t.Fatalf("GetMutuals() error = %v", err)
+	}
+	if len(users) != 1 || users[0].ID != mutual {
+		t.Errorf("GetMutuals() = %v, want [%s]", users, mutual)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsMutualFollow(t *testing.T) {
+	userA, userB := uuid.New(), uuid.New()
+	for _, tt := range []struct {
+		name  string
+		count int
+		want  bool
+	}{
+		{"both directions", 2, true},
+		{"one direction", 1, false},
+		{"neither", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "follows" WHERE ((follower_id = $1 AND following_id = $2) OR (follower_id = $3 AND following_id = $4))`)).
+				WithArgs(userA, userB, userB, userA).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.count))
+
+			got, err := NewFollowRepository(db).IsMutualFollow(context.Background(), userA, userB)
+			if err != nil {
+				t.Fatalf("IsMutualFollow() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsMutualFollow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// createTestFollow 创建关注关系，测试结束时删除
+func createTestFollow(t *testing.T, db *gorm.DB, followerID, followingID uuid.UUID) {
+	t.Helper()
+
+	follow := &models.Follow{FollowerID: followerID, FollowingID: followingID}
+	if err := db.Create(follow).Error; err != nil {
+		t.Fatalf("failed to create follow: %v", err)
+	}
+	t.Cleanup(func() { db.Unscoped().Delete(follow) })
+}
+
+// createTestMutedFollow 创建屏蔽通知的关注关系，测试结束时删除
+func createTestMutedFollow(t *testing.T, db *gorm.DB, followerID, followingID uuid.UUID) *models.Follow {
+	t.Helper()
+
+	follow := &models.Follow{FollowerID: followerID, FollowingID: followingID, MuteNotifications: true}
+	if err := db.Create(follow).Error; err != nil {
+		t.Fatalf("failed to create follow: %v", err)
+	}
+	t.Cleanup(func() { db.Unscoped().Delete(follow) })
+	return follow
+}
+
+func TestGetMutualsIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewFollowRepository(db)
+	ctx := context.Background()
+
+	userA, userB, userC := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	shared1, shared2, onlyA, onlyB := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	for _, followee := range []*models.User{shared1, shared2, onlyA} {
+		createTestFollow(t, db, userA.ID, followee.ID)
+	}
+	for _, followee := range []*models.User{shared1, shared2, onlyB} {
+		createTestFollow(t, db, userB.ID, followee.ID)
+	}
+	createTestFollow(t, db, userC.ID, onlyB.ID)
+
+	// 有重叠的关注列表只返回交集
+	mutuals, err := repo.GetMutuals(ctx, userA.ID, userB.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("GetMutuals() error = %v", err)
+	}
+	got := map[uuid.UUID]bool{}
+	for _, user := range mutuals {
+		got[user.ID] = true
+	}
+	if len(mutuals) != 2 || !got[shared1.ID] || !got[shared2.ID] {
+		t.Errorf("GetMutuals(A, B) = %v, want %s and %s", mutuals, shared1.ID, shared2.ID)
+	}
+
+	// 分页不重复
+	first, err := repo.GetMutuals(ctx, userA.ID, userB.ID, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.GetMutuals(ctx, userA.ID, userB.ID, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || len(second) != 1 || first[0].ID == second[0].ID {
+		t.Errorf("pages = %v, %v, want two distinct mutuals", first, second)
+	}
+
+	// 不相交的关注列表没有共同关注
+	mutuals, err = repo.GetMutuals(ctx, userA.ID, userC.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("GetMutuals() error = %v", err)
+	}
+	if len(mutuals) != 0 {
+		t.Errorf("GetMutuals(A, C) = %v, want none", mutuals)
+	}
+
+	createTestFollow(t, db, shared1.ID, userA.ID)
+	if mutual, err := repo.IsMutualFollow(ctx, userA.ID, shared1.ID); err != nil || !mutual {
+		t.Errorf("IsMutualFollow(A, shared1) = %v, %v, want true", mutual, err)
+	}
+	if mutual, err := repo.IsMutualFollow(ctx, userA.ID, shared2.ID); err != nil || mutual {
+		t.Errorf("IsMutualFollow(A, shared2) = %v, %v, want false", mutual, err)
+	}
+}
+
+// 屏蔽的关注对象不参与展开，屏蔽过的用户（包括已取消关注的）不被推荐
+func TestGetSecondDegreeFollowingExcludesMuted(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID, candidate := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE follows.follower_id IN (SELECT "following_id" FROM "follows" WHERE (follower_id = $2 AND mute_notifications = $3) AND "follows"."deleted_at" IS NULL ORDER BY created_at DESC LIMIT 50)`)+
+		`.*`+regexp.QuoteMeta(`AND follows.following_id NOT IN (SELECT "following_id" FROM "follows" WHERE follower_id = $6 AND mute_notifications = $7) AND follows.deleted_at IS NULL`)).
+		WithArgs(true, userID, false, userID, userID, userID, true).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "mutual_count"}).AddRow(candidate, 2))
+
+	suggestions, err := NewFollowRepository(db).GetSecondDegreeFollowing(context.Background(), userID, 50, 10)
+	if err != nil {
+		t.Fatalf("GetSecondDegreeFollowing() error = %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].UserID != candidate || suggestions[0].MutualCount != 2 {
+		t.Errorf("GetSecondDegreeFollowing() = %v, want [%s/2]", suggestions, candidate)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetSecondDegreeFollowingIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewFollowRepository(db)
+	ctx := context.Background()
+
+	// me -> f1, f2, f3；f1、f2、f3都关注popular，f1、f2关注rising，f3关注niche和me，me已关注followed
+	me := createTestUser(t, db)
+	f1, f2, f3 := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	popular, rising, niche, followed := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	for _, followee := range []*models.User{f1, f2, f3, followed} {
+		createTestFollow(t, db, me.ID, followee.ID)
+	}
+	for _, follower := range []*models.User{f1, f2, f3} {
+		createTestFollow(t, db, follower.ID, popular.ID)
+		createTestFollow(t, db, follower.ID, followed.ID)
+	}
+	createTestFollow(t, db, f1.ID, rising.ID)
+	createTestFollow(t, db, f2.ID, rising.ID)
+	createTestFollow(t, db, f3.ID, niche.ID)
+	createTestFollow(t, db, f3.ID, me.ID)
+
+	// me屏蔽了noisy，noisy关注的viaMuted不应出现；me屏蔽后又取消关注了unfollowedMuted，f1关注它也不推荐
+	noisy, viaMuted, unfollowedMuted := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	createTestMutedFollow(t, db, me.ID, noisy.ID)
+	createTestFollow(t, db, noisy.ID, viaMuted.ID)
+	mutedThenUnfollowed := createTestMutedFollow(t, db, me.ID, unfollowedMuted.ID)
+	if err := db.Delete(mutedThenUnfollowed).Error; err != nil {
+		t.Fatal(err)
+	}
+	createTestFollow(t, db, f1.ID, unfollowedMuted.ID)
+
+	suggestions, err := repo.GetSecondDegreeFollowing(ctx, me.ID, 100, 10)
+	if err != nil {
+		t.Fatalf("GetSecondDegreeFollowing() error = %v", err)
+	}
+
+	// 按共同关注人数排序，排除自己、已关注和屏蔽过的用户
+	want := []struct {
+		id     uuid.UUID
+		mutual int64
+	}{{popular.ID, 3}, {rising.ID, 2}, {niche.ID, 1}}
+	if len(suggestions) != len(want) {
+		t.Fatalf("got %d suggestions %v, want %d", len(suggestions), suggestions, len(want))
+	}
+	for i, w := range want {
+		if suggestions[i].UserID != w.id || suggestions[i].MutualCount != w.mutual {
+			t.Errorf("suggestion %d = %+v, want %s with %d mutuals", i, suggestions[i], w.id, w.mutual)
+		}
+	}
+}
+
+func TestAreFollowingReturnsFollowedSubset(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewFollowRepository(db)
+	viewer := uuid.New()
+	followed1, notFollowed, followed2 := uuid.New(), uuid.New(), uuid.New()
+
+	// 一条查询取回整批作者的关注状态
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "following_id" FROM "follows" WHERE (follower_id = $1 AND following_id IN ($2,$3,$4))`)).
+		WithArgs(viewer, followed1, notFollowed, followed2).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(followed1).AddRow(followed2))
+
+	following, err := repo.AreFollowing(context.Background(), viewer, []uuid.UUID{followed1, notFollowed, followed2})
+	if err != nil {
+		t.Fatalf("AreFollowing() error = %v", err)
+	}
+	if len(following) != 2 || !following[followed1] || !following[followed2] || following[notFollowed] {
+		t.Errorf("AreFollowing() = %v, want only %s and %s", following, followed1, followed2)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// 空列表不查询数据库
+	if following, err := repo.AreFollowing(context.Background(), viewer, nil); err != nil || len(following) != 0 {
+		t.Errorf("AreFollowing(nil) = %v, %v", following, err)
+	}
+}
+
+func TestAreFollowingIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewFollowRepository(db)
+	ctx := context.Background()
+
+	viewer := createTestUser(t, db)
+	followed, unfollowed, stranger := createTestUser(t, db), createTestUser(t, db), createTestUser(t, db)
+	createTestFollow(t, db, viewer.ID, followed.ID)
+	createTestFollow(t, db, viewer.ID, unfollowed.ID)
+	createTestFollow(t, db, stranger.ID, viewer.ID)
+	if err := repo.Delete(ctx, viewer.ID, unfollowed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已取消的关注和反向关注都不算
+	following, err := repo.AreFollowing(ctx, viewer.ID, []uuid.UUID{followed.ID, unfollowed.ID, stranger.ID})
+	if err != nil {
+		t.Fatalf("AreFollowing() error = %v", err)
+	}
+	if len(following) != 1 || !following[followed.ID] {
+		t.Errorf("AreFollowing() = %v, want only %s", following, followed.ID)
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newIntegrationDB 连接FEEDSYSTEM_TEST_DATABASE_DSN指定的Postgres并执行迁移，未设置时跳过
+func newIntegrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("FEEDSYSTEM_TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("FEEDSYSTEM_TEST_DATABASE_DSN not set, skipping integration test")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	if _, err := (&Database{db}).Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// createTestUser 创建测试用户，测试结束时连同其帖子一起删除
+func createTestUser(t *testing.T, db *gorm.DB) *models.User {
+	t.Helper()
+
+	name := "it_" + uuid.NewString()[:12]
+	user := &models.User{Username: name, Email: name + "@example.com", Password: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Post{})
+		db.Unscoped().Delete(user)
+	})
+	return user
+}
@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type LikeRepository struct {
@@ -17,20 +19,47 @@ func NewLikeRepository(db *gorm.DB) *LikeRepository {
 	return &LikeRepository{db: db}
 }
 
-func (r *LikeRepository) Create(ctx context.Context, like *models.Like) error {
-	if err := r.db.WithContext(ctx).Create(like).Error; err != nil {
-		return fmt.Errorf("failed to create like: %w", err)
+// likeOnConflict 命中未删除点赞上的部分唯一索引idx_likes_user_post_unique时跳过，并发重复点赞只会插入一行
+var likeOnConflict = clause.OnConflict{
+	Columns:     []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+	TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+	DoNothing:   true,
+}
+
+// Create 创建点赞记录，返回是否实际插入；已点赞时不做任何修改并返回false
+func (r *LikeRepository) Create(ctx context.Context, like *models.Like) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(likeOnConflict).Create(like)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create like: %w", result.Error)
 	}
-	return nil
+	return result.RowsAffected > 0, nil
 }
 
-func (r *LikeRepository) Delete(ctx context.Context, userID, postID uuid.UUID) error {
-	if err := r.db.WithContext(ctx).
-		Where("user_id = ? AND post_id = ?", userID, postID).
-		Delete(&models.Like{}).Error; err != nil {
-		return fmt.Errorf("failed to delete like: %w", err)
+// Delete 删除点赞记录，返回被删除的回应类型；没有可删除的记录时返回空字符串，
+// 并发取消同一个赞时只有一个调用返回非空
+func (r *LikeRepository) Delete(ctx context.Context, userID, postID uuid.UUID) (string, error) {
+	var reactionTypes []string
+	if err := r.db.WithContext(ctx).Raw(`UPDATE likes SET deleted_at = ?
+		WHERE user_id = ? AND post_id = ? AND deleted_at IS NULL
+		RETURNING reaction_type`, time.Now(), userID, postID).
+		Scan(&reactionTypes).Error; err != nil {
+		return "", fmt.Errorf("failed to delete like: %w", err)
+	}
+	if len(reactionTypes) == 0 {
+		return "", nil
 	}
-	return nil
+	return reactionTypes[0], nil
+}
+
+// UpdateReactionType 把回应从from切换为to，返回是否实际更新；回应已被并发修改或取消时返回false
+func (r *LikeRepository) UpdateReactionType(ctx context.Context, userID, postID uuid.UUID, from, to string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Like{}).
+		Where("user_id = ? AND post_id = ? AND reaction_type = ?", userID, postID, from).
+		UpdateColumn("reaction_type", to)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update reaction type: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
 }
 
 func (r *LikeRepository) Get(ctx context.Context, userID, postID uuid.UUID) (*models.Like, error) {
@@ -80,4 +109,71 @@ func (r *LikeRepository) IsLiked(ctx context.Context, userID, postID uuid.UUID)
 		return false, fmt.Errorf("failed to check like status: %w", err)
 	}
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// GetRecentLikesForPosts 一次查询为每个帖子取最近的perPost条点赞（含点赞用户），用于"某某等人赞过"的展示。
+// 没有点赞的帖子不在结果中
+func (r *LikeRepository) GetRecentLikesForPosts(ctx context.Context, postIDs []uuid.UUID, perPost int) (map[uuid.UUID][]*models.Like, error) {
+	result := make(map[uuid.UUID][]*models.Like)
+	if len(postIDs) == 0 || perPost <= 0 {
+		return result, nil
+	}
+
+	subQuery := r.db.Raw(`SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY post_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM likes
+			WHERE post_id IN ? AND deleted_at IS NULL
+		) ranked WHERE rn <= ?`, postIDs, perPost)
+
+	var likes []*models.Like
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id IN (?)", subQuery).
+		Order("created_at DESC, id DESC").
+		Find(&likes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recent likes for posts: %w", err)
+	}
+
+	for _, like := range likes {
+		result[like.PostID] = append(result[like.PostID], like)
+	}
+	return result, nil
+}
+
+// GetLikedPostsByUser 按点赞时间倒序以keyset方式分页获取userID的点赞记录，并带出帖子及作者。
+// 已删除的帖子不返回；beforeID为uuid.Nil时从最新的点赞开始
+func (r *LikeRepository) GetLikedPostsByUser(ctx context.Context, userID uuid.UUID, beforeLikedAt time.Time, beforeID uuid.UUID, limit int) ([]*models.Like, error) {
+	db := r.db.WithContext(ctx).
+		Preload("Post.User").
+		Joins("JOIN posts ON posts.id = likes.post_id AND posts.is_deleted = false AND posts.deleted_at IS NULL").
+		Where("likes.user_id = ?", userID)
+	if beforeID != uuid.Nil {
+		db = db.Where("(likes.created_at, likes.id) < (?, ?)", beforeLikedAt, beforeID)
+	}
+
+	var likes []*models.Like
+	if err := db.Order("likes.created_at DESC, likes.id DESC").Limit(limit).Find(&likes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get liked posts by user: %w", err)
+	}
+	return likes, nil
+}
+
+// GetReactionsForPosts 批量查询userID对帖子的回应类型，只返回postIDs中有回应的部分
+func (r *LikeRepository) GetReactionsForPosts(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	reactions := make(map[uuid.UUID]string, len(postIDs))
+	if len(postIDs) == 0 {
+		return reactions, nil
+	}
+
+	var likes []*models.Like
+	if err := r.db.WithContext(ctx).
+		Select("post_id", "reaction_type").
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Find(&likes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reactions for posts: %w", err)
+	}
+	for _, like := range likes {
+		reactions[like.PostID] = like.ReactionType
+	}
+	return reactions, nil
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次版本化的数据库迁移，按Version升序执行且只执行一次
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行迁移的记录
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 迁移记录表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrationLockKey 迁移时持有的事务级advisory lock，避免多个实例同时启动时重复执行
+const migrationLockKey = 7260856
+
+// execStatements 依次执行一组SQL
+func execStatements(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrations 全部迁移，只能在末尾追加，已发布的迁移不要修改
+var migrations = []Migration{
+	{
+		// 基线：固定为引入版本化迁移时AutoMigrate生成的DDL，不随模型变化；之后新增的列由后续迁移添加。
+		// 已有库上表和索引都已存在，执行时不做任何修改。
+		// timelines上同名的idx_user_post在原先的AutoMigrate中因索引名已被likes占用而被跳过，这里保持一致
+		Version: 1,
+		Name:    "create_base_schema",
+		Up: execStatements(
+			`CREATE TABLE IF NOT EXISTS "users" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"username" text NOT NULL,
+				"email" text NOT NULL,
+				"password" text NOT NULL,
+				"display_name" text,
+				"avatar" text,
+				"bio" text,
+				"followers" bigint DEFAULT 0,
+				"following" bigint DEFAULT 0,
+				"is_active" boolean DEFAULT true,
+				"last_active_at" timestamptz,
+				"activity_score" decimal DEFAULT 0,
+				"is_online" boolean DEFAULT false,
+				"created_at" timestamptz,
+				"updated_at" timestamptz,
+				"deleted_at" timestamptz,
+				PRIMARY KEY ("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at")`,
+			`CREATE INDEX IF NOT EXISTS "idx_users_last_active_at" ON "users" ("last_active_at")`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email")`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username")`,
+			`CREATE TABLE IF NOT EXISTS "follows" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"follower_id" uuid NOT NULL,
+				"following_id" uuid NOT NULL,
+				"created_at" timestamptz,
+				"deleted_at" timestamptz,
+				PRIMARY KEY ("id"),
+				CONSTRAINT "fk_follows_follower" FOREIGN KEY ("follower_id") REFERENCES "users"("id"),
+				CONSTRAINT "fk_follows_following" FOREIGN KEY ("following_id") REFERENCES "users"("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_follower_following" ON "follows" ("follower_id","following_id")`,
+			`CREATE INDEX IF NOT EXISTS "idx_follows_deleted_at" ON "follows" ("deleted_at")`,
+			`CREATE TABLE IF NOT EXISTS "posts" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"user_id" uuid NOT NULL,
+				"content" text NOT NULL,
+				"image_urls" text[],
+				"like_count" bigint DEFAULT 0,
+				"comment_count" bigint DEFAULT 0,
+				"share_count" bigint DEFAULT 0,
+				"score" decimal DEFAULT 0,
+				"is_deleted" boolean DEFAULT false,
+				"created_at" timestamptz,
+				"updated_at" timestamptz,
+				"deleted_at" timestamptz,
+				PRIMARY KEY ("id"),
+				CONSTRAINT "fk_posts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_posts_deleted_at" ON "posts" ("deleted_at")`,
+			`CREATE INDEX IF NOT EXISTS "idx_posts_user_id" ON "posts" ("user_id")`,
+			`CREATE TABLE IF NOT EXISTS "likes" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"user_id" uuid NOT NULL,
+				"post_id" uuid NOT NULL,
+				"created_at" timestamptz,
+				"deleted_at" timestamptz,
+				PRIMARY KEY ("id"),
+				CONSTRAINT "fk_likes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+				CONSTRAINT "fk_likes_post" FOREIGN KEY ("post_id") REFERENCES "posts"("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_likes_deleted_at" ON "likes" ("deleted_at")`,
+			`CREATE INDEX IF NOT EXISTS "idx_user_post" ON "likes" ("user_id","post_id")`,
+			`CREATE TABLE IF NOT EXISTS "comments" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"user_id" uuid NOT NULL,
+				"post_id" uuid NOT NULL,
+				"content" text NOT NULL,
+				"parent_id" uuid,
+				"like_count" bigint DEFAULT 0,
+				"created_at" timestamptz,
+				"deleted_at" timestamptz,
+				PRIMARY KEY ("id"),
+				CONSTRAINT "fk_comments_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+				CONSTRAINT "fk_comments_post" FOREIGN KEY ("post_id") REFERENCES "posts"("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_comments_deleted_at" ON "comments" ("deleted_at")`,
+			`CREATE INDEX IF NOT EXISTS "idx_comments_post_id" ON "comments" ("post_id")`,
+			`CREATE TABLE IF NOT EXISTS "timelines" (
+				"id" uuid DEFAULT gen_random_uuid(),
+				"user_id" uuid NOT NULL,
+				"post_id" uuid NOT NULL,
+				"score" decimal DEFAULT 0,
+				"created_at" timestamptz,
+				PRIMARY KEY ("id"),
+				CONSTRAINT "fk_timelines_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+				CONSTRAINT "fk_timelines_post" FOREIGN KEY ("post_id") REFERENCES "posts"("id")
+			)`,
+			`CREATE INDEX IF NOT EXISTS "idx_timelines_created_at" ON "timelines" ("created_at")`,
+		),
+	},
+	{
+		// 关注和点赞是软删除的，唯一约束只覆盖未删除的行；建索引前先清掉历史重复数据，保留最早的一条
+		Version: 2,
+		Name:    "add_unique_follows_likes_and_post_search",
+		Up: execStatements(
+			`DELETE FROM follows a USING follows b
+			 WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			   AND a.follower_id = b.follower_id AND a.following_id = b.following_id
+			   AND (a.created_at > b.created_at OR (a.created_at = b.created_at AND a.id > b.id))`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_follows_follower_following_unique
+			 ON follows (follower_id, following_id) WHERE deleted_at IS NULL`,
+			`DELETE FROM likes a USING likes b
+			 WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			   AND a.user_id = b.user_id AND a.post_id = b.post_id
+			   AND (a.created_at > b.created_at OR (a.created_at = b.created_at AND a.id > b.id))`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_user_post_unique
+			 ON likes (user_id, post_id) WHERE deleted_at IS NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_posts_content_tsv
+			 ON posts USING gin (to_tsvector('simple', content))`,
+		),
+	},
+	{
+		// 覆盖TimelineRepository.GetByUserID的过滤和排序，分页时无需再排序
+		Version: 3,
+		Name:    "add_timelines_user_score_index",
+		Up: execStatements(
+			`CREATE INDEX IF NOT EXISTS idx_timelines_user_score_created
+			 ON timelines (user_id, score DESC, created_at DESC, id DESC)`,
+		),
+	},
+	{
+		// 同一帖子重复分发（如重试）不应产生重复的Timeline行，写入侧配合ON CONFLICT DO NOTHING
+		Version: 4,
+		Name:    "add_unique_timelines_user_post",
+		Up: execStatements(
+			`DELETE FROM timelines a USING timelines b
+			 WHERE a.user_id = b.user_id AND a.post_id = b.post_id
+			   AND (a.created_at > b.created_at OR (a.created_at = b.created_at AND a.id > b.id))`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_timelines_user_post_unique
+			 ON timelines (user_id, post_id)`,
+		),
+	},
+	{
+		// 关注关系上的通知屏蔽和密友标记，以及帖子的可见范围；密友索引用于按作者取密友列表
+		Version: 5,
+		Name:    "add_follow_preferences_and_post_visibility",
+		Up: execStatements(
+			`ALTER TABLE follows ADD COLUMN IF NOT EXISTS mute_notifications boolean NOT NULL DEFAULT false`,
+			`ALTER TABLE follows ADD COLUMN IF NOT EXISTS close_friend boolean NOT NULL DEFAULT false`,
+			`CREATE INDEX IF NOT EXISTS idx_follows_close_friends
+			 ON follows (following_id) WHERE close_friend AND deleted_at IS NULL`,
+			`ALTER TABLE posts ADD COLUMN IF NOT EXISTS visibility varchar(20) NOT NULL DEFAULT 'public'`,
+		),
+	},
+	{
+		// 覆盖CommentRepository.GetByPostIDCursor的keyset分页
+		Version: 6,
+		Name:    "add_comments_post_created_index",
+		Up: execStatements(
+			`CREATE INDEX IF NOT EXISTS idx_comments_post_created
+			 ON comments (post_id, created_at DESC, id DESC)`,
+		),
+	},
+	{
+		// 用户点赞列表的隐私开关，以及覆盖LikeRepository.GetLikedPostsByUser的keyset分页
+		Version: 7,
+		Name:    "add_user_show_likes_and_likes_user_created_index",
+		Up: execStatements(
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS show_likes boolean NOT NULL DEFAULT true`,
+			`CREATE INDEX IF NOT EXISTS idx_likes_user_created
+			 ON likes (user_id, created_at DESC, id DESC) WHERE deleted_at IS NULL`,
+		),
+	},
+	{
+		// 点赞扩展为多种回应：点赞行记录回应类型，帖子按类型计数；已有点赞都是like
+		Version: 8,
+		Name:    "add_like_reaction_types",
+		Up: execStatements(
+			`ALTER TABLE likes ADD COLUMN IF NOT EXISTS reaction_type varchar(20) NOT NULL DEFAULT 'like'`,
+			`ALTER TABLE posts ADD COLUMN IF NOT EXISTS reaction_counts jsonb NOT NULL DEFAULT '{}'`,
+			`UPDATE posts SET reaction_counts = jsonb_build_object('like', like_count) WHERE like_count > 0`,
+		),
+	},
+	{
+		// 引入版本化迁移之前由AutoMigrate加上的列：基线库上没有，需要补上；已由AutoMigrate加过的库不受影响
+		Version: 9,
+		Name:    "add_post_view_count_removed_at_and_user_show_profile_views",
+		Up: execStatements(
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS show_profile_views boolean DEFAULT true`,
+			`ALTER TABLE posts ADD COLUMN IF NOT EXISTS view_count bigint DEFAULT 0`,
+			`ALTER TABLE posts ADD COLUMN IF NOT EXISTS removed_at timestamptz`,
+		),
+	},
+	{
+		// 覆盖PostRepository.GetPostsByUserIDs和GetTopPostsPerUser的keyset分页
+		Version: 10,
+		Name:    "add_posts_user_created_index",
+		Up: execStatements(
+			`CREATE INDEX IF NOT EXISTS idx_posts_user_created
+			 ON posts (user_id, created_at DESC, id DESC) WHERE is_deleted = false`,
+		),
+	},
+}
+
+// Migrate 执行所有未执行的迁移，每个迁移在独立事务中执行并记录到schema_migrations，
+// 重复调用是安全的。返回本次执行的迁移数
+func (db *Database) Migrate(ctx context.Context) (int, error) {
+	conn := db.DB.WithContext(ctx)
+	if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		ran := false
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			// 拿到锁后再确认一次，其他实例可能已经执行过
+			var count int64
+			if err := tx.Model(&SchemaMigration{}).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			ran = true
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if ran {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// SchemaVersion 返回当前已执行的最大迁移版本，未执行过任何迁移时为0
+func (db *Database) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	if err := db.DB.WithContext(ctx).Model(&SchemaMigration{}).
+		Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
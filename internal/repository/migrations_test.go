@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/feed-system/feed-system/internal/models"
+)
+
+// 版本必须严格递增，Migrate按切片顺序执行
+func TestMigrationVersionsAscending(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migrations[%d].Version = %d, want %d", i, m.Version, i+1)
+		}
+		if m.Name == "" || m.Up == nil {
+			t.Errorf("migration %d missing name or Up", m.Version)
+		}
+	}
+}
+
+func TestMigrateTwiceIsNoopIntegration(t *testing.T) {
+	db := newIntegrationDB(t) // 已执行过一次Migrate
+	database := &Database{db}
+	ctx := context.Background()
+
+	applied, err := database.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("second Migrate() applied %d migrations, want 0", applied)
+	}
+
+	version, err := database.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("SchemaVersion() error = %v", err)
+	}
+	if want := migrations[len(migrations)-1].Version; version != want {
+		t.Errorf("SchemaVersion() = %d, want %d", version, want)
+	}
+
+	var count int64
+	if err := db.Model(&SchemaMigration{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != int64(len(migrations)) {
+		t.Errorf("schema_migrations has %d rows, want %d", count, len(migrations))
+	}
+
+	// 基线之后加上的列由迁移补齐
+	for _, c := range []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.User{}, "show_profile_views"},
+		{&models.Post{}, "view_count"},
+		{&models.Post{}, "removed_at"},
+	} {
+		if !db.Migrator().HasColumn(c.model, c.column) {
+			t.Errorf("column %s missing after Migrate()", c.column)
+		}
+	}
+}
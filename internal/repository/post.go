@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
@@ -38,11 +39,16 @@ func (r *PostRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Pos
 	return &post, nil
 }
 
-func (r *PostRepository) GetByUserID(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*models.Post, error) {
+// GetByUserID 分页获取用户的帖子，includeCloseFriends为false时不包含仅密友可见的帖子
+func (r *PostRepository) GetByUserID(ctx context.Context, userID uuid.UUID, offset, limit int, includeCloseFriends bool) ([]*models.Post, error) {
 	var posts []*models.Post
-	if err := r.db.WithContext(ctx).
+	db := r.db.WithContext(ctx).
 		Preload("User").
-		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Where("user_id = ? AND is_deleted = ?", userID, false)
+	if !includeCloseFriends {
+		db = db.Where("visibility = ?", models.PostVisibilityPublic)
+	}
+	if err := db.
 		Order("created_at DESC").
 		Offset(offset).
 		Limit(limit).
@@ -52,6 +58,33 @@ func (r *PostRepository) GetByUserID(ctx context.Context, userID uuid.UUID, offs
 	return posts, nil
 }
 
+// ScanByUserID 按(created_at, id)升序以keyset方式分页获取用户的帖子，用于导出等全量遍历。
+// afterID为uuid.Nil时从头开始
+func (r *PostRepository) ScanByUserID(ctx context.Context, userID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int) ([]*models.Post, error) {
+	db := r.db.WithContext(ctx).Where("user_id = ? AND is_deleted = ?", userID, false)
+	if afterID != uuid.Nil {
+		db = db.Where("(created_at, id) > (?, ?)", afterCreatedAt, afterID)
+	}
+
+	var posts []*models.Post
+	if err := db.Order("created_at ASC, id ASC").Limit(limit).Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to scan posts by user: %w", err)
+	}
+	return posts, nil
+}
+
+// CountByUserIDSince 统计用户在since之后发布的帖子数（含已删除），用于判断发帖频率
+func (r *PostRepository) CountByUserIDSince(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recent posts: %w", err)
+	}
+	return count, nil
+}
+
 func (r *PostRepository) Update(ctx context.Context, post *models.Post) error {
 	if err := r.db.WithContext(ctx).Save(post).Error; err != nil {
 		return fmt.Errorf("failed to update post: %w", err)
@@ -59,56 +92,243 @@ func (r *PostRepository) Update(ctx context.Context, post *models.Post) error {
 	return nil
 }
 
-// GetByIDs 根据ID列表批量获取帖子
+// GetByIDs 根据ID列表获取帖子，ID较多时分块并发查询，结果按传入顺序返回
 func (r *PostRepository) GetByIDs(ctx context.Context, postIDs []uuid.UUID) ([]*models.Post, error) {
-	var posts []*models.Post
-	if err := r.db.WithContext(ctx).
-		Preload("User").
-		Where("id IN (?)", postIDs).
-		Where("is_deleted = ?", false).
-		Find(&posts).Error; err != nil {
-		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+	if len(postIDs) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	chunks, err := queryInChunks(ctx, postIDs, func(ctx context.Context, chunk []uuid.UUID) ([]*models.Post, error) {
+		var posts []*models.Post
+		if err := r.db.WithContext(ctx).
+			Where("id IN (?)", chunk).
+			Where("is_deleted = ?", false).
+			Find(&posts).Error; err != nil {
+			return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+		}
+		return posts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	postMap := make(map[uuid.UUID]*models.Post, len(postIDs))
+	for _, posts := range chunks {
+		for _, post := range posts {
+			postMap[post.ID] = post
+		}
+	}
+
+	posts := make([]*models.Post, 0, len(postMap))
+	for _, id := range postIDs {
+		if post, ok := postMap[id]; ok {
+			posts = append(posts, post)
+			delete(postMap, id) // 重复ID只返回一次
+		}
+	}
+
+	if err := r.attachAuthors(ctx, posts); err != nil {
+		return nil, err
 	}
 	return posts, nil
 }
 
-// GetPostsByUserIDs 根据用户ID列表获取帖子（用于拉模式）
-func (r *PostRepository) GetPostsByUserIDs(ctx context.Context, userIDs []uuid.UUID, cursor string, limit int) ([]*models.Post, error) {
-	var posts []*models.Post
-	db := r.db.WithContext(ctx).
-		Preload("User").
-		Where("user_id IN (?)", userIDs).
-		Where("is_deleted = ?", false)
+// attachAuthors 为帖子填充作者信息。Feed中同一作者常出现多次，且按ID分块查询时
+// 各块的Preload会重复加载同一作者，这里对作者ID去重后统一查询一次再回填
+func (r *PostRepository) attachAuthors(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
 
-	// 处理游标分页
-	if cursor != "" {
-		if cursorTime, err := time.Parse(time.RFC3339Nano, cursor); err == nil {
-			db = db.Where("created_at < ?", cursorTime)
+	seen := make(map[uuid.UUID]struct{}, len(posts))
+	authorIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		if _, ok := seen[post.UserID]; !ok {
+			seen[post.UserID] = struct{}{}
+			authorIDs = append(authorIDs, post.UserID)
 		}
 	}
 
-	if err := db.Order("created_at DESC").
-		Limit(limit).
-		Find(&posts).Error; err != nil {
-		return nil, fmt.Errorf("failed to get posts by user IDs: %w", err)
+	chunks, err := queryInChunks(ctx, authorIDs, func(ctx context.Context, chunk []uuid.UUID) ([]*models.User, error) {
+		var users []*models.User
+		if err := r.db.WithContext(ctx).Where("id IN (?)", chunk).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to get post authors: %w", err)
+		}
+		return users, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	authors := make(map[uuid.UUID]*models.User, len(authorIDs))
+	for _, users := range chunks {
+		for _, user := range users {
+			authors[user.ID] = user
+		}
+	}
+	for _, post := range posts {
+		if author, ok := authors[post.UserID]; ok {
+			post.User = *author
+		}
+	}
+	return nil
+}
+
+// GetPostsByUserIDs 根据用户ID列表按(created_at, id)降序以keyset方式分页获取帖子（用于拉模式）
+// 关注数较多时按作者分块并发查询，每块各取limit条后归并。beforeCreatedAt为零值时从最新的帖子开始
+func (r *PostRepository) GetPostsByUserIDs(ctx context.Context, userIDs []uuid.UUID, beforeCreatedAt time.Time, beforeID uuid.UUID, limit int) ([]*models.Post, error) {
+	if len(userIDs) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	chunks, err := queryInChunks(ctx, userIDs, func(ctx context.Context, chunk []uuid.UUID) ([]*models.Post, error) {
+		var posts []*models.Post
+		db := r.db.WithContext(ctx).
+			Preload("User").
+			Where("user_id IN (?)", chunk).
+			Where("is_deleted = ?", false)
+
+		if !beforeCreatedAt.IsZero() {
+			db = db.Where("(created_at, id) < (?, ?)", beforeCreatedAt, beforeID)
+		}
+
+		if err := db.Order("created_at DESC, id DESC").
+			Limit(limit).
+			Find(&posts).Error; err != nil {
+			return nil, fmt.Errorf("failed to get posts by user IDs: %w", err)
+		}
+		return posts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 1 {
+		return chunks[0], nil
+	}
+
+	var posts []*models.Post
+	for _, chunk := range chunks {
+		posts = append(posts, chunk...)
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID.String() > posts[j].ID.String()
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
 	}
 	return posts, nil
 }
 
+// GetTopPostsPerUser 为每个用户分别取(created_at, id)在游标之前的最新perUser条帖子（用于拉模式的多路归并）
+// 每个作者的查询都能走(user_id, created_at, id)索引，避免单个巨大IN查询的全局排序。beforeCreatedAt为零值时从最新的帖子开始
+func (r *PostRepository) GetTopPostsPerUser(ctx context.Context, userIDs []uuid.UUID, beforeCreatedAt time.Time, beforeID uuid.UUID, perUser int) (map[uuid.UUID][]*models.Post, error) {
+	result := make(map[uuid.UUID][]*models.Post)
+	if len(userIDs) == 0 || perUser <= 0 {
+		return result, nil
+	}
+
+	// 没有游标时取一个略晚于当前的时间，配合uuid.Nil等价于created_at < 该时间
+	if beforeCreatedAt.IsZero() {
+		beforeCreatedAt, beforeID = time.Now().Add(time.Minute), uuid.Nil
+	}
+
+	subQuery := r.db.Raw(`SELECT p.id FROM unnest(?::uuid[]) AS a(user_id)
+		CROSS JOIN LATERAL (
+			SELECT id FROM posts
+			WHERE posts.user_id = a.user_id AND posts.is_deleted = false AND (posts.created_at, posts.id) < (?, ?)
+			ORDER BY posts.created_at DESC, posts.id DESC
+			LIMIT ?
+		) p`, uuidArray(userIDs), beforeCreatedAt, beforeID, perUser)
+
+	var posts []*models.Post
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id IN (?)", subQuery).
+		Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get top posts per user: %w", err)
+	}
+
+	for _, post := range posts {
+		result[post.UserID] = append(result[post.UserID], post)
+	}
+	return result, nil
+}
+
 func (r *PostRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	if err := r.db.WithContext(ctx).
 		Model(&models.Post{}).
 		Where("id = ?", id).
-		Update("is_deleted", true).Error; err != nil {
+		Updates(map[string]interface{}{
+			"is_deleted": true,
+			"removed_at": time.Now(),
+		}).Error; err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
 	return nil
 }
 
+// GetDeletedByID 获取已被用户删除的帖子，不存在或未删除时返回nil
+func (r *PostRepository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*models.Post, error) {
+	var post models.Post
+	if err := r.db.WithContext(ctx).
+		First(&post, "id = ? AND is_deleted = ?", id, true).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get deleted post: %w", err)
+	}
+	return &post, nil
+}
+
+// Restore 恢复在removedSince之后删除的帖子，条件在同一条UPDATE中判断，返回是否恢复成功
+func (r *PostRepository) Restore(ctx context.Context, id uuid.UUID, removedSince time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Where("id = ? AND is_deleted = ? AND removed_at >= ?", id, true, removedSince).
+		Updates(map[string]interface{}{
+			"is_deleted": false,
+			"removed_at": nil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to restore post: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateReactionCounts 在一条UPDATE中调整点赞总数和按回应类型的计数，计数不会小于0
+func (r *PostRepository) UpdateReactionCounts(ctx context.Context, postID uuid.UUID, likeDelta int64, deltas map[string]int64) error {
+	types := make([]string, 0, len(deltas))
+	for reactionType := range deltas {
+		types = append(types, reactionType)
+	}
+	sort.Strings(types)
+
+	countsExpr := "reaction_counts"
+	args := make([]interface{}, 0, len(types)*3)
+	for _, reactionType := range types {
+		countsExpr = "jsonb_set(" + countsExpr + ", ARRAY[?]::text[], to_jsonb(GREATEST(COALESCE((reaction_counts->>?::text)::bigint, 0) + ?, 0)))"
+		args = append(args, reactionType, reactionType, deltas[reactionType])
+	}
+
+	if err := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ?", postID).
+		UpdateColumns(map[string]interface{}{
+			"like_count":      gorm.Expr("GREATEST(like_count + ?, 0)", likeDelta),
+			"reaction_counts": gorm.Expr(countsExpr, args...),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update reaction counts: %w", err)
+	}
+	return nil
+}
+
 func (r *PostRepository) UpdateLikeCount(ctx context.Context, postID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.Post{}).
 		Where("id = ?", postID).
-		UpdateColumn("like_count", gorm.Expr("like_count + ?", delta)).Error; err != nil {
+		UpdateColumn("like_count", gorm.Expr("GREATEST(like_count + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update like count: %w", err)
 	}
 	return nil
@@ -117,7 +337,7 @@ func (r *PostRepository) UpdateLikeCount(ctx context.Context, postID uuid.UUID,
 func (r *PostRepository) UpdateCommentCount(ctx context.Context, postID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.Post{}).
 		Where("id = ?", postID).
-		UpdateColumn("comment_count", gorm.Expr("comment_count + ?", delta)).Error; err != nil {
+		UpdateColumn("comment_count", gorm.Expr("GREATEST(comment_count + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update comment count: %w", err)
 	}
 	return nil
@@ -126,15 +346,54 @@ func (r *PostRepository) UpdateCommentCount(ctx context.Context, postID uuid.UUI
 func (r *PostRepository) UpdateShareCount(ctx context.Context, postID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.Post{}).
 		Where("id = ?", postID).
-		UpdateColumn("share_count", gorm.Expr("share_count + ?", delta)).Error; err != nil {
+		UpdateColumn("share_count", gorm.Expr("GREATEST(share_count + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update share count: %w", err)
 	}
 	return nil
 }
 
+// GetRecentlyEngaged 获取指定时间之后有新点赞或评论的帖子
+func (r *PostRepository) GetRecentlyEngaged(ctx context.Context, since time.Time, limit int) ([]*models.Post, error) {
+	var posts []*models.Post
+	engaged := r.db.Raw(`
+		SELECT post_id FROM likes WHERE created_at > ? AND deleted_at IS NULL
+		UNION
+		SELECT post_id FROM comments WHERE created_at > ? AND deleted_at IS NULL`, since, since)
+
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("id IN (?)", engaged).
+		Where("is_deleted = ?", false).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recently engaged posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (r *PostRepository) UpdateScore(ctx context.Context, postID uuid.UUID, score float64) error {
+	if err := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ?", postID).
+		UpdateColumn("score", score).Error; err != nil {
+		return fmt.Errorf("failed to update post score: %w", err)
+	}
+	return nil
+}
+
+func (r *PostRepository) IncrementViewCount(ctx context.Context, postID uuid.UUID, delta int64) error {
+	if err := r.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ?", postID).
+		UpdateColumn("view_count", gorm.Expr("view_count + ?", delta)).Error; err != nil {
+		return fmt.Errorf("failed to update view count: %w", err)
+	}
+	return nil
+}
+
 func (r *PostRepository) Search(ctx context.Context, query string, offset, limit int) ([]*models.Post, error) {
 	var posts []*models.Post
-	db := r.db.WithContext(ctx).Preload("User").Where("is_deleted = ?", false)
+	db := r.db.WithContext(ctx).Preload("User").
+		Where("is_deleted = ? AND visibility = ?", false, models.PostVisibilityPublic)
 
 	if query != "" {
 		db = db.Where("content LIKE ?", "%"+query+"%")
@@ -145,3 +404,38 @@ func (r *PostRepository) Search(ctx context.Context, query string, offset, limit
 	}
 	return posts, nil
 }
+
+// UserPostStats 用户帖子的聚合统计
+type UserPostStats struct {
+	TotalPosts    int64 `json:"total_posts"`
+	TotalLikes    int64 `json:"total_likes"`
+	TotalComments int64 `json:"total_comments"`
+	TotalShares   int64 `json:"total_shares"`
+}
+
+// AggregateUserStats 汇总用户未删除帖子的数量和收到的互动数
+func (r *PostRepository) AggregateUserStats(ctx context.Context, userID uuid.UUID) (*UserPostStats, error) {
+	var stats UserPostStats
+	if err := r.db.WithContext(ctx).Model(&models.Post{}).
+		Select("COUNT(*) AS total_posts, COALESCE(SUM(like_count), 0) AS total_likes, COALESCE(SUM(comment_count), 0) AS total_comments, COALESCE(SUM(share_count), 0) AS total_shares").
+		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate user post stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// GetMostEngagedPost 获取用户互动数（点赞+评论+分享）最高的帖子
+func (r *PostRepository) GetMostEngagedPost(ctx context.Context, userID uuid.UUID) (*models.Post, error) {
+	var post models.Post
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND is_deleted = ?", userID, false).
+		Order("like_count + comment_count + share_count DESC, created_at DESC").
+		First(&post).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get most engaged post: %w", err)
+	}
+	return &post, nil
+}
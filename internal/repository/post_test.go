@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestUUIDArrayValue(t *testing.T) {
+	a := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	b := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	value, err := uuidArray{a, b}.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	want := "{11111111-1111-1111-1111-111111111111,22222222-2222-2222-2222-222222222222}"
+	if value != want {
+		t.Errorf("Value() = %v, want %v", value, want)
+	}
+
+	if value, _ := (uuidArray{}).Value(); value != "{}" {
+		t.Errorf("empty Value() = %v, want {}", value)
+	}
+}
+
+// GetTopPostsPerUser必须把作者列表绑定成一个数组参数，否则unnest收到的是一条记录
+func TestGetTopPostsPerUserBindsSingleArrayParam(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewPostRepository(db)
+
+	userIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	array, _ := uuidArray(userIDs).Value()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`unnest($1::uuid[]) AS a(user_id)`)).
+		WithArgs(array, sqlmock.AnyArg(), sqlmock.AnyArg(), 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+
+	posts, err := repo.GetTopPostsPerUser(context.Background(), userIDs, time.Time{}, uuid.Nil, 5)
+	if err != nil {
+		t.Fatalf("GetTopPostsPerUser() error = %v", err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("GetTopPostsPerUser() = %v, want empty", posts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetTopPostsPerUserIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	var userIDs []uuid.UUID
+	for i := 0; i < 3; i++ {
+		user := createTestUser(t, db)
+		userIDs = append(userIDs, user.ID)
+		for j := 0; j < 4; j++ {
+			post := &models.Post{
+				UserID:    user.ID,
+				Content:   "post",
+				CreatedAt: base.Add(time.Duration(j*3+i) * time.Minute),
+			}
+			if err := db.Create(post).Error; err != nil {
+				t.Fatalf("failed to create post: %v", err)
+			}
+		}
+	}
+
+	result, err := repo.GetTopPostsPerUser(ctx, userIDs, time.Time{}, uuid.Nil, 2)
+	if err != nil {
+		t.Fatalf("GetTopPostsPerUser() error = %v", err)
+	}
+	if len(result) != len(userIDs) {
+		t.Fatalf("got posts for %d users, want %d", len(result), len(userIDs))
+	}
+	for _, userID := range userIDs {
+		posts := result[userID]
+		if len(posts) != 2 {
+			t.Fatalf("user %s: got %d posts, want 2", userID, len(posts))
+		}
+		// 每个作者取到的应是最新的两条
+		for _, post := range posts {
+			if post.CreatedAt.Before(base.Add(6 * time.Minute)) {
+				t.Errorf("user %s: got older post at %v", userID, post.CreatedAt)
+			}
+		}
+	}
+}
+
+// 拉模式翻页按(created_at, id)做keyset，同一时刻的帖子不会在翻页边界被漏掉
+func TestPullModeQueriesUseKeysetCursor(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+	userID, beforeID := uuid.New(), uuid.New()
+	before := time.Now().Add(-time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`(created_at, id) < ($3, $4)`)+`.* ORDER BY created_at DESC, id DESC LIMIT 10`).
+		WithArgs(userID, false, before, beforeID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+	if _, err := repo.GetPostsByUserIDs(ctx, []uuid.UUID{userID}, before, beforeID, 10); err != nil {
+		t.Fatalf("GetPostsByUserIDs() error = %v", err)
+	}
+
+	array, _ := uuidArray{userID}.Value()
+	mock.ExpectQuery(regexp.QuoteMeta(`(posts.created_at, posts.id) < ($2, $3)`)+`\s+`+regexp.QuoteMeta(`ORDER BY posts.created_at DESC, posts.id DESC`)).
+		WithArgs(array, before, beforeID, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+	if _, err := repo.GetTopPostsPerUser(ctx, []uuid.UUID{userID}, before, beforeID, 10); err != nil {
+		t.Fatalf("GetTopPostsPerUser() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// 创建帖子后、写入分数前有点赞到达：分数更新只写score列，不会用创建时的旧计数覆盖点赞数
+func TestUpdateScoreKeepsConcurrentCounterUpdates(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+	postID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET "like_count"=GREATEST(like_count + $1, 0) WHERE id = $2`)).
+		WithArgs(1, postID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET "score"=$1 WHERE id = $2`)).
+		WithArgs(12.5, postID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.UpdateLikeCount(ctx, postID, 1); err != nil {
+		t.Fatalf("UpdateLikeCount() error = %v", err)
+	}
+	if err := repo.UpdateScore(ctx, postID, 12.5); err != nil {
+		t.Fatalf("UpdateScore() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateScoreKeepsConcurrentCounterUpdatesIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	post := &models.Post{UserID: user.ID, Content: "post", Score: 1}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	// post仍持有创建时的计数，期间另一个请求点赞
+	if err := repo.UpdateLikeCount(ctx, post.ID, 1); err != nil {
+		t.Fatalf("UpdateLikeCount() error = %v", err)
+	}
+	if err := repo.UpdateScore(ctx, post.ID, 12.5); err != nil {
+		t.Fatalf("UpdateScore() error = %v", err)
+	}
+
+	var stored models.Post
+	if err := db.First(&stored, "id = ?", post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LikeCount != 1 || stored.Score != 12.5 {
+		t.Errorf("stored like_count = %d, score = %v, want 1 and 12.5", stored.LikeCount, stored.Score)
+	}
+}
+
+func TestPostCountersClampAtZero(t *testing.T) {
+	postID := uuid.New()
+	tests := []struct {
+		column string
+		update func(repo *PostRepository) error
+	}{
+		{"like_count", func(repo *PostRepository) error { return repo.UpdateLikeCount(context.Background(), postID, -1) }},
+		{"comment_count", func(repo *PostRepository) error { return repo.UpdateCommentCount(context.Background(), postID, -1) }},
+		{"share_count", func(repo *PostRepository) error { return repo.UpdateShareCount(context.Background(), postID, -1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "posts" SET "`+tt.column+`"=GREATEST(`+tt.column+` + $1, 0) WHERE id = $2`)).
+				WithArgs(-1, postID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := tt.update(NewPostRepository(db)); err != nil {
+				t.Fatalf("update error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPostCountersClampAtZeroIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	post := &models.Post{UserID: user.ID, Content: "post"}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	// 重复取消点赞、丢失的点赞事件都不能让计数变成负数
+	if err := repo.UpdateLikeCount(ctx, post.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.UpdateLikeCount(ctx, post.ID, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateCommentCount(ctx, post.ID, -3); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateShareCount(ctx, post.ID, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.Post
+	if err := db.First(&stored, "id = ?", post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LikeCount != 0 || stored.CommentCount != 0 || stored.ShareCount != 0 {
+		t.Errorf("counts = like %d, comment %d, share %d, want all 0", stored.LikeCount, stored.CommentCount, stored.ShareCount)
+	}
+}
+
+func TestAggregateUserStatsIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewPostRepository(db)
+	ctx := context.Background()
+
+	user, other := createTestUser(t, db), createTestUser(t, db)
+	seed := []*models.Post{
+		{UserID: user.ID, Content: "a", LikeCount: 5, CommentCount: 1, ShareCount: 0},
+		{UserID: user.ID, Content: "b", LikeCount: 2, CommentCount: 4, ShareCount: 3},
+		{UserID: user.ID, Content: "deleted", LikeCount: 100, CommentCount: 100, IsDeleted: true},
+		{UserID: other.ID, Content: "other", LikeCount: 50},
+	}
+	for _, post := range seed {
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+
+	// 已删除的帖子和其他用户的帖子不计入
+	stats, err := repo.AggregateUserStats(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("AggregateUserStats() error = %v", err)
+	}
+	want := UserPostStats{TotalPosts: 2, TotalLikes: 7, TotalComments: 5, TotalShares: 3}
+	if *stats != want {
+		t.Errorf("AggregateUserStats() = %+v, want %+v", *stats, want)
+	}
+
+	top, err := repo.GetMostEngagedPost(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetMostEngagedPost() error = %v", err)
+	}
+	if top == nil || top.ID != seed[1].ID {
+		t.Errorf("GetMostEngagedPost() = %v, want post %s", top, seed[1].ID)
+	}
+
+	empty := createTestUser(t, db)
+	if stats, err := repo.AggregateUserStats(ctx, empty.ID); err != nil || *stats != (UserPostStats{}) {
+		t.Errorf("AggregateUserStats(no posts) = %+v, %v, want zeros", stats, err)
+	}
+}
+
+// 一页帖子无论来自多少作者，作者信息都只查询一次，且每个作者只查一遍
+func TestGetByIDsLoadsAuthorsInOneQuery(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewPostRepository(db)
+
+	authors := make([]uuid.UUID, 20)
+	for i := range authors {
+		authors[i] = uuid.New()
+	}
+	// 60条帖子轮流来自20个作者，最后一个作者已被删除
+	postIDs := make([]uuid.UUID, 60)
+	postRows := sqlmock.NewRows([]string{"id", "user_id", "content"})
+	for i := range postIDs {
+		postIDs[i] = uuid.New()
+		postRows.AddRow(postIDs[i], authors[i%len(authors)], "post")
+	}
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).WillReturnRows(postRows)
+
+	authorArgs := make([]driver.Value, len(authors))
+	userRows := sqlmock.NewRows([]string{"id", "username"})
+	for i, id := range authors {
+		authorArgs[i] = id
+		if i < len(authors)-1 {
+			userRows.AddRow(id, fmt.Sprintf("author%d", i))
+		}
+	}
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN \(.*\) AND "users"."deleted_at" IS NULL`).
+		WithArgs(authorArgs...).
+		WillReturnRows(userRows)
+
+	posts, err := repo.GetByIDs(context.Background(), postIDs)
+	if err != nil {
+		t.Fatalf("GetByIDs() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(posts) != len(postIDs) {
+		t.Fatalf("got %d posts, want %d", len(posts), len(postIDs))
+	}
+	for i, post := range posts {
+		authorIndex := i % len(authors)
+		if post.ID != postIDs[i] || post.UserID != authors[authorIndex] {
+			t.Fatalf("post %d = %s by %s, want %s by %s", i, post.ID, post.UserID, postIDs[i], authors[authorIndex])
+		}
+		if authorIndex == len(authors)-1 {
+			if post.User.ID != uuid.Nil {
+				t.Errorf("post %d has deleted author %+v", i, post.User)
+			}
+			continue
+		}
+		if post.User.ID != post.UserID || post.User.Username != fmt.Sprintf("author%d", authorIndex) {
+			t.Errorf("post %d author = %s/%s, want %s/author%d", i, post.User.ID, post.User.Username, post.UserID, authorIndex)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TimelineRepository struct {
@@ -17,26 +18,35 @@ func NewTimelineRepository(db *gorm.DB) *TimelineRepository {
 	return &TimelineRepository{db: db}
 }
 
+// timelineOnConflict (user_id, post_id)已存在时跳过，重复分发同一帖子是幂等的
+var timelineOnConflict = clause.OnConflict{
+	Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+	DoNothing: true,
+}
+
+// Create 创建Timeline记录，同一用户的同一帖子已存在时不做任何修改
 func (r *TimelineRepository) Create(ctx context.Context, timeline *models.Timeline) error {
-	if err := r.db.WithContext(ctx).Create(timeline).Error; err != nil {
+	if err := r.db.WithContext(ctx).Clauses(timelineOnConflict).Create(timeline).Error; err != nil {
 		return fmt.Errorf("failed to create timeline: %w", err)
 	}
 	return nil
 }
 
+// CreateBatch 批量创建Timeline记录，已存在的(user_id, post_id)会被跳过
 func (r *TimelineRepository) CreateBatch(ctx context.Context, timelines []*models.Timeline) error {
-	if err := r.db.WithContext(ctx).CreateInBatches(timelines, 100).Error; err != nil {
+	if err := r.db.WithContext(ctx).Clauses(timelineOnConflict).CreateInBatches(timelines, 100).Error; err != nil {
 		return fmt.Errorf("failed to create timelines in batch: %w", err)
 	}
 	return nil
 }
 
+// GetByUserID 按分数倒序分页获取用户Timeline，分数和时间都相同时按id排序，保证翻页结果稳定
 func (r *TimelineRepository) GetByUserID(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*models.Timeline, error) {
 	var timelines []*models.Timeline
 	if err := r.db.WithContext(ctx).
 		Preload("Post.User").
 		Where("user_id = ?", userID).
-		Order("score DESC, created_at DESC").
+		Order("score DESC, created_at DESC, id DESC").
 		Offset(offset).
 		Limit(limit).
 		Find(&timelines).Error; err != nil {
@@ -63,6 +73,17 @@ func (r *TimelineRepository) DeleteByUserIDAndPostID(ctx context.Context, userID
 	return nil
 }
 
+// UpdateScoreByPostID 更新所有包含该帖子的Timeline条目分数
+func (r *TimelineRepository) UpdateScoreByPostID(ctx context.Context, postID uuid.UUID, score float64) error {
+	if err := r.db.WithContext(ctx).
+		Model(&models.Timeline{}).
+		Where("post_id = ?", postID).
+		UpdateColumn("score", score).Error; err != nil {
+		return fmt.Errorf("failed to update timeline score: %w", err)
+	}
+	return nil
+}
+
 func (r *TimelineRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
 	var count int64
 	if err := r.db.WithContext(ctx).
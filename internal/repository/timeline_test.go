@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestGetByUserIDOrdersByIDLast(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "timelines" WHERE user_id = $1 ORDER BY score DESC, created_at DESC, id DESC LIMIT 10 OFFSET 20`)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := NewTimelineRepository(db).GetByUserID(context.Background(), userID, 20, 10); err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetByUserIDStableTiesIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewTimelineRepository(db)
+	ctx := context.Background()
+
+	owner, author := createTestUser(t, db), createTestUser(t, db)
+	t.Cleanup(func() { db.Where("user_id = ?", owner.ID).Delete(&models.Timeline{}) })
+
+	// 五条分数和时间完全相同的条目
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	for i := 0; i < 5; i++ {
+		post := &models.Post{UserID: author.ID, Content: "post", CreatedAt: createdAt}
+		if err := db.Create(post).Error; err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+		entry := &models.Timeline{UserID: owner.ID, PostID: post.ID, Score: 7, CreatedAt: createdAt}
+		if err := db.Create(entry).Error; err != nil {
+			t.Fatalf("failed to create timeline entry: %v", err)
+		}
+	}
+
+	all, err := repo.GetByUserID(ctx, owner.ID, 0, 10)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d entries, want 5", len(all))
+	}
+
+	// 逐页读取，每页与整体顺序一致，没有重复或遗漏
+	for i := range all {
+		page, err := repo.GetByUserID(ctx, owner.ID, i, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != all[i].ID {
+			t.Errorf("page %d = %v, want %s", i, page, all[i].ID)
+		}
+		if i > 0 && all[i-1].ID.String() <= all[i].ID.String() {
+			t.Errorf("entries %d and %d not ordered by id desc", i-1, i)
+		}
+	}
+}
+
+// 大表上GetByUserID的分页查询应走idx_timelines_user_score_created，不需要额外排序
+func TestGetByUserIDUsesCompositeIndexIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	ctx := context.Background()
+
+	author := createTestUser(t, db)
+	if err := db.Exec(`INSERT INTO posts (user_id, content, created_at, updated_at)
+		SELECT ?, 'post', now() - i * interval '1 minute', now() FROM generate_series(1, 500) AS i`, author.ID).Error; err != nil {
+		t.Fatalf("failed to create posts: %v", err)
+	}
+
+	// 20个用户各500条Timeline，单个用户只占一小部分
+	var owners []uuid.UUID
+	for i := 0; i < 20; i++ {
+		owner := createTestUser(t, db)
+		owners = append(owners, owner.ID)
+		if err := db.Exec(`INSERT INTO timelines (user_id, post_id, score, created_at)
+			SELECT ?, id, random() * 100, created_at FROM posts WHERE user_id = ?`, owner.ID, author.ID).Error; err != nil {
+			t.Fatalf("failed to create timelines: %v", err)
+		}
+	}
+	t.Cleanup(func() { db.Where("user_id IN ?", owners).Delete(&models.Timeline{}) })
+	if err := db.Exec(`ANALYZE timelines`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 与TimelineRepository.GetByUserID生成的查询一致
+	var plan string
+	if err := db.WithContext(ctx).Raw(`EXPLAIN (FORMAT JSON)
+		SELECT * FROM timelines WHERE user_id = ? ORDER BY score DESC, created_at DESC, id DESC LIMIT 20 OFFSET 40`,
+		owners[0]).Row().Scan(&plan); err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	if !json.Valid([]byte(plan)) {
+		t.Fatalf("unexpected plan output: %s", plan)
+	}
+	if !strings.Contains(plan, `"Index Name": "idx_timelines_user_score_created"`) {
+		t.Errorf("feed page query does not use idx_timelines_user_score_created:\n%s", plan)
+	}
+	if strings.Contains(plan, `"Node Type": "Sort"`) {
+		t.Errorf("feed page query still sorts:\n%s", plan)
+	}
+}
+
+func TestTimelineCreateSkipsExistingUserPost(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewTimelineRepository(db)
+	ctx := context.Background()
+	userID, postID := uuid.New(), uuid.New()
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "timelines" .* ON CONFLICT \("user_id","post_id"\) DO NOTHING`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+	}
+
+	if err := repo.Create(ctx, &models.Timeline{UserID: userID, PostID: postID, Score: 1}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.CreateBatch(ctx, []*models.Timeline{{UserID: userID, PostID: postID, Score: 1}}); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTimelineRedistributeKeepsOneRowIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewTimelineRepository(db)
+	ctx := context.Background()
+
+	owner, author := createTestUser(t, db), createTestUser(t, db)
+	t.Cleanup(func() { db.Where("user_id = ?", owner.ID).Delete(&models.Timeline{}) })
+	post := &models.Post{UserID: author.ID, Content: "post"}
+	if err := db.Create(post).Error; err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	// 同一帖子分发多次：单条写入、批量写入以及批内重复
+	createdAt := time.Now().Truncate(time.Microsecond)
+	if err := repo.Create(ctx, &models.Timeline{UserID: owner.ID, PostID: post.ID, Score: 1, CreatedAt: createdAt}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.Create(ctx, &models.Timeline{UserID: owner.ID, PostID: post.ID, Score: 2, CreatedAt: createdAt}); err != nil {
+		t.Fatalf("second Create() error = %v", err)
+	}
+	batch := []*models.Timeline{
+		{UserID: owner.ID, PostID: post.ID, Score: 3, CreatedAt: createdAt},
+		{UserID: owner.ID, PostID: post.ID, Score: 4, CreatedAt: createdAt},
+	}
+	if err := repo.CreateBatch(ctx, batch); err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+
+	var rows []*models.Timeline
+	if err := db.Where("user_id = ? AND post_id = ?", owner.ID, post.ID).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d timeline rows, want 1", len(rows))
+	}
+	// 已有的行不被覆盖
+	if rows[0].Score != 1 {
+		t.Errorf("score = %v, want the first distribution's 1", rows[0].Score)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/google/uuid"
@@ -64,10 +65,51 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	return nil
 }
 
+// UpdateProfile 只更新资料字段，不覆盖关注数等计数器
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	if err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", user.ID).
+		Updates(map[string]interface{}{
+			"display_name": user.DisplayName,
+			"avatar":       user.Avatar,
+			"bio":          user.Bio,
+
+			"show_profile_views": user.ShowProfileViews,
+			"show_likes":         user.ShowLikes,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+	return nil
+}
+
+// UpdateActivity 只更新活跃度相关字段
+func (r *UserRepository) UpdateActivity(ctx context.Context, userID uuid.UUID, lastActiveAt *time.Time, activityScore float64, isOnline bool) error {
+	if err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"last_active_at": lastActiveAt,
+			"activity_score": activityScore,
+			"is_online":      isOnline,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update user activity: %w", err)
+	}
+	return nil
+}
+
+// UpdateOnlineStatus 只更新在线状态
+func (r *UserRepository) UpdateOnlineStatus(ctx context.Context, userID uuid.UUID, isOnline bool) error {
+	if err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("is_online", isOnline).Error; err != nil {
+		return fmt.Errorf("failed to update user online status: %w", err)
+	}
+	return nil
+}
+
 func (r *UserRepository) UpdateFollowersCount(ctx context.Context, userID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.User{}).
 		Where("id = ?", userID).
-		UpdateColumn("followers", gorm.Expr("followers + ?", delta)).Error; err != nil {
+		UpdateColumn("followers", gorm.Expr("GREATEST(followers + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update followers count: %w", err)
 	}
 	return nil
@@ -76,12 +118,49 @@ func (r *UserRepository) UpdateFollowersCount(ctx context.Context, userID uuid.U
 func (r *UserRepository) UpdateFollowingCount(ctx context.Context, userID uuid.UUID, delta int64) error {
 	if err := r.db.WithContext(ctx).Model(&models.User{}).
 		Where("id = ?", userID).
-		UpdateColumn("following", gorm.Expr("following + ?", delta)).Error; err != nil {
+		UpdateColumn("following", gorm.Expr("GREATEST(following + ?, 0)", delta)).Error; err != nil {
 		return fmt.Errorf("failed to update following count: %w", err)
 	}
 	return nil
 }
 
+func (r *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	var users []*models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users by IDs: %w", err)
+	}
+	return users, nil
+}
+
+// GetByUsernames 按用户名批量获取用户，不存在的用户名被忽略
+func (r *UserRepository) GetByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	var users []*models.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	if err := r.db.WithContext(ctx).Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users by usernames: %w", err)
+	}
+	return users, nil
+}
+
+// GetMostActive 按活跃度分数倒序返回最近活跃过的用户ID
+func (r *UserRepository) GetMostActive(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("last_active_at IS NOT NULL").
+		Order("activity_score DESC").
+		Limit(limit).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get most active users: %w", err)
+	}
+	return ids, nil
+}
+
 func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]*models.User, error) {
 	var users []*models.User
 	if err := r.db.WithContext(ctx).
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+// 活跃度更新只写活跃度相关的列，不会覆盖同时发生的关注数变更
+func TestUpdateActivityWritesOnlyActivityColumns(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db)
+	userID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "activity_score"=$1,"is_online"=$2,"last_active_at"=$3 WHERE id = $4`)).
+		WithArgs(7.5, true, &now, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.UpdateActivity(context.Background(), userID, &now, 7.5, true); err != nil {
+		t.Fatalf("UpdateActivity() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUserCountersClampAtZero(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		column string
+		update func(repo *UserRepository) error
+	}{
+		{"followers", func(repo *UserRepository) error { return repo.UpdateFollowersCount(context.Background(), userID, -1) }},
+		{"following", func(repo *UserRepository) error { return repo.UpdateFollowingCount(context.Background(), userID, -1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "`+tt.column+`"=GREATEST(`+tt.column+` + $1, 0) WHERE id = $2`)).
+				WithArgs(-1, userID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			if err := tt.update(NewUserRepository(db)); err != nil {
+				t.Fatalf("update error = %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestUserCountersClampAtZeroIntegration(t *testing.T) {
+	db := newIntegrationDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := createTestUser(t, db)
+	if err := repo.UpdateFollowersCount(ctx, user.ID, -2); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateFollowingCount(ctx, user.ID, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Followers != 0 || stored.Following != 0 {
+		t.Errorf("followers = %d, following = %d, want 0", stored.Followers, stored.Following)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
 	"time"
 
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
 	"github.com/feed-system/feed-system/pkg/cache"
@@ -16,20 +19,26 @@ import (
 
 // ActivityService 用户活跃度服务
 type ActivityService struct {
-	userRepo *repository.UserRepository
-	cache    *cache.RedisClient
-	logger   *logger.Logger
+	userRepo   *repository.UserRepository
+	followRepo *repository.FollowRepository
+	cache      *cache.RedisClient
+	config     *config.ConfigWatcher
+	logger     *logger.Logger
 }
 
 func NewActivityService(
 	userRepo *repository.UserRepository,
+	followRepo *repository.FollowRepository,
 	cache *cache.RedisClient,
+	config *config.ConfigWatcher,
 	logger *logger.Logger,
 ) *ActivityService {
 	return &ActivityService{
-		userRepo: userRepo,
-		cache:    cache,
-		logger:   logger,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		cache:      cache,
+		config:     config,
+		logger:     logger,
 	}
 }
 
@@ -43,8 +52,21 @@ const (
 	ActivityDecayFactor = 0.9
 	// 最大活跃度分数
 	MaxActivityScore = 1000.0
+	// 关注者最后一次心跳超过该时间后不再算作活跃关注者
+	ActiveFollowerWindow = OnlineUserCacheTTL
+	// 每个用户的活跃关注者集合最多保留的人数，超出时淘汰最久没有心跳的
+	MaxActiveFollowers = 5000
+	// 同一用户在该时间内重复心跳只更新一次活跃关注者集合
+	ActiveFollowerMarkInterval = time.Minute
+	// 一次心跳最多更新多少个被关注者的集合
+	MaxActiveFollowerFollowees = 2000
 )
 
+// activeFollowersKey 用户的活跃关注者ZSet，score为关注者最后一次心跳的时间戳
+func activeFollowersKey(userID string) string {
+	return fmt.Sprintf("active_followers:%s", userID)
+}
+
 // IsUserActive 判断用户是否活跃
 func (s *ActivityService) IsUserActive(ctx context.Context, userID uuid.UUID) (bool, error) {
 	// 先从缓存检查
@@ -77,6 +99,38 @@ func (s *ActivityService) IsUserActive(ctx context.Context, userID uuid.UUID) (b
 	return isActive, nil
 }
 
+// GetUsersByIDs 批量获取用户的活跃度信息
+func (s *ActivityService) GetUsersByIDs(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*models.User, error) {
+	users, err := s.userRepo.GetByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]*models.User, len(users))
+	for _, user := range users {
+		result[user.ID] = user
+	}
+	return result, nil
+}
+
+// PartitionByActivity 将用户按是否活跃分为两组
+func (s *ActivityService) PartitionByActivity(ctx context.Context, userIDs []uuid.UUID) ([]uuid.UUID, []uuid.UUID, error) {
+	users, err := s.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var active, inactive []uuid.UUID
+	for _, userID := range userIDs {
+		if user, ok := users[userID]; ok && s.calculateUserActivity(user) {
+			active = append(active, userID)
+		} else {
+			inactive = append(inactive, userID)
+		}
+	}
+	return active, inactive, nil
+}
+
 // UpdateUserActivity 更新用户活跃度
 func (s *ActivityService) UpdateUserActivity(ctx context.Context, userID uuid.UUID, activityType string) error {
 	user, err := s.userRepo.GetByID(ctx, userID)
@@ -84,7 +138,7 @@ func (s *ActivityService) UpdateUserActivity(ctx context.Context, userID uuid.UU
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return fmt.Errorf("user not found")
+		return apperrors.NotFound("user not found")
 	}
 
 	now := time.Now()
@@ -111,7 +165,7 @@ func (s *ActivityService) UpdateUserActivity(ctx context.Context, userID uuid.UU
 	}
 
 	// 更新数据库
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err := s.userRepo.UpdateActivity(ctx, user.ID, user.LastActiveAt, user.ActivityScore, user.IsOnline); err != nil {
 		return fmt.Errorf("failed to update user activity: %w", err)
 	}
 
@@ -127,32 +181,69 @@ func (s *ActivityService) UpdateUserActivity(ctx context.Context, userID uuid.UU
 		s.logger.WithError(err).Error("Failed to clear user activity cache")
 	}
 
+	if err := s.MarkFollowerActive(ctx, userID); err != nil {
+		s.logger.WithError(err).Error("Failed to mark follower active")
+	}
+
 	return nil
 }
 
-// GetActiveFollowers 获取活跃的关注者列表
-func (s *ActivityService) GetActiveFollowers(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
-	cacheKey := fmt.Sprintf("active_followers:%s", userID.String())
+// MarkFollowerActive 用户心跳时把他加入所有被关注者的活跃关注者集合，
+// 过期的成员和超出上限的成员在同一次pipeline中清理
+func (s *ActivityService) MarkFollowerActive(ctx context.Context, followerID uuid.UUID) error {
+	first, err := s.cache.SetNX(ctx, fmt.Sprintf("active_follower_mark:%s", followerID.String()), "1", ActiveFollowerMarkInterval)
+	if err != nil {
+		return fmt.Errorf("failed to throttle active follower mark: %w", err)
+	}
+	if !first {
+		return nil
+	}
 
-	// 尝试从缓存获取
-	if cachedFollowers, err := s.getActiveFollowersFromCache(ctx, cacheKey); err == nil && len(cachedFollowers) > 0 {
-		if len(cachedFollowers) > limit {
-			return cachedFollowers[:limit], nil
-		}
-		return cachedFollowers, nil
+	followingIDs, err := s.followRepo.GetFollowingIDs(ctx, followerID, MaxActiveFollowerFollowees)
+	if err != nil {
+		return err
+	}
+	if len(followingIDs) == 0 {
+		return nil
 	}
 
-	// 从数据库查询所有关注者，然后过滤活跃用户
-	// 这里需要添加相应的repository方法
-	// 暂时返回空列表，实际实现需要查询follow表并过滤活跃用户
-	var activeFollowers []uuid.UUID
+	now := time.Now()
+	cutoff := strconv.FormatInt(now.Add(-ActiveFollowerWindow).Unix(), 10)
+	member := &redis.Z{Score: float64(now.Unix()), Member: followerID.String()}
+
+	pipe := s.cache.Pipeline()
+	for _, followingID := range followingIDs {
+		key := activeFollowersKey(followingID.String())
+		pipe.ZAdd(ctx, key, member)
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+cutoff)
+		pipe.ZRemRangeByRank(ctx, key, 0, -MaxActiveFollowers-1)
+		pipe.Expire(ctx, key, ActiveFollowerWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update active followers: %w", err)
+	}
+	return nil
+}
 
-	// 缓存结果
-	if err := s.cacheActiveFollowers(ctx, cacheKey, activeFollowers); err != nil {
-		s.logger.WithError(err).Error("Failed to cache active followers")
+// GetActiveFollowers 获取活跃的关注者列表，按最近心跳时间倒序。集合由关注者心跳时增量维护，
+// 这里只读取窗口内的成员
+func (s *ActivityService) GetActiveFollowers(ctx context.Context, userID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	results, err := s.cache.ZRevRangeByScoreWithScores(ctx, activeFollowersKey(userID.String()), &redis.ZRangeBy{
+		Min:   strconv.FormatInt(time.Now().Add(-ActiveFollowerWindow).Unix(), 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active followers: %w", err)
 	}
 
-	return activeFollowers, nil
+	followers := make([]uuid.UUID, 0, len(results))
+	for _, result := range results {
+		if id, err := uuid.Parse(result.Member.(string)); err == nil {
+			followers = append(followers, id)
+		}
+	}
+	return followers, nil
 }
 
 // SetUserOffline 设置用户离线
@@ -167,7 +258,7 @@ func (s *ActivityService) SetUserOffline(ctx context.Context, userID uuid.UUID)
 	}
 
 	user.IsOnline = false
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err := s.userRepo.UpdateOnlineStatus(ctx, user.ID, false); err != nil {
 		return fmt.Errorf("failed to update user offline status: %w", err)
 	}
 
@@ -200,6 +291,24 @@ func (s *ActivityService) calculateUserActivity(user *models.User) bool {
 	return false
 }
 
+// QualifiesForLongTTL 活跃用户是否使用长TTL：在线或活跃度分数达标的用户直接满足，
+// 仅凭最近活跃判定为活跃的用户还需活跃度不低于min_recent_score，避免零星活动的用户一直占用长TTL的缓存
+func (s *ActivityService) QualifiesForLongTTL(ctx context.Context, userID uuid.UUID) (bool, error) {
+	minScore := s.config.Feed().Optimization.ActiveUser.MinRecentScore
+	if minScore <= 0 {
+		return true, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return false, nil
+	}
+	return user.IsOnline || user.ActivityScore >= ActiveUserScoreThreshold || user.ActivityScore >= minScore, nil
+}
+
 // getActivityIncrement 根据活动类型获取活跃度增量
 func (s *ActivityService) getActivityIncrement(activityType string) float64 {
 	switch activityType {
@@ -219,42 +328,3 @@ func (s *ActivityService) getActivityIncrement(activityType string) float64 {
 		return 1.0
 	}
 }
-
-// getActiveFollowersFromCache 从缓存获取活跃关注者
-func (s *ActivityService) getActiveFollowersFromCache(ctx context.Context, key string) ([]uuid.UUID, error) {
-	// 使用Redis Set存储活跃关注者ID
-	members, err := s.cache.ZRevRange(ctx, key, 0, -1)
-	if err != nil {
-		return nil, err
-	}
-
-	var followers []uuid.UUID
-	for _, member := range members {
-		if id, err := uuid.Parse(member); err == nil {
-			followers = append(followers, id)
-		}
-	}
-
-	return followers, nil
-}
-
-// cacheActiveFollowers 缓存活跃关注者
-func (s *ActivityService) cacheActiveFollowers(ctx context.Context, key string, followers []uuid.UUID) error {
-	if len(followers) == 0 {
-		return nil
-	}
-
-	// 使用ZSet存储，score为当前时间戳
-	now := float64(time.Now().Unix())
-	for _, follower := range followers {
-		if err := s.cache.ZAdd(ctx, key, &redis.Z{
-			Score:  now,
-			Member: follower.String(),
-		}); err != nil {
-			return err
-		}
-	}
-
-	// 设置过期时间
-	return s.cache.Expire(ctx, key, 10*time.Minute)
-}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestMarkFollowerActive(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	activity := NewActivityService(repository.NewUserRepository(db), repository.NewFollowRepository(db), redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+
+	followerID, stale, recent := uuid.New(), uuid.New(), uuid.New()
+	authorA, authorB := uuid.New(), uuid.New()
+	keyA := activeFollowersKey(authorA.String())
+	mr.ZAdd(keyA, float64(time.Now().Add(-2*ActiveFollowerWindow).Unix()), stale.String())
+	mr.ZAdd(keyA, float64(time.Now().Add(-time.Minute).Unix()), recent.String())
+
+	// 同一关注者在标记间隔内的重复心跳只查询一次关注列表
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(authorA).AddRow(authorB))
+
+	for i := 0; i < 2; i++ {
+		if err := activity.MarkFollowerActive(ctx, followerID); err != nil {
+			t.Fatalf("MarkFollowerActive: %v", err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := mr.ZMembers(keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("active followers of A = %v, want stale member pruned", members)
+	}
+	if ttl := mr.TTL(keyA); ttl <= 0 || ttl > ActiveFollowerWindow {
+		t.Errorf("active followers TTL = %v", ttl)
+	}
+
+	followers, err := activity.GetActiveFollowers(ctx, authorA, 10)
+	if err != nil {
+		t.Fatalf("GetActiveFollowers: %v", err)
+	}
+	if len(followers) != 2 || followers[0] != followerID || followers[1] != recent {
+		t.Errorf("GetActiveFollowers() = %v, want [%s %s]", followers, followerID, recent)
+	}
+
+	followers, err = activity.GetActiveFollowers(ctx, authorB, 10)
+	if err != nil {
+		t.Fatalf("GetActiveFollowers: %v", err)
+	}
+	if len(followers) != 1 || followers[0] != followerID {
+		t.Errorf("GetActiveFollowers() = %v, want [%s]", followers, followerID)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+// 单个统计项的最长耗时，超时的统计项记为错误，不影响其他统计项
+const adminStatsSectionTimeout = 5 * time.Second
+
+// StatsSection 一个可独立失败的统计项
+type StatsSection func(ctx context.Context) (interface{}, error)
+
+// AdminStatsService 聚合运维面板需要的各类统计
+type AdminStatsService struct {
+	logger *logger.Logger
+
+	mu       sync.RWMutex
+	names    []string
+	sections map[string]StatsSection
+}
+
+func NewAdminStatsService(logger *logger.Logger) *AdminStatsService {
+	return &AdminStatsService{
+		logger:   logger,
+		sections: make(map[string]StatsSection),
+	}
+}
+
+// Register 注册统计项，同名统计项会被覆盖
+func (s *AdminStatsService) Register(name string, section StatsSection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.sections[name]; !exists {
+		s.names = append(s.names, name)
+	}
+	s.sections[name] = section
+}
+
+// AdminStats 聚合统计结果，失败的统计项记录在Errors中
+type AdminStats struct {
+	Sections    map[string]interface{} `json:"sections"`
+	Errors      map[string]string      `json:"errors,omitempty"`
+	CollectedAt time.Time              `json:"collected_at"`
+}
+
+// Collect 并发收集所有统计项，单项失败或超时只影响该项
+func (s *AdminStatsService) Collect(ctx context.Context) *AdminStats {
+	s.mu.RLock()
+	names := append([]string(nil), s.names...)
+	sections := make(map[string]StatsSection, len(s.sections))
+	for name, section := range s.sections {
+		sections[name] = section
+	}
+	s.mu.RUnlock()
+
+	result := &AdminStats{
+		Sections:    make(map[string]interface{}, len(names)),
+		Errors:      make(map[string]string),
+		CollectedAt: time.Now(),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string, section StatsSection) {
+			defer wg.Done()
+
+			value, err := s.collectSection(ctx, section)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				s.logger.WithError(err).WithField("section", name).Warn("Failed to collect admin stats section")
+				result.Sections[name] = nil
+				result.Errors[name] = err.Error()
+				return
+			}
+			result.Sections[name] = value
+		}(name, sections[name])
+	}
+	wg.Wait()
+
+	return result
+}
+
+// collectSection 执行单个统计项，限制耗时并把panic转换为错误
+func (s *AdminStatsService) collectSection(ctx context.Context, section StatsSection) (value interface{}, err error) {
+	ctx, cancel := context.WithTimeout(ctx, adminStatsSectionTimeout)
+	defer cancel()
+
+	type outcome struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("stats section panicked: %v", r)}
+			}
+		}()
+		v, err := section(ctx)
+		done <- outcome{value: v, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.value, out.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
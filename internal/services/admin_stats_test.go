@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+func TestAdminStatsCollectDegradesPerSection(t *testing.T) {
+	service := NewAdminStatsService(logger.NewLogger())
+	service.Register("cache", func(ctx context.Context) (interface{}, error) {
+		return map[string]int{"timelines": 3}, nil
+	})
+	service.Register("distribution", func(ctx context.Context) (interface{}, error) {
+		return "stale", nil
+	})
+	// 同名统计项覆盖旧的
+	service.Register("distribution", func(ctx context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	service.Register("consumer_lag", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("broker unavailable")
+	})
+	service.Register("db_pool", func(ctx context.Context) (interface{}, error) {
+		panic("nil pool")
+	})
+	service.Register("jobs", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stats := service.Collect(ctx)
+
+	for _, name := range []string{"cache", "distribution", "consumer_lag", "db_pool", "jobs"} {
+		if _, ok := stats.Sections[name]; !ok {
+			t.Errorf("section %q missing from aggregate", name)
+		}
+	}
+	if len(stats.Sections) != 5 {
+		t.Errorf("got %d sections, want 5", len(stats.Sections))
+	}
+	if stats.Sections["distribution"] != "fresh" {
+		t.Errorf("distribution = %v, want the re-registered section", stats.Sections["distribution"])
+	}
+	if cache, ok := stats.Sections["cache"].(map[string]int); !ok || cache["timelines"] != 3 {
+		t.Errorf("cache = %v", stats.Sections["cache"])
+	}
+
+	// 失败、panic和超时的统计项只记录错误，不影响其他统计项
+	for _, name := range []string{"consumer_lag", "db_pool", "jobs"} {
+		if stats.Sections[name] != nil || stats.Errors[name] == "" {
+			t.Errorf("section %q = %v, error %q, want nil value with an error", name, stats.Sections[name], stats.Errors[name])
+		}
+	}
+	if _, ok := stats.Errors["cache"]; ok || len(stats.Errors) != 3 {
+		t.Errorf("errors = %v, want only the three failing sections", stats.Errors)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// timelineMemory 单个Timeline缓存的内存占用及其用户活跃度
+type timelineMemory struct {
+	key           string
+	userID        uuid.UUID
+	bytes         int64
+	activityScore float64
+	lastActiveAt  time.Time
+}
+
+// memoryBudgetBytes 获取Timeline缓存内存预算，0表示不限制
+func (s *CacheStrategyService) memoryBudgetBytes() int64 {
+	return int64(s.config.Feed().Optimization.Timeline.MemoryBudgetMB) * 1024 * 1024
+}
+
+// measureTimelineMemory 批量获取Timeline key的近似内存占用
+func (s *CacheStrategyService) measureTimelineMemory(ctx context.Context, keys []string) ([]*timelineMemory, int64, error) {
+	pipe := s.cache.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.MemoryUsage(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, 0, fmt.Errorf("failed to measure timeline memory: %w", err)
+	}
+
+	var timelines []*timelineMemory
+	var total int64
+	for i, key := range keys {
+		bytes, err := cmds[i].Result()
+		if err != nil {
+			// key可能在扫描后已过期
+			continue
+		}
+		userID, err := s.extractUserIDFromTimelineKey(key)
+		if err != nil {
+			continue
+		}
+		timelines = append(timelines, &timelineMemory{key: key, userID: userID, bytes: bytes})
+		total += bytes
+	}
+
+	return timelines, total, nil
+}
+
+// EnforceMemoryBudget 超出内存预算时，按活跃度从低到高淘汰用户Timeline直到回到预算内
+// 被淘汰的用户下次读取Feed时会通过拉模式重建
+func (s *CacheStrategyService) EnforceMemoryBudget(ctx context.Context, keys []string) (int, error) {
+	budget := s.memoryBudgetBytes()
+	if budget <= 0 || len(keys) == 0 {
+		return 0, nil
+	}
+
+	timelines, total, err := s.measureTimelineMemory(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	if total <= budget {
+		return 0, nil
+	}
+
+	userIDs := make([]uuid.UUID, len(timelines))
+	for i, t := range timelines {
+		userIDs[i] = t.userID
+	}
+	users, err := s.activityService.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get users for eviction: %w", err)
+	}
+	for _, t := range timelines {
+		if user, ok := users[t.userID]; ok {
+			t.activityScore = user.ActivityScore
+			if user.LastActiveAt != nil {
+				t.lastActiveAt = *user.LastActiveAt
+			}
+		}
+	}
+
+	// 最不活跃的用户排在最前面：先比较最后活跃时间，再比较活跃度分数
+	sort.Slice(timelines, func(i, j int) bool {
+		if !timelines[i].lastActiveAt.Equal(timelines[j].lastActiveAt) {
+			return timelines[i].lastActiveAt.Before(timelines[j].lastActiveAt)
+		}
+		return timelines[i].activityScore < timelines[j].activityScore
+	})
+
+	evicted := 0
+	for _, t := range timelines {
+		if total <= budget {
+			break
+		}
+		if err := s.cache.Delete(ctx, t.key, RankedTimelineKey(t.userID)); err != nil {
+			s.logger.WithError(err).WithField("key", t.key).Error("Failed to evict timeline")
+			continue
+		}
+		total -= t.bytes
+		evicted++
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"evicted":      evicted,
+		"budget_bytes": budget,
+		"usage_bytes":  total,
+	}).Info("Timeline memory budget enforced")
+
+	return evicted, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// newMemoryUsageRedis 与newTestRedis相同，但经由一个代理连接miniredis：
+// go-redis以小写发送"memory usage"，而miniredis只识别大写的USAGE子命令
+func newMemoryUsageRedis(t *testing.T) (*cache.RedisClient, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", mr.Addr())
+			if err != nil {
+				conn.Close()
+				return
+			}
+			go func() {
+				defer upstream.Close()
+				buf := make([]byte, 64*1024)
+				for {
+					n, err := conn.Read(buf)
+					if n > 0 {
+						chunk := bytes.ReplaceAll(buf[:n], []byte("\r\nusage\r\n"), []byte("\r\nUSAGE\r\n"))
+						if _, werr := upstream.Write(chunk); werr != nil {
+							return
+						}
+					}
+					if err != nil {
+						return
+					}
+				}
+			}()
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	client := cache.NewRedisClient(listener.Addr().String(), "", 0, 10, 0)
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+func TestEnforceMemoryBudgetEvictsLeastActiveFirst(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newMemoryUsageRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) {
+		feed.Optimization.Timeline.MemoryBudgetMB = 1
+	})
+	log := logger.NewLogger()
+	activityService := NewActivityService(repository.NewUserRepository(db), repository.NewFollowRepository(db), redisClient, cfg, log)
+	service := NewCacheStrategyService(redisClient, cfg, log, activityService, NewTimelineCacheService(redisClient, cfg, log))
+	ctx := context.Background()
+
+	// 三个大小相同的Timeline，合计超出1MB预算，淘汰一个后回到预算内
+	stale, idle, recent := uuid.New(), uuid.New(), uuid.New()
+	keys := make([]string, 0, 3)
+	for _, userID := range []uuid.UUID{recent, stale, idle} {
+		key := "timeline:" + userID.String()
+		for i := 0; i < 6000; i++ {
+			mr.ZAdd(key, float64(i), uuid.NewString())
+		}
+		mr.ZAdd(RankedTimelineKey(userID), 1, uuid.NewString())
+		keys = append(keys, key)
+	}
+	timelines, total, err := service.measureTimelineMemory(ctx, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := service.memoryBudgetBytes()
+	if largest := timelines[0].bytes; total <= budget || total-largest > budget {
+		t.Fatalf("timelines of %d bytes (total %d) do not straddle the %d byte budget", largest, total, budget)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "activity_score", "last_active_at"}).
+			AddRow(recent, 50.0, time.Now()).
+			AddRow(stale, 80.0, time.Now().Add(-10*24*time.Hour)).
+			AddRow(idle, 5.0, time.Now().Add(-24*time.Hour)))
+
+	evicted, err := service.EnforceMemoryBudget(ctx, keys)
+	if err != nil {
+		t.Fatalf("EnforceMemoryBudget: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 1 {
+		t.Errorf("evicted = %d, want 1", evicted)
+	}
+	if mr.Exists("timeline:"+stale.String()) || mr.Exists(RankedTimelineKey(stale)) {
+		t.Error("least recently active user's timelines were not evicted")
+	}
+	for _, userID := range []uuid.UUID{idle, recent} {
+		if !mr.Exists("timeline:"+userID.String()) || !mr.Exists(RankedTimelineKey(userID)) {
+			t.Errorf("timelines of more active user %s evicted", userID)
+		}
+	}
+}
+
+func TestEnforceMemoryBudgetUnderBudget(t *testing.T) {
+	_, mock := newTestDB(t)
+	redisClient, mr := newMemoryUsageRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) {
+		feed.Optimization.Timeline.MemoryBudgetMB = 1
+	})
+	service := NewCacheStrategyService(redisClient, cfg, logger.NewLogger(), nil, nil)
+
+	key := "timeline:" + uuid.NewString()
+	mr.ZAdd(key, 1, uuid.NewString())
+
+	// 未超出预算时不查询用户活跃度，也不淘汰
+	evicted, err := service.EnforceMemoryBudget(context.Background(), []string{key})
+	if err != nil || evicted != 0 {
+		t.Fatalf("EnforceMemoryBudget() = %d, %v", evicted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists(key) {
+		t.Error("timeline evicted while under budget")
+	}
+}
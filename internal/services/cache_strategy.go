@@ -6,15 +6,17 @@ import (
 	"time"
 
 	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 )
 
 // CacheStrategyService 缓存策略管理服务
 type CacheStrategyService struct {
 	cache                *cache.RedisClient
-	config               *config.FeedConfig
+	config               *config.ConfigWatcher
 	logger               *logger.Logger
 	activityService      *ActivityService
 	timelineCacheService *TimelineCacheService
@@ -22,7 +24,7 @@ type CacheStrategyService struct {
 
 func NewCacheStrategyService(
 	cache *cache.RedisClient,
-	config *config.FeedConfig,
+	config *config.ConfigWatcher,
 	logger *logger.Logger,
 	activityService *ActivityService,
 	timelineCacheService *TimelineCacheService,
@@ -45,6 +47,13 @@ const (
 	MaxTimelineItemsInactive = 200     // 非活跃用户最大Timeline条数
 )
 
+// 缓存策略档位，用于管理员强制指定
+const (
+	CacheTierActive   = "active"
+	CacheTierInactive = "inactive"
+	CacheTierVIP      = "vip"
+)
+
 // UserCacheStrategy 用户缓存策略
 type UserCacheStrategy struct {
 	UserID           uuid.UUID     `json:"user_id"`
@@ -52,11 +61,23 @@ type UserCacheStrategy struct {
 	IsVIP            bool          `json:"is_vip"`
 	CacheTTL         time.Duration `json:"cache_ttl"`
 	MaxTimelineItems int           `json:"max_timeline_items"`
+	Overridden       bool          `json:"overridden,omitempty"` // 由管理员强制指定，而非根据活跃度计算
+	ShortTTL         bool          `json:"short_ttl,omitempty"`  // 活跃但活跃度不足，使用非活跃用户的TTL（Timeline条数不受影响）
 	LastUpdated      time.Time     `json:"last_updated"`
 }
 
-// DetermineUserCacheStrategy 确定用户的缓存策略
+// DetermineUserCacheStrategy 确定用户的缓存策略，存在管理员覆盖时优先使用覆盖的档位
 func (s *CacheStrategyService) DetermineUserCacheStrategy(ctx context.Context, userID uuid.UUID) (*UserCacheStrategy, error) {
+	tier, err := s.GetCacheStrategyOverride(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get cache strategy override")
+	}
+	if tier != "" {
+		strategy := s.buildCacheStrategy(userID, tier == CacheTierActive || tier == CacheTierVIP, tier == CacheTierVIP)
+		strategy.Overridden = true
+		return strategy, nil
+	}
+
 	// 检查用户是否活跃
 	isActive, err := s.activityService.IsUserActive(ctx, userID)
 	if err != nil {
@@ -68,6 +89,26 @@ func (s *CacheStrategyService) DetermineUserCacheStrategy(ctx context.Context, u
 	isVIP := false
 	// TODO: 实现VIP用户判断逻辑
 
+	strategy := s.buildCacheStrategy(userID, isActive, isVIP)
+	if isActive && !isVIP {
+		longTTL, err := s.activityService.QualifiesForLongTTL(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to check long TTL eligibility")
+		} else if !longTTL {
+			strategy.ShortTTL = true
+			strategy.CacheTTL = InactiveUserCacheHours * time.Hour
+		}
+	}
+	return strategy, nil
+}
+
+// usesActiveTTL 是否使用活跃用户的缓存时间
+func (s *UserCacheStrategy) usesActiveTTL() bool {
+	return s.IsActive && !s.ShortTTL
+}
+
+// buildCacheStrategy 根据活跃和VIP状态生成缓存策略
+func (s *CacheStrategyService) buildCacheStrategy(userID uuid.UUID, isActive, isVIP bool) *UserCacheStrategy {
 	// 确定缓存策略
 	var cacheTTL time.Duration
 	var maxItems int
@@ -92,7 +133,89 @@ func (s *CacheStrategyService) DetermineUserCacheStrategy(ctx context.Context, u
 		LastUpdated:      time.Now(),
 	}
 
-	return strategy, nil
+	return strategy
+}
+
+// TimelineCaps 批量计算用户Timeline的条数上限，供写入Timeline时按档位裁剪
+// 管理员覆盖的档位优先，其余按活跃度划分；查询失败时返回nil，由调用方使用默认上限
+func (s *CacheStrategyService) TimelineCaps(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]int {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	caps := make(map[uuid.UUID]int, len(userIDs))
+	pending := userIDs
+
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = cacheStrategyOverrideKey(userID)
+	}
+	if tiers, err := s.cache.MGet(ctx, keys...); err != nil {
+		s.logger.WithError(err).Warn("Failed to get cache strategy overrides")
+	} else {
+		pending = make([]uuid.UUID, 0, len(userIDs))
+		for i, value := range tiers {
+			tier, _ := value.(string)
+			if tier == "" {
+				pending = append(pending, userIDs[i])
+				continue
+			}
+			strategy := s.buildCacheStrategy(userIDs[i], tier == CacheTierActive || tier == CacheTierVIP, tier == CacheTierVIP)
+			caps[userIDs[i]] = strategy.MaxTimelineItems
+		}
+	}
+	if len(pending) == 0 {
+		return caps
+	}
+
+	active, inactive, err := s.activityService.PartitionByActivity(ctx, pending)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to partition users for timeline caps")
+		return caps
+	}
+	for _, userID := range active {
+		caps[userID] = MaxTimelineItemsActive
+	}
+	for _, userID := range inactive {
+		caps[userID] = MaxTimelineItemsInactive
+	}
+	return caps
+}
+
+// cacheStrategyOverrideKey 管理员覆盖的缓存策略档位key
+func cacheStrategyOverrideKey(userID uuid.UUID) string {
+	return fmt.Sprintf("cache_strategy_override:%s", userID.String())
+}
+
+// GetCacheStrategyOverride 获取管理员为用户强制指定的档位，未指定时返回空字符串
+func (s *CacheStrategyService) GetCacheStrategyOverride(ctx context.Context, userID uuid.UUID) (string, error) {
+	tier, err := s.cache.Get(ctx, cacheStrategyOverrideKey(userID))
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return tier, nil
+}
+
+// SetCacheStrategyOverride 强制用户使用指定档位（用于测试），tier为空时清除覆盖
+func (s *CacheStrategyService) SetCacheStrategyOverride(ctx context.Context, userID uuid.UUID, tier string) error {
+	switch tier {
+	case "":
+		if err := s.cache.Delete(ctx, cacheStrategyOverrideKey(userID)); err != nil {
+			return fmt.Errorf("failed to clear cache strategy override: %w", err)
+		}
+	case CacheTierActive, CacheTierInactive, CacheTierVIP:
+		if err := s.cache.Set(ctx, cacheStrategyOverrideKey(userID), tier, 0); err != nil {
+			return fmt.Errorf("failed to set cache strategy override: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid cache tier: %s", tier)
+	}
+
+	// 重新计算并应用策略，使覆盖立即生效
+	return s.ApplyCacheStrategy(ctx, userID)
 }
 
 // ApplyCacheStrategy 应用缓存策略
@@ -103,7 +226,7 @@ func (s *CacheStrategyService) ApplyCacheStrategy(ctx context.Context, userID uu
 	}
 
 	// 设置Timeline缓存过期时间
-	if err := s.timelineCacheService.SetTimelineExpiration(ctx, userID, strategy.IsActive); err != nil {
+	if err := s.timelineCacheService.SetTimelineExpiration(ctx, userID, strategy.usesActiveTTL()); err != nil {
 		s.logger.WithError(err).Error("Failed to set timeline expiration")
 	}
 
@@ -127,13 +250,9 @@ func (s *CacheStrategyService) ApplyCacheStrategy(ctx context.Context, userID uu
 	return nil
 }
 
-// trimUserTimeline 裁剪用户Timeline
+// trimUserTimeline 裁剪用户Timeline，保留最新的maxItems条，排序时间线删除同样的条目
 func (s *CacheStrategyService) trimUserTimeline(ctx context.Context, userID uuid.UUID, maxItems int) error {
-	timelineKey := fmt.Sprintf("timeline:%s", userID.String())
-
-	// 保留最新的maxItems条记录，删除其余的
-	// 使用ZRemRangeByRank删除最旧的记录
-	return s.cache.ZRemRangeByRank(ctx, timelineKey, 0, -int64(maxItems)-1)
+	return s.timelineCacheService.TrimTimeline(ctx, userID, maxItems)
 }
 
 // cacheUserStrategy 缓存用户策略
@@ -153,17 +272,68 @@ func (s *CacheStrategyService) GetUserCacheStrategy(ctx context.Context, userID
 	return &strategy, nil
 }
 
+// FeedCacheTTL 返回用户Feed响应的缓存时间：按用户当前档位读取配置，未配置时使用feed.cache_ttl
+// 非活跃用户使用较短的TTL减少陈旧数据，VIP使用较长的TTL降低负载
+func (s *CacheStrategyService) FeedCacheTTL(ctx context.Context, userID uuid.UUID) time.Duration {
+	feedConfig := s.config.Feed()
+
+	strategy, err := s.GetUserCacheStrategy(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get user cache strategy")
+		return feedConfig.CacheTTL
+	}
+
+	tier := feedConfig.Optimization.InactiveUser
+	if strategy.IsVIP {
+		tier = feedConfig.Optimization.VIPUser
+	} else if strategy.usesActiveTTL() {
+		tier = feedConfig.Optimization.ActiveUser
+	}
+	if tier.FeedCacheTTL > 0 {
+		return tier.FeedCacheTTL
+	}
+	return feedConfig.CacheTTL
+}
+
 // CleanupInactiveUserCaches 清理非活跃用户的缓存
+// 按SCAN游标分页读取Timeline，每批处理batch_size个，批次之间间隔batch_delay，避免压垮Redis和DB
+// 每次最多检查max_per_run个Timeline，游标保存在Redis中，下一次从中断处继续
 func (s *CacheStrategyService) CleanupInactiveUserCaches(ctx context.Context) error {
 	s.logger.Info("Starting cleanup of inactive user caches")
 
-	// 扫描所有Timeline缓存
-	pattern := "timeline:*"
-	keys, err := s.scanTimelineKeys(ctx, pattern)
+	cleanedCount := 0
+	result, err := scanResumable(ctx, s.cache, "cleanup_cursor:inactive_user_caches", "timeline:*",
+		s.config.Feed().Optimization.CacheCleanup,
+		func(ctx context.Context, keys []string) {
+			cleanedCount += s.cleanupTimelineBatch(ctx, keys)
+		})
 	if err != nil {
-		return fmt.Errorf("failed to scan timeline keys: %w", err)
+		return fmt.Errorf("failed to cleanup timeline caches: %w", err)
 	}
 
+	// 裁剪后仍超出内存预算时，淘汰最不活跃用户的Timeline；预算针对全部Timeline，需要完整扫描
+	evicted := 0
+	if s.memoryBudgetBytes() > 0 {
+		keys, err := s.scanTimelineKeys(ctx, "timeline:*")
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to scan timeline keys for memory budget")
+		} else if evicted, err = s.EnforceMemoryBudget(ctx, keys); err != nil {
+			s.logger.WithError(err).Error("Failed to enforce timeline memory budget")
+		}
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"inspected":     result.Inspected,
+		"completed":     result.Completed,
+		"cleaned_count": cleanedCount,
+		"evicted_count": evicted,
+		"batches":       result.Batches,
+	}).Info("Inactive user cache cleanup completed")
+	return nil
+}
+
+// cleanupTimelineBatch 处理一批Timeline key，返回被裁剪的数量
+func (s *CacheStrategyService) cleanupTimelineBatch(ctx context.Context, keys []string) int {
 	cleanedCount := 0
 	for _, key := range keys {
 		// 提取用户ID
@@ -195,15 +365,13 @@ func (s *CacheStrategyService) CleanupInactiveUserCaches(ctx context.Context) er
 				cleanedCount++
 			}
 
-			// 设置较短的过期时间
-			if err := s.cache.Expire(ctx, key, InactiveUserCacheHours*time.Hour); err != nil {
+			// 设置较短的过期时间，时间线和排序时间线一致
+			if err := s.timelineCacheService.SetTimelineExpiration(ctx, userID, false); err != nil {
 				s.logger.WithError(err).Error("Failed to set expiration for inactive user timeline")
 			}
 		}
 	}
-
-	s.logger.WithField("cleaned_count", cleanedCount).Info("Inactive user cache cleanup completed")
-	return nil
+	return cleanedCount
 }
 
 // StartCacheCleanupJob 启动缓存清理任务
@@ -244,6 +412,11 @@ func (s *CacheStrategyService) GetCacheStats(ctx context.Context) (map[string]in
 
 	stats["total_timelines"] = len(keys)
 
+	if _, usage, err := s.measureTimelineMemory(ctx, keys); err == nil {
+		stats["memory_usage_mb"] = float64(usage) / 1024 / 1024
+	}
+	stats["memory_budget_mb"] = s.config.Feed().Optimization.Timeline.MemoryBudgetMB
+
 	// 统计不同类型用户
 	activeCount := 0
 	inactiveCount := 0
@@ -278,9 +451,7 @@ func (s *CacheStrategyService) GetCacheStats(ctx context.Context) (map[string]in
 
 // scanTimelineKeys 扫描Timeline相关的keys
 func (s *CacheStrategyService) scanTimelineKeys(ctx context.Context, pattern string) ([]string, error) {
-	// 这里需要实现Redis SCAN命令
-	// 暂时返回空列表，实际实现需要添加SCAN支持
-	return []string{}, nil
+	return s.cache.Scan(ctx, pattern, 1000)
 }
 
 // extractUserIDFromTimelineKey 从Timeline key中提取用户ID
@@ -294,11 +465,21 @@ func (s *CacheStrategyService) extractUserIDFromTimelineKey(key string) (uuid.UU
 	return uuid.Parse(userIDStr)
 }
 
-// PrewarmCache 预热缓存（为活跃用户预先构建Timeline）
-func (s *CacheStrategyService) PrewarmCache(ctx context.Context, userIDs []uuid.UUID) error {
+// TimelineLoader 从数据库加载用户Timeline，用于缓存预热
+type TimelineLoader func(ctx context.Context, userID uuid.UUID) ([]*models.Timeline, error)
+
+// PrewarmCache 预热缓存（为活跃用户预先构建Timeline），已缓存的跳过
+// 每个用户之间按prewarm.delay间隔，返回实际预热的用户数
+func (s *CacheStrategyService) PrewarmCache(ctx context.Context, userIDs []uuid.UUID, load TimelineLoader) (int, error) {
 	s.logger.WithField("user_count", len(userIDs)).Info("Starting cache prewarm")
 
+	delay := s.config.Feed().Optimization.Prewarm.Delay
+	warmed := 0
 	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return warmed, err
+		}
+
 		// 检查用户是否活跃
 		isActive, err := s.activityService.IsUserActive(ctx, userID)
 		if err != nil {
@@ -307,23 +488,41 @@ func (s *CacheStrategyService) PrewarmCache(ctx context.Context, userIDs []uuid.
 		}
 
 		// 只为活跃用户预热缓存
-		if isActive {
-			// 检查Timeline是否已存在
-			exists, err := s.timelineCacheService.IsTimelineCached(ctx, userID)
-			if err != nil {
-				s.logger.WithError(err).Error("Failed to check timeline cache")
-				continue
-			}
+		if !isActive {
+			continue
+		}
+
+		// 检查Timeline是否已存在
+		exists, err := s.timelineCacheService.IsTimelineCached(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to check timeline cache")
+			continue
+		}
+		if exists {
+			continue
+		}
 
-			if !exists {
-				// Timeline不存在，需要构建
-				// 这里可以触发拉模式来构建Timeline
-				s.logger.WithField("user_id", userID).Info("Prewarming timeline for active user")
-				// TODO: 实现Timeline预热逻辑
+		// Timeline不存在，从数据库重建
+		timelines, err := load(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).WithField("user_id", userID).Error("Failed to load timeline for prewarm")
+			continue
+		}
+		if err := s.timelineCacheService.RebuildTimelineFromDB(ctx, userID, timelines); err != nil {
+			s.logger.WithError(err).WithField("user_id", userID).Error("Failed to prewarm timeline")
+			continue
+		}
+		warmed++
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return warmed, ctx.Err()
 			}
 		}
 	}
 
-	s.logger.Info("Cache prewarm completed")
-	return nil
+	s.logger.WithField("warmed", warmed).Info("Cache prewarm completed")
+	return warmed, nil
 }
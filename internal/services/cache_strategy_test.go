@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestDetermineUserCacheStrategyMinRecentScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		activityScore float64
+		wantTTL       time.Duration
+	}{
+		{"barely active user gets the short TTL", 2, InactiveUserCacheHours * time.Hour},
+		{"regularly active user keeps the long TTL", 20, ActiveUserCacheHours * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newTestDB(t)
+			redisClient, mr := newTestRedis(t)
+			cfg := newTestConfig(func(feed *config.FeedConfig) {
+				feed.Optimization.ActiveUser.MinRecentScore = 10
+			})
+			log := logger.NewLogger()
+			userRepo := repository.NewUserRepository(db)
+			activityService := NewActivityService(userRepo, repository.NewFollowRepository(db), redisClient, cfg, log)
+			service := NewCacheStrategyService(redisClient, cfg, log, activityService, NewTimelineCacheService(redisClient, cfg, log))
+
+			// 最近一天内有活动，按7天规则判定为活跃
+			userID := uuid.New()
+			mr.Set("user_active:"+userID.String(), "1")
+			mock.ExpectQuery(`SELECT \* FROM "users"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "activity_score", "last_active_at"}).
+					AddRow(userID, tt.activityScore, time.Now().Add(-24*time.Hour)))
+
+			strategy, err := service.DetermineUserCacheStrategy(context.Background(), userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strategy.IsActive || strategy.MaxTimelineItems != MaxTimelineItemsActive {
+				t.Errorf("user should stay in the active tier, got %+v", strategy)
+			}
+			if strategy.CacheTTL != tt.wantTTL {
+				t.Errorf("CacheTTL = %s, want %s", strategy.CacheTTL, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestCacheStrategyOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		computed string // user_active缓存值，决定计算出的档位
+		override string
+		want     func(*UserCacheStrategy) bool
+	}{
+		{"vip forced on inactive user", "0", CacheTierVIP, func(s *UserCacheStrategy) bool {
+			return s.IsVIP && s.IsActive && s.CacheTTL == VIPUserCacheHours*time.Hour
+		}},
+		{"inactive forced on active user", "1", CacheTierInactive, func(s *UserCacheStrategy) bool {
+			return !s.IsActive && s.MaxTimelineItems == MaxTimelineItemsInactive
+		}},
+		{"active forced on inactive user", "0", CacheTierActive, func(s *UserCacheStrategy) bool {
+			return s.IsActive && !s.IsVIP && s.MaxTimelineItems == MaxTimelineItemsActive
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisClient, mr := newTestRedis(t)
+			cfg := newTestConfig(nil)
+			log := logger.NewLogger()
+			activityService := NewActivityService(nil, nil, redisClient, cfg, log)
+			service := NewCacheStrategyService(redisClient, cfg, log, activityService, NewTimelineCacheService(redisClient, cfg, log))
+			ctx := context.Background()
+
+			userID := uuid.New()
+			mr.Set("user_active:"+userID.String(), tt.computed)
+			computed, err := service.DetermineUserCacheStrategy(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := service.SetCacheStrategyOverride(ctx, userID, tt.override); err != nil {
+				t.Fatalf("SetCacheStrategyOverride: %v", err)
+			}
+			forced, err := service.DetermineUserCacheStrategy(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !forced.Overridden || !tt.want(forced) {
+				t.Errorf("overridden strategy = %+v", forced)
+			}
+
+			// 清除覆盖后恢复按活跃度计算的档位
+			if err := service.SetCacheStrategyOverride(ctx, userID, ""); err != nil {
+				t.Fatalf("SetCacheStrategyOverride(clear): %v", err)
+			}
+			reverted, err := service.DetermineUserCacheStrategy(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reverted.Overridden || reverted.IsActive != computed.IsActive || reverted.IsVIP != computed.IsVIP ||
+				reverted.MaxTimelineItems != computed.MaxTimelineItems {
+				t.Errorf("strategy after clearing = %+v, want computed %+v", reverted, computed)
+			}
+		})
+	}
+
+	t.Run("invalid tier", func(t *testing.T) {
+		redisClient, mr := newTestRedis(t)
+		service := NewCacheStrategyService(redisClient, newTestConfig(nil), logger.NewLogger(), nil, nil)
+		userID := uuid.New()
+		if err := service.SetCacheStrategyOverride(context.Background(), userID, "platinum"); err == nil {
+			t.Error("SetCacheStrategyOverride accepted an unknown tier")
+		}
+		if mr.Exists(cacheStrategyOverrideKey(userID)) {
+			t.Error("unknown tier stored")
+		}
+	})
+}
+
+// 写入Timeline时通过真实的TimelineCaps按档位裁剪：非活跃用户只保留MaxTimelineItemsInactive条
+func TestTimelineCapsTrimInactiveUserOnWrite(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(nil)
+	log := logger.NewLogger()
+	userRepo := repository.NewUserRepository(db)
+	activityService := NewActivityService(userRepo, repository.NewFollowRepository(db), redisClient, cfg, log)
+	timelineCache := NewTimelineCacheService(redisClient, cfg, log)
+	service := NewCacheStrategyService(redisClient, cfg, log, activityService, timelineCache)
+	timelineCache.SetCapResolver(service.TimelineCaps)
+	ctx := context.Background()
+
+	activeID, inactiveID := uuid.New(), uuid.New()
+	existing := MaxTimelineItemsInactive + 10
+	base := time.Now().Add(-24 * time.Hour)
+	for _, userID := range []uuid.UUID{activeID, inactiveID} {
+		for i := 0; i < existing; i++ {
+			postID := uuid.NewString()
+			mr.ZAdd(timelineCache.getTimelineKey(userID), float64(base.Add(time.Duration(i)*time.Second).Unix()), postID)
+			mr.ZAdd(RankedTimelineKey(userID), 1, postID)
+		}
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_online", "activity_score", "last_active_at"}).
+			AddRow(activeID, true, 0, time.Now()).
+			AddRow(inactiveID, false, 0, time.Now().Add(-30*24*time.Hour)))
+
+	postID := uuid.New()
+	if err := timelineCache.BatchAddToTimeline(ctx, []uuid.UUID{activeID, inactiveID}, postID, 5, time.Now()); err != nil {
+		t.Fatalf("BatchAddToTimeline: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		userID uuid.UUID
+		want   int
+	}{
+		{"active", activeID, existing + 1},
+		{"inactive", inactiveID, MaxTimelineItemsInactive},
+	} {
+		for _, key := range []string{timelineCache.getTimelineKey(tt.userID), RankedTimelineKey(tt.userID)} {
+			members, err := mr.ZMembers(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(members) != tt.want {
+				t.Errorf("%s user %s has %d items, want %d", tt.name, key, len(members), tt.want)
+			}
+		}
+		if score, err := mr.ZScore(RankedTimelineKey(tt.userID), postID.String()); err != nil || score != 5 {
+			t.Errorf("%s user: new post score = %v, %v; want it kept", tt.name, score, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+// 清理游标的保留时间，超过后下一次从头开始扫描
+const cleanupCursorTTL = 7 * 24 * time.Hour
+
+// CleanupRunResult 一次清理运行的结果
+type CleanupRunResult struct {
+	Inspected int  // 本次检查的key数量
+	Batches   int  // 本次处理的批次数
+	Completed bool // 是否已扫描完一整轮（下次从头开始）
+}
+
+// scanResumable 从Redis中保存的SCAN游标继续扫描，按batch_size分批处理并在批次间休眠batch_delay
+// 检查满max_per_run个key（0表示不限制）后在页边界停止，未扫描完时保存游标供下一次运行继续
+func scanResumable(
+	ctx context.Context,
+	c *cache.RedisClient,
+	cursorKey, pattern string,
+	cfg config.CleanupConfig,
+	process func(ctx context.Context, keys []string),
+) (*CleanupRunResult, error) {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var cursor uint64
+	if saved, err := c.Get(ctx, cursorKey); err == nil {
+		if parsed, err := strconv.ParseUint(saved, 10, 64); err == nil {
+			cursor = parsed
+		}
+	} else if err != redis.Nil {
+		return nil, fmt.Errorf("failed to load cleanup cursor: %w", err)
+	}
+
+	result := &CleanupRunResult{}
+	for {
+		if cfg.MaxPerRun > 0 && result.Inspected >= cfg.MaxPerRun {
+			break
+		}
+
+		page, next, err := c.ScanPage(ctx, cursor, pattern, int64(batchSize))
+		if err != nil {
+			return result, fmt.Errorf("failed to scan keys: %w", err)
+		}
+
+		// 已取到的页必须全部处理：保存的游标指向该页之后，截断的部分下一轮不会再被扫描到，
+		// 因此max_per_run是软上限，最多超出一页。SCAN的COUNT只是提示，单页可能超过batchSize，需要再切分
+		for start := 0; start < len(page); start += batchSize {
+			end := start + batchSize
+			if end > len(page) {
+				end = len(page)
+			}
+
+			if result.Batches > 0 && cfg.BatchDelay > 0 {
+				select {
+				case <-ctx.Done():
+					return result, ctx.Err()
+				case <-time.After(cfg.BatchDelay):
+				}
+			}
+			process(ctx, page[start:end])
+			result.Inspected += end - start
+			result.Batches++
+		}
+
+		cursor = next
+		if cursor == 0 {
+			result.Completed = true
+			break
+		}
+	}
+
+	// 保存游标，扫描完一整轮后清除游标
+	if result.Completed {
+		if err := c.Delete(ctx, cursorKey); err != nil {
+			return result, fmt.Errorf("failed to reset cleanup cursor: %w", err)
+		}
+	} else if err := c.Set(ctx, cursorKey, strconv.FormatUint(cursor, 10), cleanupCursorTTL); err != nil {
+		return result, fmt.Errorf("failed to save cleanup cursor: %w", err)
+	}
+
+	return result, nil
+}
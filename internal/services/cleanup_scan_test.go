@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/cache"
+)
+
+func TestScanResumableClusterCoversAllKeys(t *testing.T) {
+	// 集群模式下ScanPage一次返回全部key且游标为0，超出单次上限的key不能被截断丢弃
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClusterClient([]string{mr.Addr()}, "", 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		mr.Set(fmt.Sprintf("timeline:%d", i), "x")
+	}
+
+	seen := map[string]bool{}
+	cfg := config.CleanupConfig{BatchSize: 2, MaxPerRun: 3}
+	for run := 0; run < 2; run++ {
+		if _, err := scanResumable(ctx, redisClient, "cleanup:test:cursor", "timeline:*", cfg, func(ctx context.Context, keys []string) {
+			for _, key := range keys {
+				seen[key] = true
+			}
+		}); err != nil {
+			t.Fatalf("scanResumable: %v", err)
+		}
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("inspected %d distinct keys over two runs, want 5", len(seen))
+	}
+}
+
+func TestScanResumableOverCapResumesWithoutSkipping(t *testing.T) {
+	// 单次上限落在页中间时，本页剩余的key也要处理，保存的游标之后不会再扫描到它们
+	redisClient, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		mr.Set(fmt.Sprintf("timeline:%d", i), "x")
+	}
+
+	visits := map[string]int{}
+	cfg := config.CleanupConfig{BatchSize: 4, MaxPerRun: 6}
+	process := func(ctx context.Context, keys []string) {
+		for _, key := range keys {
+			visits[key]++
+		}
+	}
+
+	first, err := scanResumable(ctx, redisClient, "cleanup:test:cursor", "timeline:*", cfg, process)
+	if err != nil {
+		t.Fatalf("scanResumable: %v", err)
+	}
+	if first.Completed || first.Inspected != 8 {
+		t.Fatalf("first run = %+v, want it to finish the page crossing max_per_run and stop", first)
+	}
+	second, err := scanResumable(ctx, redisClient, "cleanup:test:cursor", "timeline:*", cfg, process)
+	if err != nil {
+		t.Fatalf("scanResumable: %v", err)
+	}
+	if !second.Completed {
+		t.Errorf("resumed run did not finish the scan")
+	}
+
+	if first.Inspected+second.Inspected != 10 {
+		t.Errorf("inspected %d + %d keys, want 10", first.Inspected, second.Inspected)
+	}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("timeline:%d", i)
+		if visits[key] != 1 {
+			t.Errorf("%s visited %d times, want 1", key, visits[key])
+		}
+	}
+	if mr.Exists("cleanup:test:cursor") {
+		t.Errorf("cursor kept after a completed scan")
+	}
+}
+
+func TestScanResumableRespectsBatchSizeAndRate(t *testing.T) {
+	// 集群模式下整页一次返回，仍需按batch_size切分，并在批次之间间隔batch_delay
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClusterClient([]string{mr.Addr()}, "", 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		mr.Set(fmt.Sprintf("timeline:%d", i), "x")
+	}
+
+	const delay = 20 * time.Millisecond
+	cfg := config.CleanupConfig{BatchSize: 3, BatchDelay: delay}
+	var sizes []int
+	var starts []time.Time
+	result, err := scanResumable(ctx, redisClient, "cleanup:test:cursor", "timeline:*", cfg, func(ctx context.Context, keys []string) {
+		sizes = append(sizes, len(keys))
+		starts = append(starts, time.Now())
+	})
+	if err != nil {
+		t.Fatalf("scanResumable: %v", err)
+	}
+
+	if !result.Completed || result.Inspected != 7 || result.Batches != 3 {
+		t.Errorf("result = %+v, want 7 keys in 3 batches", result)
+	}
+	if fmt.Sprint(sizes) != "[3 3 1]" {
+		t.Errorf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < delay {
+			t.Errorf("batch %d started %s after the previous one, want at least %s", i, gap, delay)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// normalizeVisibility 校验发帖时指定的可见范围，未指定时为public
+func normalizeVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "", models.PostVisibilityPublic:
+		return models.PostVisibilityPublic, nil
+	case models.PostVisibilityCloseFriends, "closefriends":
+		return models.PostVisibilityCloseFriends, nil
+	default:
+		return "", apperrors.InvalidInput(fmt.Sprintf("invalid visibility %q", visibility))
+	}
+}
+
+// canViewPost 判断查看者能否看到帖子：仅密友可见的帖子只对作者和作者标记的密友可见
+func canViewPost(ctx context.Context, followRepo *repository.FollowRepository, viewerID uuid.UUID, post *models.Post) (bool, error) {
+	if !post.IsCloseFriendsOnly() || viewerID == post.UserID {
+		return true, nil
+	}
+	return followRepo.IsCloseFriend(ctx, viewerID, post.UserID)
+}
+
+// ensurePostVisible 查看者看不到帖子时按不存在处理，不暴露帖子存在；
+// 评论、点赞等以帖子ID为入口的操作都要先经过这里
+func ensurePostVisible(ctx context.Context, followRepo *repository.FollowRepository, viewerID uuid.UUID, post *models.Post) error {
+	visible, err := canViewPost(ctx, followRepo, viewerID, post)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return apperrors.NotFound("post not found")
+	}
+	return nil
+}
+
+func (s *FeedService) canViewPost(ctx context.Context, viewerID uuid.UUID, post *models.Post) (bool, error) {
+	return canViewPost(ctx, s.followRepo, viewerID, post)
+}
+
+// pushToCloseFriends 仅密友可见的帖子只写入作者和密友的Timeline
+func (s *FeedService) pushToCloseFriends(ctx context.Context, post *models.Post, author *models.User) error {
+	closeFriendIDs, err := s.followRepo.GetCloseFriendFollowerIDs(ctx, author.ID, int(s.config.Feed().MaxFeedSize))
+	if err != nil {
+		return err
+	}
+
+	timelines := make([]*models.Timeline, 0, len(closeFriendIDs)+1)
+	for _, userID := range append(closeFriendIDs, author.ID) {
+		timelines = append(timelines, &models.Timeline{
+			UserID:    userID,
+			PostID:    post.ID,
+			Score:     post.Score,
+			CreatedAt: post.CreatedAt,
+		})
+	}
+	if err := s.timelineRepo.CreateBatch(ctx, timelines); err != nil {
+		return fmt.Errorf("failed to create timelines: %w", err)
+	}
+	return nil
+}
+
+// distributeToCloseFriends 仅密友可见的帖子只推送给作者和密友，不走推/拉分层
+func (s *OptimizedFeedService) distributeToCloseFriends(ctx context.Context, post *models.Post, author *models.User, closeFriendIDs []uuid.UUID) error {
+	if err := s.timelineCacheService.BatchAddToTimeline(ctx, append(closeFriendIDs, author.ID), post.ID, post.Score, post.CreatedAt); err != nil {
+		return fmt.Errorf("failed to add close friends post to timelines: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"post_id":       post.ID,
+		"author_id":     author.ID,
+		"close_friends": len(closeFriendIDs),
+	}).Info("Close friends post distributed")
+
+	return nil
+}
+
+// filterVisiblePosts 在读取时过滤掉查看者看不到的仅密友可见帖子。
+// 推送时只写入密友的Timeline，这里兜底作者取消密友标记后残留的条目
+func filterVisiblePosts(ctx context.Context, followRepo *repository.FollowRepository, viewerID uuid.UUID, posts []*models.Post) ([]*models.Post, error) {
+	needCheck := false
+	for _, post := range posts {
+		if post.IsCloseFriendsOnly() && post.UserID != viewerID {
+			needCheck = true
+			break
+		}
+	}
+	if !needCheck {
+		return posts, nil
+	}
+
+	authorIDs, err := followRepo.GetCloseFriendOfIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	closeAuthors := make(map[uuid.UUID]bool, len(authorIDs))
+	for _, id := range authorIDs {
+		closeAuthors[id] = true
+	}
+
+	visible := make([]*models.Post, 0, len(posts))
+	for _, post := range posts {
+		if !post.IsCloseFriendsOnly() || post.UserID == viewerID || closeAuthors[post.UserID] {
+			visible = append(visible, post)
+		}
+	}
+	return visible, nil
+}
+
+// visiblePostFetcher 按ID取帖子并过滤掉viewerID看不到的仅密友可见帖子
+func (s *OptimizedFeedService) visiblePostFetcher(viewerID uuid.UUID) PostFetcher {
+	return func(ctx context.Context, postIDs []uuid.UUID) ([]*models.Post, error) {
+		posts, err := s.postRepo.GetByIDs(ctx, postIDs)
+		if err != nil {
+			return nil, err
+		}
+		return filterVisiblePosts(ctx, s.followRepo, viewerID, posts)
+	}
+}
+
+// closeFriendAuthors 查看者能看到其仅密友可见帖子的作者集合（包含查看者自己），用于拉模式过滤
+func (s *OptimizedFeedService) closeFriendAuthors(ctx context.Context, viewerID uuid.UUID) map[uuid.UUID]bool {
+	authors := map[uuid.UUID]bool{viewerID: true}
+	ids, err := s.followRepo.GetCloseFriendOfIDs(ctx, viewerID)
+	if err != nil {
+		// 查询失败时只隐藏他人的密友帖子，不影响公开帖子
+		s.logger.WithError(err).Error("Failed to get close friend authors")
+		return authors
+	}
+	for _, id := range ids {
+		authors[id] = true
+	}
+	return authors
+}
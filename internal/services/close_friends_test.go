@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func expectCloseFriendCount(mock sqlmock.Sqlmock, count int64) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func TestEnsurePostVisible(t *testing.T) {
+	authorID, viewerID := uuid.New(), uuid.New()
+	public := &models.Post{ID: uuid.New(), UserID: authorID, Visibility: models.PostVisibilityPublic}
+	closeOnly := &models.Post{ID: uuid.New(), UserID: authorID, Visibility: models.PostVisibilityCloseFriends}
+
+	t.Run("public post needs no lookup", func(t *testing.T) {
+		db, mock := newTestDB(t)
+		if err := ensurePostVisible(context.Background(), repository.NewFollowRepository(db), viewerID, public); err != nil {
+			t.Fatalf("public post should be visible: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("author sees own close friends post", func(t *testing.T) {
+		db, _ := newTestDB(t)
+		if err := ensurePostVisible(context.Background(), repository.NewFollowRepository(db), authorID, closeOnly); err != nil {
+			t.Fatalf("author should see own post: %v", err)
+		}
+	})
+
+	t.Run("close friend sees post", func(t *testing.T) {
+		db, mock := newTestDB(t)
+		expectCloseFriendCount(mock, 1)
+		if err := ensurePostVisible(context.Background(), repository.NewFollowRepository(db), viewerID, closeOnly); err != nil {
+			t.Fatalf("close friend should see post: %v", err)
+		}
+	})
+
+	t.Run("other viewer gets not found", func(t *testing.T) {
+		db, mock := newTestDB(t)
+		expectCloseFriendCount(mock, 0)
+		err := ensurePostVisible(context.Background(), repository.NewFollowRepository(db), viewerID, closeOnly)
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+}
+
+func TestGetPostCommentsHidesCloseFriendsPost(t *testing.T) {
+	db, mock := newTestDB(t)
+	authorID, viewerID, postID := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "visibility"}).
+			AddRow(postID, authorID, models.PostVisibilityCloseFriends))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+	expectCloseFriendCount(mock, 0)
+
+	service := NewCommentService(
+		repository.NewPostRepository(db),
+		repository.NewCommentRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewFollowRepository(db),
+		nil, logger.NewLogger(), nil,
+	)
+
+	_, err := service.GetPostComments(context.Background(), viewerID.String(), postID.String(), 0, 20)
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDistributeToCloseFriendsOnlyReachesCloseFriends(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(nil)
+
+	author := &models.User{ID: uuid.New()}
+	closeFriend, otherFollower := uuid.New(), uuid.New()
+	post := &models.Post{ID: uuid.New(), UserID: author.ID, Visibility: models.PostVisibilityCloseFriends, CreatedAt: time.Now()}
+
+	mock.ExpectQuery(`SELECT "follower_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id"}).AddRow(closeFriend))
+
+	service := &OptimizedFeedService{
+		followRepo:           repository.NewFollowRepository(db),
+		config:               cfg,
+		logger:               logger.NewLogger(),
+		timelineCacheService: NewTimelineCacheService(redisClient, cfg, logger.NewLogger()),
+	}
+	if err := service.distributePostOptimized(context.Background(), post, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+
+	for _, userID := range []uuid.UUID{closeFriend, author.ID} {
+		if _, err := mr.ZScore("timeline:"+userID.String(), post.ID.String()); err != nil {
+			t.Errorf("post missing from timeline of %s: %v", userID, err)
+		}
+	}
+	if mr.Exists("timeline:" + otherFollower.String()) {
+		t.Errorf("post leaked to a follower who is not a close friend")
+	}
+}
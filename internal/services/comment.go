@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
 	"github.com/feed-system/feed-system/pkg/logger"
@@ -16,22 +18,26 @@ type CommentService struct {
 	postRepo    *repository.PostRepository
 	commentRepo *repository.CommentRepository
 	userRepo    *repository.UserRepository
-	producer    *queue.KafkaProducer
+	followRepo  *repository.FollowRepository
+	producer    queue.Publisher
 	logger      *logger.Logger
+	moderator   Moderator
 }
 
-func NewCommentService(postRepo *repository.PostRepository, commentRepo *repository.CommentRepository, userRepo *repository.UserRepository, producer *queue.KafkaProducer, logger *logger.Logger) *CommentService {
+func NewCommentService(postRepo *repository.PostRepository, commentRepo *repository.CommentRepository, userRepo *repository.UserRepository, followRepo *repository.FollowRepository, producer queue.Publisher, logger *logger.Logger, moderator Moderator) *CommentService {
 	return &CommentService{
 		postRepo:    postRepo,
 		commentRepo: commentRepo,
 		userRepo:    userRepo,
+		followRepo:  followRepo,
 		producer:    producer,
 		logger:      logger,
+		moderator:   moderator,
 	}
 }
 
 type CreateCommentRequest struct {
-	Content  string  `json:"content" binding:"required,min=1,max=500"`
+	Content  string  `json:"content" binding:"required"` // 长度在服务层按字符校验
 	ParentID *string `json:"parent_id"`
 }
 
@@ -46,13 +52,22 @@ func (s *CommentService) CreateComment(ctx context.Context, userID, postID strin
 		return nil, fmt.Errorf("invalid post ID: %w", err)
 	}
 
+	// 内容校验和审核，在任何写入之前执行
+	content, err := normalizeContent(req.Content, MaxCommentContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := moderate(ctx, s.moderator, s.logger, userID, "comment", content); err != nil {
+		return nil, err
+	}
+
 	// 检查用户是否存在
 	user, err := s.userRepo.GetByID(ctx, userUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found")
 	}
 
 	// 检查帖子是否存在
@@ -61,7 +76,10 @@ func (s *CommentService) CreateComment(ctx context.Context, userID, postID strin
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
 	if post == nil {
-		return nil, errors.New("post not found")
+		return nil, apperrors.NotFound("post not found")
+	}
+	if err := ensurePostVisible(ctx, s.followRepo, userUUID, post); err != nil {
+		return nil, err
 	}
 
 	// 验证parent comment是否存在（如果是回复）
@@ -77,11 +95,11 @@ func (s *CommentService) CreateComment(ctx context.Context, userID, postID strin
 			return nil, fmt.Errorf("failed to get parent comment: %w", err)
 		}
 		if parentComment == nil {
-			return nil, errors.New("parent comment not found")
+			return nil, apperrors.NotFound("parent comment not found")
 		}
 
 		if parentComment.PostID != postUUID {
-			return nil, errors.New("parent comment does not belong to this post")
+			return nil, apperrors.InvalidInput("parent comment does not belong to this post")
 		}
 
 		parentUUID = &parentID
@@ -91,7 +109,7 @@ func (s *CommentService) CreateComment(ctx context.Context, userID, postID strin
 	comment := &models.Comment{
 		UserID:    userUUID,
 		PostID:    postUUID,
-		Content:   req.Content,
+		Content:   content,
 		ParentID:  parentUUID,
 		CreatedAt: post.CreatedAt,
 	}
@@ -116,7 +134,7 @@ func (s *CommentService) CreateComment(ctx context.Context, userID, postID strin
 			Content:   comment.Content,
 		},
 	}
-	if err := s.producer.Publish(ctx, userID, event); err != nil {
+	if err := s.producer.Publish(ctx, postID, event); err != nil {
 		s.logger.WithError(err).Error("Failed to publish comment created event")
 	}
 
@@ -140,16 +158,38 @@ func (s *CommentService) GetCommentByID(ctx context.Context, commentID string) (
 		return nil, fmt.Errorf("failed to get comment: %w", err)
 	}
 	if comment == nil {
-		return nil, errors.New("comment not found")
+		return nil, apperrors.NotFound("comment not found")
 	}
 
 	return comment, nil
 }
 
-func (s *CommentService) GetPostComments(ctx context.Context, postID string, offset, limit int) ([]*models.Comment, error) {
+// visiblePost 获取帖子并确认查看者能看到，看不到的密友帖子按不存在处理
+func (s *CommentService) visiblePost(ctx context.Context, viewerID, postID string) (uuid.UUID, error) {
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid post ID: %w", err)
+		return uuid.Nil, fmt.Errorf("invalid post ID: %w", err)
+	}
+
+	post, err := s.postRepo.GetByID(ctx, postUUID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if post == nil {
+		return uuid.Nil, apperrors.NotFound("post not found")
+	}
+
+	viewerUUID, _ := uuid.Parse(viewerID)
+	if err := ensurePostVisible(ctx, s.followRepo, viewerUUID, post); err != nil {
+		return uuid.Nil, err
+	}
+	return postUUID, nil
+}
+
+func (s *CommentService) GetPostComments(ctx context.Context, viewerID, postID string, offset, limit int) ([]*models.Comment, error) {
+	postUUID, err := s.visiblePost(ctx, viewerID, postID)
+	if err != nil {
+		return nil, err
 	}
 
 	comments, err := s.commentRepo.GetByPostID(ctx, postUUID, offset, limit)
@@ -160,6 +200,60 @@ func (s *CommentService) GetPostComments(ctx context.Context, postID string, off
 	return comments, nil
 }
 
+// CommentPage 按游标分页的评论列表
+type CommentPage struct {
+	Comments   []*models.Comment `json:"comments"`
+	NextCursor string            `json:"next_cursor"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// GetPostCommentsPage 按游标分页获取帖子评论（最新在前），游标为上一页最后一条评论的"创建时间_评论ID"
+func (s *CommentService) GetPostCommentsPage(ctx context.Context, viewerID, postID, cursor string, limit int) (*CommentPage, error) {
+	postUUID, err := s.visiblePost(ctx, viewerID, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var beforeCreatedAt time.Time
+	beforeID := uuid.Nil
+	if cursor != "" {
+		if beforeCreatedAt, beforeID, err = parseTimeIDCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	comments, err := s.commentRepo.GetByPostIDCursor(ctx, postUUID, beforeCreatedAt, beforeID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post comments: %w", err)
+	}
+
+	page := &CommentPage{Comments: comments}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		page.HasMore = true
+		last := page.Comments[limit-1]
+		page.NextCursor = last.CreatedAt.Format(time.RFC3339Nano) + "_" + last.ID.String()
+	}
+	return page, nil
+}
+
+// parseTimeIDCursor 解析"时间_ID"形式的keyset游标，用于评论和点赞列表
+func parseTimeIDCursor(cursor string) (time.Time, uuid.UUID, error) {
+	idx := strings.LastIndex(cursor, "_")
+	if idx < 0 {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, cursor[:idx])
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(cursor[idx+1:])
+	if err != nil || id == uuid.Nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
+
 func (s *CommentService) DeleteComment(ctx context.Context, userID, commentID string) error {
 	commentUUID, err := uuid.Parse(commentID)
 	if err != nil {
@@ -171,12 +265,12 @@ func (s *CommentService) DeleteComment(ctx context.Context, userID, commentID st
 		return fmt.Errorf("failed to get comment: %w", err)
 	}
 	if comment == nil {
-		return errors.New("comment not found")
+		return apperrors.NotFound("comment not found")
 	}
 
 	// 检查权限
 	if comment.UserID.String() != userID {
-		return errors.New("permission denied")
+		return apperrors.PermissionDenied("permission denied")
 	}
 
 	// 删除评论
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// 创建时间相同的评论翻页时，游标带上评论ID，下一页从上一页最后一条之后继续
+func TestGetPostCommentsPageEqualTimestamps(t *testing.T) {
+	db, mock := newTestDB(t)
+	service := NewCommentService(repository.NewPostRepository(db), repository.NewCommentRepository(db), nil, nil, nil, logger.NewLogger(), nil)
+	ctx := context.Background()
+	authorID, postID := uuid.New(), uuid.New()
+
+	// 五条评论时间相同，按id降序排列
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() > ids[j].String() })
+
+	expectPage := func(keyset []driver.Value, page []uuid.UUID) {
+		mock.ExpectQuery(`SELECT \* FROM "posts"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(postID, authorID))
+		mock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+		rows := sqlmock.NewRows([]string{"id", "user_id", "post_id", "created_at"})
+		for _, id := range page {
+			rows.AddRow(id, authorID, postID, createdAt)
+		}
+		query := `SELECT \* FROM "comments" WHERE post_id = \$1 AND "comments"`
+		if keyset != nil {
+			query = `SELECT \* FROM "comments" WHERE post_id = \$1 AND \(created_at, id\) < \(\$2, \$3\)`
+		}
+		mock.ExpectQuery(query).WithArgs(append([]driver.Value{postID}, keyset...)...).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+	}
+
+	var seen []uuid.UUID
+	expectPage(nil, ids[0:3])
+	page, err := service.GetPostCommentsPage(ctx, authorID.String(), postID.String(), "", 2)
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	for _, comment := range page.Comments {
+		seen = append(seen, comment.ID)
+	}
+
+	expectPage([]driver.Value{createdAt, ids[1]}, ids[2:5])
+	page, err = service.GetPostCommentsPage(ctx, authorID.String(), postID.String(), page.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	for _, comment := range page.Comments {
+		seen = append(seen, comment.ID)
+	}
+
+	expectPage([]driver.Value{createdAt, ids[3]}, ids[4:5])
+	page, err = service.GetPostCommentsPage(ctx, authorID.String(), postID.String(), page.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("third page: %v", err)
+	}
+	for _, comment := range page.Comments {
+		seen = append(seen, comment.ID)
+	}
+	if page.HasMore || page.NextCursor != "" {
+		t.Errorf("last page has_more = %v, next_cursor = %q", page.HasMore, page.NextCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != len(ids) {
+		t.Fatalf("paged through %v, want %v", seen, ids)
+	}
+	for i, id := range ids {
+		if seen[i] != id {
+			t.Errorf("position %d = %s, want %s", i, seen[i], id)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+)
+
+// 内容长度上限（按用户可见字符数计，见contentLength）
+// 请求DTO的binding按rune计数会误伤组合emoji，因此长度只在服务层校验
+const (
+	MaxPostContentLength    = 1000
+	MaxCommentContentLength = 500
+)
+
+var (
+	// ErrEmptyContent 去除首尾空白后内容为空
+	ErrEmptyContent = apperrors.InvalidInput("content must not be empty")
+	// ErrContentTooLong 内容超过长度上限
+	ErrContentTooLong = apperrors.InvalidInput("content is too long")
+)
+
+// normalizeContent 去除首尾空白并校验长度，服务层调用方（包括非HTTP调用）都经过这里
+func normalizeContent(content string, maxLength int) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", ErrEmptyContent
+	}
+	if contentLength(content) > maxLength {
+		return "", fmt.Errorf("%w: at most %d characters", ErrContentTooLong, maxLength)
+	}
+	return content, nil
+}
+
+// contentLength 近似按字素簇（用户看到的一个字符）计数：
+// 组合符号、变体选择符、肤色修饰符、ZWJ连接的emoji序列、成对的国旗区域指示符都不单独计数
+func contentLength(content string) int {
+	count := 0
+	joinNext := false
+	regionalPending := false
+	for _, r := range content {
+		switch {
+		case joinNext:
+			// ZWJ之后的字符与前一个字符合并
+			joinNext = false
+			continue
+		case r == '\u200d':
+			joinNext = true
+			continue
+		case unicode.Is(unicode.Mn, r), unicode.Is(unicode.Me, r), unicode.Is(unicode.Mc, r):
+			continue
+		case r >= 0xFE00 && r <= 0xFE0F, r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+			// 变体选择符、肤色修饰符、标签字符
+			continue
+		case r >= 0x1F1E6 && r <= 0x1F1FF:
+			// 两个区域指示符组成一面国旗
+			if regionalPending {
+				regionalPending = false
+				continue
+			}
+			regionalPending = true
+			count++
+			continue
+		}
+		regionalPending = false
+		count++
+	}
+	return count
+}
+
+// 内容HTML处理方式，对应feed.content_sanitize
+const (
+	ContentSanitizeOff    = "off"
+	ContentSanitizeEscape = "escape"
+	ContentSanitizeStrip  = "strip"
+)
+
+var (
+	// 脚本/样式块连同内容一起去除
+	scriptBlockPattern = regexp.MustCompile(`(?is)<(script|style)\b[^>]*>.*?</(script|style)\s*>`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// sanitizeContent 按配置处理内容中的HTML，防止Web客户端出现存储型XSS
+// escape转义所有HTML特殊字符；strip去除标签（脚本块连同内容），剩余文本中的尖括号仍会被转义
+func sanitizeContent(content, mode string) (string, error) {
+	switch mode {
+	case ContentSanitizeEscape:
+		return html.EscapeString(content), nil
+	case ContentSanitizeStrip:
+		content = scriptBlockPattern.ReplaceAllString(content, "")
+		content = htmlTagPattern.ReplaceAllString(content, "")
+		content = strings.TrimSpace(content)
+		if content == "" {
+			return "", ErrEmptyContent
+		}
+		return strings.NewReplacer("<", "&lt;", ">", "&gt;").Replace(content), nil
+	default:
+		return content, nil
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestCreateEnforcesContentLength(t *testing.T) {
+	ctx := context.Background()
+	userID, postID := uuid.New(), uuid.New()
+
+	newServices := func(t *testing.T) (*FeedService, *OptimizedFeedService, *CommentService, sqlmock.Sqlmock, *recordingModerator) {
+		db, mock := newTestDB(t)
+		redisClient, _ := newTestRedis(t)
+		moderator := &recordingModerator{}
+		log := logger.NewLogger()
+		cfg := newTestConfig(nil)
+		feedService := &FeedService{userRepo: repository.NewUserRepository(db), cache: redisClient, config: cfg, logger: log, moderator: moderator}
+		optimized := &OptimizedFeedService{userRepo: repository.NewUserRepository(db), cache: redisClient, config: cfg, logger: log, moderator: moderator}
+		commentService := NewCommentService(repository.NewPostRepository(db), repository.NewCommentRepository(db),
+			repository.NewUserRepository(db), repository.NewFollowRepository(db), &fakePublisher{}, log, moderator)
+		return feedService, optimized, commentService, mock, moderator
+	}
+	create := func(feed *FeedService, optimized *OptimizedFeedService, comments *CommentService) map[string]func(content string) error {
+		return map[string]func(content string) error{
+			"post": func(content string) error {
+				_, err := feed.CreatePost(ctx, userID.String(), &CreatePostRequest{Content: content})
+				return err
+			},
+			"optimized post": func(content string) error {
+				_, err := optimized.CreatePost(ctx, userID.String(), &CreatePostRequest{Content: content})
+				return err
+			},
+			"comment": func(content string) error {
+				_, err := comments.CreateComment(ctx, userID.String(), postID.String(), &CreateCommentRequest{Content: content})
+				return err
+			},
+		}
+	}
+	limits := map[string]int{"post": MaxPostContentLength, "optimized post": MaxPostContentLength, "comment": MaxCommentContentLength}
+
+	t.Run("rejects before any database access", func(t *testing.T) {
+		feed, optimized, comments, mock, moderator := newServices(t)
+		for name, fn := range create(feed, optimized, comments) {
+			if err := fn(strings.Repeat("a", limits[name]+1)); !errors.Is(err, ErrContentTooLong) || !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("%s: oversized content error = %v, want content too long", name, err)
+			}
+			for _, blank := range []string{"", "   ", "\n\t \r\n"} {
+				if err := fn(blank); !errors.Is(err, ErrEmptyContent) {
+					t.Errorf("%s: whitespace-only content %q error = %v, want empty content", name, blank, err)
+				}
+			}
+		}
+		if len(moderator.checked) != 0 {
+			t.Errorf("moderated %v, want invalid content rejected first", moderator.checked)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("trims surrounding whitespace before measuring", func(t *testing.T) {
+		feed, optimized, comments, mock, moderator := newServices(t)
+		for name, fn := range create(feed, optimized, comments) {
+			if name == "optimized post" {
+				// 优化版在查询作者前先记录活跃度，这里只验证拒绝路径
+				continue
+			}
+			// 达到上限的内容加上首尾空白仍然通过，作者不存在时在写入前返回
+			mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+				WithArgs(userID).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			content := strings.Repeat("a", limits[name])
+			if err := fn("  \n" + content + "\t "); !errors.Is(err, apperrors.ErrNotFound) {
+				t.Errorf("%s: content at the limit error = %v, want it accepted", name, err)
+			}
+			if last := moderator.checked[len(moderator.checked)-1]; last != content {
+				t.Errorf("%s: moderated %q, want trimmed content", name, last)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSanitizeContent(t *testing.T) {
+	const content = `hi <b>there</b><script>alert("x")</script> 1 < 2`
+	for _, tt := range []struct {
+		mode string
+		want string
+	}{
+		// 关闭时原样保存，交给纯文本客户端
+		{ContentSanitizeOff, content},
+		{"", content},
+		{ContentSanitizeEscape, `hi &lt;b&gt;there&lt;/b&gt;&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; 1 &lt; 2`},
+		{ContentSanitizeStrip, `hi there 1 &lt; 2`},
+	} {
+		got, err := sanitizeContent(content, tt.mode)
+		if err != nil {
+			t.Fatalf("sanitizeContent(%q): %v", tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("sanitizeContent(%q) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	// 去除标签后没有剩余文本的内容按空内容拒绝
+	if _, err := sanitizeContent(`<SCRIPT type="text/javascript">steal()</script >`, ContentSanitizeStrip); !errors.Is(err, ErrEmptyContent) {
+		t.Errorf("script-only content error = %v, want empty content", err)
+	}
+}
+
+func TestCreatePostSanitizesBeforeWrite(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, _ := newTestRedis(t)
+	service := &FeedService{
+		userRepo: repository.NewUserRepository(db),
+		cache:    redisClient,
+		config: newTestConfig(func(feed *config.FeedConfig) {
+			feed.ContentSanitize = ContentSanitizeStrip
+		}),
+		logger: logger.NewLogger(),
+	}
+
+	// 只有脚本的帖子在查询作者前就被拒绝
+	_, err := service.CreatePost(context.Background(), uuid.NewString(), &CreatePostRequest{Content: "<script>alert(1)</script>"})
+	if !errors.Is(err, ErrEmptyContent) {
+		t.Errorf("CreatePost() error = %v, want empty content", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContentLengthCountsVisibleCharacters(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"ascii", "hello", 5},
+		{"cjk", "你好，世界", 5},
+		{"emoji", "😀😀", 2},
+		{"skin tone", "👍🏽", 1},
+		{"variation selector", "❤️", 1},
+		{"zwj family", "👨‍👩‍👧‍👦", 1},
+		{"flags", "🇨🇳🇺🇸", 2},
+		{"combining accent", "e\u0301", 1},
+	} {
+		if got := contentLength(tt.content); got != tt.want {
+			t.Errorf("%s: contentLength(%q) = %d, want %d", tt.name, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeContentMultiByteBoundary(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		unit  string
+		limit int
+	}{
+		{"post emoji", "😀", MaxPostContentLength},
+		{"post cjk", "字", MaxPostContentLength},
+		{"post zwj emoji", "👩‍💻", MaxPostContentLength},
+		{"comment emoji", "🎉", MaxCommentContentLength},
+		{"comment cjk", "评", MaxCommentContentLength},
+	} {
+		// 恰好达到上限的多字节内容远超上限字节数，仍然通过
+		atLimit := strings.Repeat(tt.unit, tt.limit)
+		if len(atLimit) <= tt.limit {
+			t.Fatalf("%s: test content is not multi-byte", tt.name)
+		}
+		if got, err := normalizeContent(atLimit, tt.limit); err != nil || got != atLimit {
+			t.Errorf("%s: content at the limit rejected: %v", tt.name, err)
+		}
+		if _, err := normalizeContent(atLimit+tt.unit, tt.limit); !errors.Is(err, ErrContentTooLong) {
+			t.Errorf("%s: content one character over the limit error = %v, want content too long", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// 待处理的延迟扇出任务（ZSet，score为入队时间）
+	delayedFanoutQueueKey = "delayed_fanout:pending"
+	// 延迟扇出任务保留时间，超过后即使未处理也会过期（用户活跃后拉模式会补齐）
+	DelayedFanoutTTL = 48 * time.Hour
+)
+
+// DelayedFanoutJob 针对非活跃关注者的延迟扇出任务
+type DelayedFanoutJob struct {
+	PostID      uuid.UUID   `json:"post_id"`
+	AuthorID    uuid.UUID   `json:"author_id"`
+	FollowerIDs []uuid.UUID `json:"follower_ids"`
+	Score       float64     `json:"score"`
+	CreatedAt   time.Time   `json:"created_at"`
+	EnqueuedAt  time.Time   `json:"enqueued_at"`
+}
+
+// enqueueDelayedFanout 将非活跃关注者的扇出放入延迟队列，在低峰期处理
+func (s *OptimizedFeedService) enqueueDelayedFanout(ctx context.Context, post *models.Post, followerIDs []uuid.UUID) error {
+	job := DelayedFanoutJob{
+		PostID:      post.ID,
+		AuthorID:    post.UserID,
+		FollowerIDs: followerIDs,
+		Score:       post.Score,
+		CreatedAt:   post.CreatedAt,
+		EnqueuedAt:  time.Now(),
+	}
+
+	if err := s.cache.SetJSON(ctx, delayedFanoutJobKey(post.ID), job, DelayedFanoutTTL); err != nil {
+		return fmt.Errorf("failed to save delayed fan-out job: %w", err)
+	}
+	if err := s.cache.ZAdd(ctx, delayedFanoutQueueKey, &redis.Z{
+		Score:  float64(job.EnqueuedAt.Unix()),
+		Member: post.ID.String(),
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue delayed fan-out job: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessDelayedFanouts 处理最早入队的一批延迟扇出任务，返回处理的任务数
+// 任务在扇出成功后才出队，进程崩溃时下次会重新处理（ZAdd幂等）
+func (s *OptimizedFeedService) ProcessDelayedFanouts(ctx context.Context, batchSize int) (int, error) {
+	postIDs, err := s.cache.ZRange(ctx, delayedFanoutQueueKey, 0, int64(batchSize)-1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get delayed fan-out jobs: %w", err)
+	}
+
+	processed := 0
+	for _, member := range postIDs {
+		postID, err := uuid.Parse(member)
+		if err != nil {
+			s.cache.ZRem(ctx, delayedFanoutQueueKey, member)
+			continue
+		}
+
+		var job DelayedFanoutJob
+		if err := s.cache.GetJSON(ctx, delayedFanoutJobKey(postID), &job); err != nil {
+			// 任务已过期或被删除
+			s.cache.ZRem(ctx, delayedFanoutQueueKey, member)
+			continue
+		}
+
+		if err := s.runDelayedFanout(ctx, &job); err != nil {
+			s.logger.WithError(err).WithField("post_id", job.PostID).Error("Failed to process delayed fan-out")
+			continue
+		}
+
+		if err := s.cache.ZRem(ctx, delayedFanoutQueueKey, member); err != nil {
+			s.logger.WithError(err).WithField("post_id", job.PostID).Error("Failed to dequeue delayed fan-out job")
+		}
+		if err := s.cache.Delete(ctx, delayedFanoutJobKey(postID)); err != nil {
+			s.logger.WithError(err).WithField("post_id", job.PostID).Error("Failed to delete delayed fan-out job")
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// runDelayedFanout 执行一个延迟扇出任务。任务入队后帖子可能已被删除，删除的帖子不再写入Timeline
+func (s *OptimizedFeedService) runDelayedFanout(ctx context.Context, job *DelayedFanoutJob) error {
+	if len(job.FollowerIDs) == 0 || !s.config.Feed().IsPushEligible(job.CreatedAt) {
+		return nil
+	}
+
+	post, err := s.postRepo.GetByID(ctx, job.PostID)
+	if err != nil {
+		return err
+	}
+	if post == nil {
+		s.logger.WithField("post_id", job.PostID).Info("Post deleted before delayed fan-out, skipping")
+		return nil
+	}
+
+	return s.timelineCacheService.BatchAddToTimeline(ctx, job.FollowerIDs, job.PostID, job.Score, job.CreatedAt)
+}
+
+// PendingDelayedFanouts 获取待处理的延迟扇出任务数
+func (s *OptimizedFeedService) PendingDelayedFanouts(ctx context.Context) (int64, error) {
+	return s.cache.ZCard(ctx, delayedFanoutQueueKey)
+}
+
+// StartDelayedFanoutJob 在配置的低峰时段内定期处理延迟扇出任务
+func (s *OptimizedFeedService) StartDelayedFanoutJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Delayed fan-out job stopped")
+			return
+		case now := <-ticker.C:
+			cfg := s.config.Feed().Optimization.DelayedFanout
+			if !inOffPeakWindow(now, cfg.OffPeakStartHour, cfg.OffPeakEndHour) {
+				continue
+			}
+
+			processed, err := s.ProcessDelayedFanouts(ctx, cfg.BatchSize)
+			if err != nil {
+				s.logger.WithError(err).Error("Delayed fan-out job failed")
+				continue
+			}
+			if processed > 0 {
+				s.logger.WithField("processed", processed).Info("Delayed fan-out jobs processed")
+			}
+		}
+	}
+}
+
+// inOffPeakWindow 判断当前小时是否在低峰时段[start, end)内，支持跨零点（如22点到6点）
+func inOffPeakWindow(now time.Time, start, end int) bool {
+	hour := now.Hour()
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func delayedFanoutJobKey(postID uuid.UUID) string {
+	return fmt.Sprintf("delayed_fanout:job:%s", postID.String())
+}
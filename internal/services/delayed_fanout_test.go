@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestProcessDelayedFanoutsSkipsDeletedPost(t *testing.T) {
+	service, mock, timelineCache := newDistributionTestService(t, nil)
+	ctx := context.Background()
+
+	follower := uuid.New()
+	post := &models.Post{ID: uuid.New(), UserID: uuid.New(), Score: 1, CreatedAt: time.Now()}
+	if err := service.enqueueDelayedFanout(ctx, post, []uuid.UUID{follower}); err != nil {
+		t.Fatalf("enqueueDelayedFanout: %v", err)
+	}
+
+	// 帖子在入队后被删除，按ID查询不到
+	mock.ExpectQuery(`SELECT \* FROM "posts"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if processed, err := service.ProcessDelayedFanouts(ctx, 10); err != nil || processed != 1 {
+		t.Fatalf("ProcessDelayedFanouts() = %d, %v", processed, err)
+	}
+	assertTimelineContains(t, timelineCache, follower, post.ID, false)
+
+	pending, err := service.PendingDelayedFanouts(ctx)
+	if err != nil || pending != 0 {
+		t.Fatalf("PendingDelayedFanouts() = %d, %v, want job dequeued", pending, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
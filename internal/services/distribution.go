@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// 帖子分发模式
+const (
+	DistributionPush   = "push"   // 推送给全部关注者
+	DistributionHybrid = "hybrid" // 只同步推送给活跃关注者，其余关注者延迟扇出
+	DistributionPull   = "pull"   // 不同步扇出，活跃关注者延迟扇出，其余关注者读取时拉取
+)
+
+// decideDistribution 根据粉丝数、24小时发帖数和活跃关注者占比选择分发模式
+// activeRatio为负数表示未知（未开启或估算失败），此时不参与判断
+//
+//	头部作者 + 高频发帖 -> pull
+//	头部作者            -> hybrid
+//	普通作者 + 高频发帖 -> hybrid
+//	普通作者 + 活跃占比低 -> hybrid
+//	其他                -> push
+func decideDistribution(cfg config.Distribution, pushThreshold int, followers, postsLastDay int64, activeRatio float64) string {
+	influencer := followers > int64(pushThreshold)
+	frequent := cfg.FrequentPosterPerDay > 0 && postsLastDay >= int64(cfg.FrequentPosterPerDay)
+
+	switch {
+	case influencer && frequent:
+		return DistributionPull
+	case influencer, frequent:
+		return DistributionHybrid
+	case cfg.MinActiveRatio > 0 && activeRatio >= 0 && activeRatio < cfg.MinActiveRatio:
+		return DistributionHybrid
+	default:
+		return DistributionPush
+	}
+}
+
+// chooseDistribution 收集作者的发帖频率和关注者活跃占比并选择分发模式，查询失败时该项不参与判断
+func (s *OptimizedFeedService) chooseDistribution(ctx context.Context, author *models.User) string {
+	feedCfg := s.config.Feed()
+	distCfg := feedCfg.Optimization.Distribution
+
+	var postsLastDay int64
+	if distCfg.FrequentPosterPerDay > 0 {
+		count, err := s.postRepo.CountByUserIDSince(ctx, author.ID, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to count recent posts for distribution decision")
+		} else {
+			postsLastDay = count
+		}
+	}
+
+	activeRatio := -1.0
+	if distCfg.MinActiveRatio > 0 && author.Followers > 0 && author.Followers <= int64(feedCfg.PushThreshold) {
+		activeRatio = s.activeFollowerRatio(ctx, author, distCfg.ActiveSampleSize)
+	}
+
+	return decideDistribution(distCfg, feedCfg.PushThreshold, author.Followers, postsLastDay, activeRatio)
+}
+
+// activeFollowerRatio 抽样最近的关注者估算活跃占比，失败时返回-1
+func (s *OptimizedFeedService) activeFollowerRatio(ctx context.Context, author *models.User, sampleSize int) float64 {
+	followers, err := s.followRepo.GetFollowers(ctx, author.ID, 0, sampleSize)
+	if err != nil || len(followers) == 0 {
+		return -1
+	}
+	sample := make([]uuid.UUID, 0, len(followers))
+	for _, follower := range followers {
+		sample = append(sample, follower.ID)
+	}
+	active, _, err := s.activityService.PartitionByActivity(ctx, sample)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to estimate active follower ratio")
+		return -1
+	}
+	return float64(len(active)) / float64(len(sample))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/go-redis/redis/v8"
+)
+
+// distributionMetricsKey 分发统计Hash，field为"<分发类型>:<指标>"，跨进程累计
+const distributionMetricsKey = "distribution_metrics"
+
+// 分发统计指标
+const (
+	distributionMetricCompleted       = "completed"
+	distributionMetricActiveFollowers = "active_followers"
+	distributionMetricDurationMs      = "duration_ms"
+)
+
+// distributionStatusCompleted 收到分发完成事件后的最终状态
+const distributionStatusCompleted = "completed"
+
+// RecordDistributionCompleted 记录一次分发完成：将分发状态更新为completed，并按分发类型累计
+// 完成次数、推送的活跃关注者数和耗时
+func (s *RecoveryService) RecordDistributionCompleted(ctx context.Context, data queue.DistributionCompletedEventData) error {
+	// 状态可能已过期或被恢复任务清理，此时只累计统计
+	key := fmt.Sprintf("distribution_status:%s", data.PostID)
+	if err := s.updateDistributionStatus(ctx, key, distributionStatusCompleted); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to update distribution status: %w", err)
+	}
+
+	distributionType := data.DistributionType
+	if distributionType == "" {
+		distributionType = "unknown"
+	}
+
+	pipe := s.cache.Pipeline()
+	pipe.HIncrBy(ctx, distributionMetricsKey, distributionType+":"+distributionMetricCompleted, 1)
+	pipe.HIncrBy(ctx, distributionMetricsKey, distributionType+":"+distributionMetricActiveFollowers, int64(data.ActiveFollowers))
+	pipe.HIncrBy(ctx, distributionMetricsKey, distributionType+":"+distributionMetricDurationMs, data.DurationMs)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record distribution metrics: %w", err)
+	}
+	return nil
+}
+
+// GetDistributionMetrics 按分发类型返回累计的分发统计，附带平均耗时avg_duration_ms
+func (s *RecoveryService) GetDistributionMetrics(ctx context.Context) (map[string]map[string]int64, error) {
+	fields, err := s.cache.HGetAll(ctx, distributionMetricsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distribution metrics: %w", err)
+	}
+
+	metrics := make(map[string]map[string]int64)
+	for field, value := range fields {
+		distributionType, metric, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		if metrics[distributionType] == nil {
+			metrics[distributionType] = make(map[string]int64)
+		}
+		metrics[distributionType][metric] = n
+	}
+
+	for _, m := range metrics {
+		if completed := m[distributionMetricCompleted]; completed > 0 {
+			m["avg_duration_ms"] = m[distributionMetricDurationMs] / completed
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+func TestDecideDistribution(t *testing.T) {
+	cfg := config.Distribution{FrequentPosterPerDay: 10, MinActiveRatio: 0.2}
+
+	tests := []struct {
+		name         string
+		followers    int64
+		postsLastDay int64
+		activeRatio  float64
+		want         string
+	}{
+		{"frequent influencer", 5000, 10, -1, DistributionPull},
+		{"influencer", 5000, 1, -1, DistributionHybrid},
+		{"frequent regular author", 100, 12, 0.9, DistributionHybrid},
+		{"mostly inactive followers", 100, 1, 0.1, DistributionHybrid},
+		{"unknown active ratio", 100, 1, -1, DistributionPush},
+		{"regular author", 100, 1, 0.5, DistributionPush},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideDistribution(cfg, 1000, tt.followers, tt.postsLastDay, tt.activeRatio); got != tt.want {
+				t.Errorf("decideDistribution() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("disabled thresholds", func(t *testing.T) {
+		if got := decideDistribution(config.Distribution{}, 1000, 100, 50, 0); got != DistributionPush {
+			t.Errorf("decideDistribution() = %q, want %q", got, DistributionPush)
+		}
+	})
+}
+
+func newDistributionTestService(t *testing.T, mutate func(*config.FeedConfig)) (*OptimizedFeedService, sqlmock.Sqlmock, *TimelineCacheService) {
+	t.Helper()
+
+	db, mock := newTestDB(t)
+	redisClient, _ := newTestRedis(t)
+	cfg := newTestConfig(mutate)
+	log := logger.NewLogger()
+	userRepo := repository.NewUserRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	timelineCache := NewTimelineCacheService(redisClient, cfg, log)
+
+	return &OptimizedFeedService{
+		postRepo:             repository.NewPostRepository(db),
+		userRepo:             userRepo,
+		followRepo:           followRepo,
+		cache:                redisClient,
+		producer:             &fakePublisher{},
+		config:               cfg,
+		logger:               log,
+		activityService:      NewActivityService(userRepo, followRepo, redisClient, cfg, log),
+		timelineCacheService: timelineCache,
+	}, mock, timelineCache
+}
+
+// enableDelayedFanout 开启延迟扇出，普通作者的非活跃关注者也延迟处理
+func enableDelayedFanout(feed *config.FeedConfig) {
+	feed.Optimization.DelayedFanout.Enabled = true
+}
+
+// frequentPosters 24小时发帖10篇即视为高频发帖者
+func frequentPosters(feed *config.FeedConfig) {
+	feed.Optimization.Distribution.FrequentPosterPerDay = 10
+}
+
+// expectRegularFanoutTargets 普通作者的关注者列表和活跃度查询
+func expectRegularFanoutTargets(mock sqlmock.Sqlmock, active, inactive uuid.UUID) {
+	mock.ExpectQuery(`SELECT .* FROM "users" JOIN follows`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(active).AddRow(inactive))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_online"}).AddRow(active, true).AddRow(inactive, false))
+}
+
+// expectRecentPostCount 发帖频率查询
+func expectRecentPostCount(mock sqlmock.Sqlmock, count int) {
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+// markActiveFollowers 将关注者写入作者的活跃关注者集合
+func markActiveFollowers(t *testing.T, service *OptimizedFeedService, authorID uuid.UUID, followers ...uuid.UUID) {
+	t.Helper()
+	for _, follower := range followers {
+		if err := service.cache.ZAdd(context.Background(), activeFollowersKey(authorID.String()), &redis.Z{
+			Score:  float64(time.Now().Unix()),
+			Member: follower.String(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHybridDistributionDelaysInactiveFollowers(t *testing.T) {
+	service, mock, timelineCache := newDistributionTestService(t, enableDelayedFanout)
+	ctx := context.Background()
+
+	author := &models.User{ID: uuid.New(), Followers: 2}
+	active, inactive := uuid.New(), uuid.New()
+	post := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now()}
+
+	expectRegularFanoutTargets(mock, active, inactive)
+
+	if err := service.distributePostOptimized(ctx, post, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+
+	assertTimelineContains(t, timelineCache, active, post.ID, true)
+	assertTimelineContains(t, timelineCache, author.ID, post.ID, true)
+	assertTimelineContains(t, timelineCache, inactive, post.ID, false)
+
+	// 非活跃关注者在延迟扇出后收到帖子
+	expectPostLookup(mock, post)
+	if processed, err := service.ProcessDelayedFanouts(ctx, 10); err != nil || processed != 1 {
+		t.Fatalf("ProcessDelayedFanouts() = %d, %v", processed, err)
+	}
+	assertTimelineContains(t, timelineCache, inactive, post.ID, true)
+}
+
+func TestPullDistributionDelaysActiveFollowers(t *testing.T) {
+	service, mock, timelineCache := newDistributionTestService(t, frequentPosters)
+	ctx := context.Background()
+
+	author := &models.User{ID: uuid.New(), Followers: 5000}
+	follower := uuid.New()
+	post := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now()}
+	markActiveFollowers(t, service, author.ID, follower)
+	expectRecentPostCount(mock, 12)
+
+	if err := service.distributePostOptimized(ctx, post, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+	assertTimelineContains(t, timelineCache, follower, post.ID, false)
+
+	expectPostLookup(mock, post)
+	if processed, err := service.ProcessDelayedFanouts(ctx, 10); err != nil || processed != 1 {
+		t.Fatalf("ProcessDelayedFanouts() = %d, %v", processed, err)
+	}
+	assertTimelineContains(t, timelineCache, follower, post.ID, true)
+}
+
+func TestDistributionUsesReloadedPushThreshold(t *testing.T) {
+	service, mock, timelineCache := newDistributionTestService(t, nil)
+	ctx := context.Background()
+
+	author := &models.User{ID: uuid.New(), Followers: 1500}
+	active, other := uuid.New(), uuid.New()
+	markActiveFollowers(t, service, author.ID, active)
+
+	// 粉丝数超过阈值1000：头部用户只推送给活跃关注者
+	before := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now()}
+	if err := service.distributePostOptimized(ctx, before, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+	assertTimelineContains(t, timelineCache, active, before.ID, true)
+	assertTimelineContains(t, timelineCache, other, before.ID, false)
+
+	// 热更新后阈值为2000，同一作者按普通用户推送给全部关注者
+	reloaded := *service.config.Feed()
+	reloaded.PushThreshold = 2000
+	service.config.Store(&reloaded)
+
+	mock.ExpectQuery(`SELECT .* FROM "users" JOIN follows`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(active).AddRow(other))
+	after := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now()}
+	if err := service.distributePostOptimized(ctx, after, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+	assertTimelineContains(t, timelineCache, other, after.ID, true)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDistributionSkipsPostsOlderThanMaxPushAge(t *testing.T) {
+	service, mock, timelineCache := newDistributionTestService(t, func(feed *config.FeedConfig) {
+		feed.MaxPushAge = time.Hour
+	})
+	ctx := context.Background()
+
+	author := &models.User{ID: uuid.New(), Followers: 2}
+	active, inactive := uuid.New(), uuid.New()
+
+	// 超出推送窗口的帖子不查询关注者，也不写入任何Timeline
+	old := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now().Add(-2 * time.Hour)}
+	if err := service.distributePostOptimized(ctx, old, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []uuid.UUID{author.ID, active, inactive} {
+		assertTimelineContains(t, timelineCache, userID, old.ID, false)
+	}
+
+	// 窗口内的帖子正常推送
+	expectRegularFanoutTargets(mock, active, inactive)
+	recent := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now().Add(-30 * time.Minute)}
+	if err := service.distributePostOptimized(ctx, recent, author); err != nil {
+		t.Fatalf("distributePostOptimized: %v", err)
+	}
+	for _, userID := range []uuid.UUID{author.ID, active, inactive} {
+		assertTimelineContains(t, timelineCache, userID, recent.ID, true)
+	}
+}
+
+func TestPreviewReachMatchesDistribution(t *testing.T) {
+	active, inactive := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		mutate      func(*config.FeedConfig)
+		followers   int64
+		setup       func(t *testing.T, service *OptimizedFeedService, mock sqlmock.Sqlmock, authorID uuid.UUID)
+		wantTier    string
+		wantMode    string
+		wantDelayed int
+	}{
+		{
+			name:      "regular push",
+			followers: 2,
+			setup: func(t *testing.T, service *OptimizedFeedService, mock sqlmock.Sqlmock, authorID uuid.UUID) {
+				mock.ExpectQuery(`SELECT .* FROM "users" JOIN follows`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(active).AddRow(inactive))
+			},
+			wantTier: FanoutTierRegular,
+			wantMode: DistributionPush,
+		},
+		{
+			name:      "regular with delayed fan-out",
+			mutate:    enableDelayedFanout,
+			followers: 2,
+			setup: func(t *testing.T, service *OptimizedFeedService, mock sqlmock.Sqlmock, authorID uuid.UUID) {
+				expectRegularFanoutTargets(mock, active, inactive)
+			},
+			wantTier:    FanoutTierRegular,
+			wantMode:    DistributionPush,
+			wantDelayed: 1,
+		},
+		{
+			name:      "influencer hybrid",
+			followers: 5000,
+			setup: func(t *testing.T, service *OptimizedFeedService, mock sqlmock.Sqlmock, authorID uuid.UUID) {
+				markActiveFollowers(t, service, authorID, active, inactive)
+			},
+			wantTier: FanoutTierInfluencer,
+			wantMode: DistributionHybrid,
+		},
+		{
+			name:      "influencer pull",
+			mutate:    frequentPosters,
+			followers: 5000,
+			setup: func(t *testing.T, service *OptimizedFeedService, mock sqlmock.Sqlmock, authorID uuid.UUID) {
+				markActiveFollowers(t, service, authorID, active, inactive)
+				expectRecentPostCount(mock, 12)
+			},
+			wantTier:    FanoutTierInfluencer,
+			wantMode:    DistributionPull,
+			wantDelayed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock, timelineCache := newDistributionTestService(t, tt.mutate)
+			ctx := context.Background()
+
+			author := &models.User{ID: uuid.New(), Followers: tt.followers}
+			post := &models.Post{ID: uuid.New(), UserID: author.ID, Score: 1, CreatedAt: time.Now()}
+
+			mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "followers"}).AddRow(author.ID, author.Followers))
+			tt.setup(t, service, mock, author.ID)
+			preview, err := service.PreviewReach(ctx, author.ID.String(), "")
+			if err != nil {
+				t.Fatalf("PreviewReach: %v", err)
+			}
+			if preview.Tier != tt.wantTier || preview.Mode != tt.wantMode || preview.DelayedRecipients != tt.wantDelayed {
+				t.Errorf("PreviewReach() = %+v, want tier %q mode %q delayed %d", preview, tt.wantTier, tt.wantMode, tt.wantDelayed)
+			}
+
+			// 实际分发（包括延迟扇出）送达的关注者数与预估一致
+			tt.setup(t, service, mock, author.ID)
+			if err := service.distributePostOptimized(ctx, post, author); err != nil {
+				t.Fatalf("distributePostOptimized: %v", err)
+			}
+			if tt.wantDelayed > 0 {
+				expectPostLookup(mock, post)
+				if processed, err := service.ProcessDelayedFanouts(ctx, 10); err != nil || processed != 1 {
+					t.Fatalf("ProcessDelayedFanouts() = %d, %v", processed, err)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+
+			reached := 0
+			for _, follower := range []uuid.UUID{active, inactive} {
+				if timelineHas(t, timelineCache, follower, post.ID) {
+					reached++
+				}
+			}
+			if reached != preview.EstimatedRecipients {
+				t.Errorf("distribution reached %d followers, preview estimated %d", reached, preview.EstimatedRecipients)
+			}
+		})
+	}
+
+	t.Run("close friends", func(t *testing.T) {
+		service, mock, _ := newDistributionTestService(t, nil)
+		author := &models.User{ID: uuid.New(), Followers: 5000}
+
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "followers"}).AddRow(author.ID, author.Followers))
+		mock.ExpectQuery(`SELECT "follower_id" FROM "follows"`).
+			WillReturnRows(sqlmock.NewRows([]string{"follower_id"}).AddRow(active))
+
+		preview, err := service.PreviewReach(context.Background(), author.ID.String(), models.PostVisibilityCloseFriends)
+		if err != nil {
+			t.Fatalf("PreviewReach: %v", err)
+		}
+		if preview.Mode != DistributionPush || preview.EstimatedRecipients != 1 {
+			t.Errorf("PreviewReach() = %+v, want 1 close friend pushed", preview)
+		}
+	})
+
+	t.Run("invalid visibility", func(t *testing.T) {
+		service, _, _ := newDistributionTestService(t, nil)
+		_, err := service.PreviewReach(context.Background(), uuid.New().String(), "everyone")
+		if !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Fatalf("expected invalid input, got %v", err)
+		}
+	})
+}
+
+// timelineHas 判断用户的Timeline缓存中是否包含帖子
+func timelineHas(t *testing.T, timelineCache *TimelineCacheService, userID, postID uuid.UUID) bool {
+	t.Helper()
+
+	members, err := timelineCache.cache.ZRange(context.Background(), timelineCache.getTimelineKey(userID), 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		if member == postID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func assertTimelineContains(t *testing.T, timelineCache *TimelineCacheService, userID, postID uuid.UUID, want bool) {
+	t.Helper()
+
+	if found := timelineHas(t, timelineCache, userID, postID); found != want {
+		t.Errorf("timeline of %s contains post = %v, want %v", userID, found, want)
+	}
+}
+
+// expectPostLookup 延迟扇出前确认帖子仍存在的查询
+func expectPostLookup(mock sqlmock.Sqlmock, post *models.Post) {
+	mock.ExpectQuery(`SELECT \* FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(post.ID, post.UserID))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(post.UserID))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// 排序Feed中已互动帖子的处理方式
+const (
+	EngagedRankingOff      = "off"
+	EngagedRankingPenalize = "penalize"
+	EngagedRankingExclude  = "exclude"
+)
+
+// applyEngagedRanking 对排序Feed的一页结果按查看者的互动状态调整：降权后重新排序，或直接去掉已互动的帖子
+// 调整只在当前页内进行，分页仍按排序时间线的偏移量，查询失败时保持原顺序
+func (s *OptimizedFeedService) applyEngagedRanking(ctx context.Context, viewerID uuid.UUID, posts []*models.Post, items []TimelineItem) []*models.Post {
+	feedCfg := s.config.Feed()
+	if feedCfg.EngagedRanking != EngagedRankingPenalize && feedCfg.EngagedRanking != EngagedRankingExclude {
+		return posts
+	}
+	if len(posts) == 0 {
+		return posts
+	}
+
+	engaged, err := s.engagedPostIDs(ctx, viewerID, postIDsOf(posts))
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to look up engaged posts, keeping ranked order")
+		return posts
+	}
+
+	if feedCfg.EngagedRanking == EngagedRankingExclude {
+		kept := make([]*models.Post, 0, len(posts))
+		for _, post := range posts {
+			if !engaged[post.ID] {
+				kept = append(kept, post)
+			}
+		}
+		return kept
+	}
+
+	scores := make(map[uuid.UUID]float64, len(posts))
+	for _, item := range items {
+		if postID, err := uuid.Parse(item.PostID); err == nil {
+			scores[postID] = item.Score
+		}
+	}
+	for _, post := range posts {
+		if engaged[post.ID] {
+			scores[post.ID] *= feedCfg.EngagedPenalty
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return scores[posts[i].ID] > scores[posts[j].ID]
+	})
+	return posts
+}
+
+// engagedPostIDs 批量查询查看者点赞过或评论过的帖子，点赞状态走LikeStateCache
+func (s *OptimizedFeedService) engagedPostIDs(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	engaged, err := s.likeState.Lookup(ctx, viewerID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	commented, err := s.commentRepo.GetCommentedPostIDs(ctx, viewerID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	for postID := range commented {
+		engaged[postID] = true
+	}
+	return engaged, nil
+}
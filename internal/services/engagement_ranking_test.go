@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestApplyEngagedRanking(t *testing.T) {
+	ctx := context.Background()
+	viewerID := uuid.New()
+	liked, fresh, commented := uuid.New(), uuid.New(), uuid.New()
+
+	// 已点赞的帖子和未互动的帖子分数相同，已评论的帖子分数略低
+	newPage := func() ([]*models.Post, []TimelineItem) {
+		posts := []*models.Post{{ID: liked}, {ID: fresh}, {ID: commented}}
+		items := []TimelineItem{
+			{PostID: liked.String(), Score: 10},
+			{PostID: fresh.String(), Score: 10},
+			{PostID: commented.String(), Score: 9},
+		}
+		return posts, items
+	}
+	newService := func(t *testing.T, mode string) (*OptimizedFeedService, sqlmock.Sqlmock) {
+		db, mock := newTestDB(t)
+		redisClient, mr := newTestRedis(t)
+		mr.Set(likeStateKey(viewerID, liked), models.ReactionLike)
+		mr.Set(likeStateKey(viewerID, fresh), "0")
+		mr.Set(likeStateKey(viewerID, commented), "0")
+		log := logger.NewLogger()
+		return &OptimizedFeedService{
+			commentRepo: repository.NewCommentRepository(db),
+			config: newTestConfig(func(feed *config.FeedConfig) {
+				feed.EngagedRanking = mode
+				feed.EngagedPenalty = 0.5
+			}),
+			logger:    log,
+			likeState: NewLikeStateCache(redisClient, repository.NewLikeRepository(db), log),
+		}, mock
+	}
+	expectCommented := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT DISTINCT "post_id" FROM "comments" WHERE \(user_id = \$1 AND post_id IN \(\$2,\$3,\$4\)\)`).
+			WithArgs(viewerID, liked, fresh, commented).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id"}).AddRow(commented))
+	}
+	order := func(posts []*models.Post) []uuid.UUID {
+		ids := make([]uuid.UUID, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+
+	for _, tt := range []struct {
+		mode    string
+		queries bool
+		want    []uuid.UUID
+	}{
+		// 降权后已点赞的帖子排在同分的未互动帖子之后，已评论的帖子降到最后
+		{EngagedRankingPenalize, true, []uuid.UUID{fresh, liked, commented}},
+		{EngagedRankingExclude, true, []uuid.UUID{fresh}},
+		// 关闭时不查询互动状态，保持原顺序
+		{EngagedRankingOff, false, []uuid.UUID{liked, fresh, commented}},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			service, mock := newService(t, tt.mode)
+			if tt.queries {
+				expectCommented(mock)
+			}
+			posts, items := newPage()
+
+			got := order(service.applyEngagedRanking(ctx, viewerID, posts, items))
+			if len(got) != len(tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("order = %v, want %v", got, tt.want)
+					break
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("lookup failure keeps ranked order", func(t *testing.T) {
+		service, _ := newService(t, EngagedRankingPenalize)
+		posts, items := newPage()
+
+		// 评论查询没有预期，返回错误
+		got := order(service.applyEngagedRanking(ctx, viewerID, posts, items))
+		if len(got) != 3 || got[0] != liked || got[1] != fresh || got[2] != commented {
+			t.Errorf("order = %v, want the original ranked order", got)
+		}
+	})
+}
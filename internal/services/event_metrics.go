@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+// 事件处理结果标签
+const (
+	EventOutcomeSuccess = "success"
+	EventOutcomeError   = "error"
+)
+
+// EventMetric 单个(worker, 事件类型, 结果)组合的处理统计
+type EventMetric struct {
+	Worker    string           `json:"worker"`
+	EventType string           `json:"event_type"`
+	Outcome   string           `json:"outcome"`
+	Count     int64            `json:"count"`
+	AvgMs     float64          `json:"avg_ms"`
+	MaxMs     float64          `json:"max_ms"`
+	Histogram map[string]int64 `json:"histogram"`
+}
+
+type eventMetricKey struct {
+	worker    string
+	eventType string
+	outcome   string
+}
+
+type eventMetricStat struct {
+	count   int64
+	totalMs float64
+	maxMs   float64
+	buckets []int64 // 与latencyBucketsMs对应，最后一个为超出最大桶的计数
+}
+
+// EventMetrics 按worker、事件类型和处理结果统计事件处理次数和耗时分布，多个worker可共用一个实例
+// nil实例的方法均为空操作，便于在不需要统计的场景直接传nil
+type EventMetrics struct {
+	logger *logger.Logger
+
+	mu    sync.Mutex
+	stats map[eventMetricKey]*eventMetricStat
+}
+
+func NewEventMetrics(logger *logger.Logger) *EventMetrics {
+	return &EventMetrics{
+		logger: logger,
+		stats:  make(map[eventMetricKey]*eventMetricStat),
+	}
+}
+
+// Observe 记录一次事件处理，err非nil时计为error
+func (m *EventMetrics) Observe(worker, eventType string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	outcome := EventOutcomeSuccess
+	if err != nil {
+		outcome = EventOutcomeError
+	}
+	if eventType == "" {
+		eventType = "unknown"
+	}
+	ms := toMs(duration)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := eventMetricKey{worker: worker, eventType: eventType, outcome: outcome}
+	stat, ok := m.stats[key]
+	if !ok {
+		stat = &eventMetricStat{buckets: make([]int64, len(latencyBucketsMs)+1)}
+		m.stats[key] = stat
+	}
+	stat.count++
+	stat.totalMs += ms
+	if ms > stat.maxMs {
+		stat.maxMs = ms
+	}
+	bucket := len(latencyBucketsMs)
+	for i, bound := range latencyBucketsMs {
+		if ms <= bound {
+			bucket = i
+			break
+		}
+	}
+	stat.buckets[bucket]++
+}
+
+// Snapshot 返回当前所有统计，按worker、事件类型、结果排序
+func (m *EventMetrics) Snapshot() []EventMetric {
+	if m == nil {
+		return []EventMetric{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := make([]EventMetric, 0, len(m.stats))
+	for key, stat := range m.stats {
+		histogram := make(map[string]int64, len(stat.buckets))
+		for i, bound := range latencyBucketsMs {
+			histogram[fmt.Sprintf("le_%gms", bound)] = stat.buckets[i]
+		}
+		histogram[fmt.Sprintf("gt_%gms", latencyBucketsMs[len(latencyBucketsMs)-1])] = stat.buckets[len(latencyBucketsMs)]
+
+		metrics = append(metrics, EventMetric{
+			Worker:    key.worker,
+			EventType: key.eventType,
+			Outcome:   key.outcome,
+			Count:     stat.count,
+			AvgMs:     stat.totalMs / float64(stat.count),
+			MaxMs:     stat.maxMs,
+			Histogram: histogram,
+		})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		a, b := metrics[i], metrics[j]
+		if a.Worker != b.Worker {
+			return a.Worker < b.Worker
+		}
+		if a.EventType != b.EventType {
+			return a.EventType < b.EventType
+		}
+		return a.Outcome < b.Outcome
+	})
+	return metrics
+}
+
+// StartReportJob 定期把统计写入日志，用于没有HTTP接口的独立worker进程
+func (m *EventMetrics) StartReportJob(ctx context.Context, interval time.Duration) {
+	if m == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, metric := range m.Snapshot() {
+				m.logger.WithFields(map[string]interface{}{
+					"worker":     metric.Worker,
+					"event_type": metric.EventType,
+					"outcome":    metric.Outcome,
+					"count":      metric.Count,
+					"avg_ms":     metric.AvgMs,
+					"max_ms":     metric.MaxMs,
+				}).Info("Event processing metrics")
+			}
+		}
+	}
+}
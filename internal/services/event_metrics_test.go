@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+func TestEventMetricsObserve(t *testing.T) {
+	metrics := NewEventMetrics(logger.NewLogger())
+	failure := errors.New("boom")
+
+	metrics.Observe("feed_worker", "post_created", 3*time.Millisecond, nil)
+	metrics.Observe("feed_worker", "post_created", 40*time.Millisecond, nil)
+	metrics.Observe("feed_worker", "post_created", 10*time.Second, failure)
+	metrics.Observe("user_event_worker", "", time.Millisecond, nil)
+	metrics.Observe("feed_worker", "like_created", time.Millisecond, nil)
+
+	snapshot := metrics.Snapshot()
+	// 按worker、事件类型、结果排序
+	want := []struct {
+		worker, eventType, outcome string
+		count                      int64
+	}{
+		{"feed_worker", "like_created", EventOutcomeSuccess, 1},
+		{"feed_worker", "post_created", EventOutcomeError, 1},
+		{"feed_worker", "post_created", EventOutcomeSuccess, 2},
+		{"user_event_worker", "unknown", EventOutcomeSuccess, 1},
+	}
+	if len(snapshot) != len(want) {
+		t.Fatalf("got %d metrics %+v, want %d", len(snapshot), snapshot, len(want))
+	}
+	for i, w := range want {
+		got := snapshot[i]
+		if got.Worker != w.worker || got.EventType != w.eventType || got.Outcome != w.outcome || got.Count != w.count {
+			t.Errorf("metric %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	// 耗时落入对应的直方图桶，超过最大桶的单独计数
+	success := snapshot[2]
+	if success.Histogram["le_5ms"] != 1 || success.Histogram["le_50ms"] != 1 {
+		t.Errorf("success histogram = %v, want one in le_5ms and one in le_50ms", success.Histogram)
+	}
+	if success.AvgMs != 21.5 || success.MaxMs != 40 {
+		t.Errorf("success avg/max = %v/%v, want 21.5/40", success.AvgMs, success.MaxMs)
+	}
+	if failed := snapshot[1]; failed.Histogram["gt_2500ms"] != 1 {
+		t.Errorf("error histogram = %v, want the slow event in gt_2500ms", failed.Histogram)
+	}
+}
+
+func TestEventMetricsNilReceiver(t *testing.T) {
+	var metrics *EventMetrics
+	metrics.Observe("feed_worker", "post_created", time.Millisecond, nil)
+	if snapshot := metrics.Snapshot(); len(snapshot) != 0 {
+		t.Errorf("Snapshot() on nil = %v, want empty", snapshot)
+	}
+}
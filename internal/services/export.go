@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/google/uuid"
+)
+
+// ExportPageSize 导出时每次从数据库读取的条数
+const ExportPageSize = 500
+
+// 导出记录类型
+const (
+	ExportRecordPost    = "post"
+	ExportRecordComment = "comment"
+	// 导出中途失败时写入的最后一行
+	ExportRecordError = "error"
+)
+
+// ExportedPost 导出的帖子
+type ExportedPost struct {
+	ID           uuid.UUID `json:"id"`
+	Content      string    `json:"content"`
+	ImageURLs    []string  `json:"image_urls,omitempty"`
+	LikeCount    int64     `json:"like_count"`
+	CommentCount int64     `json:"comment_count"`
+	ShareCount   int64     `json:"share_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ExportedComment 导出的评论
+type ExportedComment struct {
+	ID        uuid.UUID  `json:"id"`
+	PostID    uuid.UUID  `json:"post_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty"`
+	Content   string     `json:"content"`
+	LikeCount int64      `json:"like_count"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ExportRecord 导出文件中的一行，Type决定Post、Comment和Error中哪个有值
+type ExportRecord struct {
+	Type    string           `json:"type"`
+	Post    *ExportedPost    `json:"post,omitempty"`
+	Comment *ExportedComment `json:"comment,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// ExportUserData 按时间顺序导出用户的全部帖子和评论。数据按页从数据库读取，
+// 每读完一页调用一次emit，调用方负责写出，整个导出过程不会把全部数据加载到内存
+func (s *FeedService) ExportUserData(ctx context.Context, userID string, emit func([]ExportRecord) error) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return apperrors.InvalidInput("invalid user ID")
+	}
+
+	var afterAt time.Time
+	afterID := uuid.Nil
+	for {
+		posts, err := s.postRepo.ScanByUserID(ctx, userUUID, afterAt, afterID, ExportPageSize)
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		records := make([]ExportRecord, len(posts))
+		for i, post := range posts {
+			records[i] = ExportRecord{Type: ExportRecordPost, Post: &ExportedPost{
+				ID:           post.ID,
+				Content:      post.Content,
+				ImageURLs:    post.ImageURLs,
+				LikeCount:    post.LikeCount,
+				CommentCount: post.CommentCount,
+				ShareCount:   post.ShareCount,
+				CreatedAt:    post.CreatedAt,
+				UpdatedAt:    post.UpdatedAt,
+			}}
+		}
+		if err := emit(records); err != nil {
+			return err
+		}
+
+		last := posts[len(posts)-1]
+		afterAt, afterID = last.CreatedAt, last.ID
+		if len(posts) < ExportPageSize {
+			break
+		}
+	}
+
+	afterAt, afterID = time.Time{}, uuid.Nil
+	for {
+		comments, err := s.commentRepo.ScanByUserID(ctx, userUUID, afterAt, afterID, ExportPageSize)
+		if err != nil {
+			return err
+		}
+		if len(comments) == 0 {
+			break
+		}
+
+		records := make([]ExportRecord, len(comments))
+		for i, comment := range comments {
+			records[i] = ExportRecord{Type: ExportRecordComment, Comment: &ExportedComment{
+				ID:        comment.ID,
+				PostID:    comment.PostID,
+				ParentID:  comment.ParentID,
+				Content:   comment.Content,
+				LikeCount: comment.LikeCount,
+				CreatedAt: comment.CreatedAt,
+			}}
+		}
+		if err := emit(records); err != nil {
+			return err
+		}
+
+		last := comments[len(comments)-1]
+		afterAt, afterID = last.CreatedAt, last.ID
+		if len(comments) < ExportPageSize {
+			break
+		}
+	}
+
+	return nil
+}
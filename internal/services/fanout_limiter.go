@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultMaxInflightFanouts 未配置时允许同时进行的扇出数
+const DefaultMaxInflightFanouts = 64
+
+// FanoutQueueStats 扇出队列状态
+type FanoutQueueStats struct {
+	Limit    int   `json:"limit"`
+	InFlight int64 `json:"in_flight"`
+	Waiting  int64 `json:"waiting"`
+	Depth    int64 `json:"depth"` // 进行中 + 等待中
+	Shed     int64 `json:"shed"`  // 饱和时被丢弃的非关键任务数
+}
+
+// FanoutLimiter 限制同时进行的扇出数量
+// 关键任务（帖子分发）在饱和时阻塞等待，从而减慢生产者；非关键任务（缓存重建）直接丢弃
+type FanoutLimiter struct {
+	slots    chan struct{}
+	inflight int64
+	waiting  int64
+	shed     int64
+}
+
+func NewFanoutLimiter(limit int) *FanoutLimiter {
+	if limit <= 0 {
+		limit = DefaultMaxInflightFanouts
+	}
+	return &FanoutLimiter{slots: make(chan struct{}, limit)}
+}
+
+// Acquire 获取一个扇出名额，饱和时阻塞直到有空闲名额或ctx结束
+func (l *FanoutLimiter) Acquire(ctx context.Context) error {
+	atomic.AddInt64(&l.waiting, 1)
+	defer atomic.AddInt64(&l.waiting, -1)
+
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inflight, 1)
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("fan-out queue saturated: %w", ctx.Err())
+	}
+}
+
+// TryAcquire 非阻塞获取名额，饱和时返回false并计入丢弃数
+func (l *FanoutLimiter) TryAcquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inflight, 1)
+		return true
+	default:
+		atomic.AddInt64(&l.shed, 1)
+		return false
+	}
+}
+
+// Release 归还名额
+func (l *FanoutLimiter) Release() {
+	atomic.AddInt64(&l.inflight, -1)
+	<-l.slots
+}
+
+// Stats 获取扇出队列状态
+func (l *FanoutLimiter) Stats() FanoutQueueStats {
+	inflight := atomic.LoadInt64(&l.inflight)
+	waiting := atomic.LoadInt64(&l.waiting)
+	return FanoutQueueStats{
+		Limit:    cap(l.slots),
+		InFlight: inflight,
+		Waiting:  waiting,
+		Depth:    inflight + waiting,
+		Shed:     atomic.LoadInt64(&l.shed),
+	}
+}
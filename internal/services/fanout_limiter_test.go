@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanoutLimiterBoundsConcurrency(t *testing.T) {
+	const limit, workers = 3, 20
+	limiter := NewFanoutLimiter(limit)
+	ctx := context.Background()
+
+	var active, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.Acquire(ctx); err != nil {
+				t.Errorf("Acquire: %v", err)
+				return
+			}
+			defer limiter.Release()
+
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("peak concurrency %d exceeds limit %d", peak, limit)
+	}
+	if stats := limiter.Stats(); stats.InFlight != 0 || stats.Waiting != 0 || stats.Depth != 0 {
+		t.Errorf("stats after drain = %+v, want empty queue", stats)
+	}
+}
+
+func TestFanoutLimiterDepthReflectsLoad(t *testing.T) {
+	limiter := NewFanoutLimiter(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+
+	// 饱和后关键任务阻塞等待，计入队列深度
+	acquired := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			if err := limiter.Acquire(ctx); err == nil {
+				acquired <- struct{}{}
+			}
+		}()
+	}
+	waitForStats(t, limiter, func(s FanoutQueueStats) bool { return s.Waiting == 3 })
+
+	stats := limiter.Stats()
+	if stats.Limit != 2 || stats.InFlight != 2 || stats.Depth != 5 {
+		t.Errorf("saturated stats = %+v, want limit 2, in flight 2, depth 5", stats)
+	}
+
+	// 非关键任务直接丢弃
+	if limiter.TryAcquire() {
+		t.Fatal("TryAcquire succeeded on a saturated limiter")
+	}
+	if shed := limiter.Stats().Shed; shed != 1 {
+		t.Errorf("shed = %d, want 1", shed)
+	}
+
+	// 释放名额后等待者依次获得名额
+	limiter.Release()
+	<-acquired
+	waitForStats(t, limiter, func(s FanoutQueueStats) bool { return s.Waiting == 2 && s.InFlight == 2 })
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelTimeout()
+	if err := limiter.Acquire(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire on saturated limiter = %v, want deadline exceeded", err)
+	}
+}
+
+func TestFanoutLimiterDefaultLimit(t *testing.T) {
+	if limit := NewFanoutLimiter(0).Stats().Limit; limit != DefaultMaxInflightFanouts {
+		t.Errorf("limit = %d, want %d", limit, DefaultMaxInflightFanouts)
+	}
+}
+
+func waitForStats(t *testing.T, limiter *FanoutLimiter, ok func(FanoutQueueStats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !ok(limiter.Stats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("stats never reached expected state: %+v", limiter.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
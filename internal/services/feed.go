@@ -8,6 +8,7 @@ import (
 	"math"
 	"time"
 
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/config"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
@@ -25,9 +26,14 @@ type FeedService struct {
 	likeRepo     *repository.LikeRepository
 	commentRepo  *repository.CommentRepository
 	cache        *cache.RedisClient
-	producer     *queue.KafkaProducer
-	config       *config.FeedConfig
+	producer     queue.Publisher
+	config       *config.ConfigWatcher
 	logger       *logger.Logger
+	impressions  *ImpressionTracker
+	likeState    *LikeStateCache
+	moderator    Moderator
+
+	cacheStrategy *CacheStrategyService
 }
 
 func NewFeedService(
@@ -39,8 +45,10 @@ func NewFeedService(
 	commentRepo *repository.CommentRepository,
 	cache *cache.RedisClient,
 	producer *queue.KafkaProducer,
-	config *config.FeedConfig,
+	config *config.ConfigWatcher,
 	logger *logger.Logger,
+	moderator Moderator,
+	cacheStrategy *CacheStrategyService,
 ) *FeedService {
 	return &FeedService{
 		postRepo:     postRepo,
@@ -53,18 +61,29 @@ func NewFeedService(
 		producer:     producer,
 		config:       config,
 		logger:       logger,
+		impressions:  NewImpressionTracker(cache, postRepo, logger),
+		likeState:    NewLikeStateCache(cache, likeRepo, logger),
+		moderator:    moderator,
+
+		cacheStrategy: cacheStrategy,
 	}
 }
 
 type CreatePostRequest struct {
-	Content   string   `json:"content" binding:"required,min=1,max=1000"`
-	ImageURLs []string `json:"image_urls"`
+	Content    string   `json:"content" binding:"required"` // 长度在服务层按字符校验
+	ImageURLs  []string `json:"image_urls"`
+	Visibility string   `json:"visibility"` // public（默认）| close_friends
 }
 
 type FeedResponse struct {
 	Posts      []*models.Post `json:"posts"`
 	NextCursor string         `json:"next_cursor"`
 	HasMore    bool           `json:"has_more"`
+	Sampled    bool           `json:"sampled,omitempty"` // 关注数过多时只合并了部分关注用户
+	Stale      bool           `json:"stale,omitempty"`   // 实时读取失败，返回的是最近一次成功的Feed快照
+	Degraded   bool           `json:"degraded,omitempty"` // 数据库不可用，只返回了缓存中能找到的部分帖子
+
+	ScoreBreakdown []ScoreBreakdown `json:"score_breakdown,omitempty"` // 仅debug模式返回
 }
 
 func (s *FeedService) CreatePost(ctx context.Context, userID string, req *CreatePostRequest) (*models.Post, error) {
@@ -73,20 +92,37 @@ func (s *FeedService) CreatePost(ctx context.Context, userID string, req *Create
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	// 内容校验和审核，在任何写入之前执行
+	content, err := normalizeContent(req.Content, MaxPostContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := moderate(ctx, s.moderator, s.logger, userID, "post", content); err != nil {
+		return nil, err
+	}
+	if content, err = sanitizeContent(content, s.config.Feed().ContentSanitize); err != nil {
+		return nil, err
+	}
+	visibility, err := normalizeVisibility(req.Visibility)
+	if err != nil {
+		return nil, err
+	}
+
 	// 获取用户信息
 	user, err := s.userRepo.GetByID(ctx, userUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found")
 	}
 
 	// 创建帖子
 	post := &models.Post{
 		UserID:      userUUID,
-		Content:     req.Content,
+		Content:     content,
 		ImageURLs:   req.ImageURLs,
+		Visibility:  visibility,
 		Score:       s.calculateInitialScore(user),
 		CreatedAt:   time.Now(),
 	}
@@ -97,7 +133,8 @@ func (s *FeedService) CreatePost(ctx context.Context, userID string, req *Create
 
 	// 计算帖子分数
 	post.Score = s.calculatePostScore(post, user)
-	if err := s.postRepo.Update(ctx, post); err != nil {
+	// 只更新score列，避免整行Save覆盖并发写入的计数器
+	if err := s.postRepo.UpdateScore(ctx, post.ID, post.Score); err != nil {
 		s.logger.WithError(err).Error("Failed to update post score")
 	}
 
@@ -136,8 +173,9 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, cursor string,
 	}
 
 	// 检查缓存
-	cacheKey := fmt.Sprintf("feed:%s:%s:%d", userID, cursor, limit)
+	cacheKey := s.feedCacheKey(ctx, userID, cursor, limit)
 	if cachedFeed, err := s.getCachedFeed(ctx, cacheKey); err == nil && cachedFeed != nil {
+		s.RecordImpressions(ctx, userUUID, postIDsOf(cachedFeed.Posts))
 		return cachedFeed, nil
 	}
 
@@ -169,9 +207,13 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, cursor string,
 			posts = append(posts, &timeline.Post)
 		}
 	}
+	if posts, err = filterVisiblePosts(ctx, s.followRepo, userUUID, posts); err != nil {
+		return nil, fmt.Errorf("failed to filter timeline posts: %w", err)
+	}
 
 	// 更新阅读量等动态数据
 	s.updateDynamicData(ctx, posts, userUUID)
+	s.RecordImpressions(ctx, userUUID, postIDsOf(posts))
 
 	response := &FeedResponse{
 		Posts:      posts,
@@ -180,20 +222,30 @@ func (s *FeedService) GetFeed(ctx context.Context, userID string, cursor string,
 	}
 
 	// 缓存结果
-	if err := s.cacheFeed(ctx, cacheKey, response); err != nil {
+	if err := s.cacheFeed(ctx, userUUID, cacheKey, response); err != nil {
 		s.logger.WithError(err).Error("Failed to cache feed")
 	}
 
 	return response, nil
 }
 
-func (s *FeedService) GetUserPosts(ctx context.Context, targetUserID string, offset, limit int) ([]*models.Post, error) {
+// GetUserPosts 获取用户发布的帖子，仅密友可见的帖子只返回给作者本人和作者的密友
+func (s *FeedService) GetUserPosts(ctx context.Context, viewerID, targetUserID string, offset, limit int) ([]*models.Post, error) {
 	userUUID, err := uuid.Parse(targetUserID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
-	posts, err := s.postRepo.GetByUserID(ctx, userUUID, offset, limit)
+	includeCloseFriends := viewerID == userUUID.String()
+	if !includeCloseFriends && viewerID != "" {
+		if viewerUUID, err := uuid.Parse(viewerID); err == nil {
+			if includeCloseFriends, err = s.followRepo.IsCloseFriend(ctx, viewerUUID, userUUID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	posts, err := s.postRepo.GetByUserID(ctx, userUUID, offset, limit, includeCloseFriends)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user posts: %w", err)
 	}
@@ -201,23 +253,85 @@ func (s *FeedService) GetUserPosts(ctx context.Context, targetUserID string, off
 	return posts, nil
 }
 
+// GetPostForViewer 获取单个帖子，hydrate为true且有查看者时填充查看者的点赞状态
+func (s *FeedService) GetPostForViewer(ctx context.Context, postID, viewerID string, hydrate bool) (*models.Post, error) {
+	post, err := s.GetPostByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 看不到的密友帖子按不存在处理，不暴露帖子存在
+	if post.IsCloseFriendsOnly() {
+		viewerUUID, err := uuid.Parse(viewerID)
+		if err != nil {
+			return nil, apperrors.NotFound("post not found")
+		}
+		visible, err := s.canViewPost(ctx, viewerUUID, post)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, apperrors.NotFound("post not found")
+		}
+	}
+
+	if hydrate && viewerID != "" {
+		if viewerUUID, err := uuid.Parse(viewerID); err == nil {
+			s.hydrateViewerState(ctx, viewerUUID, []*models.Post{post})
+		}
+	}
+	return post, nil
+}
+
+// ViewerStateEnabled 单帖查询默认是否填充查看者状态
+func (s *FeedService) ViewerStateEnabled() bool {
+	return s.config.Feed().HydrateViewerState
+}
+
+// PostCacheKey 单帖缓存key，点赞/评论事件和删除帖子时失效
+func PostCacheKey(postID string) string {
+	return fmt.Sprintf("post:%s", postID)
+}
+
+// GetPostByID 获取单个帖子，优先读取单帖缓存
 func (s *FeedService) GetPostByID(ctx context.Context, postID string) (*models.Post, error) {
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid post ID: %w", err)
 	}
 
+	ttl := s.config.Feed().PostCacheTTL
+	if ttl > 0 {
+		var cached models.Post
+		if err := s.cache.GetJSON(ctx, PostCacheKey(postUUID.String()), &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
 	post, err := s.postRepo.GetByID(ctx, postUUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
 	if post == nil {
-		return nil, errors.New("post not found")
+		return nil, apperrors.NotFound("post not found")
+	}
+
+	if ttl > 0 {
+		if err := s.cache.SetJSON(ctx, PostCacheKey(postUUID.String()), post, ttl); err != nil {
+			s.logger.WithError(err).Error("Failed to cache post")
+		}
 	}
 
 	return post, nil
 }
 
+// invalidatePost 删除单帖缓存
+func (s *FeedService) invalidatePost(ctx context.Context, postID string) {
+	if err := s.cache.Delete(ctx, PostCacheKey(postID)); err != nil {
+		s.logger.WithError(err).Error("Failed to invalidate post cache")
+	}
+}
+
 func (s *FeedService) DeletePost(ctx context.Context, userID, postID string) error {
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
@@ -230,18 +344,19 @@ func (s *FeedService) DeletePost(ctx context.Context, userID, postID string) err
 		return fmt.Errorf("failed to get post: %w", err)
 	}
 	if post == nil {
-		return errors.New("post not found")
+		return apperrors.NotFound("post not found")
 	}
 
 	// 检查权限
 	if post.UserID.String() != userID {
-		return errors.New("permission denied")
+		return apperrors.PermissionDenied("permission denied")
 	}
 
 	// 删除帖子
 	if err := s.postRepo.Delete(ctx, postUUID); err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
+	s.invalidatePost(ctx, postUUID.String())
 
 	// 从所有timeline中删除
 	if err := s.timelineRepo.DeleteByPostID(ctx, postUUID); err != nil {
@@ -249,7 +364,9 @@ func (s *FeedService) DeletePost(ctx context.Context, userID, postID string) err
 	}
 
 	// 清除相关缓存
-	s.clearFeedCache(ctx, post.UserID.String())
+	if err := s.clearFeedCache(ctx, post.UserID.String()); err != nil {
+		s.logger.WithError(err).Error("Failed to clear feed cache")
+	}
 
 	// 发送帖子删除事件
 	event := queue.Event{
@@ -272,6 +389,83 @@ func (s *FeedService) DeletePost(ctx context.Context, userID, postID string) err
 	return nil
 }
 
+// ErrRestoreWindowExpired 帖子删除已超过可恢复的时间窗口
+var ErrRestoreWindowExpired = errors.New("post restore window has expired")
+
+// RestorePost 作者在宽限期内恢复已删除的帖子，并重新分发到关注者的timeline
+func (s *FeedService) RestorePost(ctx context.Context, userID, postID string) (*models.Post, error) {
+	postUUID, err := uuid.Parse(postID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post ID: %w", err)
+	}
+
+	post, err := s.postRepo.GetDeletedByID(ctx, postUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if post == nil {
+		return nil, apperrors.NotFound("post not found")
+	}
+
+	// 检查权限
+	if post.UserID.String() != userID {
+		return nil, apperrors.PermissionDenied("permission denied")
+	}
+
+	window := s.config.Feed().RestoreGraceWindow
+	if window <= 0 || post.RemovedAt == nil {
+		return nil, ErrRestoreWindowExpired
+	}
+	restored, err := s.postRepo.Restore(ctx, postUUID, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrRestoreWindowExpired
+	}
+	s.invalidatePost(ctx, postUUID.String())
+
+	author, err := s.userRepo.GetByID(ctx, post.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if author == nil {
+		return nil, apperrors.NotFound("user not found")
+	}
+	post.IsDeleted = false
+	post.RemovedAt = nil
+	post.User = *author
+
+	// 删除时已清理timeline，这里重新分发
+	if err := s.distributePost(ctx, post, author); err != nil {
+		s.logger.WithError(err).Error("Failed to redistribute restored post")
+	}
+	if err := s.clearFeedCache(ctx, userID); err != nil {
+		s.logger.WithError(err).Error("Failed to clear feed cache")
+	}
+
+	event := queue.Event{
+		Type:      queue.EventPostCreated,
+		Timestamp: time.Now(),
+		Data: queue.PostEventData{
+			PostID:    post.ID.String(),
+			UserID:    userID,
+			Content:   post.Content,
+			CreatedAt: post.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		},
+	}
+	if err := s.producer.Publish(ctx, userID, event); err != nil {
+		s.logger.WithError(err).Error("Failed to publish post restored event")
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"post_id": postID,
+		"user_id": userID,
+	}).Info("Post restored successfully")
+
+	return post, nil
+}
+
 func (s *FeedService) SearchPosts(ctx context.Context, query string, offset, limit int) ([]*models.Post, error) {
 	posts, err := s.postRepo.Search(ctx, query, offset, limit)
 	if err != nil {
@@ -281,17 +475,59 @@ func (s *FeedService) SearchPosts(ctx context.Context, query string, offset, lim
 }
 
 func (s *FeedService) distributePost(ctx context.Context, post *models.Post, author *models.User) error {
+	// 过旧的帖子不再推送
+	if !s.IsPushEligible(post) {
+		return nil
+	}
+
+	if post.IsCloseFriendsOnly() {
+		return s.pushToCloseFriends(ctx, post, author)
+	}
+
 	// 根据粉丝数量决定使用推模式还是拉模式
-	if author.Followers <= int64(s.config.PushThreshold) {
+	if author.Followers <= int64(s.config.Feed().PushThreshold) {
 		return s.pushPost(ctx, post, author)
 	} else {
 		return s.pullPost(ctx, post, author)
 	}
 }
 
+// AcquireBackfillSlot 判断新关注是否需要回填：冷却窗口内同一对关注关系只返回一次true
+// Redis出错时放行，宁可重复回填也不漏掉
+func (s *FeedService) AcquireBackfillSlot(ctx context.Context, followerID, followingID string) bool {
+	cooldown := s.config.Feed().BackfillCooldown
+	if cooldown <= 0 {
+		return true
+	}
+
+	acquired, err := s.cache.SetNX(ctx, backfillSlotKey(followerID, followingID), 1, cooldown)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check backfill cooldown")
+		return true
+	}
+	return acquired
+}
+
+// ReleaseBackfillSlot 释放回填冷却，使下一次关注事件重新回填。
+// 取消关注会删除已回填的帖子，回填失败时也没有写入，这两种情况都需要释放
+func (s *FeedService) ReleaseBackfillSlot(ctx context.Context, followerID, followingID string) {
+	if err := s.cache.Delete(ctx, backfillSlotKey(followerID, followingID)); err != nil {
+		s.logger.WithError(err).Error("Failed to release backfill cooldown")
+	}
+}
+
+func backfillSlotKey(followerID, followingID string) string {
+	return fmt.Sprintf("follow_backfill:%s:%s", followerID, followingID)
+}
+
+// IsPushEligible 判断帖子是否在可推送的时间窗口内
+func (s *FeedService) IsPushEligible(post *models.Post) bool {
+	return s.config.Feed().IsPushEligible(post.CreatedAt)
+}
+
 func (s *FeedService) pushPost(ctx context.Context, post *models.Post, author *models.User) error {
 	// 推模式：将帖子推送给所有关注者
-	followers, err := s.followRepo.GetFollowers(ctx, author.ID, 0, int(s.config.MaxFeedSize))
+	followers, err := s.followRepo.GetFollowers(ctx, author.ID, 0, int(s.config.Feed().MaxFeedSize))
 	if err != nil {
 		return fmt.Errorf("failed to get followers: %w", err)
 	}
@@ -382,20 +618,92 @@ func (s *FeedService) calculateInitialScore(user *models.User) float64 {
 }
 
 func (s *FeedService) calculatePostScore(post *models.Post, user *models.User) float64 {
+	base, engagement, affinity, decay := s.scoreComponents(post, user)
+	return (base + engagement + affinity) * decay
+}
+
+// ScoreBreakdown 帖子排序分数的组成。各项为已乘以时间衰减的加性贡献，
+// BaseScore + EngagementScore + AffinityBoost = FinalScore，即排序实际使用的分数（上次重算时存储的post.Score）
+type ScoreBreakdown struct {
+	PostID          uuid.UUID `json:"post_id"`
+	BaseScore       float64   `json:"base_score"`
+	EngagementScore float64   `json:"engagement_score"`
+	AffinityBoost   float64   `json:"affinity_boost"`
+	Decay           float64   `json:"decay"`            // 当前的时间衰减系数，已计入各项贡献
+	FinalScore      float64   `json:"final_score"`      // 排序使用的分数
+	RecomputedScore float64   `json:"recomputed_score"` // 按当前互动数据重算的分数，下次重算后成为FinalScore
+}
+
+// scoreComponents 计算排序分数的各组成部分（未衰减）和时间衰减系数
+func (s *FeedService) scoreComponents(post *models.Post, user *models.User) (float64, float64, float64, float64) {
 	// 计算帖子的综合得分，用于排序
-	score := s.calculateInitialScore(user)
+	base := s.calculateInitialScore(user)
 
 	// 时间衰减
 	hoursSinceCreated := time.Since(post.CreatedAt).Hours()
 	timeDecay := math.Exp(-hoursSinceCreated / 24.0) // 24小时衰减
 
 	// 互动分数
-	engagementScore := float64(post.LikeCount)*0.1 + float64(post.CommentCount)*0.2 + float64(post.ShareCount)*0.3
+	engagement := float64(post.LikeCount)*0.1 + float64(post.CommentCount)*0.2 + float64(post.ShareCount)*0.3 + float64(post.ViewCount)*0.01
+
+	// 亲密度加成（暂未实现）
+	affinity := 0.0
+
+	return base, engagement, affinity, timeDecay
+}
 
-	// 综合分数
-	finalScore := (score + engagementScore) * timeDecay
+// explainPostScore 将存储的排序分数按当前各组成部分的比例拆分为加性贡献。
+// 存储的分数在上次重算后不再变化，而互动数和衰减在持续变化，按比例拆分保证各项之和等于实际排序使用的分数
+func (s *FeedService) explainPostScore(post *models.Post, user *models.User) ScoreBreakdown {
+	base, engagement, affinity, decay := s.scoreComponents(post, user)
+
+	breakdown := ScoreBreakdown{
+		PostID:          post.ID,
+		Decay:           decay,
+		FinalScore:      post.Score,
+		RecomputedScore: (base + engagement + affinity) * decay,
+	}
+	if total := base + engagement + affinity; total > 0 {
+		breakdown.BaseScore = post.Score * base / total
+		breakdown.EngagementScore = post.Score * engagement / total
+		breakdown.AffinityBoost = post.Score * affinity / total
+	} else {
+		breakdown.BaseScore = post.Score
+	}
+	return breakdown
+}
 
-	return finalScore
+// ExplainScores 返回Feed中每个帖子的分数组成，用于排查排序问题
+func (s *FeedService) ExplainScores(posts []*models.Post) []ScoreBreakdown {
+	breakdowns := make([]ScoreBreakdown, 0, len(posts))
+	for _, post := range posts {
+		breakdowns = append(breakdowns, s.explainPostScore(post, &post.User))
+	}
+	return breakdowns
+}
+
+// feedCacheVersionKey Redis中的全局Feed缓存版本号
+const feedCacheVersionKey = "feed_cache:version"
+
+// feedCacheVersion 获取Feed缓存版本：配置版本 + Redis全局版本号
+// 任一变化都会让所有旧缓存key不再被命中，旧数据随TTL自然过期
+func (s *FeedService) feedCacheVersion(ctx context.Context) string {
+	version := s.config.Feed().CacheVersion
+	if v, err := s.cache.Get(ctx, feedCacheVersionKey); err == nil {
+		return version + "." + v
+	}
+	return version + ".0"
+}
+
+// BumpFeedCacheVersion 递增全局Feed缓存版本，立即使所有已缓存Feed失效
+func (s *FeedService) BumpFeedCacheVersion(ctx context.Context) (string, error) {
+	if _, err := s.cache.Incr(ctx, feedCacheVersionKey); err != nil {
+		return "", fmt.Errorf("failed to bump feed cache version: %w", err)
+	}
+
+	version := s.feedCacheVersion(ctx)
+	s.logger.WithField("version", version).Info("Feed cache version bumped")
+	return version, nil
 }
 
 func (s *FeedService) getCachedFeed(ctx context.Context, key string) (*FeedResponse, error) {
@@ -406,31 +714,79 @@ func (s *FeedService) getCachedFeed(ctx context.Context, key string) (*FeedRespo
 	return &response, nil
 }
 
-func (s *FeedService) cacheFeed(ctx context.Context, key string, response *FeedResponse) error {
-	return s.cache.SetJSON(ctx, key, response, s.config.CacheTTL)
+// cacheFeed 缓存Feed响应，TTL按用户的缓存档位确定
+func (s *FeedService) cacheFeed(ctx context.Context, userID uuid.UUID, key string, response *FeedResponse) error {
+	ttl := s.config.Feed().CacheTTL
+	if s.cacheStrategy != nil {
+		ttl = s.cacheStrategy.FeedCacheTTL(ctx, userID)
+	}
+	return s.cache.SetJSON(ctx, key, response, ttl)
+}
+
+// userFeedCacheVersionKey 用户级Feed缓存版本号
+func userFeedCacheVersionKey(userID string) string {
+	return "feed_cache:version:" + userID
+}
+
+// feedCacheKey Feed缓存key，包含全局版本和用户级版本，任一递增都会让该用户的旧缓存失效
+func (s *FeedService) feedCacheKey(ctx context.Context, userID, cursor string, limit int) string {
+	userVersion := "0"
+	if v, err := s.cache.Get(ctx, userFeedCacheVersionKey(userID)); err == nil {
+		userVersion = v
+	}
+	return fmt.Sprintf("feed:%s:v%s.%s:%s:%d", userID, s.feedCacheVersion(ctx), userVersion, cursor, limit)
 }
 
+// clearFeedCache 递增用户级Feed缓存版本，使该用户所有分页的Feed缓存失效，旧数据随TTL自然过期
 func (s *FeedService) clearFeedCache(ctx context.Context, userID string) error {
-	// 清除用户的所有feed缓存
-	// pattern := fmt.Sprintf("feed:%s:*", userID)
-	// 这里需要实现keys命令或扫描删除
+	if _, err := s.cache.Incr(ctx, userFeedCacheVersionKey(userID)); err != nil {
+		return fmt.Errorf("failed to clear feed cache: %w", err)
+	}
 	return nil
 }
 
 func (s *FeedService) updateDynamicData(ctx context.Context, posts []*models.Post, viewerID uuid.UUID) {
 	// 更新阅读量等动态数据（这里简化处理）
+	// Feed结果会被缓存，这里不填充点赞状态，只批量预热状态缓存，后续单帖查询可直接命中
+	if _, err := s.likeState.Lookup(ctx, viewerID, postIDsOf(posts)); err != nil {
+		s.logger.WithError(err).Error("Failed to check like status")
+	}
+}
+
+// hydrateViewerState 填充查看者对帖子的点赞状态和回应类型，查询失败时保持未填充
+// 目前没有收藏功能，只填充点赞状态
+func (s *FeedService) hydrateViewerState(ctx context.Context, viewerID uuid.UUID, posts []*models.Post) {
+	reactions, err := s.likeState.LookupReactions(ctx, viewerID, postIDsOf(posts))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check like status")
+		return
+	}
+	applyViewerReactions(posts, reactions)
+}
+
+// applyViewerReactions 按查看者的回应填充IsLiked和ViewerReaction
+func applyViewerReactions(posts []*models.Post, reactions map[uuid.UUID]string) {
 	for _, post := range posts {
-		// 检查是否已点赞
-		isLiked, err := s.likeRepo.IsLiked(ctx, viewerID, post.ID)
-		if err != nil {
-			s.logger.WithError(err).Error("Failed to check like status")
-			continue
+		reaction, ok := reactions[post.ID]
+		post.IsLiked = &ok
+		if ok {
+			post.ViewerReaction = &reaction
 		}
-		// 这里可以添加更多动态数据
-		_ = isLiked
 	}
 }
 
+// RecordImpressions 记录Feed中帖子的曝光，失败只记录日志不影响Feed返回
+func (s *FeedService) RecordImpressions(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) {
+	if err := s.impressions.Record(ctx, viewerID, postIDs); err != nil {
+		s.logger.WithError(err).Error("Failed to record impressions")
+	}
+}
+
+// StartImpressionFlushJob 定期将曝光计数刷入数据库
+func (s *FeedService) StartImpressionFlushJob(ctx context.Context) {
+	s.impressions.StartFlushJob(ctx, ImpressionFlushInterval)
+}
+
 func (s *FeedService) encodeCursor(offset int) string {
 	data := map[string]int{"offset": offset}
 	jsonData, _ := json.Marshal(data)
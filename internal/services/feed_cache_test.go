@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestClearFeedCacheInvalidatesOnlyThatUser(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	service := &FeedService{cache: redisClient, config: newTestConfig(nil), logger: logger.NewLogger()}
+	ctx := context.Background()
+	author, other := uuid.NewString(), uuid.NewString()
+
+	authorKey := service.feedCacheKey(ctx, author, "", 20)
+	otherKey := service.feedCacheKey(ctx, other, "", 20)
+	if err := redisClient.SetJSON(ctx, authorKey, &FeedResponse{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.clearFeedCache(ctx, author); err != nil {
+		t.Fatalf("clearFeedCache: %v", err)
+	}
+
+	newKey := service.feedCacheKey(ctx, author, "", 20)
+	if newKey == authorKey {
+		t.Errorf("feed cache key unchanged after clear: %s", newKey)
+	}
+	if _, err := service.getCachedFeed(ctx, newKey); err == nil {
+		t.Errorf("cached feed still served after clear")
+	}
+	if got := service.feedCacheKey(ctx, other, "", 20); got != otherKey {
+		t.Errorf("feed cache key of other user changed: %s -> %s", otherKey, got)
+	}
+}
+
+func TestFeedCacheVersionBumpForcesFreshAssembly(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, _ := newTestRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) { feed.CacheVersion = "1" })
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), repository.NewLikeRepository(db), nil, redisClient, nil, cfg, logger.NewLogger(), nil, nil,
+	)
+	ctx := context.Background()
+	userID := uuid.NewString()
+
+	expectAssembly := func() {
+		mock.ExpectQuery(`SELECT \* FROM "timelines" WHERE user_id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+	getFeed := func(step string) {
+		t.Helper()
+		if _, err := service.GetFeed(ctx, userID, "", 20); err != nil {
+			t.Fatalf("%s: GetFeed: %v", step, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+
+	// 首次读取从数据库组装并缓存，再次读取命中缓存
+	expectAssembly()
+	getFeed("first read")
+	getFeed("cached read")
+
+	// 递增全局版本后所有旧缓存不再命中，重新组装
+	version, err := service.BumpFeedCacheVersion(ctx)
+	if err != nil {
+		t.Fatalf("BumpFeedCacheVersion: %v", err)
+	}
+	if version != "1.1" {
+		t.Errorf("version = %q, want 1.1", version)
+	}
+	expectAssembly()
+	getFeed("after bump")
+	getFeed("cached after bump")
+
+	// 重新加载的配置修改了版本，同样使缓存失效
+	reloaded := *cfg.Feed()
+	reloaded.CacheVersion = "2"
+	cfg.Store(&reloaded)
+	expectAssembly()
+	getFeed("after config change")
+}
+
+// 缓存Feed响应时的TTL与用户当前档位的配置一致，档位未配置时使用feed.cache_ttl
+func TestFeedCacheTTLFollowsUserTier(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) {
+		feed.Optimization.ActiveUser.FeedCacheTTL = 10 * time.Minute
+		feed.Optimization.InactiveUser.FeedCacheTTL = 2 * time.Minute
+	})
+	log := logger.NewLogger()
+	strategy := NewCacheStrategyService(redisClient, cfg, log, NewActivityService(nil, nil, redisClient, cfg, log), NewTimelineCacheService(redisClient, cfg, log))
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), repository.NewLikeRepository(db), nil, redisClient, nil, cfg, log, nil, strategy,
+	)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		tier string
+		want time.Duration
+	}{
+		{CacheTierActive, 10 * time.Minute},
+		{CacheTierInactive, 2 * time.Minute},
+		{CacheTierVIP, time.Hour},
+	} {
+		t.Run(tt.tier, func(t *testing.T) {
+			userID := uuid.New()
+			if err := strategy.SetCacheStrategyOverride(ctx, userID, tt.tier); err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectQuery(`SELECT \* FROM "timelines" WHERE user_id = \$1`).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			if _, err := service.GetFeed(ctx, userID.String(), "", 20); err != nil {
+				t.Fatalf("GetFeed: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+			if ttl := mr.TTL(service.feedCacheKey(ctx, userID.String(), "", 20)); ttl != tt.want {
+				t.Errorf("feed cache TTL = %s, want %s", ttl, tt.want)
+			}
+		})
+	}
+}
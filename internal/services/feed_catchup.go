@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// FeedCatchUpSessionTTL 回归重建的去重窗口，用户持续有操作时窗口顺延，即每个会话最多重建一次
+const FeedCatchUpSessionTTL = OnlineUserCacheTTL
+
+// feedCatchUpKey 回归重建的会话标记key
+func feedCatchUpKey(userID uuid.UUID) string {
+	return fmt.Sprintf("feed_catchup:%s", userID.String())
+}
+
+// RecordActivity 更新用户活跃度；此前处于非活跃状态的用户在本会话首次操作时异步触发回归重建
+func (s *OptimizedFeedService) RecordActivity(ctx context.Context, userID uuid.UUID, activityType string) error {
+	wasActive, err := s.activityService.IsUserActive(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to check user activity before update")
+		wasActive = true
+	}
+
+	if err := s.activityService.UpdateUserActivity(ctx, userID, activityType); err != nil {
+		return err
+	}
+	if wasActive {
+		return nil
+	}
+
+	// 非活跃用户的活跃度可能要多次操作后才越过阈值，用会话标记保证只重建一次
+	key := feedCatchUpKey(userID)
+	first, err := s.cache.SetNX(ctx, key, "1", FeedCatchUpSessionTTL)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to mark feed catch-up session")
+		return nil
+	}
+	if !first {
+		if err := s.cache.Expire(ctx, key, FeedCatchUpSessionTTL); err != nil {
+			s.logger.WithError(err).Error("Failed to extend feed catch-up session")
+		}
+		return nil
+	}
+
+	s.runAsync(func() {
+		count, err := s.RebuildFeedOnReturn(context.Background(), userID)
+		if err != nil {
+			s.logger.WithError(err).WithField("user_id", userID).Error("Failed to rebuild feed for returning user")
+			return
+		}
+		s.logger.WithFields(map[string]interface{}{
+			"user_id": userID,
+			"posts":   count,
+		}).Info("Rebuilt feed for returning user")
+	})
+	return nil
+}
+
+// RebuildFeedOnReturn 为长期不活跃后回归的用户重建Timeline缓存：
+// 从所有关注的人（及自己）拉取最新帖子，按活跃档位上限填充，返回写入的帖子数
+func (s *OptimizedFeedService) RebuildFeedOnReturn(ctx context.Context, userID uuid.UUID) (int, error) {
+	authorIDs, _, err := s.getPullModeAuthors(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	authorIDs = append(authorIDs, userID)
+
+	posts, _, _, err := s.collectPullModePage(ctx, authorIDs, s.closeFriendAuthors(ctx, userID), "", MaxTimelineItemsActive)
+	if err != nil {
+		return 0, err
+	}
+
+	timelines := make([]*models.Timeline, 0, len(posts))
+	for _, post := range posts {
+		timelines = append(timelines, &models.Timeline{
+			UserID:    userID,
+			PostID:    post.ID,
+			Score:     post.Score,
+			CreatedAt: post.CreatedAt,
+		})
+	}
+	if err := s.timelineCacheService.RebuildTimelineFromDB(ctx, userID, timelines); err != nil {
+		return 0, err
+	}
+	// 用户已回归，按活跃用户的TTL保留
+	if err := s.timelineCacheService.SetTimelineExpiration(ctx, userID, true); err != nil {
+		s.logger.WithError(err).Error("Failed to set timeline expiration")
+	}
+	return len(posts), nil
+}
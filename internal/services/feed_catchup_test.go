@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// expectActivityUpdate UpdateUserActivity读取并更新用户活跃度
+func expectActivityUpdate(mock sqlmock.Sqlmock, userID uuid.UUID) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "activity_score"}).AddRow(userID, 5))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "activity_score"=\$1,"is_online"=\$2,"last_active_at"=\$3 WHERE id = \$4`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectCatchUpRebuild 回归重建拉取关注的人和自己的帖子：authorID有一条公开帖子和一条仅密友可见的帖子，
+// 用户自己有一条仅密友可见的帖子
+func expectCatchUpRebuild(mock sqlmock.Sqlmock, userID, authorID uuid.UUID, public, hidden, own uuid.UUID) {
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows" WHERE follower_id = \$1 .*ORDER BY created_at DESC LIMIT 1001`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(authorID))
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows" WHERE \(follower_id = \$1 AND close_friend = \$2\)`).
+		WithArgs(userID, true).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}))
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id IN \(\$1,\$2\) AND is_deleted = \$3 .*ORDER BY created_at DESC`).
+		WithArgs(authorID, userID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "visibility", "score", "created_at"}).
+			AddRow(public, authorID, models.PostVisibilityPublic, 3.5, now.Add(-time.Minute)).
+			AddRow(hidden, authorID, models.PostVisibilityCloseFriends, 2, now.Add(-2*time.Minute)).
+			AddRow(own, userID, models.PostVisibilityCloseFriends, 1, now.Add(-3*time.Minute)))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID).AddRow(userID))
+}
+
+func TestRebuildFeedOnReturn(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, nil)
+	ctx := context.Background()
+	userID, authorID := uuid.New(), uuid.New()
+	public, hidden, own := uuid.New(), uuid.New(), uuid.New()
+
+	// 旧的Timeline缓存被替换
+	stale := uuid.NewString()
+	mr.ZAdd(fmt.Sprintf("timeline:%s", userID), 1, stale)
+
+	expectCatchUpRebuild(mock, userID, authorID, public, hidden, own)
+	count, err := service.RebuildFeedOnReturn(ctx, userID)
+	if err != nil {
+		t.Fatalf("RebuildFeedOnReturn() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("RebuildFeedOnReturn() = %d, want 2 visible posts", count)
+	}
+
+	key := fmt.Sprintf("timeline:%s", userID)
+	members, err := mr.ZMembers(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{public.String(): true, own.String(): true}
+	if len(members) != len(want) {
+		t.Fatalf("timeline = %v, want %v", members, want)
+	}
+	for _, member := range members {
+		if !want[member] {
+			t.Errorf("unexpected timeline member %s", member)
+		}
+	}
+	if score, err := mr.ZScore(RankedTimelineKey(userID), public.String()); err != nil || score != 3.5 {
+		t.Errorf("ranked score = %v, %v; want 3.5", score, err)
+	}
+	for _, k := range []string{key, RankedTimelineKey(userID)} {
+		if ttl := mr.TTL(k); ttl != ActiveUserCacheTTL {
+			t.Errorf("%s TTL = %s, want %s", k, ttl, ActiveUserCacheTTL)
+		}
+	}
+}
+
+func TestRecordActivityCatchUpOncePerSession(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, nil)
+	ctx := context.Background()
+	userID, authorID := uuid.New(), uuid.New()
+	sessionKey := feedCatchUpKey(userID)
+
+	// 跳过活跃关注者标记，只关注回归重建
+	mr.Set(fmt.Sprintf("active_follower_mark:%s", userID), "1")
+	recordInactive := func() {
+		t.Helper()
+		mr.Set(fmt.Sprintf("user_active:%s", userID), "0")
+		if err := service.RecordActivity(ctx, userID, "view"); err != nil {
+			t.Fatalf("RecordActivity() error = %v", err)
+		}
+		service.inflight.Wait()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timelineSize := func() int {
+		members, _ := mr.ZMembers(fmt.Sprintf("timeline:%s", userID))
+		return len(members)
+	}
+
+	// 非活跃用户首次操作：开启会话并重建
+	expectActivityUpdate(mock, userID)
+	expectCatchUpRebuild(mock, userID, authorID, uuid.New(), uuid.New(), uuid.New())
+	recordInactive()
+	if timelineSize() != 2 {
+		t.Fatalf("timeline has %d posts after catch-up, want 2", timelineSize())
+	}
+	if ttl := mr.TTL(sessionKey); ttl != FeedCatchUpSessionTTL {
+		t.Errorf("session TTL = %s, want %s", ttl, FeedCatchUpSessionTTL)
+	}
+
+	// 同一会话内活跃度仍未越过阈值：不再重建，会话窗口顺延
+	mr.FastForward(FeedCatchUpSessionTTL / 2)
+	mr.Del(fmt.Sprintf("timeline:%s", userID))
+	expectActivityUpdate(mock, userID)
+	recordInactive()
+	if timelineSize() != 0 {
+		t.Error("timeline rebuilt twice in one session")
+	}
+	if ttl := mr.TTL(sessionKey); ttl != FeedCatchUpSessionTTL {
+		t.Errorf("session TTL = %s, want extended to %s", ttl, FeedCatchUpSessionTTL)
+	}
+
+	// 会话过期后再次回归重新重建
+	mr.FastForward(FeedCatchUpSessionTTL)
+	expectActivityUpdate(mock, userID)
+	expectCatchUpRebuild(mock, userID, authorID, uuid.New(), uuid.New(), uuid.New())
+	recordInactive()
+	if timelineSize() != 2 {
+		t.Errorf("timeline has %d posts after the second session, want 2", timelineSize())
+	}
+
+	// 活跃用户的操作不触发重建
+	activeID := uuid.New()
+	mr.Set(fmt.Sprintf("active_follower_mark:%s", activeID), "1")
+	mr.Set(fmt.Sprintf("user_active:%s", activeID), "1")
+	expectActivityUpdate(mock, activeID)
+	if err := service.RecordActivity(ctx, activeID, "view"); err != nil {
+		t.Fatalf("RecordActivity() error = %v", err)
+	}
+	service.inflight.Wait()
+	if mr.Exists(feedCatchUpKey(activeID)) {
+		t.Error("active user started a catch-up session")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
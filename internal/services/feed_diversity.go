@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+
+	"github.com/feed-system/feed-system/internal/models"
+)
+
+type authorDiversityKey struct{}
+
+// WithAuthorDiversity 为本次Feed请求指定同一作者最多连续出现的帖子数，覆盖feed.author_diversity配置，0表示关闭
+func WithAuthorDiversity(ctx context.Context, maxConsecutive int) context.Context {
+	return context.WithValue(ctx, authorDiversityKey{}, maxConsecutive)
+}
+
+// authorDiversity 本次请求生效的同作者连续上限，请求未指定时使用配置
+func (s *OptimizedFeedService) authorDiversity(ctx context.Context) int {
+	if maxConsecutive, ok := ctx.Value(authorDiversityKey{}).(int); ok {
+		return maxConsecutive
+	}
+	return s.config.Feed().AuthorDiversity
+}
+
+// diversifyByAuthor 在页内重排帖子，使同一作者最多连续出现maxConsecutive条。
+// 每个位置选取原顺序中第一个不违反限制的帖子，其余帖子保持相对顺序；
+// 剩下的帖子都来自同一作者时无法再穿插，按原顺序追加在末尾
+func diversifyByAuthor(posts []*models.Post, maxConsecutive int) []*models.Post {
+	if maxConsecutive <= 0 || len(posts) <= maxConsecutive {
+		return posts
+	}
+
+	remaining := append([]*models.Post(nil), posts...)
+	result := make([]*models.Post, 0, len(posts))
+	run := 0
+	for len(remaining) > 0 {
+		pick := 0
+		if run >= maxConsecutive {
+			last := result[len(result)-1].UserID
+			pick = -1
+			for i, post := range remaining {
+				if post.UserID != last {
+					pick = i
+					break
+				}
+			}
+			if pick < 0 {
+				return append(result, remaining...)
+			}
+		}
+
+		post := remaining[pick]
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+		if len(result) > 0 && result[len(result)-1].UserID == post.UserID {
+			run++
+		} else {
+			run = 1
+		}
+		result = append(result, post)
+	}
+	return result
+}
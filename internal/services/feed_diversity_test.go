@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// floodedPage 一页13条：作者A的10条帖子排在最前，其他3个作者各1条
+func floodedPage() ([]*models.Post, uuid.UUID) {
+	flooder := uuid.New()
+	posts := make([]*models.Post, 0, 13)
+	for i := 0; i < 10; i++ {
+		posts = append(posts, &models.Post{ID: uuid.New(), UserID: flooder})
+	}
+	for i := 0; i < 3; i++ {
+		posts = append(posts, &models.Post{ID: uuid.New(), UserID: uuid.New()})
+	}
+	return posts, flooder
+}
+
+// maxRun 页内同一作者最长的连续帖子数
+func maxRun(posts []*models.Post) int {
+	longest, run := 0, 0
+	for i, post := range posts {
+		if i > 0 && posts[i-1].UserID == post.UserID {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func TestDiversifyByAuthorInterleavesFlooder(t *testing.T) {
+	posts, flooder := floodedPage()
+	got := diversifyByAuthor(posts, 2)
+
+	if len(got) != len(posts) {
+		t.Fatalf("got %d posts, want %d", len(got), len(posts))
+	}
+	// 其他作者用完之前，作者A最多连续2条：A A x A A x A A x，剩下的A只能追加在末尾
+	for i, post := range got[:9] {
+		wantFlooder := i%3 != 2
+		if (post.UserID == flooder) != wantFlooder {
+			t.Fatalf("position %d: flooder = %v, want %v", i, post.UserID == flooder, wantFlooder)
+		}
+	}
+	if run := maxRun(got[:9]); run > 2 {
+		t.Errorf("longest run before other authors ran out = %d, want at most 2", run)
+	}
+
+	// 重排不丢帖子，各作者帖子的相对顺序不变
+	var flooderOrder []uuid.UUID
+	for _, post := range got {
+		if post.UserID == flooder {
+			flooderOrder = append(flooderOrder, post.ID)
+		}
+	}
+	for i, id := range flooderOrder {
+		if posts[i].ID != id {
+			t.Fatalf("flooder posts reordered: position %d = %s, want %s", i, id, posts[i].ID)
+		}
+	}
+	others := 0
+	for _, post := range got {
+		if post.UserID != flooder {
+			if post.ID != posts[10+others].ID {
+				t.Fatalf("other authors reordered")
+			}
+			others++
+		}
+	}
+}
+
+func TestDiversifyByAuthorNoop(t *testing.T) {
+	posts, _ := floodedPage()
+	for name, maxConsecutive := range map[string]int{"disabled": 0, "limit above page size": len(posts)} {
+		got := diversifyByAuthor(posts, maxConsecutive)
+		for i := range posts {
+			if got[i] != posts[i] {
+				t.Errorf("%s: page reordered at %d", name, i)
+				break
+			}
+		}
+	}
+
+	single := posts[:10]
+	got := diversifyByAuthor(single, 2)
+	for i := range single {
+		if got[i] != single[i] {
+			t.Errorf("single-author page reordered at %d", i)
+			break
+		}
+	}
+}
+
+func TestGetFeedAppliesAuthorDiversity(t *testing.T) {
+	ctx := context.Background()
+	posts, flooder := floodedPage()
+	base := time.Now().Add(-time.Hour)
+
+	newService := func(t *testing.T) (*OptimizedFeedService, sqlmock.Sqlmock, uuid.UUID) {
+		service, mock, mr := newOptimizedTestService(t, func(feed *config.FeedConfig) { feed.AuthorDiversity = 2 })
+		viewerID := uuid.New()
+		mr.Set("user_active:"+viewerID.String(), "1")
+		for i, post := range posts {
+			if err := service.timelineCacheService.AddToTimeline(ctx, viewerID, post.ID, float64(len(posts)-i), base.Add(-time.Duration(i)*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "created_at"})
+		users := sqlmock.NewRows([]string{"id"})
+		for i, post := range posts {
+			rows.AddRow(post.ID, post.UserID, base.Add(-time.Duration(i)*time.Minute))
+			if i == 0 || i >= 10 {
+				users.AddRow(post.UserID)
+			}
+		}
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).WillReturnRows(users)
+		mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}))
+		return service, mock, viewerID
+	}
+
+	t.Run("config caps consecutive posts", func(t *testing.T) {
+		service, mock, viewerID := newService(t)
+		response, err := service.GetFeed(ctx, viewerID.String(), "", len(posts))
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Posts) != len(posts) {
+			t.Fatalf("got %d posts, want %d", len(response.Posts), len(posts))
+		}
+		if run := maxRun(response.Posts[:9]); run != 2 || response.Posts[2].UserID == flooder {
+			t.Errorf("longest run = %d, third post by flooder = %v; want interleaved", run, response.Posts[2].UserID == flooder)
+		}
+	})
+
+	t.Run("request param disables it", func(t *testing.T) {
+		service, mock, viewerID := newService(t)
+		response, err := service.GetFeed(WithAuthorDiversity(ctx, 0), viewerID.String(), "", len(posts))
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		for i, post := range response.Posts {
+			if post.ID != posts[i].ID {
+				t.Fatalf("position %d = %s, want timeline order", i, post.ID)
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/feed-system/feed-system/internal/models"
+)
+
+// postNewer 判断a是否应排在b之前（时间倒序，时间相同时按ID保证稳定）
+func postNewer(a, b *models.Post) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID.String() > b.ID.String()
+}
+
+// postListCursor 多路归并中每一路的读取位置
+type postListCursor struct {
+	posts []*models.Post
+	index int
+}
+
+// postMergeHeap 按当前头部帖子时间排序的最大堆
+type postMergeHeap []*postListCursor
+
+func (h postMergeHeap) Len() int { return len(h) }
+func (h postMergeHeap) Less(i, j int) bool {
+	return postNewer(h[i].posts[h[i].index], h[j].posts[h[j].index])
+}
+func (h postMergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *postMergeHeap) Push(x interface{}) { *h = append(*h, x.(*postListCursor)) }
+func (h *postMergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// mergePostsByTime 将多个作者的帖子列表按时间倒序归并，最多返回limit条
+func mergePostsByTime(lists [][]*models.Post, limit int) []*models.Post {
+	h := make(postMergeHeap, 0, len(lists))
+	for _, list := range lists {
+		if len(list) == 0 {
+			continue
+		}
+		// 每一路内部也需要有序
+		sort.SliceStable(list, func(i, j int) bool { return postNewer(list[i], list[j]) })
+		h = append(h, &postListCursor{posts: list})
+	}
+	heap.Init(&h)
+
+	merged := make([]*models.Post, 0, limit)
+	for h.Len() > 0 && len(merged) < limit {
+		top := h[0]
+		merged = append(merged, top.posts[top.index])
+		top.index++
+		if top.index < len(top.posts) {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+
+	return merged
+}
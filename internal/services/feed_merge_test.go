@@ -0,0 +1,59 @@
+package services
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// 多路归并的结果应与把所有帖子放在一起全局排序后截断完全一致
+func TestMergePostsByTimeMatchesGlobalSort(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var lists [][]*models.Post
+	var all []*models.Post
+	for author := 0; author < 5; author++ {
+		var list []*models.Post
+		n := rng.Intn(6)
+		for i := 0; i < n; i++ {
+			// 秒级时间，制造同一时间的帖子，验证按ID的稳定顺序
+			post := &models.Post{
+				ID:        uuid.New(),
+				CreatedAt: base.Add(time.Duration(rng.Intn(20)) * time.Second),
+			}
+			list = append(list, post)
+			all = append(all, post)
+		}
+		lists = append(lists, list)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return postNewer(all[i], all[j]) })
+
+	for _, limit := range []int{1, 5, len(all), len(all) + 3} {
+		merged := mergePostsByTime(lists, limit)
+
+		want := all
+		if limit < len(want) {
+			want = want[:limit]
+		}
+		if len(merged) != len(want) {
+			t.Fatalf("limit %d: got %d posts, want %d", limit, len(merged), len(want))
+		}
+		for i := range want {
+			if merged[i].ID != want[i].ID {
+				t.Fatalf("limit %d: position %d got %s, want %s", limit, i, merged[i].ID, want[i].ID)
+			}
+		}
+	}
+}
+
+func TestMergePostsByTimeEmpty(t *testing.T) {
+	if merged := mergePostsByTime([][]*models.Post{nil, {}}, 10); len(merged) != 0 {
+		t.Errorf("mergePostsByTime() = %v, want empty", merged)
+	}
+}
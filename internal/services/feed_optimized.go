@@ -6,9 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
 	"github.com/feed-system/feed-system/pkg/cache"
@@ -26,13 +29,26 @@ type OptimizedFeedService struct {
 	likeRepo     *repository.LikeRepository
 	commentRepo  *repository.CommentRepository
 	cache        *cache.RedisClient
-	producer     *queue.KafkaProducer
-	config       *config.FeedConfig
+	producer     queue.Publisher
+	config       *config.ConfigWatcher
 	logger       *logger.Logger
 
 	// 新增的服务
 	activityService      *ActivityService
 	timelineCacheService *TimelineCacheService
+
+	// 进行中的分发/重建任务，关闭时等待其完成
+	inflight     sync.WaitGroup
+	inflightMu   sync.Mutex
+	shuttingDown bool
+
+	// 扇出并发限制（背压）
+	fanoutLimiter *FanoutLimiter
+
+	impressions *ImpressionTracker
+	likeState   *LikeStateCache
+
+	moderator Moderator
 }
 
 func NewOptimizedFeedService(
@@ -44,10 +60,11 @@ func NewOptimizedFeedService(
 	commentRepo *repository.CommentRepository,
 	cache *cache.RedisClient,
 	producer *queue.KafkaProducer,
-	config *config.FeedConfig,
+	config *config.ConfigWatcher,
 	logger *logger.Logger,
 	activityService *ActivityService,
 	timelineCacheService *TimelineCacheService,
+	moderator Moderator,
 ) *OptimizedFeedService {
 	return &OptimizedFeedService{
 		postRepo:             postRepo,
@@ -62,6 +79,10 @@ func NewOptimizedFeedService(
 		logger:               logger,
 		activityService:      activityService,
 		timelineCacheService: timelineCacheService,
+		fanoutLimiter:        NewFanoutLimiter(config.Feed().Optimization.Timeline.MaxInflightFanouts),
+		impressions:          NewImpressionTracker(cache, postRepo, logger),
+		likeState:            NewLikeStateCache(cache, likeRepo, logger),
+		moderator:            moderator,
 	}
 }
 
@@ -72,8 +93,24 @@ func (s *OptimizedFeedService) CreatePost(ctx context.Context, userID string, re
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	// 内容校验和审核，在任何写入之前执行
+	content, err := normalizeContent(req.Content, MaxPostContentLength)
+	if err != nil {
+		return nil, err
+	}
+	if err := moderate(ctx, s.moderator, s.logger, userID, "post", content); err != nil {
+		return nil, err
+	}
+	if content, err = sanitizeContent(content, s.config.Feed().ContentSanitize); err != nil {
+		return nil, err
+	}
+	visibility, err := normalizeVisibility(req.Visibility)
+	if err != nil {
+		return nil, err
+	}
+
 	// 更新用户活跃度
-	if err := s.activityService.UpdateUserActivity(ctx, userUUID, "post"); err != nil {
+	if err := s.RecordActivity(ctx, userUUID, "post"); err != nil {
 		s.logger.WithError(err).Error("Failed to update user activity")
 	}
 
@@ -83,16 +120,17 @@ func (s *OptimizedFeedService) CreatePost(ctx context.Context, userID string, re
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found")
 	}
 
 	// 创建帖子
 	post := &models.Post{
-		UserID:    userUUID,
-		Content:   req.Content,
-		ImageURLs: req.ImageURLs,
-		Score:     s.calculateInitialScore(user),
-		CreatedAt: time.Now(),
+		UserID:     userUUID,
+		Content:    content,
+		ImageURLs:  req.ImageURLs,
+		Visibility: visibility,
+		Score:      s.calculateInitialScore(user),
+		CreatedAt:  time.Now(),
 	}
 
 	if err := s.postRepo.Create(ctx, post); err != nil {
@@ -101,13 +139,23 @@ func (s *OptimizedFeedService) CreatePost(ctx context.Context, userID string, re
 
 	// 计算帖子分数
 	post.Score = s.calculatePostScore(post, user)
-	if err := s.postRepo.Update(ctx, post); err != nil {
+	// 只更新score列，避免整行Save覆盖并发写入的计数器
+	if err := s.postRepo.UpdateScore(ctx, post.ID, post.Score); err != nil {
 		s.logger.WithError(err).Error("Failed to update post score")
 	}
 
-	// 使用优化的分发策略
-	if err := s.distributePostOptimized(ctx, post, user); err != nil {
-		s.logger.WithError(err).Error("Failed to distribute post")
+	// 使用优化的分发策略，扇出饱和时阻塞等待名额以减慢写入
+	tracked := s.beginInflight()
+	if err := s.fanoutLimiter.Acquire(ctx); err != nil {
+		s.logger.WithError(err).WithField("post_id", post.ID).Error("Failed to acquire fan-out slot, skipping distribution")
+	} else {
+		if err := s.distributePostOptimized(ctx, post, user); err != nil {
+			s.logger.WithError(err).Error("Failed to distribute post")
+		}
+		s.fanoutLimiter.Release()
+	}
+	if tracked {
+		s.inflight.Done()
 	}
 
 	// 发送帖子创建事件
@@ -134,34 +182,64 @@ func (s *OptimizedFeedService) CreatePost(ctx context.Context, userID string, re
 }
 
 // GetFeed 获取Feed (优化版 - 使用游标分页)
+// 首页读取成功时保存快照，Redis和数据库都读取失败时返回快照（标记stale）而不是错误
 func (s *OptimizedFeedService) GetFeed(ctx context.Context, userID string, cursor string, limit int) (*FeedResponse, error) {
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
-	// 更新用户活跃度
-	if err := s.activityService.UpdateUserActivity(ctx, userUUID, "view_feed"); err != nil {
-		s.logger.WithError(err).Error("Failed to update user activity")
+	response, err := s.getFeedLive(ctx, userUUID, cursor, limit)
+	if err == nil {
+		response.Posts = diversifyByAuthor(response.Posts, s.authorDiversity(ctx))
+	}
+	if cursor != "" || isFeedInspection(ctx) {
+		return response, err
 	}
-
-	// 首先尝试从Redis缓存获取Timeline
-	timelineItems, nextCursor, hasMore, err := s.timelineCacheService.GetTimeline(ctx, userUUID, cursor, limit)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get timeline from cache")
+		if errors.Is(err, apperrors.ErrInvalidInput) {
+			return nil, err
+		}
+		if snapshot, ok := s.loadFeedSnapshot(ctx, userUUID); ok {
+			s.logger.WithError(err).WithField("user_id", userUUID).Warn("Live feed unavailable, serving stale snapshot")
+			return snapshot, nil
+		}
+		return nil, err
+	}
+	if !response.Degraded {
+		s.saveFeedSnapshot(userUUID, response)
 	}
+	return response, nil
+}
 
-	var posts []*models.Post
+// getFeedLive 实时获取Feed：优先读取Redis Timeline，缺失或失败时走拉模式
+func (s *OptimizedFeedService) getFeedLive(ctx context.Context, userUUID uuid.UUID, cursor string, limit int) (*FeedResponse, error) {
+	// 更新用户活跃度（管理员查看时跳过）
+	if !isFeedInspection(ctx) {
+		if err := s.RecordActivity(ctx, userUUID, "view_feed"); err != nil {
+			s.logger.WithError(err).Error("Failed to update user activity")
+		}
+	}
 
-	if len(timelineItems) > 0 {
-		// 从缓存获取到数据，根据PostID获取完整的Post信息
-		posts, err = s.getPostsByIDs(ctx, timelineItems)
-		if err != nil {
-			s.logger.WithError(err).Error("Failed to get posts by IDs")
-			// 如果获取失败，回退到拉模式
-			return s.getFeedByPullMode(ctx, userUUID, cursor, limit)
+	// 首先尝试从Redis缓存获取Timeline，并直接取回完整的Post信息
+	posts, nextCursor, hasMore, err := s.timelineCacheService.GetTimelineHydrated(ctx, userUUID, cursor, limit, s.visiblePostFetcher(userUUID))
+	if errors.Is(err, ErrInvalidCursor) {
+		return nil, err
+	}
+	if err != nil {
+		// 读取失败，回退到拉模式；拉模式也失败时尽量用缓存数据拼出部分Feed
+		s.logger.WithError(err).Error("Failed to get hydrated timeline from cache")
+		response, pullErr := s.getFeedByPullMode(ctx, userUUID, cursor, limit)
+		if pullErr == nil {
+			return response, nil
 		}
-	} else {
+		if degraded, ok := s.getDegradedFeed(ctx, userUUID, cursor, limit); ok {
+			s.logger.WithError(pullErr).WithField("user_id", userUUID).Warn("Pull mode failed, serving degraded feed from cache")
+			return degraded, nil
+		}
+		return nil, pullErr
+	}
+	if nextCursor == "" {
 		// 缓存中没有数据，使用拉模式重建Timeline
 		return s.getFeedByPullMode(ctx, userUUID, cursor, limit)
 	}
@@ -178,48 +256,240 @@ func (s *OptimizedFeedService) GetFeed(ctx context.Context, userID string, curso
 	return response, nil
 }
 
+// getDegradedFeed 数据库不可用时的兜底：按Redis Timeline中的帖子ID，从Feed快照里取出仍能找到的帖子
+// 结果可能不完整（快照中没有的帖子被跳过），标记degraded；没有可用数据或已关闭时返回false
+func (s *OptimizedFeedService) getDegradedFeed(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*FeedResponse, bool) {
+	if !s.config.Feed().DegradedFallback {
+		return nil, false
+	}
+	items, nextCursor, hasMore, err := s.timelineCacheService.GetTimeline(ctx, userID, cursor, limit)
+	if err != nil || len(items) == 0 {
+		return nil, false
+	}
+
+	var snapshot FeedResponse
+	if err := s.cache.GetJSON(ctx, feedSnapshotKey(userID), &snapshot); err != nil {
+		return nil, false
+	}
+	cached := func(context.Context, []uuid.UUID) ([]*models.Post, error) {
+		return snapshot.Posts, nil
+	}
+	posts, err := hydrateTimelineItems(ctx, items, cached)
+	if err != nil {
+		return nil, false
+	}
+	if posts = postsBeforePullCursor(posts, cursor); len(posts) == 0 {
+		return nil, false
+	}
+
+	return &FeedResponse{
+		Posts:      posts,
+		NextCursor: nextCursor,
+		HasMore:    hasMore,
+		Degraded:   true,
+	}, true
+}
+
+func feedSnapshotKey(userID uuid.UUID) string {
+	return fmt.Sprintf("feed_snapshot:%s", userID)
+}
+
+// saveFeedSnapshot 异步保存Feed首页快照，失败只记录日志
+func (s *OptimizedFeedService) saveFeedSnapshot(userID uuid.UUID, response *FeedResponse) {
+	ttl := s.config.Feed().SnapshotTTL
+	if ttl <= 0 || len(response.Posts) == 0 {
+		return
+	}
+	snapshot := *response
+	s.runAsync(func() {
+		if err := s.cache.SetJSON(context.Background(), feedSnapshotKey(userID), &snapshot, ttl); err != nil {
+			s.logger.WithError(err).WithField("user_id", userID).Warn("Failed to save feed snapshot")
+		}
+	})
+}
+
+// loadFeedSnapshot 读取Feed首页快照，不存在或已关闭时返回false
+func (s *OptimizedFeedService) loadFeedSnapshot(ctx context.Context, userID uuid.UUID) (*FeedResponse, bool) {
+	if s.config.Feed().SnapshotTTL <= 0 {
+		return nil, false
+	}
+	var snapshot FeedResponse
+	if err := s.cache.GetJSON(ctx, feedSnapshotKey(userID), &snapshot); err != nil {
+		return nil, false
+	}
+	snapshot.Stale = true
+	return &snapshot, true
+}
+
+type feedInspectionKey struct{}
+
+// InspectFeed 管理员以指定用户的视角获取Feed，用于排查问题
+// 与用户自己请求走同一路径，结果一致，但不更新该用户的活跃度，也不记录曝光
+func (s *OptimizedFeedService) InspectFeed(ctx context.Context, userID string, cursor string, limit int, top bool) (*FeedResponse, error) {
+	ctx = context.WithValue(ctx, feedInspectionKey{}, true)
+	if top {
+		return s.GetTopFeed(ctx, userID, cursor, limit)
+	}
+	return s.GetFeed(ctx, userID, cursor, limit)
+}
+
+func isFeedInspection(ctx context.Context) bool {
+	inspection, _ := ctx.Value(feedInspectionKey{}).(bool)
+	return inspection
+}
+
+// GetTopFeed 按帖子分数排序获取Feed，直接读取Redis中的排序时间线，游标为偏移量
+// 页内同样按作者多样性重排；排序时间线不存在时回退到按时间排序的Feed（会触发重建，下次即可命中）
+func (s *OptimizedFeedService) GetTopFeed(ctx context.Context, userID string, cursor string, limit int) (*FeedResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	offset := 0
+	if cursor != "" {
+		parsed, err := strconv.Atoi(cursor)
+		if err != nil || parsed <= 0 {
+			return nil, ErrInvalidCursor
+		}
+		offset = parsed
+	}
+
+	items, hasMore, err := s.timelineCacheService.GetRankedTimeline(ctx, userUUID, offset, limit)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get ranked timeline from cache")
+	}
+	if len(items) == 0 {
+		if offset > 0 {
+			return &FeedResponse{Posts: []*models.Post{}}, nil
+		}
+		// 排序集合为空时退回时间线首页；其游标是时间线格式，这里换成排序Feed的offset游标，
+		// 下一页读取时拉模式已重建出排序集合
+		response, err := s.GetFeed(ctx, userID, "", limit)
+		if err != nil {
+			return nil, err
+		}
+		response.NextCursor = ""
+		if response.HasMore {
+			response.NextCursor = strconv.Itoa(len(response.Posts))
+		}
+		return response, nil
+	}
+
+	posts, err := s.getPostsByIDs(ctx, userUUID, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+	}
+	posts = s.applyEngagedRanking(ctx, userUUID, posts, items)
+	posts = diversifyByAuthor(posts, s.authorDiversity(ctx))
+	s.updateDynamicData(ctx, posts, userUUID)
+
+	response := &FeedResponse{
+		Posts:   posts,
+		HasMore: hasMore,
+	}
+	if hasMore {
+		response.NextCursor = strconv.Itoa(offset + len(items))
+	}
+	return response, nil
+}
+
+// IncrementalFeedResponse 增量轮询的Feed响应
+type IncrementalFeedResponse struct {
+	Posts    []*models.Post `json:"posts"`
+	NewCount int64          `json:"new_count"` // since之后的新条目总数，可用于"X条新内容"提示
+	Since    string         `json:"since"`     // 下一次轮询使用的since
+	HasMore  bool           `json:"has_more"`
+}
+
+// GetFeedSince 获取since之后的新帖子，与cursor（向更旧翻页）相反
+func (s *OptimizedFeedService) GetFeedSince(ctx context.Context, userID string, since string, limit int) (*IncrementalFeedResponse, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	items, newCount, nextSince, err := s.timelineCacheService.GetTimelineSince(ctx, userUUID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &IncrementalFeedResponse{
+		Posts:    []*models.Post{},
+		NewCount: newCount,
+		Since:    nextSince,
+		HasMore:  newCount > int64(len(items)),
+	}
+	if len(items) == 0 {
+		return response, nil
+	}
+
+	posts, err := s.getPostsByIDs(ctx, userUUID, items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+	}
+	s.updateDynamicData(ctx, posts, userUUID)
+	response.Posts = posts
+
+	return response, nil
+}
+
 // distributePostOptimized 优化的帖子分发策略
 func (s *OptimizedFeedService) distributePostOptimized(ctx context.Context, post *models.Post, author *models.User) error {
-	// 判断是否为头部用户（粉丝数超过阈值）
-	if author.Followers > int64(s.config.PushThreshold) {
-		// 头部用户：使用"在线推、离线拉"策略
-		return s.distributeForInfluencer(ctx, post, author)
-	} else {
-		// 普通用户：使用推模式
-		return s.distributeForRegularUser(ctx, post, author)
+	// 过旧的帖子不再推送，用户读取时通过拉模式获得
+	if !s.config.Feed().IsPushEligible(post.CreatedAt) {
+		s.logger.WithField("post_id", post.ID).Info("Post too old for push fan-out, skipping")
+		return nil
 	}
-}
 
-// distributeForInfluencer 头部用户的分发策略
-func (s *OptimizedFeedService) distributeForInfluencer(ctx context.Context, post *models.Post, author *models.User) error {
-	// 1. 获取活跃的关注者（在线推）
-	activeFollowers, err := s.activityService.GetActiveFollowers(ctx, author.ID, 1000) // 限制推送给前1000个活跃用户
+	plan, err := s.planFanout(ctx, author, post.Visibility)
 	if err != nil {
-		s.logger.WithError(err).Error("Failed to get active followers")
-		activeFollowers = []uuid.UUID{} // 继续执行，但不推送给任何人
+		return err
 	}
 
-	// 2. 推送给活跃用户的Timeline缓存
+	switch {
+	case post.IsCloseFriendsOnly():
+		// 仅密友可见的帖子只推送给密友，拉模式会过滤掉非密友
+		return s.distributeToCloseFriends(ctx, post, author, plan.Push)
+	case plan.Mode == DistributionPull:
+		// 头部高频发帖者：不同步扇出。已缓存Timeline的读取不会回查数据库，
+		// 因此仍通过延迟扇出写入活跃关注者的Timeline，其余关注者重建时通过拉模式获得
+		return s.distributeForFrequentInfluencer(ctx, post, author, plan.Delayed)
+	case plan.Tier == FanoutTierInfluencer:
+		// 头部用户：使用"在线推、离线拉"策略
+		return s.distributeForInfluencer(ctx, post, author, plan.Push)
+	default:
+		// 普通用户：推模式推给全部关注者，混合模式或开启延迟扇出时非活跃关注者延迟处理
+		return s.distributeForRegularUser(ctx, post, author, plan)
+	}
+}
+
+// distributeForInfluencer 头部用户的分发策略，activeFollowers为planFanout选出的活跃关注者
+func (s *OptimizedFeedService) distributeForInfluencer(ctx context.Context, post *models.Post, author *models.User, activeFollowers []uuid.UUID) error {
+	start := time.Now()
+
+	// 1. 推送给活跃用户的Timeline缓存（在线推）
 	if len(activeFollowers) > 0 {
 		if err := s.timelineCacheService.BatchAddToTimeline(ctx, activeFollowers, post.ID, post.Score, post.CreatedAt); err != nil {
 			s.logger.WithError(err).Error("Failed to batch add to active followers timeline")
 		}
 	}
 
-	// 3. 记录推送状态，用于崩溃恢复
+	// 2. 记录推送状态，用于崩溃恢复
 	if err := s.recordDistributionStatus(ctx, post.ID, author.ID, "influencer_push_completed"); err != nil {
 		s.logger.WithError(err).Error("Failed to record distribution status")
 	}
 
-	// 4. 发送异步任务处理非活跃用户（离线拉模式会在用户活跃时处理）
+	// 3. 发送异步任务处理非活跃用户（离线拉模式会在用户活跃时处理）
 	event := queue.Event{
-		Type:      "post_distribution_completed",
+		Type:      queue.EventPostDistributionCompleted,
 		Timestamp: time.Now(),
-		Data: map[string]interface{}{
-			"post_id":           post.ID.String(),
-			"author_id":         author.ID.String(),
-			"active_followers":  len(activeFollowers),
-			"distribution_type": "influencer",
+		Data: queue.DistributionCompletedEventData{
+			PostID:           post.ID.String(),
+			AuthorID:         author.ID.String(),
+			ActiveFollowers:  len(activeFollowers),
+			DistributionType: "influencer",
+			DurationMs:       time.Since(start).Milliseconds(),
 		},
 	}
 	if err := s.producer.Publish(ctx, author.ID.String(), event); err != nil {
@@ -235,22 +505,20 @@ func (s *OptimizedFeedService) distributeForInfluencer(ctx context.Context, post
 	return nil
 }
 
-// distributeForRegularUser 普通用户的分发策略
-func (s *OptimizedFeedService) distributeForRegularUser(ctx context.Context, post *models.Post, author *models.User) error {
-	// 获取所有关注者
-	followers, err := s.followRepo.GetFollowers(ctx, author.ID, 0, int(s.config.MaxFeedSize))
-	if err != nil {
-		return fmt.Errorf("failed to get followers: %w", err)
-	}
-
-	var followerIDs []uuid.UUID
-	for _, follower := range followers {
-		followerIDs = append(followerIDs, follower.ID)
+// distributeForRegularUser 普通用户的分发策略：同步推送plan.Push，plan.Delayed放入延迟扇出
+func (s *OptimizedFeedService) distributeForRegularUser(ctx context.Context, post *models.Post, author *models.User, plan *fanoutPlan) error {
+	// 非活跃关注者的Timeline可能仍在缓存中，读取时不会回查数据库，因此入队失败时不能直接跳过
+	pushIDs := plan.Push
+	if len(plan.Delayed) > 0 {
+		if err := s.enqueueDelayedFanout(ctx, post, plan.Delayed); err != nil {
+			s.logger.WithError(err).Error("Failed to enqueue delayed fan-out, pushing to all")
+			pushIDs = append(append([]uuid.UUID{}, plan.Push...), plan.Delayed...)
+		}
 	}
 
-	// 推送到所有关注者的Timeline缓存
-	if len(followerIDs) > 0 {
-		if err := s.timelineCacheService.BatchAddToTimeline(ctx, followerIDs, post.ID, post.Score, post.CreatedAt); err != nil {
+	// 推送到关注者的Timeline缓存
+	if len(pushIDs) > 0 {
+		if err := s.timelineCacheService.BatchAddToTimeline(ctx, pushIDs, post.ID, post.Score, post.CreatedAt); err != nil {
 			s.logger.WithError(err).Error("Failed to batch add to followers timeline")
 		}
 	}
@@ -263,48 +531,192 @@ func (s *OptimizedFeedService) distributeForRegularUser(ctx context.Context, pos
 	s.logger.WithFields(map[string]interface{}{
 		"post_id":   post.ID,
 		"author_id": author.ID,
-		"followers": len(followerIDs),
-	}).Info("Regular user post distributed to all followers")
+		"followers": plan.Recipients(),
+		"pushed":    len(pushIDs),
+		"delayed":   plan.Recipients() - len(pushIDs),
+	}).Info("Regular user post distributed to followers")
 
 	return nil
 }
 
-// getFeedByPullMode 使用拉模式获取Feed
-func (s *OptimizedFeedService) getFeedByPullMode(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*FeedResponse, error) {
-	// 获取关注的用户
-	following, err := s.followRepo.GetFollowing(ctx, userID, 0, 1000) // 限制关注数量
+// distributeForFrequentInfluencer 头部高频发帖者的分发策略：活跃关注者放入延迟扇出，不占用发帖时的扇出资源
+func (s *OptimizedFeedService) distributeForFrequentInfluencer(ctx context.Context, post *models.Post, author *models.User, targets []uuid.UUID) error {
+	if len(targets) > 0 {
+		if err := s.enqueueDelayedFanout(ctx, post, targets); err != nil {
+			s.logger.WithError(err).Error("Failed to enqueue delayed fan-out for frequent influencer")
+		}
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"post_id":   post.ID,
+		"author_id": author.ID,
+		"delayed":   len(targets),
+	}).Info("Frequent influencer post left to delayed fan-out and pull mode")
+
+	return nil
+}
+
+// 推送分层
+const (
+	FanoutTierInfluencer = "influencer"
+	FanoutTierRegular    = "regular"
+)
+
+// fanoutPlan 一次分发的推送对象，不包含作者自己
+type fanoutPlan struct {
+	Tier    string
+	Mode    string
+	Push    []uuid.UUID // 发帖时同步推送的关注者
+	Delayed []uuid.UUID // 放入延迟扇出的关注者
+}
+
+// Recipients 会收到帖子的关注者数，包括延迟扇出的部分
+func (p *fanoutPlan) Recipients() int {
+	return len(p.Push) + len(p.Delayed)
+}
+
+// planFanout 按分层规则确定推送对象，分发和推送范围预估共用同一份规则
+func (s *OptimizedFeedService) planFanout(ctx context.Context, author *models.User, visibility string) (*fanoutPlan, error) {
+	feedCfg := s.config.Feed()
+	plan := &fanoutPlan{Tier: FanoutTierRegular}
+	if author.Followers > int64(feedCfg.PushThreshold) {
+		plan.Tier = FanoutTierInfluencer
+	}
+
+	// 仅密友可见的帖子只推送给密友，不走推/拉分层
+	if visibility == models.PostVisibilityCloseFriends {
+		closeFriendIDs, err := s.followRepo.GetCloseFriendFollowerIDs(ctx, author.ID, int(feedCfg.MaxFeedSize))
+		if err != nil {
+			return nil, err
+		}
+		plan.Mode = DistributionPush
+		plan.Push = closeFriendIDs
+		return plan, nil
+	}
+
+	// 按粉丝数、发帖频率和关注者活跃占比选择推/拉/混合模式
+	plan.Mode = s.chooseDistribution(ctx, author)
+	if plan.Mode == DistributionPull {
+		plan.Delayed = s.influencerFanoutTargets(ctx, author)
+		return plan, nil
+	}
+	if plan.Tier == FanoutTierInfluencer {
+		plan.Push = s.influencerFanoutTargets(ctx, author)
+		return plan, nil
+	}
+
+	followerIDs, err := s.regularFanoutTargets(ctx, author)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get following users: %w", err)
+		return nil, err
 	}
+	plan.Push = followerIDs
 
-	var followingIDs []uuid.UUID
-	for _, user := range following {
-		followingIDs = append(followingIDs, user.ID)
+	// 混合模式或开启延迟扇出时只同步推送给活跃关注者，非活跃关注者在低峰期处理
+	if (plan.Mode == DistributionHybrid || feedCfg.Optimization.DelayedFanout.Enabled) && len(followerIDs) > 0 {
+		active, inactive, err := s.activityService.PartitionByActivity(ctx, followerIDs)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to partition followers by activity, pushing to all")
+		} else {
+			plan.Push = active
+			plan.Delayed = inactive
+		}
 	}
-	// 包含自己的帖子
-	followingIDs = append(followingIDs, userID)
+	return plan, nil
+}
 
-	// 从数据库拉取最新的帖子
-	posts, err := s.postRepo.GetPostsByUserIDs(ctx, followingIDs, cursor, limit+1)
+// influencerFanoutTargets 头部用户的推送对象：前1000个活跃关注者
+func (s *OptimizedFeedService) influencerFanoutTargets(ctx context.Context, author *models.User) []uuid.UUID {
+	activeFollowers, err := s.activityService.GetActiveFollowers(ctx, author.ID, 1000) // 限制推送给前1000个活跃用户
 	if err != nil {
-		return nil, fmt.Errorf("failed to get posts by user IDs: %w", err)
+		s.logger.WithError(err).Error("Failed to get active followers")
+		return []uuid.UUID{} // 继续执行，但不推送给任何人
 	}
+	return activeFollowers
+}
 
-	// 处理分页
-	hasMore := len(posts) > limit
-	if hasMore {
-		posts = posts[:limit]
+// regularFanoutTargets 普通用户的推送对象：全部关注者（最多max_feed_size个）
+func (s *OptimizedFeedService) regularFanoutTargets(ctx context.Context, author *models.User) ([]uuid.UUID, error) {
+	followers, err := s.followRepo.GetFollowers(ctx, author.ID, 0, int(s.config.Feed().MaxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+
+	var followerIDs []uuid.UUID
+	for _, follower := range followers {
+		followerIDs = append(followerIDs, follower.ID)
 	}
+	return followerIDs, nil
+}
 
-	var nextCursor string
-	if len(posts) > 0 {
-		nextCursor = posts[len(posts)-1].CreatedAt.Format(time.RFC3339Nano)
+// ReachPreview 发帖前预估的推送范围
+type ReachPreview struct {
+	Tier                string `json:"tier"` // influencer | regular
+	Mode                string `json:"mode"` // push | hybrid | pull
+	Followers           int64  `json:"followers"`
+	EstimatedRecipients int    `json:"estimated_recipients"` // 同步推送和延迟扇出的关注者总数
+	DelayedRecipients   int    `json:"delayed_recipients"`   // 其中延迟扇出的关注者数
+}
+
+// PreviewReach 按与分发相同的分层规则（planFanout）预估帖子会被推送给多少关注者
+func (s *OptimizedFeedService) PreviewReach(ctx context.Context, userID, visibility string) (*ReachPreview, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	visibility, err = normalizeVisibility(visibility)
+	if err != nil {
+		return nil, err
 	}
 
-	// 重建Timeline缓存（异步）
-	go func() {
-		s.rebuildTimelineCache(context.Background(), userID, posts)
-	}()
+	author, err := s.userRepo.GetByID(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if author == nil {
+		return nil, apperrors.NotFound("user not found")
+	}
+
+	plan, err := s.planFanout(ctx, author, visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReachPreview{
+		Tier:                plan.Tier,
+		Mode:                plan.Mode,
+		Followers:           author.Followers,
+		EstimatedRecipients: plan.Recipients(),
+		DelayedRecipients:   len(plan.Delayed),
+	}, nil
+}
+
+// getFeedByPullMode 使用拉模式获取Feed
+func (s *OptimizedFeedService) getFeedByPullMode(ctx context.Context, userID uuid.UUID, cursor string, limit int) (*FeedResponse, error) {
+	// 获取关注的用户
+	followingIDs, sampled, err := s.getPullModeAuthors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 包含自己的帖子
+	followingIDs = append(followingIDs, userID)
+
+	// 从数据库拉取最新的帖子，过滤后不足一页时继续补取
+	posts, nextCursor, hasMore, err := s.collectPullModePage(ctx, followingIDs, s.closeFriendAuthors(ctx, userID), cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重建Timeline缓存（异步，关闭时会等待完成；扇出饱和时跳过，下次读取会再次重建）
+	if s.fanoutLimiter.TryAcquire() {
+		if !s.runAsync(func() {
+			defer s.fanoutLimiter.Release()
+			s.rebuildTimelineCache(context.Background(), userID, posts)
+		}) {
+			s.fanoutLimiter.Release()
+		}
+	} else {
+		s.logger.WithField("user_id", userID).Warn("Fan-out queue saturated, skipping timeline rebuild")
+	}
 
 	// 更新动态数据
 	s.updateDynamicData(ctx, posts, userID)
@@ -313,45 +725,211 @@ func (s *OptimizedFeedService) getFeedByPullMode(ctx context.Context, userID uui
 		Posts:      posts,
 		NextCursor: nextCursor,
 		HasMore:    hasMore,
+		Sampled:    sampled,
 	}
 
 	return response, nil
 }
 
-// getPostsByIDs 根据Timeline项获取完整的Post信息
-func (s *OptimizedFeedService) getPostsByIDs(ctx context.Context, timelineItems []TimelineItem) ([]*models.Post, error) {
-	var postIDs []uuid.UUID
-	for _, item := range timelineItems {
-		if postID, err := uuid.Parse(item.PostID); err == nil {
-			postIDs = append(postIDs, postID)
-		}
+// getPullModeAuthors 获取拉模式需要合并的作者，关注数超过上限时退化为最活跃的一部分
+func (s *OptimizedFeedService) getPullModeAuthors(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, bool, error) {
+	maxFollowing := s.config.Feed().PullMaxFollowing
+	if maxFollowing <= 0 {
+		maxFollowing = 1000
 	}
 
-	if len(postIDs) == 0 {
-		return []*models.Post{}, nil
+	// 多取一个判断是否超过上限
+	followingIDs, err := s.followRepo.GetFollowingIDs(ctx, userID, maxFollowing+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get following users: %w", err)
+	}
+	if len(followingIDs) <= maxFollowing {
+		return followingIDs, false, nil
 	}
 
-	posts, err := s.postRepo.GetByIDs(ctx, postIDs)
+	activeIDs, err := s.followRepo.GetMostActiveFollowingIDs(ctx, userID, maxFollowing)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+		return nil, false, fmt.Errorf("failed to get most active following users: %w", err)
 	}
 
-	// 按照timeline的顺序重新排序
-	postMap := make(map[uuid.UUID]*models.Post)
-	for _, post := range posts {
-		postMap[post.ID] = post
+	s.logger.WithFields(map[string]interface{}{
+		"user_id":       userID,
+		"max_following": maxFollowing,
+	}).Info("Following exceeds pull mode limit, using sampled authors")
+
+	return activeIDs, true, nil
+}
+
+// collectPullModePage 按倍数超量拉取候选帖子并过滤，仍不足一页时沿游标继续补取，最多pull_max_rounds轮
+// 游标为"创建时间_帖子ID"形式的keyset，指向本次已扫描到的最后一条候选，被过滤掉的帖子不会在下一页重复扫描，
+// 同一时刻的帖子也不会在翻页边界被漏掉。closeAuthors为查看者能看到其仅密友可见帖子的作者
+func (s *OptimizedFeedService) collectPullModePage(ctx context.Context, authorIDs []uuid.UUID, closeAuthors map[uuid.UUID]bool, cursor string, limit int) ([]*models.Post, string, bool, error) {
+	beforeCreatedAt, beforeID, err := parsePullCursor(cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	feedCfg := s.config.Feed()
+	multiplier := feedCfg.PullOverfetch
+	if multiplier < 1 {
+		multiplier = 1
 	}
+	rounds := feedCfg.PullMaxRounds
+	if rounds < 1 {
+		rounds = 1
+	}
+	batch := (limit + 1) * multiplier
 
-	var orderedPosts []*models.Post
-	for _, item := range timelineItems {
-		if postID, err := uuid.Parse(item.PostID); err == nil {
-			if post, exists := postMap[postID]; exists && !post.IsDeleted {
-				orderedPosts = append(orderedPosts, post)
+	page := make([]*models.Post, 0, limit+1)
+	seen := make(map[uuid.UUID]struct{}, batch)
+	var lastScanned *models.Post
+	exhausted := false
+	for round := 0; round < rounds && len(page) <= limit; round++ {
+		candidates, err := s.fetchPullModePosts(ctx, authorIDs, beforeCreatedAt, beforeID, batch)
+		if err != nil {
+			return nil, "", false, err
+		}
+		for _, post := range candidates {
+			if !pullCandidateVisible(post, closeAuthors) {
+				continue
+			}
+			if _, dup := seen[post.ID]; dup {
+				continue
 			}
+			seen[post.ID] = struct{}{}
+			page = append(page, post)
+		}
+		if len(candidates) > 0 {
+			lastScanned = candidates[len(candidates)-1]
+			beforeCreatedAt, beforeID = lastScanned.CreatedAt, lastScanned.ID
+		}
+		if len(candidates) < batch {
+			exhausted = true
+			break
+		}
+	}
+
+	if len(page) > limit {
+		page = page[:limit]
+		return page, pullCursor(page[len(page)-1]), true, nil
+	}
+	if exhausted {
+		var nextCursor string
+		if len(page) > 0 {
+			nextCursor = pullCursor(page[len(page)-1])
+		}
+		return page, nextCursor, false, nil
+	}
+	// 轮数用完但数据源未耗尽：返回不足一页的结果，游标跳过已过滤的候选
+	return page, pullCursor(lastScanned), true, nil
+}
+
+// pullCursor 生成拉模式的翻页游标，格式与评论、点赞列表的keyset游标相同
+func pullCursor(post *models.Post) string {
+	return post.CreatedAt.Format(time.RFC3339Nano) + "_" + post.ID.String()
+}
+
+// parsePullCursor 解析拉模式游标，返回keyset的(创建时间, 帖子ID)，空游标返回零值。
+// 兼容旧版纯RFC3339时间游标（ID为uuid.Nil，等价于只按时间过滤），
+// 以及Timeline缓存回退过来的"时间戳_帖子ID"游标：Timeline只精确到秒，从该秒末尾继续，同一秒内的帖子可能重复但不会漏掉
+func parsePullCursor(cursor string) (time.Time, uuid.UUID, error) {
+	if cursor == "" {
+		return time.Time{}, uuid.Nil, nil
+	}
+	if createdAt, id, ok := pullCursorTime(cursor); ok {
+		return createdAt, id, nil
+	}
+	score, _, err := parseTimelineCursor(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return time.Unix(int64(score)+1, 0), uuid.Nil, nil
+}
+
+// pullCandidateVisible 判断拉模式候选帖子能否出现在Feed中，新增过滤规则时在这里扩展
+func pullCandidateVisible(post *models.Post, closeAuthors map[uuid.UUID]bool) bool {
+	if post == nil || post.IsDeleted {
+		return false
+	}
+	return !post.IsCloseFriendsOnly() || closeAuthors[post.UserID]
+}
+
+// fetchPullModePosts 按配置选择全局查询或按作者取TopK后多路归并
+func (s *OptimizedFeedService) fetchPullModePosts(ctx context.Context, authorIDs []uuid.UUID, beforeCreatedAt time.Time, beforeID uuid.UUID, limit int) ([]*models.Post, error) {
+	feedCfg := s.config.Feed()
+	if feedCfg.PullMergeMode == "kway" && len(authorIDs) >= feedCfg.KWayMinFollowing {
+		perAuthor, err := s.postRepo.GetTopPostsPerUser(ctx, authorIDs, beforeCreatedAt, beforeID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get top posts per author: %w", err)
 		}
+
+		lists := make([][]*models.Post, 0, len(perAuthor))
+		for _, authorPosts := range perAuthor {
+			lists = append(lists, authorPosts)
+		}
+		return mergePostsByTime(lists, limit), nil
+	}
+
+	posts, err := s.postRepo.GetPostsByUserIDs(ctx, authorIDs, beforeCreatedAt, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by user IDs: %w", err)
+	}
+	return posts, nil
+}
+
+// beginInflight 登记一个进行中的任务，服务关闭中返回false
+func (s *OptimizedFeedService) beginInflight() bool {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// runAsync 启动一个被跟踪的后台任务，服务关闭中不再接受新任务
+func (s *OptimizedFeedService) runAsync(task func()) bool {
+	if !s.beginInflight() {
+		s.logger.Warn("Service is shutting down, skipping async task")
+		return false
 	}
+	go func() {
+		defer s.inflight.Done()
+		task()
+	}()
+	return true
+}
 
-	return orderedPosts, nil
+// FanoutStats 获取扇出队列状态
+func (s *OptimizedFeedService) FanoutStats() FanoutQueueStats {
+	return s.fanoutLimiter.Stats()
+}
+
+// Shutdown 停止接受新的异步任务，并等待进行中的分发和重建完成（受ctx超时约束）
+func (s *OptimizedFeedService) Shutdown(ctx context.Context) error {
+	s.inflightMu.Lock()
+	s.shuttingDown = true
+	s.inflightMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("All in-flight feed distributions drained")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for in-flight feed distributions: %w", ctx.Err())
+	}
+}
+
+// getPostsByIDs 根据Timeline项获取查看者能看到的完整Post信息
+func (s *OptimizedFeedService) getPostsByIDs(ctx context.Context, viewerID uuid.UUID, timelineItems []TimelineItem) ([]*models.Post, error) {
+	return hydrateTimelineItems(ctx, timelineItems, s.visiblePostFetcher(viewerID))
 }
 
 // rebuildTimelineCache 重建Timeline缓存
@@ -411,18 +989,23 @@ func (s *OptimizedFeedService) calculatePostScore(post *models.Post, user *model
 	score := s.calculateInitialScore(user)
 	hoursSinceCreated := time.Since(post.CreatedAt).Hours()
 	timeDecay := math.Exp(-hoursSinceCreated / 24.0)
-	engagementScore := float64(post.LikeCount)*0.1 + float64(post.CommentCount)*0.2 + float64(post.ShareCount)*0.3
+	engagementScore := float64(post.LikeCount)*0.1 + float64(post.CommentCount)*0.2 + float64(post.ShareCount)*0.3 + float64(post.ViewCount)*0.01
 	finalScore := (score + engagementScore) * timeDecay
 	return finalScore
 }
 
 func (s *OptimizedFeedService) updateDynamicData(ctx context.Context, posts []*models.Post, viewerID uuid.UUID) {
-	for _, post := range posts {
-		isLiked, err := s.likeRepo.IsLiked(ctx, viewerID, post.ID)
-		if err != nil {
-			s.logger.WithError(err).Error("Failed to check like status")
-			continue
+	if !isFeedInspection(ctx) {
+		if err := s.impressions.Record(ctx, viewerID, postIDsOf(posts)); err != nil {
+			s.logger.WithError(err).Error("Failed to record impressions")
 		}
-		_ = isLiked
 	}
+
+	// 一次往返批量获取回应状态（同时预热缓存），填充查看者的点赞状态和回应类型
+	reactions, err := s.likeState.LookupReactions(ctx, viewerID, postIDsOf(posts))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check like status")
+		return
+	}
+	applyViewerReactions(posts, reactions)
 }
@@ -0,0 +1,756 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// newOptimizedTestService 使用sqlmock和miniredis构造完整的OptimizedFeedService
+func newOptimizedTestService(t *testing.T, mutate func(*config.FeedConfig)) (*OptimizedFeedService, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	t.Helper()
+
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(mutate)
+	log := logger.NewLogger()
+	userRepo := repository.NewUserRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	service := NewOptimizedFeedService(
+		repository.NewPostRepository(db), nil, userRepo, followRepo, repository.NewLikeRepository(db), nil,
+		redisClient, nil, cfg, log, NewActivityService(userRepo, followRepo, redisClient, cfg, log),
+		NewTimelineCacheService(redisClient, cfg, log), nil,
+	)
+	return service, mock, mr
+}
+
+func TestGetTopFeedCursor(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, nil)
+	ctx := context.Background()
+
+	viewerID, authorID := uuid.New(), uuid.New()
+	postIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, postID := range postIDs {
+		mr.ZAdd(RankedTimelineKey(viewerID), float64(len(postIDs)-i), postID.String())
+	}
+
+	expectPage := func(postIDs ...uuid.UUID) {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "created_at"})
+		for _, postID := range postIDs {
+			rows.AddRow(postID, authorID, time.Now())
+		}
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+		mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}))
+	}
+
+	t.Run("pages by offset", func(t *testing.T) {
+		expectPage(postIDs[0], postIDs[1])
+		first, err := service.GetTopFeed(ctx, viewerID.String(), "", 2)
+		if err != nil {
+			t.Fatalf("GetTopFeed: %v", err)
+		}
+		if len(first.Posts) != 2 || first.Posts[0].ID != postIDs[0] || first.Posts[1].ID != postIDs[1] {
+			t.Fatalf("first page = %v, want the two highest-scored posts", first.Posts)
+		}
+		if !first.HasMore || first.NextCursor != "2" {
+			t.Fatalf("first page has_more = %v, next_cursor = %q", first.HasMore, first.NextCursor)
+		}
+
+		expectPage(postIDs[2])
+		second, err := service.GetTopFeed(ctx, viewerID.String(), first.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("GetTopFeed: %v", err)
+		}
+		if len(second.Posts) != 1 || second.Posts[0].ID != postIDs[2] || second.HasMore || second.NextCursor != "" {
+			t.Errorf("second page = %+v, want the last post and no more pages", second)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("malformed cursor", func(t *testing.T) {
+		for _, cursor := range []string{"abc", "0", "-2", "1.5"} {
+			if _, err := service.GetTopFeed(ctx, viewerID.String(), cursor, 2); err != ErrInvalidCursor {
+				t.Errorf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+			}
+		}
+	})
+}
+
+func TestShutdownWaitsForInflightRebuild(t *testing.T) {
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(nil)
+	log := logger.NewLogger()
+	service := &OptimizedFeedService{
+		config:               cfg,
+		logger:               log,
+		timelineCacheService: NewTimelineCacheService(redisClient, cfg, log),
+	}
+	ctx := context.Background()
+	userID, postID := uuid.New(), uuid.New()
+
+	// 模拟拉模式读取后在后台重建Timeline
+	release := make(chan struct{})
+	if !service.runAsync(func() {
+		<-release
+		timelines := []*models.Timeline{{UserID: userID, PostID: postID, Score: 1, CreatedAt: time.Now()}}
+		if err := service.timelineCacheService.RebuildTimelineFromDB(context.Background(), userID, timelines); err != nil {
+			t.Errorf("RebuildTimelineFromDB: %v", err)
+		}
+	}) {
+		t.Fatal("runAsync rejected a task before shutdown")
+	}
+
+	t.Run("times out while the rebuild is still running", func(t *testing.T) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if err := service.Shutdown(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown() = %v, want deadline exceeded", err)
+		}
+	})
+
+	t.Run("rejects new tasks once shutting down", func(t *testing.T) {
+		if service.runAsync(func() { t.Error("task started after shutdown") }) {
+			t.Error("runAsync accepted a task during shutdown")
+		}
+	})
+
+	t.Run("returns after the rebuild finishes", func(t *testing.T) {
+		close(release)
+		if err := service.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown: %v", err)
+		}
+		if members, err := mr.ZMembers("timeline:" + userID.String()); err != nil || len(members) != 1 {
+			t.Errorf("rebuilt timeline = %v, %v; want the rebuild to complete before shutdown returns", members, err)
+		}
+	})
+}
+
+func TestGetPullModeAuthorsSamplesLargeFollowing(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	following := sqlmock.NewRows([]string{"following_id"})
+	for i := 0; i < 1001; i++ {
+		following.AddRow(uuid.New())
+	}
+
+	t.Run("following over the default limit uses the most active authors", func(t *testing.T) {
+		service, mock, _ := newOptimizedTestService(t, nil)
+		mock.ExpectQuery(`SELECT "following_id" FROM "follows" WHERE follower_id = \$1 .* LIMIT 1001`).
+			WillReturnRows(following)
+		active := sqlmock.NewRows([]string{"following_id"})
+		for i := 0; i < 1000; i++ {
+			active.AddRow(uuid.New())
+		}
+		mock.ExpectQuery(`SELECT "follows"."following_id" FROM "follows" JOIN users .* LIMIT 1000`).
+			WillReturnRows(active)
+
+		authors, sampled, err := service.getPullModeAuthors(ctx, userID)
+		if err != nil {
+			t.Fatalf("getPullModeAuthors: %v", err)
+		}
+		if !sampled || len(authors) != 1000 {
+			t.Errorf("got %d authors, sampled = %v; want 1000 sampled authors", len(authors), sampled)
+		}
+	})
+
+	t.Run("following within the limit is not sampled", func(t *testing.T) {
+		service, mock, _ := newOptimizedTestService(t, nil)
+		mock.ExpectQuery(`SELECT "following_id" FROM "follows"`).
+			WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(uuid.New()))
+
+		authors, sampled, err := service.getPullModeAuthors(ctx, userID)
+		if err != nil || sampled || len(authors) != 1 {
+			t.Errorf("getPullModeAuthors() = %d authors, %v, %v", len(authors), sampled, err)
+		}
+	})
+}
+
+func TestGetFeedByPullModeSampledFeed(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+		feed.PullMaxFollowing = 3
+	})
+	ctx := context.Background()
+	viewerID := uuid.New()
+	// 后台重建Timeline时不再查询用户活跃度
+	mr.Set("user_active:"+viewerID.String(), "1")
+
+	following := sqlmock.NewRows([]string{"following_id"})
+	for i := 0; i < 4; i++ {
+		following.AddRow(uuid.New())
+	}
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows" WHERE follower_id`).WillReturnRows(following)
+	active := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	mock.ExpectQuery(`SELECT "follows"."following_id" FROM "follows" JOIN users`).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(active[0]).AddRow(active[1]).AddRow(active[2]))
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows" WHERE \(follower_id = \$1 AND close_friend = \$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}))
+
+	now := time.Now()
+	postIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	posts := sqlmock.NewRows([]string{"id", "user_id", "created_at"})
+	for i, postID := range postIDs {
+		posts.AddRow(postID, active[i], now.Add(-time.Duration(i)*time.Minute))
+	}
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id IN`).WillReturnRows(posts)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(active[0]).AddRow(active[1]).AddRow(active[2]))
+	mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}))
+
+	response, err := service.getFeedByPullMode(ctx, viewerID, "", 2)
+	if err != nil {
+		t.Fatalf("getFeedByPullMode: %v", err)
+	}
+	if err := service.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Sampled {
+		t.Error("feed built from a sampled author set is not flagged as sampled")
+	}
+	if len(response.Posts) != 2 || response.Posts[0].ID != postIDs[0] || response.Posts[1].ID != postIDs[1] || !response.HasMore {
+		t.Errorf("feed = %+v, want the two newest posts and more to come", response)
+	}
+	if response.NextCursor != pullCursor(response.Posts[1]) {
+		t.Errorf("next cursor = %q, want the last returned post's keyset", response.NextCursor)
+	}
+}
+
+func TestCollectPullModePageOverfetchesThroughFiltering(t *testing.T) {
+	ctx := context.Background()
+	author, hidden := uuid.New(), uuid.New()
+	now := time.Now()
+
+	// candidates 生成count条候选，visible指定的下标是公开帖子，其余是查看者看不到的密友帖子
+	candidates := func(start time.Time, count int, visible ...int) (*sqlmock.Rows, []uuid.UUID) {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "visibility", "created_at"})
+		var visibleIDs []uuid.UUID
+		isVisible := map[int]bool{}
+		for _, i := range visible {
+			isVisible[i] = true
+		}
+		for i := 0; i < count; i++ {
+			id := uuid.New()
+			if isVisible[i] {
+				rows.AddRow(id, author, models.PostVisibilityPublic, start.Add(-time.Duration(i)*time.Second))
+				visibleIDs = append(visibleIDs, id)
+			} else {
+				rows.AddRow(id, hidden, models.PostVisibilityCloseFriends, start.Add(-time.Duration(i)*time.Second))
+			}
+		}
+		return rows, visibleIDs
+	}
+	expectPosts := func(mock sqlmock.Sqlmock, limit string, rows *sqlmock.Rows, authors ...uuid.UUID) {
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id IN .* LIMIT ` + limit).WillReturnRows(rows)
+		users := sqlmock.NewRows([]string{"id"})
+		for _, id := range authors {
+			users.AddRow(id)
+		}
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id"`).WillReturnRows(users)
+	}
+
+	t.Run("over-fetch and extra rounds fill the page", func(t *testing.T) {
+		service, mock, _ := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+			feed.PullOverfetch = 3
+			feed.PullMaxRounds = 3
+		})
+		// 每页2条，每轮取(2+1)*3=9条候选；第一轮只有2条可见，第二轮补足
+		first, firstVisible := candidates(now, 9, 2, 7)
+		expectPosts(mock, "9", first, author, hidden)
+		second, _ := candidates(now.Add(-time.Minute), 9, 4)
+		expectPosts(mock, "9", second, author, hidden)
+
+		page, nextCursor, hasMore, err := service.collectPullModePage(ctx, []uuid.UUID{author, hidden}, nil, "", 2)
+		if err != nil {
+			t.Fatalf("collectPullModePage: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || page[0].ID != firstVisible[0] || page[1].ID != firstVisible[1] {
+			t.Fatalf("page = %v, want the two visible posts of the first round", page)
+		}
+		if !hasMore || nextCursor != pullCursor(page[1]) {
+			t.Errorf("nextCursor = %q, hasMore = %v; want cursor at the last returned post", nextCursor, hasMore)
+		}
+	})
+
+	t.Run("without over-fetch the page comes back short", func(t *testing.T) {
+		service, mock, _ := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+			feed.PullOverfetch = 1
+			feed.PullMaxRounds = 1
+		})
+		rows, visible := candidates(now, 3, 2)
+		expectPosts(mock, "3", rows, author, hidden)
+
+		page, nextCursor, hasMore, err := service.collectPullModePage(ctx, []uuid.UUID{author, hidden}, nil, "", 2)
+		if err != nil {
+			t.Fatalf("collectPullModePage: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 1 || page[0].ID != visible[0] {
+			t.Fatalf("page = %v, want only the single visible candidate", page)
+		}
+		// 游标跳过已扫描的候选，下一页从第三条之后开始
+		if !hasMore || !strings.HasPrefix(nextCursor, now.Add(-2*time.Second).Format(time.RFC3339Nano)+"_") {
+			t.Errorf("nextCursor = %q, hasMore = %v; want cursor past the scanned candidates", nextCursor, hasMore)
+		}
+	})
+
+	t.Run("visible close friends posts are kept", func(t *testing.T) {
+		service, mock, _ := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+			feed.PullOverfetch = 1
+			feed.PullMaxRounds = 1
+		})
+		rows, _ := candidates(now, 2)
+		expectPosts(mock, "3", rows, hidden)
+
+		page, nextCursor, hasMore, err := service.collectPullModePage(ctx, []uuid.UUID{author, hidden}, map[uuid.UUID]bool{hidden: true}, "", 2)
+		if err != nil {
+			t.Fatalf("collectPullModePage: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 || hasMore || nextCursor != pullCursor(page[1]) {
+			t.Errorf("page = %v, cursor %q, hasMore %v; want both posts and no more", page, nextCursor, hasMore)
+		}
+	})
+}
+
+func TestParsePullCursor(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	postID := uuid.New()
+	tests := []struct {
+		name    string
+		cursor  string
+		wantAt  time.Time
+		wantID  uuid.UUID
+		wantErr bool
+	}{
+		{"empty", "", time.Time{}, uuid.Nil, false},
+		{"keyset", createdAt.Format(time.RFC3339Nano) + "_" + postID.String(), createdAt, postID, false},
+		{"legacy time", createdAt.Format(time.RFC3339Nano), createdAt, uuid.Nil, false},
+		// Timeline游标只精确到秒，从该秒末尾继续
+		{"timeline", fmt.Sprintf("%d_%s", createdAt.Unix(), postID), time.Unix(createdAt.Unix()+1, 0), uuid.Nil, false},
+		{"garbage", "not-a-cursor", time.Time{}, uuid.Nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, id, err := parsePullCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePullCursor(%q) error = %v, wantErr %v", tt.cursor, err, tt.wantErr)
+			}
+			if !at.Equal(tt.wantAt) || id != tt.wantID {
+				t.Errorf("parsePullCursor(%q) = %v, %v; want %v, %v", tt.cursor, at, id, tt.wantAt, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestCollectPullModePageResumesAtSameTimestamp(t *testing.T) {
+	service, mock, _ := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+		feed.PullOverfetch = 1
+		feed.PullMaxRounds = 1
+	})
+	ctx := context.Background()
+	author := uuid.New()
+
+	// 两条帖子的创建时间完全相同，按ID倒序排列
+	createdAt := time.Now().UTC().Add(-time.Minute)
+	first, second := uuid.New(), uuid.New()
+	if first.String() < second.String() {
+		first, second = second, first
+	}
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id IN .* LIMIT 2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at"}).
+			AddRow(first, author, createdAt).
+			AddRow(second, author, createdAt))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(author))
+
+	page, cursor, hasMore, err := service.collectPullModePage(ctx, []uuid.UUID{author}, nil, "", 1)
+	if err != nil {
+		t.Fatalf("collectPullModePage: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != first || !hasMore {
+		t.Fatalf("first page = %v, hasMore = %v; want only the first post", page, hasMore)
+	}
+
+	// 下一页以(created_at, id)为keyset继续，同一时刻的第二条帖子不会被跳过
+	mock.ExpectQuery(`\(created_at, id\) < \(\$3, \$4\)`).
+		WithArgs(author, false, createdAt, first).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "created_at"}).AddRow(second, author, createdAt))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(author))
+
+	page, _, hasMore, err = service.collectPullModePage(ctx, []uuid.UUID{author}, nil, cursor, 1)
+	if err != nil {
+		t.Fatalf("collectPullModePage: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != second || hasMore {
+		t.Errorf("second page = %v, hasMore = %v; want only the second post", page, hasMore)
+	}
+}
+
+func TestPullCursorContinuesInTimelineWithinSameSecond(t *testing.T) {
+	service, mock, _ := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+		feed.PullOverfetch = 1
+		feed.PullMaxRounds = 1
+	})
+	ctx := context.Background()
+	author, viewer := uuid.New(), uuid.New()
+
+	// 前两条帖子在同一秒内，拉模式第一页只返回较新的那条
+	second := time.Now().Add(-time.Minute).Truncate(time.Second)
+	posts := []*models.Post{
+		{ID: uuid.New(), UserID: author, Visibility: models.PostVisibilityPublic, CreatedAt: second.Add(600 * time.Millisecond)},
+		{ID: uuid.New(), UserID: author, Visibility: models.PostVisibilityPublic, CreatedAt: second.Add(200 * time.Millisecond)},
+		{ID: uuid.New(), UserID: author, Visibility: models.PostVisibilityPublic, CreatedAt: second.Add(-time.Second)},
+	}
+	rows := sqlmock.NewRows([]string{"id", "user_id", "visibility", "created_at"})
+	for _, post := range posts[:2] {
+		rows.AddRow(post.ID, post.UserID, post.Visibility, post.CreatedAt)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id IN .* LIMIT 2`).WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(author))
+
+	page, cursor, hasMore, err := service.collectPullModePage(ctx, []uuid.UUID{author}, nil, "", 1)
+	if err != nil {
+		t.Fatalf("collectPullModePage: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != posts[0].ID || !hasMore {
+		t.Fatalf("pull page = %v, hasMore = %v; want only the newest post", page, hasMore)
+	}
+
+	// Timeline已预热，下一页改由Timeline缓存提供，但沿用拉模式的游标
+	byID := make(map[uuid.UUID]*models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+		if err := service.timelineCacheService.AddToTimeline(ctx, viewer, post.ID, 1, post.CreatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fetch := func(_ context.Context, ids []uuid.UUID) ([]*models.Post, error) {
+		found := make([]*models.Post, 0, len(ids))
+		for _, id := range ids {
+			found = append(found, byID[id])
+		}
+		return found, nil
+	}
+	next, _, _, err := service.timelineCacheService.GetTimelineHydrated(ctx, viewer, cursor, 10, fetch)
+	if err != nil {
+		t.Fatalf("GetTimelineHydrated: %v", err)
+	}
+	if len(next) != 2 || next[0].ID != posts[1].ID || next[1].ID != posts[2].ID {
+		t.Errorf("timeline page after pull cursor = %v, want the same-second post and then the older one", next)
+	}
+}
+
+func TestGetFeedServesStaleSnapshotOnOutage(t *testing.T) {
+	ctx := context.Background()
+	viewerID := uuid.New()
+	snapshotPosts := []*models.Post{{ID: uuid.New(), UserID: uuid.New()}, {ID: uuid.New(), UserID: uuid.New()}}
+
+	// newOutageService 数据库不可用：没有设置任何SQL预期，所有查询都返回错误
+	newOutageService := func(t *testing.T, snapshotTTL time.Duration) (*OptimizedFeedService, *miniredis.Miniredis) {
+		service, _, mr := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+			feed.SnapshotTTL = snapshotTTL
+		})
+		mr.Set("user_active:"+viewerID.String(), "1")
+		return service, mr
+	}
+
+	t.Run("first page falls back to the last good feed", func(t *testing.T) {
+		service, mr := newOutageService(t, time.Hour)
+		service.saveFeedSnapshot(viewerID, &FeedResponse{Posts: snapshotPosts, NextCursor: "next", HasMore: true})
+		if err := service.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if ttl := mr.TTL(feedSnapshotKey(viewerID)); ttl != time.Hour {
+			t.Errorf("snapshot TTL = %s, want 1h", ttl)
+		}
+
+		response, err := service.GetFeed(ctx, viewerID.String(), "", 20)
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v, want the stale snapshot", err)
+		}
+		if !response.Stale || len(response.Posts) != 2 || response.Posts[0].ID != snapshotPosts[0].ID || response.NextCursor != "next" {
+			t.Errorf("response = %+v, want the stale snapshot", response)
+		}
+
+		// 翻页请求不返回首页快照
+		if _, err := service.GetFeed(ctx, viewerID.String(), time.Now().Format(time.RFC3339Nano), 20); err == nil {
+			t.Error("expected error for a later page during the outage")
+		}
+	})
+
+	t.Run("no snapshot surfaces the error", func(t *testing.T) {
+		service, _ := newOutageService(t, time.Hour)
+		if _, err := service.GetFeed(ctx, viewerID.String(), "", 20); err == nil {
+			t.Error("expected error without a snapshot")
+		}
+	})
+
+	t.Run("disabled snapshots are neither written nor served", func(t *testing.T) {
+		service, mr := newOutageService(t, 0)
+		service.saveFeedSnapshot(viewerID, &FeedResponse{Posts: snapshotPosts})
+		if mr.Exists(feedSnapshotKey(viewerID)) {
+			t.Error("snapshot written while disabled")
+		}
+		data, err := json.Marshal(&FeedResponse{Posts: snapshotPosts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mr.Set(feedSnapshotKey(viewerID), string(data))
+		if _, err := service.GetFeed(ctx, viewerID.String(), "", 20); err == nil {
+			t.Error("expected error with snapshots disabled")
+		}
+	})
+}
+
+func TestGetFeedDegradedFromCacheWhenPullModeFails(t *testing.T) {
+	ctx := context.Background()
+	viewerID := uuid.New()
+
+	// Timeline中四条帖子从新到旧，快照里只有其中两条和一条已不在Timeline中的帖子
+	base := time.Now().Add(-time.Hour)
+	postIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	snapshot := &FeedResponse{Posts: []*models.Post{{ID: postIDs[3]}, {ID: uuid.New()}, {ID: postIDs[1]}}}
+
+	// newOutageService 数据库不可用：没有设置任何SQL预期，Timeline补全和拉模式都失败
+	newOutageService := func(t *testing.T, degradedFallback bool) *OptimizedFeedService {
+		service, _, mr := newOptimizedTestService(t, func(feed *config.FeedConfig) {
+			feed.DegradedFallback = degradedFallback
+			feed.SnapshotTTL = time.Hour
+		})
+		mr.Set("user_active:"+viewerID.String(), "1")
+		for i, postID := range postIDs {
+			if err := service.timelineCacheService.AddToTimeline(ctx, viewerID, postID, 1, base.Add(-time.Duration(i)*time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mr.Set(feedSnapshotKey(viewerID), string(data))
+		return service
+	}
+
+	t.Run("partial feed from cached posts", func(t *testing.T) {
+		service := newOutageService(t, true)
+
+		response, err := service.GetFeed(ctx, viewerID.String(), "", 10)
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v, want a degraded feed", err)
+		}
+		if !response.Degraded || response.Stale {
+			t.Errorf("degraded = %v, stale = %v; want a degraded live feed", response.Degraded, response.Stale)
+		}
+		// 按Timeline顺序返回快照中能找到的帖子
+		if len(response.Posts) != 2 || response.Posts[0].ID != postIDs[1] || response.Posts[1].ID != postIDs[3] {
+			t.Errorf("posts = %v, want %s then %s", response.Posts, postIDs[1], postIDs[3])
+		}
+
+		// 翻页同样可以降级，游标来自Timeline
+		response, err = service.GetFeed(ctx, viewerID.String(), "", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, err := service.GetFeed(ctx, viewerID.String(), response.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("GetFeed(next page) error = %v", err)
+		}
+		if !next.Degraded || len(next.Posts) != 1 || next.Posts[0].ID != postIDs[3] {
+			t.Errorf("next page = %+v, want degraded page with %s", next, postIDs[3])
+		}
+		if err := service.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("disabled falls back to the stale snapshot", func(t *testing.T) {
+		service := newOutageService(t, false)
+
+		response, err := service.GetFeed(ctx, viewerID.String(), "", 10)
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v", err)
+		}
+		if response.Degraded || !response.Stale || len(response.Posts) != 3 {
+			t.Errorf("response = %+v, want the whole stale snapshot", response)
+		}
+		if _, err := service.GetFeed(ctx, viewerID.String(), fmt.Sprintf("%d", base.Unix()), 10); err == nil {
+			t.Error("expected error for a later page without degraded fallback")
+		}
+	})
+}
+
+func TestGetTopFeedFallbackPages(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, nil)
+	ctx := context.Background()
+
+	viewerID, authorID := uuid.New(), uuid.New()
+	mr.Set("user_active:"+viewerID.String(), "1")
+
+	// 排序集合为空，只有时间线：三条帖子从新到旧
+	base := time.Now().Add(-time.Hour)
+	postIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, postID := range postIDs {
+		if err := service.timelineCacheService.AddToTimeline(ctx, viewerID, postID, 1, base.Add(-time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mr.Del(RankedTimelineKey(viewerID))
+
+	expectPage := func(postIDs ...uuid.UUID) {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "created_at"})
+		for _, postID := range postIDs {
+			rows.AddRow(postID, authorID, time.Now())
+		}
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+		mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}))
+	}
+
+	expectPage(postIDs[0], postIDs[1])
+	first, err := service.GetTopFeed(ctx, viewerID.String(), "", 2)
+	if err != nil {
+		t.Fatalf("GetTopFeed: %v", err)
+	}
+	if len(first.Posts) != 2 || first.Posts[0].ID != postIDs[0] || first.Posts[1].ID != postIDs[1] {
+		t.Fatalf("first page = %v, want the two newest posts", first.Posts)
+	}
+	// 游标必须是排序Feed能解析的offset，而不是时间线游标
+	if !first.HasMore || first.NextCursor != "2" {
+		t.Fatalf("first page has_more = %v, next_cursor = %q; want offset cursor 2", first.HasMore, first.NextCursor)
+	}
+
+	// 排序集合仍为空时下一页为空而不是游标错误
+	empty, err := service.GetTopFeed(ctx, viewerID.String(), first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("GetTopFeed(next page) error = %v", err)
+	}
+	if len(empty.Posts) != 0 || empty.HasMore {
+		t.Errorf("next page before rebuild = %+v, want empty", empty)
+	}
+
+	// 排序集合重建后按offset继续翻页
+	for i, postID := range postIDs {
+		mr.ZAdd(RankedTimelineKey(viewerID), float64(len(postIDs)-i), postID.String())
+	}
+	expectPage(postIDs[2])
+	second, err := service.GetTopFeed(ctx, viewerID.String(), first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("GetTopFeed(next page) error = %v", err)
+	}
+	if len(second.Posts) != 1 || second.Posts[0].ID != postIDs[2] || second.HasMore || second.NextCursor != "" {
+		t.Errorf("second page = %+v, want the last post and no more pages", second)
+	}
+	if err := service.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// 管理员查看到的Feed与用户自己请求的结果一致，但不记录该用户的曝光
+func TestInspectFeedMatchesUserFeed(t *testing.T) {
+	service, mock, mr := newOptimizedTestService(t, nil)
+	ctx := context.Background()
+
+	viewerID, authorID := uuid.New(), uuid.New()
+	mr.Set("user_active:"+viewerID.String(), "1")
+	base := time.Now().Add(-time.Hour)
+	postIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, postID := range postIDs {
+		if err := service.timelineCacheService.AddToTimeline(ctx, viewerID, postID, float64(len(postIDs)-i), base.Add(-time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectPage := func() {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "content", "like_count", "created_at"})
+		for i, postID := range postIDs[:2] {
+			rows.AddRow(postID, authorID, fmt.Sprintf("post %d", i), i, base.Add(-time.Duration(i)*time.Minute))
+		}
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(authorID, "author"))
+	}
+
+	expectPage()
+	mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}).AddRow(postIDs[1], "like"))
+	own, err := service.GetFeed(ctx, viewerID.String(), "", 2)
+	if err != nil {
+		t.Fatalf("GetFeed() error = %v", err)
+	}
+	if err := service.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if impressions, _ := mr.HKeys(pendingImpressionsKey); len(impressions) != 2 {
+		t.Fatalf("impressions = %v, want the user's own views recorded", impressions)
+	}
+	// 清掉曝光计数和去重标记，查看后应仍然没有
+	mr.Del(pendingImpressionsKey)
+	for _, key := range mr.Keys() {
+		if strings.HasPrefix(key, "post_view:") {
+			mr.Del(key)
+		}
+	}
+
+	// 回应状态已缓存，查看时不再查询likes
+	expectPage()
+	inspected, err := service.InspectFeed(ctx, viewerID.String(), "", 2, false)
+	if err != nil {
+		t.Fatalf("InspectFeed() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := json.Marshal(own)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(inspected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("InspectFeed() = %s\nwant the user's own feed %s", got, want)
+	}
+	if len(own.Posts) != 2 || own.Posts[1].IsLiked == nil || !*own.Posts[1].IsLiked {
+		t.Errorf("own feed = %s, want two posts with the second liked", want)
+	}
+	if mr.Exists(pendingImpressionsKey) {
+		t.Error("inspection recorded impressions for the user")
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// 每轮最多重算的帖子数
+	MaxScoreRecomputeBatch = 1000
+)
+
+// StartScoreRecomputeJob 按rank_update_interval定期重算有新互动帖子的分数
+// 间隔支持热更新，每轮结束后按最新配置调整
+func (s *FeedService) StartScoreRecomputeJob(ctx context.Context) {
+	interval := s.config.Feed().RankUpdateInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastRun := time.Now().Add(-interval)
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Score recompute job stopped")
+			return
+		case now := <-ticker.C:
+			if _, err := s.RecomputeScores(ctx, lastRun); err != nil {
+				s.logger.WithError(err).Error("Score recompute job failed")
+				continue
+			}
+			lastRun = now
+
+			if next := s.config.Feed().RankUpdateInterval; next != interval {
+				s.logger.WithFields(map[string]interface{}{
+					"old_interval": interval.String(),
+					"new_interval": next.String(),
+				}).Info("Score recompute interval changed")
+				interval = next
+				ticker.Reset(interval)
+			}
+		}
+	}
+}
+
+// RecomputeScores 重算since之后有点赞/评论的帖子分数，同步更新posts、timelines和Redis中的排序时间线
+func (s *FeedService) RecomputeScores(ctx context.Context, since time.Time) (int, error) {
+	posts, err := s.postRepo.GetRecentlyEngaged(ctx, since, MaxScoreRecomputeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, post := range posts {
+		score := s.calculatePostScore(post, &post.User)
+
+		if err := s.postRepo.UpdateScore(ctx, post.ID, score); err != nil {
+			s.logger.WithError(err).WithField("post_id", post.ID).Error("Failed to update post score")
+			continue
+		}
+		if err := s.timelineRepo.UpdateScoreByPostID(ctx, post.ID, score); err != nil {
+			s.logger.WithError(err).WithField("post_id", post.ID).Error("Failed to update timeline score")
+			continue
+		}
+		if err := s.updateRankedTimelines(ctx, post, score); err != nil {
+			s.logger.WithError(err).WithField("post_id", post.ID).Error("Failed to update ranked timeline score")
+		}
+		updated++
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"candidates": len(posts),
+		"updated":    updated,
+	}).Info("Post scores recomputed")
+
+	return updated, nil
+}
+
+// updateRankedTimelines 更新排序时间线中该帖子的分数。帖子只会被推送给作者和关注者，
+// 使用ZADD XX只更新已包含该帖子的时间线，不会把帖子加入没有收到它的时间线
+func (s *FeedService) updateRankedTimelines(ctx context.Context, post *models.Post, score float64) error {
+	followerIDs, err := s.followRepo.GetFollowerIDs(ctx, post.UserID, int(s.config.Feed().MaxFeedSize))
+	if err != nil {
+		return err
+	}
+
+	member := &redis.Z{Score: score, Member: post.ID.String()}
+	pipe := s.cache.Pipeline()
+	for _, userID := range append(followerIDs, post.UserID) {
+		pipe.ZAddXX(ctx, RankedTimelineKey(userID), member)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update ranked timelines: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestRecomputeScoresUpdatesRankedTimelines(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(nil)
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), nil, nil, redisClient, nil, cfg, logger.NewLogger(), nil, nil,
+	)
+
+	authorID, postID := uuid.New(), uuid.New()
+	recipient, other := uuid.New(), uuid.New()
+	mr.ZAdd(RankedTimelineKey(recipient), 1, postID.String())
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "like_count", "created_at"}).
+			AddRow(postID, authorID, 500, time.Now()))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "followers"}).AddRow(authorID, 10))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "score"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "timelines" SET "score"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT "follower_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id"}).AddRow(recipient).AddRow(other))
+
+	updated, err := service.RecomputeScores(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil || updated != 1 {
+		t.Fatalf("RecomputeScores() = %d, %v", updated, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	score, err := mr.ZScore(RankedTimelineKey(recipient), postID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score <= 1 {
+		t.Errorf("ranked score = %v, want the recomputed engagement score", score)
+	}
+	if mr.Exists(RankedTimelineKey(other)) {
+		t.Errorf("post added to a ranked timeline that never received it")
+	}
+}
+
+// scoreArg 记录写入数据库的分数参数
+type scoreArg struct{ value *float64 }
+
+func (a scoreArg) Match(v driver.Value) bool {
+	score, ok := v.(float64)
+	*a.value = score
+	return ok
+}
+
+func TestRecomputeScoresRaisesEngagedPost(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), nil, nil, redisClient, nil, newTestConfig(nil), logger.NewLogger(), nil, nil,
+	)
+
+	author := &models.User{ID: uuid.New(), Followers: 10}
+	viewerID := uuid.New()
+	popular := &models.Post{ID: uuid.New(), UserID: author.ID, CreatedAt: time.Now().Add(-3 * time.Hour)}
+	quiet := &models.Post{ID: uuid.New(), UserID: author.ID, CreatedAt: time.Now().Add(-10 * time.Minute)}
+
+	// 发布时的初始分数：较新的帖子排在前面
+	popularStored := service.calculatePostScore(popular, author)
+	quietStored := service.calculatePostScore(quiet, author)
+	if popularStored >= quietStored {
+		t.Fatalf("initial scores popular=%v quiet=%v, want the newer post first", popularStored, quietStored)
+	}
+	mr.ZAdd(RankedTimelineKey(viewerID), popularStored, popular.ID.String())
+	mr.ZAdd(RankedTimelineKey(viewerID), quietStored, quiet.ID.String())
+
+	// 较早的帖子获得大量点赞后进入重算批次
+	var postScore, timelineScore float64
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "like_count", "created_at"}).
+			AddRow(popular.ID, author.ID, 800, popular.CreatedAt))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "followers"}).AddRow(author.ID, author.Followers))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "score"=\$1 WHERE id = \$2`).
+		WithArgs(scoreArg{&postScore}, popular.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "timelines" SET "score"=\$1 WHERE post_id = \$2`).
+		WithArgs(scoreArg{&timelineScore}, popular.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT "follower_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"follower_id"}).AddRow(viewerID))
+
+	updated, err := service.RecomputeScores(context.Background(), time.Now().Add(-time.Hour))
+	if err != nil || updated != 1 {
+		t.Fatalf("RecomputeScores() = %d, %v", updated, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据库中按score排序的时间线：被点赞的帖子超过未重算的新帖子
+	if postScore != timelineScore {
+		t.Errorf("posts score %v != timelines score %v", postScore, timelineScore)
+	}
+	if timelineScore <= quietStored {
+		t.Errorf("recomputed score %v, want above the quiet post's %v", timelineScore, quietStored)
+	}
+
+	members, err := mr.ZMembers(RankedTimelineKey(viewerID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[1] != popular.ID.String() {
+		t.Errorf("ranked timeline = %v, want %s ranked highest", members, popular.ID)
+	}
+}
+
+func TestScoreRecomputeJobUsesConfiguredInterval(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, _ := newTestRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) { feed.RankUpdateInterval = 20 * time.Millisecond })
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), nil, nil, redisClient, nil, cfg, logger.NewLogger(), nil, nil,
+	)
+
+	// 默认间隔为5分钟，只有使用配置的间隔才会在测试期间触发两次重算
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.StartScoreRecomputeJob(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for mock.ExpectationsWereMet() != nil {
+		if time.Now().After(deadline) {
+			cancel()
+			<-done
+			t.Fatalf("score recompute did not run on the configured interval: %v", mock.ExpectationsWereMet())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+)
+
+func TestRestorePost(t *testing.T) {
+	ctx := context.Background()
+	authorID, postID := uuid.New(), uuid.New()
+
+	newService := func(t *testing.T) (*FeedService, sqlmock.Sqlmock, *fakePublisher) {
+		db, mock := newTestDB(t)
+		redisClient, _ := newTestRedis(t)
+		publisher := &fakePublisher{}
+		cfg := newTestConfig(func(feed *config.FeedConfig) {
+			feed.RestoreGraceWindow = time.Hour
+			// 帖子早于推送窗口，恢复时不重新扇出
+			feed.MaxPushAge = time.Hour
+		})
+		return &FeedService{
+			postRepo: repository.NewPostRepository(db),
+			userRepo: repository.NewUserRepository(db),
+			cache:    redisClient,
+			producer: publisher,
+			config:   cfg,
+			logger:   logger.NewLogger(),
+		}, mock, publisher
+	}
+	expectDeletedPost := func(mock sqlmock.Sqlmock) {
+		removedAt := time.Now().Add(-10 * time.Minute)
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(id = \$1 AND is_deleted = \$2\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "is_deleted", "removed_at", "created_at"}).
+				AddRow(postID, authorID, true, removedAt, time.Now().Add(-48*time.Hour)))
+	}
+	expectRestore := func(mock sqlmock.Sqlmock, rows int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "posts" SET "is_deleted"=\$1,"removed_at"=\$2,"updated_at"=\$3 WHERE \(id = \$4 AND is_deleted = \$5 AND removed_at >= \$6\)`).
+			WillReturnResult(sqlmock.NewResult(0, rows))
+		mock.ExpectCommit()
+	}
+
+	t.Run("restores inside the window", func(t *testing.T) {
+		service, mock, publisher := newService(t)
+		expectDeletedPost(mock)
+		expectRestore(mock, 1)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "followers"}).AddRow(authorID, 10))
+
+		post, err := service.RestorePost(ctx, authorID.String(), postID.String())
+		if err != nil {
+			t.Fatalf("RestorePost: %v", err)
+		}
+		if post.IsDeleted || post.RemovedAt != nil || post.User.ID != authorID {
+			t.Errorf("restored post = %+v", post)
+		}
+		if len(publisher.events) != 1 || publisher.events[0].Type != queue.EventPostCreated {
+			t.Errorf("published events = %v, want one post_created", publisher.events)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("window expired", func(t *testing.T) {
+		service, mock, publisher := newService(t)
+		expectDeletedPost(mock)
+		// 删除时间早于窗口，条件UPDATE没有命中
+		expectRestore(mock, 0)
+
+		_, err := service.RestorePost(ctx, authorID.String(), postID.String())
+		if !errors.Is(err, ErrRestoreWindowExpired) {
+			t.Fatalf("expected ErrRestoreWindowExpired, got %v", err)
+		}
+		if len(publisher.events) != 0 {
+			t.Errorf("published events for an expired restore: %v", publisher.events)
+		}
+	})
+
+	t.Run("non-author", func(t *testing.T) {
+		service, mock, _ := newService(t)
+		expectDeletedPost(mock)
+
+		_, err := service.RestorePost(ctx, uuid.NewString(), postID.String())
+		if !errors.Is(err, apperrors.ErrPermissionDenied) {
+			t.Fatalf("expected permission denied, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestGetPostByIDCache(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	service := &FeedService{
+		postRepo:     repository.NewPostRepository(db),
+		timelineRepo: repository.NewTimelineRepository(db),
+		cache:        redisClient,
+		producer:     &fakePublisher{},
+		config:       newTestConfig(func(feed *config.FeedConfig) { feed.PostCacheTTL = time.Hour }),
+		logger:       logger.NewLogger(),
+	}
+	ctx := context.Background()
+	authorID, postID := uuid.New(), uuid.New()
+	expectPost := func(likeCount int) {
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(id = \$1 AND is_deleted = \$2\)`).
+			WithArgs(postID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "content", "like_count"}).
+				AddRow(postID, authorID, "hello", likeCount))
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+	}
+
+	// 第一次读取查库并写入缓存，之后直接命中缓存
+	expectPost(1)
+	for i := 0; i < 2; i++ {
+		post, err := service.GetPostByID(ctx, postID.String())
+		if err != nil {
+			t.Fatalf("GetPostByID: %v", err)
+		}
+		if post.ID != postID || post.LikeCount != 1 {
+			t.Errorf("read %d: post = %+v", i, post)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL(PostCacheKey(postID.String())); ttl != time.Hour {
+		t.Errorf("post cache TTL = %s, want 1h", ttl)
+	}
+
+	// 计数变化后单帖缓存失效，下一次读取拿到新值
+	service.invalidatePost(ctx, postID.String())
+	expectPost(2)
+	post, err := service.GetPostByID(ctx, postID.String())
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if post.LikeCount != 2 {
+		t.Errorf("like_count after invalidation = %d, want 2", post.LikeCount)
+	}
+
+	// 删除帖子后缓存失效，不再返回已删除的帖子
+	expectPost(2)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "is_deleted"=\$1,"removed_at"=\$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "timelines" WHERE post_id = \$1`).
+		WithArgs(postID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	if err := service.DeletePost(ctx, authorID.String(), postID.String()); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if mr.Exists(PostCacheKey(postID.String())) {
+		t.Fatal("post cache not invalidated after delete")
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(id = \$1 AND is_deleted = \$2\)`).
+		WithArgs(postID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := service.GetPostByID(ctx, postID.String()); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("GetPostByID after delete: err = %v, want not found", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
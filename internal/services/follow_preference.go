@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/google/uuid"
+)
+
+// FollowPreference 与某个用户之间关注关系的个人设置，未提供的字段保持不变。
+// MuteNotifications作用于当前用户对对方的关注；CloseFriend作用于对方对当前用户的关注，
+// 即只有作者自己能把关注者标记为密友
+type FollowPreference struct {
+	MuteNotifications *bool `json:"mute_notifications"`
+	CloseFriend       *bool `json:"close_friend"`
+}
+
+// UpdateFollowPreferences 更新userID与otherID之间的关注设置
+func (s *UserService) UpdateFollowPreferences(ctx context.Context, userID, otherID string, pref *FollowPreference) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+	otherUUID, err := uuid.Parse(otherID)
+	if err != nil {
+		return apperrors.InvalidInput("invalid user ID")
+	}
+	if userUUID == otherUUID {
+		return apperrors.InvalidInput("cannot set follow preferences for yourself")
+	}
+	if pref.MuteNotifications == nil && pref.CloseFriend == nil {
+		return apperrors.InvalidInput("mute_notifications or close_friend is required")
+	}
+
+	if pref.MuteNotifications != nil {
+		found, err := s.followRepo.SetMuteNotifications(ctx, userUUID, otherUUID, *pref.MuteNotifications)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return apperrors.NotFound("not following this user")
+		}
+	}
+
+	if pref.CloseFriend != nil {
+		found, err := s.followRepo.SetCloseFriend(ctx, otherUUID, userUUID, *pref.CloseFriend)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return apperrors.NotFound("this user is not following you")
+		}
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"user_id":  userID,
+		"other_id": otherID,
+	}).Info("Follow preferences updated")
+
+	return nil
+}
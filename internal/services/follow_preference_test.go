@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestUpdateFollowPreferences(t *testing.T) {
+	ctx := context.Background()
+	userID, otherID := uuid.New(), uuid.New()
+	yes := true
+
+	newService := func(t *testing.T) (*UserService, sqlmock.Sqlmock) {
+		db, mock := newTestDB(t)
+		return &UserService{followRepo: repository.NewFollowRepository(db), logger: logger.NewLogger()}, mock
+	}
+	expectUpdate := func(mock sqlmock.Sqlmock, column string, followerID, followingID uuid.UUID, rows int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "follows" SET "`+column+`"=\$1 WHERE \(follower_id = \$2 AND following_id = \$3\)`).
+			WithArgs(true, followerID, followingID).
+			WillReturnResult(sqlmock.NewResult(0, rows))
+		mock.ExpectCommit()
+	}
+
+	t.Run("mute applies to own follow", func(t *testing.T) {
+		service, mock := newService(t)
+		expectUpdate(mock, "mute_notifications", userID, otherID, 1)
+
+		if err := service.UpdateFollowPreferences(ctx, userID.String(), otherID.String(), &FollowPreference{MuteNotifications: &yes}); err != nil {
+			t.Fatalf("UpdateFollowPreferences: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("close friend applies to the other user's follow", func(t *testing.T) {
+		service, mock := newService(t)
+		expectUpdate(mock, "close_friend", otherID, userID, 1)
+
+		if err := service.UpdateFollowPreferences(ctx, userID.String(), otherID.String(), &FollowPreference{CloseFriend: &yes}); err != nil {
+			t.Fatalf("UpdateFollowPreferences: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("missing follow is not found", func(t *testing.T) {
+		service, mock := newService(t)
+		expectUpdate(mock, "close_friend", otherID, userID, 0)
+
+		err := service.UpdateFollowPreferences(ctx, userID.String(), otherID.String(), &FollowPreference{CloseFriend: &yes})
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			t.Fatalf("expected not found, got %v", err)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		service, _ := newService(t)
+		for name, err := range map[string]error{
+			"self":  service.UpdateFollowPreferences(ctx, userID.String(), userID.String(), &FollowPreference{MuteNotifications: &yes}),
+			"empty": service.UpdateFollowPreferences(ctx, userID.String(), otherID.String(), &FollowPreference{}),
+		} {
+			if !errors.Is(err, apperrors.ErrInvalidInput) {
+				t.Errorf("%s: expected invalid input, got %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+)
+
+// newUserTestService 基于sqlmock和miniredis的UserService，事件写入返回的fakePublisher
+func newUserTestService(t *testing.T) (*UserService, sqlmock.Sqlmock, *miniredis.Miniredis, *fakePublisher) {
+	t.Helper()
+
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	producer := &fakePublisher{}
+	service := NewUserService(repository.NewUserRepository(db), repository.NewFollowRepository(db), redisClient, nil, 0, logger.NewLogger())
+	service.producer = producer
+	return service, mock, mr, producer
+}
+
+func expectIsFollowing(mock sqlmock.Sqlmock, followerID, followingID uuid.UUID, following bool) {
+	count := 0
+	if following {
+		count = 1
+	}
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "follows" WHERE \(follower_id = \$1 AND following_id = \$2\)`).
+		WithArgs(followerID, followingID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func expectUserLookup(mock sqlmock.Sqlmock, userID uuid.UUID) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userID))
+}
+
+func TestFollowBack(t *testing.T) {
+	ctx := context.Background()
+	userID, followerID := uuid.New(), uuid.New()
+
+	t.Run("follows back a real follower", func(t *testing.T) {
+		service, mock, mr, producer := newUserTestService(t)
+		mr.Set(ProfileCacheKey(userID.String()), "{}")
+
+		expectIsFollowing(mock, followerID, userID, true)
+		expectUserLookup(mock, userID)
+		expectUserLookup(mock, followerID)
+		mock.ExpectQuery(`SELECT \* FROM "follows" WHERE \(follower_id = \$1 AND following_id = \$2\)`).
+			WithArgs(userID, followerID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "follows"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "following"=GREATEST\(following \+ \$1, 0\) WHERE id = \$2`).
+			WithArgs(1, userID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "followers"=GREATEST\(followers \+ \$1, 0\) WHERE id = \$2`).
+			WithArgs(1, followerID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := service.FollowBack(ctx, userID.String(), followerID.String()); err != nil {
+			t.Fatalf("FollowBack: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(producer.events) != 1 || producer.events[0].Type != queue.EventFollowCreated {
+			t.Fatalf("events = %+v, want one follow created event", producer.events)
+		}
+		data := producer.events[0].Data.(queue.FollowEventData)
+		if data.FollowerID != userID.String() || data.FollowingID != followerID.String() {
+			t.Errorf("follow event = %+v, want %s following %s", data, userID, followerID)
+		}
+		if mr.Exists(ProfileCacheKey(userID.String())) {
+			t.Error("profile cache not invalidated after follow-back")
+		}
+	})
+
+	t.Run("rejects a non-follower", func(t *testing.T) {
+		service, mock, _, producer := newUserTestService(t)
+		expectIsFollowing(mock, followerID, userID, false)
+
+		err := service.FollowBack(ctx, userID.String(), followerID.String())
+		if !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Fatalf("FollowBack() error = %v, want invalid input", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(producer.events) != 0 {
+			t.Errorf("events = %+v, want none", producer.events)
+		}
+	})
+}
+
+func TestGetFollowSuggestionsKeepsRanking(t *testing.T) {
+	service, mock, _, _ := newUserTestService(t)
+	userID := uuid.New()
+	top, second, deleted := uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT follows.following_id AS user_id, COUNT\(\*\) AS mutual_count FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "mutual_count"}).
+			AddRow(top, 5).AddRow(deleted, 3).AddRow(second, 2))
+	// 用户查询不保证顺序，被删除的候选不返回
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).
+			AddRow(second, "second").AddRow(top, "top"))
+
+	suggestions, err := service.GetFollowSuggestions(context.Background(), userID.String(), 10)
+	if err != nil {
+		t.Fatalf("GetFollowSuggestions: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("got %d suggestions, want 2", len(suggestions))
+	}
+	if suggestions[0].User.ID != top || suggestions[0].MutualCount != 5 ||
+		suggestions[1].User.ID != second || suggestions[1].MutualCount != 2 {
+		t.Errorf("suggestions = [%s/%d %s/%d], want [%s/5 %s/2]",
+			suggestions[0].User.ID, suggestions[0].MutualCount, suggestions[1].User.ID, suggestions[1].MutualCount, top, second)
+	}
+}
+
+func TestBatchFollowMixedResults(t *testing.T) {
+	service, mock, _, producer := newUserTestService(t)
+	followerID := uuid.New()
+	first, second, missing, already := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first).AddRow(already).AddRow(second))
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(already))
+	// 两个有效目标在一个事务中创建，计数按实际创建的数量调整
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()).AddRow(uuid.New()))
+	mock.ExpectExec(`UPDATE "users" SET "following"=GREATEST\(following \+ \$1, 0\) WHERE id = \$2`).
+		WithArgs(2, followerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "users" SET "followers"=GREATEST\(followers \+ \$1, 0\) WHERE id IN \(\$2,\$3\)`).
+		WithArgs(1, first, second).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	requested := []string{first.String(), missing.String(), already.String(), "not-a-uuid", followerID.String(), first.String(), second.String()}
+	results, err := service.BatchFollow(context.Background(), followerID.String(), requested)
+	if err != nil {
+		t.Fatalf("BatchFollow: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []BatchFollowResult{
+		{UserID: first.String(), Success: true},
+		{UserID: missing.String(), Error: "user not found"},
+		{UserID: already.String(), Error: "already following"},
+		{UserID: "not-a-uuid", Error: "invalid user ID"},
+		{UserID: followerID.String(), Error: "cannot follow yourself"},
+		{UserID: second.String(), Success: true},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if *results[i] != w {
+			t.Errorf("result %d = %+v, want %+v", i, *results[i], w)
+		}
+	}
+	if len(producer.events) != 2 {
+		t.Errorf("published %d events, want one per followed user", len(producer.events))
+	}
+}
+
+func TestBatchUnfollowMixedResults(t *testing.T) {
+	service, mock, mr, producer := newUserTestService(t)
+	followerID := uuid.New()
+	followed, notFollowed := uuid.New(), uuid.New()
+	mr.ZAdd(activeFollowersKey(followed.String()), 1, followerID.String())
+
+	// 只有实际删除的关注关系调整计数
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE "follows" SET "deleted_at"=\$1 WHERE \(follower_id = \$2 AND following_id IN \(\$3,\$4\)\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "follower_id", "following_id"}).AddRow(uuid.New(), followerID, followed))
+	mock.ExpectExec(`UPDATE "users" SET "following"=GREATEST\(following - \$1, 0\) WHERE id = \$2`).
+		WithArgs(1, followerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "users" SET "followers"=GREATEST\(followers - \$1, 0\) WHERE id IN \(\$2\)`).
+		WithArgs(1, followed).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	results, err := service.BatchUnfollow(context.Background(), followerID.String(), []string{followed.String(), notFollowed.String()})
+	if err != nil {
+		t.Fatalf("BatchUnfollow: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || !results[0].Success || results[1].Success || results[1].Error != "not following" {
+		t.Errorf("results = %+v, %+v", *results[0], *results[1])
+	}
+	if len(producer.events) != 1 || producer.events[0].Type != queue.EventFollowDeleted {
+		t.Errorf("events = %+v, want one follow deleted event", producer.events)
+	}
+	if members, _ := mr.ZMembers(activeFollowersKey(followed.String())); len(members) != 0 {
+		t.Errorf("follower still in active followers of unfollowed user: %v", members)
+	}
+}
+
+func TestBatchFollowSizeCap(t *testing.T) {
+	service, _, _, _ := newUserTestService(t)
+	ids := make([]string, MaxBatchFollowSize+1)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+	}
+
+	for name, batch := range map[string]func(context.Context, string, []string) ([]*BatchFollowResult, error){
+		"follow":   service.BatchFollow,
+		"unfollow": service.BatchUnfollow,
+	} {
+		if _, err := batch(context.Background(), uuid.NewString(), ids); !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Errorf("%s over the cap: error = %v, want invalid input", name, err)
+		}
+		if _, err := batch(context.Background(), uuid.NewString(), nil); !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Errorf("%s with no IDs: error = %v, want invalid input", name, err)
+		}
+	}
+}
+
+func TestImportFollowingPartialResolution(t *testing.T) {
+	service, mock, _, producer := newUserTestService(t)
+	followerID := uuid.New()
+	byID, alice, already, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(byID).AddRow(already).AddRow(followerID))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username IN \(\$1,\$2,\$3\)`).
+		WithArgs("alice", "bob", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(alice, "alice"))
+	mock.ExpectQuery(`SELECT "following_id" FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"following_id"}).AddRow(already))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()).AddRow(uuid.New()))
+	mock.ExpectExec(`UPDATE "users" SET "following"=GREATEST\(following \+ \$1, 0\) WHERE id = \$2`).
+		WithArgs(2, followerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "users" SET "followers"=GREATEST\(followers \+ \$1, 0\) WHERE id IN \(\$2,\$3\)`).
+		WithArgs(1, byID, alice).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// 用户名和ID混合：重复、已关注和自己算跳过，不存在的用户名、ID和空白条目算未找到
+	identifiers := []string{byID.String(), "@alice", "bob", missing.String(), "alice", already.String(), followerID.String(), "   "}
+	result, err := service.ImportFollowing(context.Background(), followerID.String(), identifiers)
+	if err != nil {
+		t.Fatalf("ImportFollowing: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ImportFollowingResult{Followed: 2, Skipped: 3, NotFound: 3}
+	if *result != want {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+	if len(producer.events) != 2 {
+		t.Errorf("published %d events, want one per imported follow", len(producer.events))
+	}
+}
+
+func TestImportFollowingLimits(t *testing.T) {
+	service, mock, _, _ := newUserTestService(t)
+	followerID := uuid.NewString()
+
+	tooMany := make([]string, MaxImportFollowingSize+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewString()
+	}
+	for name, identifiers := range map[string][]string{"empty": nil, "over the cap": tooMany} {
+		if _, err := service.ImportFollowing(context.Background(), followerID, identifiers); !errors.Is(err, apperrors.ErrInvalidInput) {
+			t.Errorf("%s: error = %v, want invalid input", name, err)
+		}
+	}
+
+	// 全部都不存在时不创建关注
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}))
+	result, err := service.ImportFollowing(context.Background(), followerID, []string{"ghost"})
+	if err != nil {
+		t.Fatalf("ImportFollowing: %v", err)
+	}
+	if result.Followed != 0 || result.NotFound != 1 {
+		t.Errorf("result = %+v, want one not found", *result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
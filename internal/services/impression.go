@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// 同一用户在窗口内重复看到同一帖子只计一次曝光
+	ImpressionDedupWindow = 30 * time.Minute
+	// 曝光计数刷入数据库的间隔
+	ImpressionFlushInterval = 1 * time.Minute
+
+	// hash tag保证pending和flushing在Redis Cluster中位于同一slot，RENAME才能执行
+	pendingImpressionsKey  = "{post_views}:pending"
+	flushingImpressionsKey = "{post_views}:flushing"
+	flushLockKey           = "post_views:flush_lock"
+)
+
+// ImpressionTracker 帖子曝光计数：先在Redis中累加，定期批量刷入数据库
+type ImpressionTracker struct {
+	cache    *cache.RedisClient
+	postRepo *repository.PostRepository
+	logger   *logger.Logger
+}
+
+func NewImpressionTracker(cache *cache.RedisClient, postRepo *repository.PostRepository, logger *logger.Logger) *ImpressionTracker {
+	return &ImpressionTracker{
+		cache:    cache,
+		postRepo: postRepo,
+		logger:   logger,
+	}
+}
+
+// Record 记录viewerID对一批帖子的曝光，窗口内重复曝光会被忽略
+func (t *ImpressionTracker) Record(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	// 第一轮：SETNX去重
+	pipe := t.cache.Pipeline()
+	dedup := make([]*redis.BoolCmd, len(postIDs))
+	for i, postID := range postIDs {
+		key := fmt.Sprintf("post_view:%s:%s", viewerID.String(), postID.String())
+		dedup[i] = pipe.SetNX(ctx, key, 1, ImpressionDedupWindow)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dedup impressions: %w", err)
+	}
+
+	// 第二轮：只为首次曝光累加计数
+	pipe = t.cache.Pipeline()
+	counted := 0
+	for i, cmd := range dedup {
+		if cmd.Val() {
+			pipe.HIncrBy(ctx, pendingImpressionsKey, postIDs[i].String(), 1)
+			counted++
+		}
+	}
+	if counted == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record impressions: %w", err)
+	}
+
+	return nil
+}
+
+// Flush 将累加的曝光数刷入数据库，返回刷入的帖子数
+// 先把待刷入的hash改名，避免与新写入的计数冲突；刷入成功的字段逐个删除，失败时下轮重试
+func (t *ImpressionTracker) Flush(ctx context.Context) (int, error) {
+	// 多个进程都可能运行刷入任务，用锁保证同一时间只有一个在刷
+	locked, err := t.cache.SetNX(ctx, flushLockKey, 1, ImpressionFlushInterval)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire impression flush lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer t.cache.Delete(ctx, flushLockKey)
+
+	exists, err := t.cache.Exists(ctx, flushingImpressionsKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check flushing impressions: %w", err)
+	}
+	if exists == 0 {
+		pending, err := t.cache.Exists(ctx, pendingImpressionsKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check pending impressions: %w", err)
+		}
+		if pending == 0 {
+			return 0, nil
+		}
+		if err := t.cache.Rename(ctx, pendingImpressionsKey, flushingImpressionsKey); err != nil {
+			return 0, fmt.Errorf("failed to rotate pending impressions: %w", err)
+		}
+	}
+
+	counts, err := t.cache.HGetAll(ctx, flushingImpressionsKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending impressions: %w", err)
+	}
+
+	flushed := 0
+	for field, value := range counts {
+		postID, err := uuid.Parse(field)
+		if err != nil {
+			t.cache.HDel(ctx, flushingImpressionsKey, field)
+			continue
+		}
+		delta, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || delta <= 0 {
+			t.cache.HDel(ctx, flushingImpressionsKey, field)
+			continue
+		}
+
+		if err := t.postRepo.IncrementViewCount(ctx, postID, delta); err != nil {
+			t.logger.WithError(err).WithField("post_id", postID).Error("Failed to flush post impressions")
+			continue
+		}
+		if err := t.cache.HDel(ctx, flushingImpressionsKey, field); err != nil {
+			t.logger.WithError(err).WithField("post_id", postID).Error("Failed to clear flushed impressions")
+		}
+		flushed++
+	}
+
+	return flushed, nil
+}
+
+// StartFlushJob 定期将曝光计数刷入数据库
+func (t *ImpressionTracker) StartFlushJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Info("Impression flush job stopped")
+			return
+		case <-ticker.C:
+			flushed, err := t.Flush(ctx)
+			if err != nil {
+				t.logger.WithError(err).Error("Impression flush job failed")
+				continue
+			}
+			if flushed > 0 {
+				t.logger.WithField("posts", flushed).Info("Post impressions flushed")
+			}
+		}
+	}
+}
+
+// postIDsOf 提取帖子ID列表
+func postIDsOf(posts []*models.Post) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestImpressionRecordDedupsWithinWindow(t *testing.T) {
+	redisClient, mr := newTestRedis(t)
+	tracker := NewImpressionTracker(redisClient, nil, logger.NewLogger())
+	ctx := context.Background()
+	viewer, other := uuid.New(), uuid.New()
+	postA, postB := uuid.New(), uuid.New()
+
+	// 同一用户窗口内重复看到postA只计一次，其他用户单独计数
+	for i := 0; i < 3; i++ {
+		if err := tracker.Record(ctx, viewer, []uuid.UUID{postA}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := tracker.Record(ctx, viewer, []uuid.UUID{postA, postB}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := tracker.Record(ctx, other, []uuid.UUID{postA}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	assertPendingImpressions(t, tracker, map[uuid.UUID]string{postA: "2", postB: "1"})
+
+	// 窗口过期后再次曝光重新计数
+	mr.FastForward(ImpressionDedupWindow)
+	if err := tracker.Record(ctx, viewer, []uuid.UUID{postA}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	assertPendingImpressions(t, tracker, map[uuid.UUID]string{postA: "3", postB: "1"})
+}
+
+func TestImpressionFlushWritesViewCount(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	tracker := NewImpressionTracker(redisClient, repository.NewPostRepository(db), logger.NewLogger())
+	ctx := context.Background()
+	postID := uuid.New()
+
+	for i := 0; i < 3; i++ {
+		if err := tracker.Record(ctx, uuid.New(), []uuid.UUID{postID}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// 数据库失败时计数保留到下一轮
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "view_count"=view_count \+ \$1 WHERE id = \$2`).
+		WithArgs(3, postID).
+		WillReturnError(errors.New("db down"))
+	mock.ExpectRollback()
+	if flushed, err := tracker.Flush(ctx); err != nil || flushed != 0 {
+		t.Fatalf("Flush() = %d, %v; want nothing flushed", flushed, err)
+	}
+	if got := mr.HGet(flushingImpressionsKey, postID.String()); got != "3" {
+		t.Fatalf("flushing count after failure = %q, want 3", got)
+	}
+
+	// 失败期间新的曝光进入pending，不与待重试的计数混在一起
+	if err := tracker.Record(ctx, uuid.New(), []uuid.UUID{postID}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "view_count"=view_count \+ \$1 WHERE id = \$2`).
+		WithArgs(3, postID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if flushed, err := tracker.Flush(ctx); err != nil || flushed != 1 {
+		t.Fatalf("Flush() = %d, %v; want one post flushed", flushed, err)
+	}
+	if mr.Exists(flushingImpressionsKey) {
+		t.Error("flushed counts were not cleared")
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "view_count"=view_count \+ \$1 WHERE id = \$2`).
+		WithArgs(1, postID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if flushed, err := tracker.Flush(ctx); err != nil || flushed != 1 {
+		t.Fatalf("Flush() = %d, %v; want the pending view flushed", flushed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	// 没有待刷入的计数时不访问数据库
+	if flushed, err := tracker.Flush(ctx); err != nil || flushed != 0 {
+		t.Errorf("empty Flush() = %d, %v", flushed, err)
+	}
+}
+
+// assertPendingImpressions 检查待刷入的曝光计数
+func assertPendingImpressions(t *testing.T, tracker *ImpressionTracker, want map[uuid.UUID]string) {
+	t.Helper()
+
+	counts, err := tracker.cache.HGetAll(context.Background(), pendingImpressionsKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != len(want) {
+		t.Errorf("pending impressions = %v, want %v", counts, want)
+	}
+	for postID, count := range want {
+		if counts[postID.String()] != count {
+			t.Errorf("pending[%s] = %q, want %s", postID, counts[postID.String()], count)
+		}
+	}
+}
@@ -2,86 +2,194 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
 	"github.com/feed-system/feed-system/pkg/queue"
 	"github.com/google/uuid"
 )
 
 type LikeService struct {
-	postRepo  *repository.PostRepository
-	likeRepo  *repository.LikeRepository
-	userRepo  *repository.UserRepository
-	producer  *queue.KafkaProducer
-	logger    *logger.Logger
+	postRepo   *repository.PostRepository
+	likeRepo   *repository.LikeRepository
+	userRepo   *repository.UserRepository
+	followRepo *repository.FollowRepository
+	cache      *cache.RedisClient
+	producer   queue.Publisher
+	logger     *logger.Logger
+	likeState  *LikeStateCache
 }
 
-func NewLikeService(postRepo *repository.PostRepository, likeRepo *repository.LikeRepository, userRepo *repository.UserRepository, producer *queue.KafkaProducer, logger *logger.Logger) *LikeService {
+func NewLikeService(postRepo *repository.PostRepository, likeRepo *repository.LikeRepository, userRepo *repository.UserRepository, followRepo *repository.FollowRepository, cache *cache.RedisClient, producer queue.Publisher, logger *logger.Logger) *LikeService {
 	return &LikeService{
-		postRepo: postRepo,
-		likeRepo: likeRepo,
-		userRepo: userRepo,
-		producer: producer,
-		logger:   logger,
+		postRepo:   postRepo,
+		likeRepo:   likeRepo,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		cache:      cache,
+		producer:   producer,
+		logger:     logger,
+		likeState:  NewLikeStateCache(cache, likeRepo, logger),
 	}
 }
 
+// ReactRequest 回应帖子的请求
+type ReactRequest struct {
+	Type string `json:"type" binding:"required"`
+}
+
+// maxReactAttempts 回应被并发修改时的最大重试次数
+const maxReactAttempts = 3
+
 func (s *LikeService) LikePost(ctx context.Context, userID, postID string) error {
+	userUUID, postUUID, err := s.resolveLikeTarget(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	// 唯一索引保证并发重复点赞只插入一行，只有实际插入的请求才增加点赞数；已有其他回应时同样视为已点赞
+	inserted, err := s.createReaction(ctx, userUUID, postUUID, models.ReactionLike)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		return apperrors.AlreadyExists("already liked")
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"post_id": postID,
+	}).Info("Post liked successfully")
+
+	return nil
+}
+
+// React 对帖子做出回应，已有回应时原地切换类型并调整各类型计数，相同类型重复回应不做修改
+func (s *LikeService) React(ctx context.Context, userID, postID, reactionType string) error {
+	if !models.IsValidReactionType(reactionType) {
+		return apperrors.InvalidInput("invalid reaction type")
+	}
+
+	userUUID, postUUID, err := s.resolveLikeTarget(ctx, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < maxReactAttempts; attempt++ {
+		existing, err := s.likeRepo.Get(ctx, userUUID, postUUID)
+		if err != nil {
+			return fmt.Errorf("failed to check reaction: %w", err)
+		}
+
+		if existing == nil {
+			inserted, err := s.createReaction(ctx, userUUID, postUUID, reactionType)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				return nil
+			}
+			// 其他请求刚插入了回应，重新读取后按切换处理
+			continue
+		}
+
+		if existing.ReactionType == reactionType {
+			s.likeState.Set(ctx, userUUID, postUUID, reactionType)
+			return nil
+		}
+
+		// 条件更新：回应在读取后被并发修改或取消时不更新，重新读取
+		switched, err := s.likeRepo.UpdateReactionType(ctx, userUUID, postUUID, existing.ReactionType, reactionType)
+		if err != nil {
+			return err
+		}
+		if !switched {
+			continue
+		}
+		s.likeState.Set(ctx, userUUID, postUUID, reactionType)
+
+		deltas := map[string]int64{existing.ReactionType: -1, reactionType: 1}
+		if err := s.postRepo.UpdateReactionCounts(ctx, postUUID, 0, deltas); err != nil {
+			s.logger.WithError(err).Error("Failed to update post reaction counts")
+		}
+		// 切换回应不发送点赞事件，直接失效单帖缓存，避免返回旧的各类型计数
+		if err := s.cache.Delete(ctx, PostCacheKey(postUUID.String())); err != nil {
+			s.logger.WithError(err).Error("Failed to invalidate post cache")
+		}
+
+		s.logger.WithFields(map[string]interface{}{
+			"user_id":       userID,
+			"post_id":       postID,
+			"from_reaction": existing.ReactionType,
+			"to_reaction":   reactionType,
+		}).Info("Post reaction changed")
+		return nil
+	}
+
+	return fmt.Errorf("failed to react to post: reaction changed concurrently")
+}
+
+// resolveLikeTarget 解析ID并确认用户和帖子存在
+func (s *LikeService) resolveLikeTarget(ctx context.Context, userID, postID string) (uuid.UUID, uuid.UUID, error) {
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
-		return fmt.Errorf("invalid post ID: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid post ID: %w", err)
 	}
 
 	// 检查用户是否存在
 	user, err := s.userRepo.GetByID(ctx, userUUID)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return errors.New("user not found")
+		return uuid.Nil, uuid.Nil, apperrors.NotFound("user not found")
 	}
 
 	// 检查帖子是否存在
 	post, err := s.postRepo.GetByID(ctx, postUUID)
 	if err != nil {
-		return fmt.Errorf("failed to get post: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to get post: %w", err)
 	}
 	if post == nil {
-		return errors.New("post not found")
+		return uuid.Nil, uuid.Nil, apperrors.NotFound("post not found")
 	}
-
-	// 检查是否已经点赞
-	existingLike, err := s.likeRepo.Get(ctx, userUUID, postUUID)
-	if err != nil {
-		return fmt.Errorf("failed to check like status: %w", err)
-	}
-	if existingLike != nil {
-		return errors.New("already liked")
+	if err := ensurePostVisible(ctx, s.followRepo, userUUID, post); err != nil {
+		return uuid.Nil, uuid.Nil, err
 	}
 
-	// 创建点赞记录
+	return userUUID, postUUID, nil
+}
+
+// createReaction 插入回应，返回是否实际插入；只有实际插入时才增加计数、更新状态缓存并发送点赞事件
+func (s *LikeService) createReaction(ctx context.Context, userUUID, postUUID uuid.UUID, reactionType string) (bool, error) {
 	like := &models.Like{
-		UserID:    userUUID,
-		PostID:    postUUID,
-		CreatedAt: post.CreatedAt,
+		UserID:       userUUID,
+		PostID:       postUUID,
+		ReactionType: reactionType,
+		CreatedAt:    time.Now(),
 	}
 
-	if err := s.likeRepo.Create(ctx, like); err != nil {
-		return fmt.Errorf("failed to create like: %w", err)
+	inserted, err := s.likeRepo.Create(ctx, like)
+	if err != nil {
+		return false, fmt.Errorf("failed to create like: %w", err)
+	}
+	if !inserted {
+		return false, nil
 	}
+	s.likeState.Set(ctx, userUUID, postUUID, reactionType)
 
 	// 更新帖子点赞数
-	if err := s.postRepo.UpdateLikeCount(ctx, postUUID, 1); err != nil {
+	if err := s.postRepo.UpdateReactionCounts(ctx, postUUID, 1, map[string]int64{reactionType: 1}); err != nil {
 		s.logger.WithError(err).Error("Failed to update post like count")
 	}
 
@@ -90,20 +198,16 @@ func (s *LikeService) LikePost(ctx context.Context, userID, postID string) error
 		Type:      queue.EventLikeCreated,
 		Timestamp: like.CreatedAt,
 		Data: queue.LikeEventData{
-			UserID: userID,
-			PostID: postID,
+			UserID:       userUUID.String(),
+			PostID:       postUUID.String(),
+			ReactionType: reactionType,
 		},
 	}
-	if err := s.producer.Publish(ctx, userID, event); err != nil {
+	if err := s.producer.Publish(ctx, postUUID.String(), event); err != nil {
 		s.logger.WithError(err).Error("Failed to publish like created event")
 	}
 
-	s.logger.WithFields(map[string]interface{}{
-		"user_id": userID,
-		"post_id": postID,
-	}).Info("Post liked successfully")
-
-	return nil
+	return true, nil
 }
 
 func (s *LikeService) UnlikePost(ctx context.Context, userID, postID string) error {
@@ -117,22 +221,18 @@ func (s *LikeService) UnlikePost(ctx context.Context, userID, postID string) err
 		return fmt.Errorf("invalid post ID: %w", err)
 	}
 
-	// 检查点赞记录是否存在
-	existingLike, err := s.likeRepo.Get(ctx, userUUID, postUUID)
+	// 删除点赞记录，只有实际删除的请求才减少点赞数
+	reactionType, err := s.likeRepo.Delete(ctx, userUUID, postUUID)
 	if err != nil {
-		return fmt.Errorf("failed to check like status: %w", err)
-	}
-	if existingLike == nil {
-		return errors.New("not liked")
-	}
-
-	// 删除点赞记录
-	if err := s.likeRepo.Delete(ctx, userUUID, postUUID); err != nil {
 		return fmt.Errorf("failed to delete like: %w", err)
 	}
+	s.likeState.Set(ctx, userUUID, postUUID, "")
+	if reactionType == "" {
+		return apperrors.NotFound("not liked")
+	}
 
 	// 更新帖子点赞数
-	if err := s.postRepo.UpdateLikeCount(ctx, postUUID, -1); err != nil {
+	if err := s.postRepo.UpdateReactionCounts(ctx, postUUID, -1, map[string]int64{reactionType: -1}); err != nil {
 		s.logger.WithError(err).Error("Failed to update post like count")
 	}
 
@@ -141,11 +241,12 @@ func (s *LikeService) UnlikePost(ctx context.Context, userID, postID string) err
 		Type:      queue.EventLikeDeleted,
 		Timestamp: time.Now(),
 		Data: queue.LikeEventData{
-			UserID: userID,
-			PostID: postID,
+			UserID:       userID,
+			PostID:       postID,
+			ReactionType: reactionType,
 		},
 	}
-	if err := s.producer.Publish(ctx, userID, event); err != nil {
+	if err := s.producer.Publish(ctx, postID, event); err != nil {
 		s.logger.WithError(err).Error("Failed to publish like deleted event")
 	}
 
@@ -157,12 +258,25 @@ func (s *LikeService) UnlikePost(ctx context.Context, userID, postID string) err
 	return nil
 }
 
-func (s *LikeService) GetPostLikes(ctx context.Context, postID string, offset, limit int) ([]*models.Like, error) {
+func (s *LikeService) GetPostLikes(ctx context.Context, viewerID, postID string, offset, limit int) ([]*models.Like, error) {
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid post ID: %w", err)
 	}
 
+	// 看不到的密友帖子按不存在处理
+	post, err := s.postRepo.GetByID(ctx, postUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if post == nil {
+		return nil, apperrors.NotFound("post not found")
+	}
+	viewerUUID, _ := uuid.Parse(viewerID)
+	if err := ensurePostVisible(ctx, s.followRepo, viewerUUID, post); err != nil {
+		return nil, err
+	}
+
 	likes, err := s.likeRepo.GetByPostID(ctx, postUUID, offset, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post likes: %w", err)
@@ -197,4 +311,4 @@ func (s *LikeService) GetLikeCount(ctx context.Context, postID string) (int64, e
 	}
 
 	return count, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// LikeStateCacheTTL 查看者对帖子回应状态的缓存时间
+const LikeStateCacheTTL = 10 * time.Minute
+
+// LikeStateCache 查看者对帖子的回应状态缓存：一次MGET读取整页帖子的状态，
+// 未命中的帖子用一条SQL批量查询，再用一次pipeline回填
+type LikeStateCache struct {
+	cache    *cache.RedisClient
+	likeRepo *repository.LikeRepository
+	logger   *logger.Logger
+}
+
+func NewLikeStateCache(cache *cache.RedisClient, likeRepo *repository.LikeRepository, logger *logger.Logger) *LikeStateCache {
+	return &LikeStateCache{
+		cache:    cache,
+		likeRepo: likeRepo,
+		logger:   logger,
+	}
+}
+
+func likeStateKey(viewerID, postID uuid.UUID) string {
+	return fmt.Sprintf("like_state:%s:%s", viewerID.String(), postID.String())
+}
+
+// Lookup 返回viewerID对每个帖子是否有回应（任意类型都算点赞）
+func (c *LikeStateCache) Lookup(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	reactions, err := c.LookupReactions(ctx, viewerID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]bool, len(postIDs))
+	for _, postID := range postIDs {
+		result[postID] = reactions[postID] != ""
+	}
+	return result, nil
+}
+
+// LookupReactions 返回viewerID对帖子的回应类型，只包含有回应的帖子
+func (c *LikeStateCache) LookupReactions(ctx context.Context, viewerID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	result := make(map[uuid.UUID]string, len(postIDs))
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, len(postIDs))
+	for i, postID := range postIDs {
+		keys[i] = likeStateKey(viewerID, postID)
+	}
+
+	var misses []uuid.UUID
+	values, err := c.cache.MGet(ctx, keys...)
+	if err != nil {
+		// Redis不可用时全部回源数据库
+		c.logger.WithError(err).Error("Failed to get cached like state")
+		misses = postIDs
+	} else {
+		for i, value := range values {
+			value, _ := value.(string)
+			switch {
+			case value == "0":
+			case value == "1":
+				// 引入回应类型之前缓存的点赞状态
+				result[postIDs[i]] = models.ReactionLike
+			case models.IsValidReactionType(value):
+				result[postIDs[i]] = value
+			default:
+				misses = append(misses, postIDs[i])
+			}
+		}
+	}
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	reactions, err := c.likeRepo.GetReactionsForPosts(ctx, viewerID, misses)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := c.cache.Pipeline()
+	for _, postID := range misses {
+		if reaction := reactions[postID]; reaction != "" {
+			result[postID] = reaction
+		}
+		pipe.Set(ctx, likeStateKey(viewerID, postID), likeStateValue(reactions[postID]), LikeStateCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.logger.WithError(err).Error("Failed to cache like state")
+	}
+
+	return result, nil
+}
+
+// Set 回应/取消回应后更新缓存的状态，reactionType为空表示没有回应
+func (c *LikeStateCache) Set(ctx context.Context, viewerID, postID uuid.UUID, reactionType string) {
+	if err := c.cache.Set(ctx, likeStateKey(viewerID, postID), likeStateValue(reactionType), LikeStateCacheTTL); err != nil {
+		c.logger.WithError(err).Error("Failed to update cached like state")
+	}
+}
+
+func likeStateValue(reactionType string) string {
+	if reactionType == "" {
+		return "0"
+	}
+	return reactionType
+}
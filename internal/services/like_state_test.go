@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestLikeStateCacheLookup(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	likeState := NewLikeStateCache(redisClient, repository.NewLikeRepository(db), logger.NewLogger())
+	ctx := context.Background()
+
+	viewerID := uuid.New()
+	cachedLove, legacyLiked, cachedNone := uuid.New(), uuid.New(), uuid.New()
+	missLiked, missNone := uuid.New(), uuid.New()
+	mr.Set(likeStateKey(viewerID, cachedLove), models.ReactionLove)
+	mr.Set(likeStateKey(viewerID, legacyLiked), "1")
+	mr.Set(likeStateKey(viewerID, cachedNone), "0")
+
+	// 只有未命中缓存的帖子用一条SQL回源
+	mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes" WHERE \(user_id = \$1 AND post_id IN \(\$2,\$3\)\)`).
+		WithArgs(viewerID, missLiked, missNone).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}).AddRow(missLiked, models.ReactionHaha))
+
+	postIDs := []uuid.UUID{cachedLove, legacyLiked, cachedNone, missLiked, missNone}
+	reactions, err := likeState.LookupReactions(ctx, viewerID, postIDs)
+	if err != nil {
+		t.Fatalf("LookupReactions: %v", err)
+	}
+	want := map[uuid.UUID]string{
+		cachedLove:  models.ReactionLove,
+		legacyLiked: models.ReactionLike,
+		missLiked:   models.ReactionHaha,
+	}
+	if len(reactions) != len(want) {
+		t.Errorf("reactions = %v, want %v", reactions, want)
+	}
+	for postID, reaction := range want {
+		if reactions[postID] != reaction {
+			t.Errorf("reaction for %s = %q, want %q", postID, reactions[postID], reaction)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 回源结果已回填，再次查询不访问数据库
+	if got, _ := mr.Get(likeStateKey(viewerID, missNone)); got != "0" {
+		t.Errorf("cached state for unliked post = %q, want \"0\"", got)
+	}
+	liked, err := likeState.Lookup(ctx, viewerID, postIDs)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if !liked[missLiked] || liked[missNone] || liked[cachedNone] {
+		t.Errorf("Lookup() = %v", liked)
+	}
+}
+
+func TestGetPostForViewerHydration(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	log := logger.NewLogger()
+	service := &FeedService{
+		postRepo:  repository.NewPostRepository(db),
+		cache:     redisClient,
+		config:    newTestConfig(func(feed *config.FeedConfig) { feed.PostCacheTTL = time.Hour }),
+		logger:    log,
+		likeState: NewLikeStateCache(redisClient, repository.NewLikeRepository(db), log),
+	}
+	ctx := context.Background()
+
+	post := &models.Post{ID: uuid.New(), UserID: uuid.New(), Content: "hello"}
+	data, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set(PostCacheKey(post.ID.String()), string(data))
+	liker, other := uuid.New(), uuid.New()
+	mr.Set(likeStateKey(liker, post.ID), models.ReactionLove)
+
+	// 登录用户的查询填充点赞状态，点过赞的带上回应类型
+	got, err := service.GetPostForViewer(ctx, post.ID.String(), liker.String(), true)
+	if err != nil {
+		t.Fatalf("GetPostForViewer: %v", err)
+	}
+	if got.IsLiked == nil || !*got.IsLiked || got.ViewerReaction == nil || *got.ViewerReaction != models.ReactionLove {
+		t.Errorf("liker: is_liked = %v, reaction = %v, want true/love", got.IsLiked, got.ViewerReaction)
+	}
+
+	mock.ExpectQuery(`SELECT "post_id","reaction_type" FROM "likes"`).
+		WithArgs(other, post.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "reaction_type"}))
+	got, err = service.GetPostForViewer(ctx, post.ID.String(), other.String(), true)
+	if err != nil {
+		t.Fatalf("GetPostForViewer: %v", err)
+	}
+	if got.IsLiked == nil || *got.IsLiked || got.ViewerReaction != nil {
+		t.Errorf("other viewer: is_liked = %v, reaction = %v, want false/none", got.IsLiked, got.ViewerReaction)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 匿名查询和关闭hydrate时不查询点赞状态，字段保持为空
+	for name, viewerID := range map[string]string{"anonymous": "", "hydrate disabled": liker.String()} {
+		got, err := service.GetPostForViewer(ctx, post.ID.String(), viewerID, viewerID == "")
+		if err != nil {
+			t.Fatalf("%s: GetPostForViewer: %v", name, err)
+		}
+		if got.IsLiked != nil || got.ViewerReaction != nil {
+			t.Errorf("%s: is_liked = %v, reaction = %v, want omitted", name, got.IsLiked, got.ViewerReaction)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// BenchmarkLikeStateLookup 对比一页帖子逐个GET和一次MGET读取回应状态，roundtrips/op为Redis命令数
+func BenchmarkLikeStateLookup(b *testing.B) {
+	redisClient, mr := newTestRedis(b)
+	likeState := NewLikeStateCache(redisClient, nil, logger.NewLogger())
+	ctx := context.Background()
+
+	// 整页状态都已缓存，一半点过赞
+	viewerID := uuid.New()
+	postIDs := make([]uuid.UUID, 50)
+	for i := range postIDs {
+		postIDs[i] = uuid.New()
+		reaction := ""
+		if i%2 == 0 {
+			reaction = models.ReactionLike
+		}
+		mr.Set(likeStateKey(viewerID, postIDs[i]), likeStateValue(reaction))
+	}
+
+	b.Run("per-post GET", func(b *testing.B) {
+		start := mr.CommandCount()
+		for i := 0; i < b.N; i++ {
+			for _, postID := range postIDs {
+				if _, err := redisClient.Get(ctx, likeStateKey(viewerID, postID)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(mr.CommandCount()-start)/float64(b.N), "roundtrips/op")
+	})
+
+	b.Run("MGET", func(b *testing.B) {
+		start := mr.CommandCount()
+		for i := 0; i < b.N; i++ {
+			if _, err := likeState.Lookup(ctx, viewerID, postIDs); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(mr.CommandCount()-start)/float64(b.N), "roundtrips/op")
+	})
+}
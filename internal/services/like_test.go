@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func newLikeTestService(t *testing.T) (*LikeService, sqlmock.Sqlmock, *fakePublisher) {
+	t.Helper()
+
+	db, mock := newTestDB(t)
+	redisClient, _ := newTestRedis(t)
+	publisher := &fakePublisher{}
+	service := NewLikeService(
+		repository.NewPostRepository(db),
+		repository.NewLikeRepository(db),
+		repository.NewUserRepository(db),
+		repository.NewFollowRepository(db),
+		redisClient, publisher, logger.NewLogger(),
+	)
+	return service, mock, publisher
+}
+
+// expectLikeTarget 回应前确认用户和公开帖子存在的查询
+func expectLikeTarget(mock sqlmock.Sqlmock, userID, authorID, postID uuid.UUID) {
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userID))
+	mock.ExpectQuery(`SELECT \* FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "visibility"}).
+			AddRow(postID, authorID, models.PostVisibilityPublic))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+}
+
+func TestLikePostConcurrentDuplicate(t *testing.T) {
+	service, mock, publisher := newLikeTestService(t)
+	ctx := context.Background()
+	userID, authorID, postID := uuid.New(), uuid.New(), uuid.New()
+
+	// 第一次请求插入成功并增加一次点赞数
+	expectLikeTarget(mock, userID, authorID, postID)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "likes" .* ON CONFLICT .* DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "like_count"=GREATEST\(like_count \+ \$1, 0\)`).
+		WithArgs(int64(1), models.ReactionLike, models.ReactionLike, int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 并发的重复请求命中唯一索引，不插入也不增加点赞数
+	expectLikeTarget(mock, userID, authorID, postID)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "likes" .* ON CONFLICT .* DO NOTHING`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	if err := service.LikePost(ctx, userID.String(), postID.String()); err != nil {
+		t.Fatalf("LikePost: %v", err)
+	}
+	err := service.LikePost(ctx, userID.String(), postID.String())
+	if !errors.Is(err, apperrors.ErrAlreadyExists) {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("published %d like events, want 1", len(publisher.events))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReactSwitchAdjustsCounts(t *testing.T) {
+	service, mock, publisher := newLikeTestService(t)
+	ctx := context.Background()
+	userID, authorID, postID := uuid.New(), uuid.New(), uuid.New()
+
+	if err := service.cache.Set(ctx, PostCacheKey(postID.String()), "{}", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	expectLikeTarget(mock, userID, authorID, postID)
+	mock.ExpectQuery(`SELECT \* FROM "likes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "post_id", "reaction_type"}).
+			AddRow(uuid.New(), userID, postID, models.ReactionLike))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "likes" SET "reaction_type"`).
+		WithArgs(models.ReactionLove, userID, postID, models.ReactionLike).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// 点赞总数不变，原类型减一、新类型加一
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET "like_count"`).
+		WithArgs(int64(0),
+			models.ReactionLike, models.ReactionLike, int64(-1),
+			models.ReactionLove, models.ReactionLove, int64(1),
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := service.React(ctx, userID.String(), postID.String(), models.ReactionLove); err != nil {
+		t.Fatalf("React: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.events) != 0 {
+		t.Errorf("switching reaction published %d events, want 0", len(publisher.events))
+	}
+	if _, err := service.cache.Get(ctx, PostCacheKey(postID.String())); err == nil {
+		t.Errorf("post cache not invalidated after switching reaction")
+	}
+
+	reactions, err := service.likeState.LookupReactions(ctx, userID, []uuid.UUID{postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reactions[postID] != models.ReactionLove {
+		t.Errorf("cached reaction = %q, want %q", reactions[postID], models.ReactionLove)
+	}
+}
+
+func TestReactSameTypeIsNoop(t *testing.T) {
+	service, mock, _ := newLikeTestService(t)
+	ctx := context.Background()
+	userID, authorID, postID := uuid.New(), uuid.New(), uuid.New()
+
+	expectLikeTarget(mock, userID, authorID, postID)
+	mock.ExpectQuery(`SELECT \* FROM "likes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "post_id", "reaction_type"}).
+			AddRow(uuid.New(), userID, postID, models.ReactionLove))
+
+	if err := service.React(ctx, userID.String(), postID.String(), models.ReactionLove); err != nil {
+		t.Fatalf("React: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrLikesHidden 用户关闭了点赞列表的公开展示
+var ErrLikesHidden = apperrors.PermissionDenied("liked posts are hidden")
+
+// LikedPost 用户赞过的帖子
+type LikedPost struct {
+	Post    *models.Post `json:"post"`
+	LikedAt time.Time    `json:"liked_at"`
+}
+
+// LikedPostsPage 点赞列表的一页
+type LikedPostsPage struct {
+	Posts      []*LikedPost `json:"posts"`
+	NextCursor string       `json:"next_cursor"`
+	HasMore    bool         `json:"has_more"`
+}
+
+// GetLikedPosts 按点赞时间倒序分页获取targetUserID赞过的帖子，游标为上一页最后一次点赞的"点赞时间_点赞ID"。
+// 用户关闭了点赞列表时只有本人能查看；查看者看不到的密友帖子会被跳过，因此一页可能少于limit条
+// 早期版本写入的点赞记录created_at为帖子发布时间，无法恢复真实点赞时间，这部分记录按帖子发布时间排序
+func (s *FeedService) GetLikedPosts(ctx context.Context, viewerID, targetUserID, cursor string, limit int) (*LikedPostsPage, error) {
+	targetUUID, err := uuid.Parse(targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	viewerUUID, _ := uuid.Parse(viewerID)
+
+	if viewerUUID != targetUUID {
+		target, err := s.userRepo.GetByID(ctx, targetUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if target == nil {
+			return nil, apperrors.NotFound("user not found")
+		}
+		if !target.ShowLikes {
+			return nil, ErrLikesHidden
+		}
+	}
+
+	var beforeLikedAt time.Time
+	beforeID := uuid.Nil
+	if cursor != "" {
+		if beforeLikedAt, beforeID, err = parseTimeIDCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	likes, err := s.likeRepo.GetLikedPostsByUser(ctx, targetUUID, beforeLikedAt, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LikedPostsPage{Posts: []*LikedPost{}}
+	if len(likes) > limit {
+		likes = likes[:limit]
+		page.HasMore = true
+		last := likes[limit-1]
+		page.NextCursor = last.CreatedAt.Format(time.RFC3339Nano) + "_" + last.ID.String()
+	}
+
+	posts := make([]*models.Post, 0, len(likes))
+	for _, like := range likes {
+		posts = append(posts, &like.Post)
+	}
+	visible, err := filterVisiblePosts(ctx, s.followRepo, viewerUUID, posts)
+	if err != nil {
+		return nil, err
+	}
+	visibleIDs := make(map[uuid.UUID]bool, len(visible))
+	for _, post := range visible {
+		visibleIDs[post.ID] = true
+	}
+
+	for _, like := range likes {
+		if visibleIDs[like.PostID] {
+			page.Posts = append(page.Posts, &LikedPost{Post: &like.Post, LikedAt: like.CreatedAt})
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestGetLikedPosts(t *testing.T) {
+	ctx := context.Background()
+	likerID, authorID, viewerID := uuid.New(), uuid.New(), uuid.New()
+
+	newService := func(t *testing.T) (*FeedService, sqlmock.Sqlmock) {
+		db, mock := newTestDB(t)
+		return &FeedService{
+			userRepo:   repository.NewUserRepository(db),
+			followRepo: repository.NewFollowRepository(db),
+			likeRepo:   repository.NewLikeRepository(db),
+			logger:     logger.NewLogger(),
+		}, mock
+	}
+	expectTarget := func(mock sqlmock.Sqlmock, showLikes bool) {
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WithArgs(likerID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "show_likes"}).AddRow(likerID, showLikes))
+	}
+	type like struct {
+		id, postID uuid.UUID
+		likedAt    time.Time
+		visibility string
+	}
+	// expectLikes 点赞记录按点赞时间倒序返回，帖子发布时间与点赞时间无关
+	expectLikes := func(mock sqlmock.Sqlmock, keyset []driver.Value, likes ...like) {
+		query := `SELECT "likes"."id".* FROM "likes" JOIN posts ON posts.id = likes.post_id AND posts.is_deleted = false AND posts.deleted_at IS NULL WHERE likes.user_id = \$1 AND "likes"."deleted_at" IS NULL ORDER BY likes.created_at DESC, likes.id DESC LIMIT 3`
+		args := []driver.Value{likerID}
+		if keyset != nil {
+			query = `WHERE likes.user_id = \$1 AND \(likes.created_at, likes.id\) < \(\$2, \$3\) AND "likes"."deleted_at" IS NULL ORDER BY likes.created_at DESC, likes.id DESC LIMIT 3`
+			args = append(args, keyset...)
+		}
+		likeRows := sqlmock.NewRows([]string{"id", "user_id", "post_id", "created_at"})
+		postRows := sqlmock.NewRows([]string{"id", "user_id", "visibility", "created_at"})
+		for i, l := range likes {
+			likeRows.AddRow(l.id, likerID, l.postID, l.likedAt)
+			postRows.AddRow(l.postID, authorID, l.visibility, time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC))
+		}
+		mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(likeRows)
+		if len(likes) == 0 {
+			return
+		}
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"."id" (IN|=)`).WillReturnRows(postRows)
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(authorID))
+	}
+
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	likes := make([]like, 5)
+	for i := range likes {
+		likes[i] = like{id: uuid.New(), postID: uuid.New(), likedAt: base.Add(-time.Duration(i) * time.Hour), visibility: models.PostVisibilityPublic}
+	}
+
+	t.Run("pages by like time", func(t *testing.T) {
+		service, mock := newService(t)
+
+		expectTarget(mock, true)
+		expectLikes(mock, nil, likes[0:3]...)
+		first, err := service.GetLikedPosts(ctx, viewerID.String(), likerID.String(), "", 2)
+		if err != nil {
+			t.Fatalf("GetLikedPosts() error = %v", err)
+		}
+		if len(first.Posts) != 2 || first.Posts[0].Post.ID != likes[0].postID || first.Posts[1].Post.ID != likes[1].postID {
+			t.Fatalf("first page = %+v, want the two most recent likes", first.Posts)
+		}
+		if !first.Posts[0].LikedAt.Equal(likes[0].likedAt) || first.Posts[0].Post.User.ID != authorID {
+			t.Errorf("first liked post = %+v, want liked_at %s with the author loaded", first.Posts[0], likes[0].likedAt)
+		}
+		if !first.HasMore || first.NextCursor != likes[1].likedAt.Format(time.RFC3339Nano)+"_"+likes[1].id.String() {
+			t.Fatalf("first page has_more = %v, next_cursor = %q", first.HasMore, first.NextCursor)
+		}
+
+		// 下一页从上一页最后一次点赞之后继续
+		expectTarget(mock, true)
+		expectLikes(mock, []driver.Value{likes[1].likedAt, likes[1].id}, likes[2:5]...)
+		second, err := service.GetLikedPosts(ctx, viewerID.String(), likerID.String(), first.NextCursor, 2)
+		if err != nil {
+			t.Fatalf("GetLikedPosts() error = %v", err)
+		}
+		if len(second.Posts) != 2 || second.Posts[0].Post.ID != likes[2].postID || second.Posts[1].Post.ID != likes[3].postID || !second.HasMore {
+			t.Errorf("second page = %+v, want likes 2 and 3", second)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := service.GetLikedPosts(ctx, likerID.String(), likerID.String(), "not-a-cursor", 2); err != ErrInvalidCursor {
+			t.Errorf("malformed cursor: err = %v, want ErrInvalidCursor", err)
+		}
+	})
+
+	t.Run("skips close friends posts the viewer cannot see", func(t *testing.T) {
+		service, mock := newService(t)
+		hidden := like{id: uuid.New(), postID: uuid.New(), likedAt: base, visibility: models.PostVisibilityCloseFriends}
+
+		expectTarget(mock, true)
+		expectLikes(mock, nil, hidden, likes[1])
+		mock.ExpectQuery(`SELECT "following_id" FROM "follows"`).
+			WillReturnRows(sqlmock.NewRows([]string{"following_id"}))
+		page, err := service.GetLikedPosts(ctx, viewerID.String(), likerID.String(), "", 2)
+		if err != nil {
+			t.Fatalf("GetLikedPosts() error = %v", err)
+		}
+		if len(page.Posts) != 1 || page.Posts[0].Post.ID != likes[1].postID || page.HasMore {
+			t.Errorf("page = %+v, want only the public post", page)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("hidden likes", func(t *testing.T) {
+		service, mock := newService(t)
+
+		// 其他人查看时拒绝，不查询点赞记录
+		expectTarget(mock, false)
+		if _, err := service.GetLikedPosts(ctx, viewerID.String(), likerID.String(), "", 2); !errors.Is(err, ErrLikesHidden) {
+			t.Fatalf("other viewer: err = %v, want ErrLikesHidden", err)
+		}
+		expectTarget(mock, false)
+		if _, err := service.GetLikedPosts(ctx, "", likerID.String(), "", 2); !errors.Is(err, ErrLikesHidden) {
+			t.Fatal("anonymous viewer: expected ErrLikesHidden")
+		}
+
+		// 本人始终可以查看自己的点赞列表
+		expectLikes(mock, nil, likes[0])
+		page, err := service.GetLikedPosts(ctx, likerID.String(), likerID.String(), "", 2)
+		if err != nil {
+			t.Fatalf("owner: GetLikedPosts() error = %v", err)
+		}
+		if len(page.Posts) != 1 || page.Posts[0].Post.ID != likes[0].postID {
+			t.Errorf("owner page = %+v", page)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
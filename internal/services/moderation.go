@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+// ErrContentRejected 内容未通过审核
+var ErrContentRejected = errors.New("content rejected by moderation")
+
+// Moderator 内容审核接口，可替换为外部审核服务
+type Moderator interface {
+	Check(ctx context.Context, content string) (allowed bool, reason string)
+}
+
+// KeywordModerator 基于配置的关键词/正则黑名单审核
+type KeywordModerator struct {
+	enabled  bool
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+func NewKeywordModerator(cfg config.ModerationConfig) (*KeywordModerator, error) {
+	m := &KeywordModerator{enabled: cfg.Enabled}
+
+	for _, keyword := range cfg.BlockedKeywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			m.keywords = append(m.keywords, strings.ToLower(keyword))
+		}
+	}
+	for _, pattern := range cfg.BlockedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+
+	return m, nil
+}
+
+// Check 关键词不区分大小写匹配，正则按原样匹配
+func (m *KeywordModerator) Check(ctx context.Context, content string) (bool, string) {
+	if !m.enabled {
+		return true, ""
+	}
+
+	lower := strings.ToLower(content)
+	for _, keyword := range m.keywords {
+		if strings.Contains(lower, keyword) {
+			return false, "content contains blocked keyword"
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(content) {
+			return false, "content matches blocked pattern"
+		}
+	}
+	return true, ""
+}
+
+// moderate 执行审核，未通过时记录日志并返回ErrContentRejected
+func moderate(ctx context.Context, moderator Moderator, log *logger.Logger, userID, kind, content string) error {
+	if moderator == nil {
+		return nil
+	}
+
+	allowed, reason := moderator.Check(ctx, content)
+	if allowed {
+		return nil
+	}
+
+	log.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"kind":    kind,
+		"reason":  reason,
+	}).Warn("Content rejected by moderation")
+	return fmt.Errorf("%w: %s", ErrContentRejected, reason)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// recordingModerator 记录被审核的内容，拒绝blocked中的内容
+type recordingModerator struct {
+	blocked map[string]bool
+	checked []string
+}
+
+func (m *recordingModerator) Check(ctx context.Context, content string) (bool, string) {
+	m.checked = append(m.checked, content)
+	if m.blocked[content] {
+		return false, "blocked in test"
+	}
+	return true, ""
+}
+
+func TestKeywordModeratorCheck(t *testing.T) {
+	moderator, err := NewKeywordModerator(config.ModerationConfig{
+		Enabled:         true,
+		BlockedKeywords: []string{"Spam", "  "},
+		BlockedPatterns: []string{`buy\s+now`},
+	})
+	if err != nil {
+		t.Fatalf("NewKeywordModerator: %v", err)
+	}
+
+	for _, tt := range []struct {
+		content string
+		allowed bool
+	}{
+		{"hello world", true},
+		{"this is SPAM", false},
+		{"Buy   now", true}, // 正则区分大小写
+		{"buy  now!", false},
+	} {
+		allowed, reason := moderator.Check(context.Background(), tt.content)
+		if allowed != tt.allowed {
+			t.Errorf("Check(%q) = %v, want %v", tt.content, allowed, tt.allowed)
+		}
+		if !allowed && reason == "" {
+			t.Errorf("Check(%q) rejected without a reason", tt.content)
+		}
+	}
+
+	// 关闭审核时全部放行
+	disabled, err := NewKeywordModerator(config.ModerationConfig{BlockedKeywords: []string{"spam"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed, _ := disabled.Check(context.Background(), "spam"); !allowed {
+		t.Error("disabled moderator rejected content")
+	}
+
+	if _, err := NewKeywordModerator(config.ModerationConfig{BlockedPatterns: []string{"("}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestModerationRunsBeforeWrites(t *testing.T) {
+	ctx := context.Background()
+	userID, postID := uuid.New(), uuid.New()
+	moderator := &recordingModerator{blocked: map[string]bool{"bad words": true}}
+
+	newServices := func(t *testing.T) (*FeedService, *CommentService, sqlmock.Sqlmock, *fakePublisher) {
+		db, mock := newTestDB(t)
+		redisClient, _ := newTestRedis(t)
+		publisher := &fakePublisher{}
+		log := logger.NewLogger()
+		feedService := &FeedService{
+			postRepo:  repository.NewPostRepository(db),
+			userRepo:  repository.NewUserRepository(db),
+			cache:     redisClient,
+			producer:  publisher,
+			config:    newTestConfig(nil),
+			logger:    log,
+			moderator: moderator,
+		}
+		commentService := NewCommentService(repository.NewPostRepository(db), repository.NewCommentRepository(db),
+			repository.NewUserRepository(db), repository.NewFollowRepository(db), publisher, log, moderator)
+		return feedService, commentService, mock, publisher
+	}
+
+	t.Run("blocked content never reaches the database", func(t *testing.T) {
+		feedService, commentService, mock, publisher := newServices(t)
+
+		// 没有设置任何SQL预期，审核之后的任何查询都会失败
+		_, err := feedService.CreatePost(ctx, userID.String(), &CreatePostRequest{Content: "bad words"})
+		if !errors.Is(err, ErrContentRejected) {
+			t.Errorf("CreatePost() error = %v, want content rejected", err)
+		}
+		_, err = commentService.CreateComment(ctx, userID.String(), postID.String(), &CreateCommentRequest{Content: "bad words"})
+		if !errors.Is(err, ErrContentRejected) {
+			t.Errorf("CreateComment() error = %v, want content rejected", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(publisher.events) != 0 {
+			t.Errorf("events = %+v, want none", publisher.events)
+		}
+	})
+
+	t.Run("allowed content proceeds", func(t *testing.T) {
+		feedService, commentService, mock, _ := newServices(t)
+		moderator.checked = nil
+
+		// 审核通过后才查询作者，作者不存在时在写入前返回
+		for i := 0; i < 2; i++ {
+			mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+				WithArgs(userID).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}
+		_, err := feedService.CreatePost(ctx, userID.String(), &CreatePostRequest{Content: "good post"})
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			t.Errorf("CreatePost() error = %v, want not found after moderation", err)
+		}
+		_, err = commentService.CreateComment(ctx, userID.String(), postID.String(), &CreateCommentRequest{Content: "good comment"})
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			t.Errorf("CreateComment() error = %v, want not found after moderation", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(moderator.checked) != 2 || moderator.checked[0] != "good post" || moderator.checked[1] != "good comment" {
+			t.Errorf("moderated %v, want both contents checked", moderator.checked)
+		}
+	})
+}
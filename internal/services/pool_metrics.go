@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+// RedisPoolStats Redis连接池指标
+type RedisPoolStats struct {
+	PoolSize   int    `json:"pool_size"`
+	TotalConns uint32 `json:"total_conns"`
+	IdleConns  uint32 `json:"idle_conns"`
+	StaleConns uint32 `json:"stale_conns"`
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"` // 等待空闲连接超时的累计次数
+}
+
+// DBPoolStats 数据库连接池指标
+type DBPoolStats struct {
+	MaxOpenConns   int   `json:"max_open_conns"`
+	OpenConns      int   `json:"open_conns"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"` // 等待空闲连接的累计次数
+	WaitDurationMs int64 `json:"wait_duration_ms"`
+}
+
+// PoolStats 一次连接池采样
+type PoolStats struct {
+	Redis     RedisPoolStats `json:"redis"`
+	DB        DBPoolStats    `json:"db"`
+	SampledAt time.Time      `json:"sampled_at"`
+}
+
+// PoolMetrics 定期采样Redis和数据库连接池，连接池耗尽（出现等待）时记录警告
+type PoolMetrics struct {
+	cache         *cache.RedisClient
+	db            *repository.Database
+	redisPoolSize int
+	logger        *logger.Logger
+
+	mu   sync.RWMutex
+	last *PoolStats
+}
+
+func NewPoolMetrics(cache *cache.RedisClient, db *repository.Database, redisPoolSize int, logger *logger.Logger) *PoolMetrics {
+	return &PoolMetrics{
+		cache:         cache,
+		db:            db,
+		redisPoolSize: redisPoolSize,
+		logger:        logger,
+	}
+}
+
+// Sample 采样一次并更新最近一次的指标
+func (m *PoolMetrics) Sample() (*PoolStats, error) {
+	stats := &PoolStats{SampledAt: time.Now()}
+
+	redisStats := m.cache.PoolStats()
+	stats.Redis = RedisPoolStats{
+		PoolSize:   m.redisPoolSize,
+		TotalConns: redisStats.TotalConns,
+		IdleConns:  redisStats.IdleConns,
+		StaleConns: redisStats.StaleConns,
+		Hits:       redisStats.Hits,
+		Misses:     redisStats.Misses,
+		Timeouts:   redisStats.Timeouts,
+	}
+
+	dbStats, err := m.db.Stats()
+	if err != nil {
+		return nil, err
+	}
+	stats.DB = DBPoolStats{
+		MaxOpenConns:   dbStats.MaxOpenConnections,
+		OpenConns:      dbStats.OpenConnections,
+		InUse:          dbStats.InUse,
+		Idle:           dbStats.Idle,
+		WaitCount:      dbStats.WaitCount,
+		WaitDurationMs: dbStats.WaitDuration.Milliseconds(),
+	}
+
+	m.mu.Lock()
+	m.last = stats
+	m.mu.Unlock()
+	return stats, nil
+}
+
+// Snapshot 返回最近一次采样，尚未采样过时立即采样
+func (m *PoolMetrics) Snapshot() (*PoolStats, error) {
+	m.mu.RLock()
+	last := m.last
+	m.mu.RUnlock()
+	if last != nil {
+		return last, nil
+	}
+	return m.Sample()
+}
+
+// StartSampleJob 按interval定期采样，两次采样之间出现了等待连接时记录警告
+func (m *PoolMetrics) StartSampleJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev, err := m.Sample()
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to sample connection pools")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := m.Sample()
+			if err != nil {
+				m.logger.WithError(err).Error("Failed to sample connection pools")
+				continue
+			}
+			if prev != nil {
+				if stats.Redis.Timeouts > prev.Redis.Timeouts {
+					m.logger.WithFields(map[string]interface{}{
+						"pool_size":   stats.Redis.PoolSize,
+						"total_conns": stats.Redis.TotalConns,
+						"timeouts":    stats.Redis.Timeouts - prev.Redis.Timeouts,
+					}).Warn("Redis connection pool exhausted")
+				}
+				if stats.DB.WaitCount > prev.DB.WaitCount {
+					m.logger.WithFields(map[string]interface{}{
+						"max_open_conns": stats.DB.MaxOpenConns,
+						"in_use":         stats.DB.InUse,
+						"waits":          stats.DB.WaitCount - prev.DB.WaitCount,
+					}).Warn("Database connection pool exhausted")
+				}
+			}
+			prev = stats
+		}
+	}
+}
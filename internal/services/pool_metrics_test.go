@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+func TestPoolMetricsSample(t *testing.T) {
+	db, _ := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	metrics := NewPoolMetrics(redisClient, &repository.Database{DB: db}, 10, logger.NewLogger())
+	ctx := context.Background()
+
+	// 尚未采样时Snapshot立即采样
+	first, err := metrics.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if first.Redis.PoolSize != 10 || first.DB.MaxOpenConns != 1 || first.DB.WaitCount != 0 {
+		t.Errorf("first sample = %+v", first)
+	}
+
+	// 占住唯一的数据库连接，再取连接时需要等待
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := sqlDB.Conn(waitCtx); err == nil {
+		t.Fatal("expected waiting for a connection to time out")
+	}
+	mr.Set("k", "v")
+	if _, err := redisClient.Get(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 采样前Snapshot仍返回上一次的结果
+	if cached, err := metrics.Snapshot(); err != nil || cached != first {
+		t.Errorf("Snapshot() = %p, %v; want the previous sample %p", cached, err, first)
+	}
+
+	second, err := metrics.Sample()
+	if err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	if second.DB.InUse != 1 || second.DB.OpenConns != 1 || second.DB.WaitCount != 1 || second.DB.WaitDurationMs < 10 {
+		t.Errorf("db stats = %+v, want one connection in use and one wait", second.DB)
+	}
+	if second.Redis.TotalConns == 0 || second.Redis.Hits+second.Redis.Misses == 0 {
+		t.Errorf("redis stats = %+v, want the connection used by Get", second.Redis)
+	}
+	if latest, err := metrics.Snapshot(); err != nil || latest != second {
+		t.Errorf("Snapshot() did not return the latest sample")
+	}
+	conn.Close()
+}
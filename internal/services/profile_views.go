@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// 每个用户最多保留的最近访客数
+	MaxProfileViewers = 100
+	// 同一访客在该时间内重复访问只记录一次
+	ProfileViewDedupWindow = 24 * time.Hour
+	// 访客记录保留时间
+	ProfileViewersTTL = 30 * 24 * time.Hour
+)
+
+// ErrProfileViewsDisabled 用户关闭了"谁看过我"
+var ErrProfileViewsDisabled = apperrors.PermissionDenied("profile views are disabled")
+
+// ProfileViewer 资料访客
+type ProfileViewer struct {
+	User     *models.User `json:"user"`
+	ViewedAt time.Time    `json:"viewed_at"`
+}
+
+func profileViewersKey(userID string) string {
+	return fmt.Sprintf("profile_viewers:%s", userID)
+}
+
+// RecordProfileView 记录viewerID访问了profileUserID的资料，双方任一关闭该功能时不记录
+func (s *UserService) RecordProfileView(ctx context.Context, viewerID, profileUserID string) error {
+	if viewerID == "" || viewerID == profileUserID {
+		return nil
+	}
+
+	owner, err := s.GetByID(ctx, profileUserID)
+	if err != nil {
+		return err
+	}
+	viewer, err := s.GetByID(ctx, viewerID)
+	if err != nil {
+		return err
+	}
+	if !owner.ShowProfileViews || !viewer.ShowProfileViews {
+		return nil
+	}
+
+	// 一天内同一访客只记录一次
+	dedupKey := fmt.Sprintf("profile_view:%s:%s", profileUserID, viewerID)
+	first, err := s.cache.SetNX(ctx, dedupKey, "1", ProfileViewDedupWindow)
+	if err != nil {
+		return fmt.Errorf("failed to dedup profile view: %w", err)
+	}
+	if !first {
+		return nil
+	}
+
+	key := profileViewersKey(profileUserID)
+	pipe := s.cache.Pipeline()
+	pipe.ZAdd(ctx, key, &redis.Z{Score: float64(time.Now().Unix()), Member: viewerID})
+	pipe.ZRemRangeByRank(ctx, key, 0, -MaxProfileViewers-1)
+	pipe.Expire(ctx, key, ProfileViewersTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record profile view: %w", err)
+	}
+	return nil
+}
+
+// GetProfileViewers 获取最近访问用户资料的访客，按访问时间倒序
+func (s *UserService) GetProfileViewers(ctx context.Context, userID string, limit int) ([]*ProfileViewer, error) {
+	owner, err := s.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !owner.ShowProfileViews {
+		return nil, ErrProfileViewsDisabled
+	}
+
+	results, err := s.cache.ZRevRangeWithScores(ctx, profileViewersKey(userID), 0, int64(limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile viewers: %w", err)
+	}
+	if len(results) == 0 {
+		return []*ProfileViewer{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(results))
+	for _, result := range results {
+		if id, err := uuid.Parse(result.Member.(string)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID.String()] = u
+	}
+
+	// 访客之后关闭了该功能时不再展示
+	viewers := make([]*ProfileViewer, 0, len(results))
+	for _, result := range results {
+		u, ok := byID[result.Member.(string)]
+		if !ok || !u.ShowProfileViews {
+			continue
+		}
+		viewers = append(viewers, &ProfileViewer{
+			User:     u,
+			ViewedAt: time.Unix(int64(result.Score), 0),
+		})
+	}
+	return viewers, nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// cacheProfile 把用户资料写入资料缓存，GetByID不再访问数据库
+func cacheProfile(t *testing.T, mr *miniredis.Miniredis, user *models.User) {
+	t.Helper()
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set(ProfileCacheKey(user.ID.String()), string(data))
+}
+
+func TestRecordProfileViewDedupsWithinWindow(t *testing.T) {
+	service, mock, mr, _ := newUserTestService(t)
+	ctx := context.Background()
+	owner := &models.User{ID: uuid.New(), ShowProfileViews: true}
+	viewer := &models.User{ID: uuid.New(), Username: "viewer", ShowProfileViews: true}
+	cacheProfile(t, mr, owner)
+	cacheProfile(t, mr, viewer)
+
+	for i := 0; i < 3; i++ {
+		if err := service.RecordProfileView(ctx, viewer.ID.String(), owner.ID.String()); err != nil {
+			t.Fatalf("RecordProfileView: %v", err)
+		}
+	}
+	// 访问自己的资料不记录
+	if err := service.RecordProfileView(ctx, owner.ID.String(), owner.ID.String()); err != nil {
+		t.Fatalf("RecordProfileView(self): %v", err)
+	}
+
+	members, err := mr.ZMembers(profileViewersKey(owner.ID.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != viewer.ID.String() {
+		t.Fatalf("viewers = %v, want only %s", members, viewer.ID)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "show_profile_views"}).AddRow(viewer.ID, viewer.Username, true))
+	viewers, err := service.GetProfileViewers(ctx, owner.ID.String(), 10)
+	if err != nil {
+		t.Fatalf("GetProfileViewers: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if len(viewers) != 1 || viewers[0].User.ID != viewer.ID || viewers[0].ViewedAt.IsZero() {
+		t.Errorf("viewers = %+v, want one entry for %s", viewers, viewer.ID)
+	}
+
+	// 去重窗口过期后再次访问重新记录，访客列表仍只有一条
+	dedupKey := "profile_view:" + owner.ID.String() + ":" + viewer.ID.String()
+	mr.FastForward(ProfileViewDedupWindow)
+	if mr.Exists(dedupKey) {
+		t.Fatal("dedup marker outlived its window")
+	}
+	if err := service.RecordProfileView(ctx, viewer.ID.String(), owner.ID.String()); err != nil {
+		t.Fatalf("RecordProfileView: %v", err)
+	}
+	if !mr.Exists(dedupKey) {
+		t.Error("view after the window was not recorded")
+	}
+	if members, _ := mr.ZMembers(profileViewersKey(owner.ID.String())); len(members) != 1 {
+		t.Errorf("viewers after window = %v, want still one entry", members)
+	}
+}
+
+func TestProfileViewsPrivacyToggle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("owner opted out", func(t *testing.T) {
+		service, mock, mr, _ := newUserTestService(t)
+		owner := &models.User{ID: uuid.New(), ShowProfileViews: false}
+		viewer := &models.User{ID: uuid.New(), ShowProfileViews: true}
+		cacheProfile(t, mr, owner)
+		cacheProfile(t, mr, viewer)
+
+		if err := service.RecordProfileView(ctx, viewer.ID.String(), owner.ID.String()); err != nil {
+			t.Fatalf("RecordProfileView: %v", err)
+		}
+		if mr.Exists(profileViewersKey(owner.ID.String())) {
+			t.Error("view recorded for a user who opted out")
+		}
+		if _, err := service.GetProfileViewers(ctx, owner.ID.String(), 10); !errors.Is(err, apperrors.ErrPermissionDenied) {
+			t.Errorf("GetProfileViewers() error = %v, want permission denied", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("viewer opted out", func(t *testing.T) {
+		service, mock, mr, _ := newUserTestService(t)
+		owner := &models.User{ID: uuid.New(), ShowProfileViews: true}
+		viewer := &models.User{ID: uuid.New(), ShowProfileViews: false}
+		cacheProfile(t, mr, owner)
+		cacheProfile(t, mr, viewer)
+
+		if err := service.RecordProfileView(ctx, viewer.ID.String(), owner.ID.String()); err != nil {
+			t.Fatalf("RecordProfileView: %v", err)
+		}
+		if mr.Exists(profileViewersKey(owner.ID.String())) {
+			t.Error("view recorded from a viewer who opted out")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("viewer opted out after viewing", func(t *testing.T) {
+		service, mock, mr, _ := newUserTestService(t)
+		owner := &models.User{ID: uuid.New(), ShowProfileViews: true}
+		viewer := &models.User{ID: uuid.New(), ShowProfileViews: true}
+		cacheProfile(t, mr, owner)
+		cacheProfile(t, mr, viewer)
+		if err := service.RecordProfileView(ctx, viewer.ID.String(), owner.ID.String()); err != nil {
+			t.Fatalf("RecordProfileView: %v", err)
+		}
+
+		// 已记录的访客之后关闭该功能，不再出现在列表中
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "show_profile_views"}).AddRow(viewer.ID, false))
+		viewers, err := service.GetProfileViewers(ctx, owner.ID.String(), 10)
+		if err != nil {
+			t.Fatalf("GetProfileViewers: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		if len(viewers) != 0 {
+			t.Errorf("viewers = %+v, want none", viewers)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/feed-system/feed-system/internal/config"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
 	"github.com/feed-system/feed-system/pkg/cache"
@@ -19,6 +20,7 @@ type RecoveryService struct {
 	userRepo             *repository.UserRepository
 	followRepo           *repository.FollowRepository
 	cache                *cache.RedisClient
+	config               *config.ConfigWatcher
 	logger               *logger.Logger
 	activityService      *ActivityService
 	timelineCacheService *TimelineCacheService
@@ -29,6 +31,7 @@ func NewRecoveryService(
 	userRepo *repository.UserRepository,
 	followRepo *repository.FollowRepository,
 	cache *cache.RedisClient,
+	config *config.ConfigWatcher,
 	logger *logger.Logger,
 	activityService *ActivityService,
 	timelineCacheService *TimelineCacheService,
@@ -38,6 +41,7 @@ func NewRecoveryService(
 		userRepo:             userRepo,
 		followRepo:           followRepo,
 		cache:                cache,
+		config:               config,
 		logger:               logger,
 		activityService:      activityService,
 		timelineCacheService: timelineCacheService,
@@ -126,6 +130,12 @@ func (s *RecoveryService) recoverSingleDistribution(ctx context.Context, key str
 		return nil
 	}
 
+	// 过旧的帖子不再补推、仅密友可见的帖子不走推/拉分层，清理状态
+	if !s.config.Feed().IsPushEligible(post.CreatedAt) || post.IsCloseFriendsOnly() {
+		s.cache.Delete(ctx, key)
+		return nil
+	}
+
 	// 根据状态进行恢复
 	switch status.Status {
 	case "influencer_push_started":
@@ -176,7 +186,7 @@ func (s *RecoveryService) recoverInfluencerDistribution(ctx context.Context, pos
 
 	// 推送给需要的用户
 	if len(needDistribution) > 0 {
-		if err := s.timelineCacheService.BatchAddToTimeline(ctx, needDistribution, post.ID, post.Score, post.CreatedAt); err != nil {
+		if err := s.timelineCacheService.BatchAddToTimelineIfAbsent(ctx, needDistribution, post.ID, post.Score, post.CreatedAt); err != nil {
 			return fmt.Errorf("failed to batch add to timeline during recovery: %w", err)
 		}
 	}
@@ -218,7 +228,7 @@ func (s *RecoveryService) recoverRegularDistribution(ctx context.Context, post *
 
 	// 推送给需要的用户
 	if len(needDistribution) > 0 {
-		if err := s.timelineCacheService.BatchAddToTimeline(ctx, needDistribution, post.ID, post.Score, post.CreatedAt); err != nil {
+		if err := s.timelineCacheService.BatchAddToTimelineIfAbsent(ctx, needDistribution, post.ID, post.Score, post.CreatedAt); err != nil {
 			return fmt.Errorf("failed to batch add to timeline during recovery: %w", err)
 		}
 	}
@@ -274,7 +284,7 @@ func (s *RecoveryService) updateDistributionStatus(ctx context.Context, key, sta
 
 	// 已完成的任务保存较短时间，用于监控
 	ttl := 1 * time.Hour
-	if status == "completed" {
+	if status == distributionStatusCompleted {
 		ttl = 1 * time.Hour
 	} else {
 		ttl = 24 * time.Hour
@@ -336,7 +346,7 @@ func (s *RecoveryService) GetDistributionStats(ctx context.Context) (map[string]
 		}
 
 		switch status.Status {
-		case "influencer_push_completed", "regular_push_completed":
+		case "influencer_push_completed", "regular_push_completed", distributionStatusCompleted:
 			stats["completed"]++
 		case "influencer_push_started", "regular_push_started":
 			// 检查是否超时
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestExplainScoresSumToStoredScore(t *testing.T) {
+	service := &FeedService{}
+	author := models.User{ID: uuid.New(), Followers: 1200, Following: 30}
+
+	// 存储的分数来自上次重算，之后互动数又增加了，重算结果与存储值不同
+	post := &models.Post{
+		ID:           uuid.New(),
+		UserID:       author.ID,
+		LikeCount:    40,
+		CommentCount: 5,
+		Score:        3.5,
+		CreatedAt:    time.Now().Add(-6 * time.Hour),
+		User:         author,
+	}
+
+	breakdowns := service.ExplainScores([]*models.Post{post})
+	if len(breakdowns) != 1 {
+		t.Fatalf("got %d breakdowns, want 1", len(breakdowns))
+	}
+	b := breakdowns[0]
+
+	if b.BaseScore <= 0 || b.EngagementScore <= 0 || b.Decay <= 0 || b.Decay > 1 {
+		t.Errorf("breakdown fields missing: %+v", b)
+	}
+	if b.FinalScore != post.Score {
+		t.Errorf("FinalScore = %v, want stored score %v", b.FinalScore, post.Score)
+	}
+	if sum := b.BaseScore + b.EngagementScore + b.AffinityBoost; math.Abs(sum-post.Score) > 1e-9 {
+		t.Errorf("contributions sum to %v, want stored score %v", sum, post.Score)
+	}
+	if math.Abs(b.RecomputedScore-service.calculatePostScore(post, &author)) > 1e-9 {
+		t.Errorf("RecomputedScore = %v, want %v", b.RecomputedScore, service.calculatePostScore(post, &author))
+	}
+}
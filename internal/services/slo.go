@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+const (
+	// 窗口内最多保留的延迟样本数，超出后丢弃最旧的样本
+	MaxSLOSamples = 10000
+)
+
+// 延迟直方图的桶边界（毫秒）
+var latencyBucketsMs = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500}
+
+// SLOStatus SLO当前状态
+type SLOStatus struct {
+	Name        string           `json:"name"`
+	TargetMs    float64          `json:"target_ms"`
+	P99Ms       float64          `json:"p99_ms"`
+	SampleCount int              `json:"sample_count"`
+	Window      string           `json:"window"`
+	Breached    bool             `json:"breached"`
+	Histogram   map[string]int64 `json:"histogram"`
+	EvaluatedAt time.Time        `json:"evaluated_at"`
+}
+
+// SLOHook SLO状态变化（违约/恢复）时的回调
+type SLOHook func(ctx context.Context, status SLOStatus)
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// SLOService Feed延迟SLO评估服务
+type SLOService struct {
+	name   string
+	config config.SLOConfig
+	logger *logger.Logger
+
+	mu       sync.Mutex
+	samples  []latencySample
+	breached bool
+	hooks    []SLOHook
+}
+
+func NewSLOService(cfg config.SLOConfig, logger *logger.Logger) *SLOService {
+	s := &SLOService{
+		name:   "feed_p99_latency",
+		config: cfg,
+		logger: logger,
+	}
+
+	// 默认记录日志，配置了webhook时额外回调
+	s.AddHook(s.logHook)
+	if cfg.WebhookURL != "" {
+		s.AddHook(NewWebhookSLOHook(cfg.WebhookURL, logger))
+	}
+
+	return s
+}
+
+// AddHook 注册SLO状态变化回调
+func (s *SLOService) AddHook(hook SLOHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Record 记录一次Feed请求延迟
+func (s *SLOService) Record(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, latencySample{at: time.Now(), duration: duration})
+	if len(s.samples) > MaxSLOSamples {
+		s.samples = s.samples[len(s.samples)-MaxSLOSamples:]
+	}
+}
+
+// Status 计算窗口内的SLO状态，状态变化时触发回调
+func (s *SLOService) Status(ctx context.Context) SLOStatus {
+	s.mu.Lock()
+	status := s.evaluateLocked(time.Now())
+	changed := status.Breached != s.breached
+	s.breached = status.Breached
+	hooks := append([]SLOHook(nil), s.hooks...)
+	s.mu.Unlock()
+
+	if changed {
+		for _, hook := range hooks {
+			hook(ctx, status)
+		}
+	}
+
+	return status
+}
+
+// StartEvaluationJob 定期评估SLO，使状态变化即使没有查询也能触发回调
+func (s *SLOService) StartEvaluationJob(ctx context.Context) {
+	interval := s.config.EvaluationInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("SLO evaluation job stopped")
+			return
+		case <-ticker.C:
+			s.Status(ctx)
+		}
+	}
+}
+
+// evaluateLocked 丢弃窗口外样本并计算p99和直方图，调用方需持有锁
+func (s *SLOService) evaluateLocked(now time.Time) SLOStatus {
+	window := s.config.Window
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+
+	// 样本按时间追加，找到第一个仍在窗口内的样本
+	cutoff := now.Add(-window)
+	first := sort.Search(len(s.samples), func(i int) bool {
+		return s.samples[i].at.After(cutoff)
+	})
+	s.samples = s.samples[first:]
+
+	durations := make([]time.Duration, len(s.samples))
+	for i, sample := range s.samples {
+		durations[i] = sample.duration
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var p99 time.Duration
+	if len(durations) > 0 {
+		idx := (len(durations)*99+99)/100 - 1
+		p99 = durations[idx]
+	}
+
+	target := s.config.FeedP99Target
+
+	return SLOStatus{
+		Name:        s.name,
+		TargetMs:    toMs(target),
+		P99Ms:       toMs(p99),
+		SampleCount: len(durations),
+		Window:      window.String(),
+		Breached:    target > 0 && len(durations) > 0 && p99 > target,
+		Histogram:   buildLatencyHistogram(durations),
+		EvaluatedAt: now,
+	}
+}
+
+// logHook 记录SLO状态变化日志
+func (s *SLOService) logHook(ctx context.Context, status SLOStatus) {
+	entry := s.logger.WithFields(map[string]interface{}{
+		"slo":       status.Name,
+		"p99_ms":    status.P99Ms,
+		"target_ms": status.TargetMs,
+		"samples":   status.SampleCount,
+	})
+	if status.Breached {
+		entry.Warn("SLO breached")
+	} else {
+		entry.Info("SLO recovered")
+	}
+}
+
+// NewWebhookSLOHook 创建将SLO状态POST到指定URL的回调
+func NewWebhookSLOHook(url string, logger *logger.Logger) SLOHook {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(ctx context.Context, status SLOStatus) {
+		body, err := json.Marshal(status)
+		if err != nil {
+			logger.WithError(err).Error("Failed to marshal SLO status")
+			return
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			logger.WithError(err).Error("Failed to build SLO webhook request")
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.WithError(err).Error("Failed to call SLO webhook")
+			return
+		}
+		resp.Body.Close()
+	}
+}
+
+// buildLatencyHistogram 按桶统计延迟分布（非累积）
+func buildLatencyHistogram(sorted []time.Duration) map[string]int64 {
+	histogram := make(map[string]int64, len(latencyBucketsMs)+1)
+	for _, bound := range latencyBucketsMs {
+		histogram[fmt.Sprintf("le_%gms", bound)] = 0
+	}
+	histogram["gt_2500ms"] = 0
+
+	for _, d := range sorted {
+		ms := toMs(d)
+		placed := false
+		for _, bound := range latencyBucketsMs {
+			if ms <= bound {
+				histogram[fmt.Sprintf("le_%gms", bound)]++
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			histogram["gt_2500ms"]++
+		}
+	}
+
+	return histogram
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
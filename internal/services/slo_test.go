@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/logger"
+)
+
+func TestSLOStatusFlipsWhenP99CrossesTarget(t *testing.T) {
+	webhook := make(chan SLOStatus, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var status SLOStatus
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		webhook <- status
+	}))
+	defer server.Close()
+
+	slo := NewSLOService(config.SLOConfig{
+		FeedP99Target: 100 * time.Millisecond,
+		Window:        time.Minute,
+		WebhookURL:    server.URL,
+	}, logger.NewLogger())
+	ctx := context.Background()
+
+	// 100个样本中只有1个慢请求，p99仍在目标内
+	for i := 0; i < 99; i++ {
+		slo.Record(20 * time.Millisecond)
+	}
+	slo.Record(3 * time.Second)
+	status := slo.Status(ctx)
+	if status.Breached || status.SampleCount != 100 || status.P99Ms != 20 {
+		t.Fatalf("status = %+v, want p99 20ms and not breached", status)
+	}
+	if status.Histogram["le_25ms"] != 99 || status.Histogram["gt_2500ms"] != 1 {
+		t.Errorf("histogram = %v", status.Histogram)
+	}
+
+	// 慢请求超过1%后p99超出目标，状态变为违约并回调
+	for i := 0; i < 5; i++ {
+		slo.Record(400 * time.Millisecond)
+	}
+	status = slo.Status(ctx)
+	if !status.Breached || status.P99Ms <= 100 {
+		t.Fatalf("status = %+v, want breached", status)
+	}
+	select {
+	case got := <-webhook:
+		if !got.Breached {
+			t.Errorf("webhook status = %+v, want breached", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook not called on breach")
+	}
+
+	// 状态没有变化时不重复回调
+	slo.Status(ctx)
+	select {
+	case got := <-webhook:
+		t.Errorf("unexpected webhook call %+v", got)
+	default:
+	}
+}
+
+func TestSLOStatusDropsSamplesOutsideWindow(t *testing.T) {
+	slo := NewSLOService(config.SLOConfig{FeedP99Target: 100 * time.Millisecond, Window: time.Minute}, logger.NewLogger())
+	slo.samples = []latencySample{
+		{at: time.Now().Add(-2 * time.Minute), duration: time.Second},
+		{at: time.Now(), duration: 10 * time.Millisecond},
+	}
+
+	status := slo.Status(context.Background())
+	if status.Breached || status.SampleCount != 1 {
+		t.Errorf("status = %+v, want the stale slow sample ignored", status)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestRedis 基于miniredis的Redis客户端
+func newTestRedis(t testing.TB) (*cache.RedisClient, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+// newTestDB 基于sqlmock的gorm连接
+func newTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db, mock
+}
+
+// newTestConfig 测试用的Feed配置，mutate可覆盖个别字段
+func newTestConfig(mutate func(*config.FeedConfig)) *config.ConfigWatcher {
+	feed := &config.FeedConfig{
+		PushThreshold: 1000,
+		CacheTTL:      time.Hour,
+		MaxFeedSize:   1000,
+	}
+	if mutate != nil {
+		mutate(feed)
+	}
+	return config.NewConfigWatcher(feed, logger.NewLogger())
+}
+
+// fakePublisher 记录发布的事件
+type fakePublisher struct {
+	events []queue.Event
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, key string, value interface{}) error {
+	if event, ok := value.(queue.Event); ok {
+		f.events = append(f.events, event)
+	}
+	return nil
+}
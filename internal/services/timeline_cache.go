@@ -3,9 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/feed-system/feed-system/internal/config"
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
@@ -16,145 +21,562 @@ import (
 // TimelineCacheService Redis Timeline缓存服务
 type TimelineCacheService struct {
 	cache  *cache.RedisClient
+	config *config.ConfigWatcher
 	logger *logger.Logger
+
+	capResolver TimelineCapResolver
+
+	defaultPageLimit int
+	maxPageLimit     int
 }
 
-func NewTimelineCacheService(cache *cache.RedisClient, logger *logger.Logger) *TimelineCacheService {
+// TimelineCapResolver 批量返回用户Timeline的条数上限，未返回的用户使用MaxTimelineSize
+type TimelineCapResolver func(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]int
+
+func NewTimelineCacheService(cache *cache.RedisClient, config *config.ConfigWatcher, logger *logger.Logger) *TimelineCacheService {
 	return &TimelineCacheService{
-		cache:  cache,
-		logger: logger,
+		cache:            cache,
+		config:           config,
+		logger:           logger,
+		defaultPageLimit: DefaultTimelinePage,
+		maxPageLimit:     MaxTimelinePage,
 	}
 }
 
+// SetCapResolver 设置写入时按用户档位裁剪Timeline的上限来源，不设置时统一裁剪到MaxTimelineSize
+func (s *TimelineCacheService) SetCapResolver(resolver TimelineCapResolver) {
+	s.capResolver = resolver
+}
+
+// SetPageLimits 按server配置设置默认页大小和单页最大条数，需与handler层的分页上限一致
+func (s *TimelineCacheService) SetPageLimits(defaultLimit, maxLimit int) {
+	s.defaultPageLimit = defaultLimit
+	s.maxPageLimit = maxLimit
+}
+
 const (
 	// Timeline缓存配置
 	TimelineCacheTTL     = 24 * time.Hour     // Timeline缓存过期时间
 	MaxTimelineSize      = 1000               // 每个用户Timeline最大条数
+	DefaultFanoutChunk   = 500                // 扇出默认分块大小
+	DefaultFanoutWorkers = 4                  // 扇出默认并发数
 	ActiveUserCacheTTL   = 7 * 24 * time.Hour // 活跃用户缓存时间更长
 	InactiveUserCacheTTL = 2 * time.Hour      // 非活跃用户缓存时间较短
+	DefaultTimelinePage  = 20                 // 未指定limit时的分页大小，未调用SetPageLimits时使用
+	MaxTimelinePage      = 100                // 单页最大条数，未调用SetPageLimits时使用
 )
 
-// TimelineItem Timeline条目
-type TimelineItem struct {
-	PostID    string    `json:"post_id"`
-	Score     float64   `json:"score"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-// AddToTimeline 添加帖子到用户Timeline
-func (s *TimelineCacheService) AddToTimeline(ctx context.Context, userID uuid.UUID, postID uuid.UUID, score float64, timestamp time.Time) error {
-	key := s.getTimelineKey(userID)
+// ErrInvalidCursor 游标格式不合法
+var ErrInvalidCursor = apperrors.InvalidInput("invalid cursor")
 
-	// 使用时间戳作为score，确保时间顺序
-	scoreValue := float64(timestamp.Unix())
+// parseTimelineCursor 解析Timeline游标，返回对应的Unix时间戳和同分时的帖子ID（可能为空）
+// 支持Timeline缓存返回的"时间戳_帖子ID"游标、旧版纯时间戳游标，以及拉模式返回的"创建时间_帖子ID"和旧版RFC3339时间游标（两种模式会互相回退）
+func parseTimelineCursor(cursor string) (float64, string, error) {
+	if createdAt, _, ok := pullCursorTime(cursor); ok {
+		return float64(createdAt.Unix()), "", nil
+	}
+	var member string
+	if idx := strings.LastIndex(cursor, "_"); idx >= 0 {
+		if _, err := uuid.Parse(cursor[idx+1:]); err != nil {
+			return 0, "", ErrInvalidCursor
+		}
+		cursor, member = cursor[:idx], cursor[idx+1:]
+	}
+	if score, err := strconv.ParseFloat(cursor, 64); err == nil {
+		if math.IsNaN(score) || math.IsInf(score, 0) || score < 0 {
+			return 0, "", ErrInvalidCursor
+		}
+		return score, member, nil
+	}
+	return 0, "", ErrInvalidCursor
+}
 
-	// 添加到SortedSet
-	if err := s.cache.ZAdd(ctx, key, &redis.Z{
-		Score:  scoreValue,
-		Member: postID.String(),
-	}); err != nil {
-		return fmt.Errorf("failed to add to timeline: %w", err)
+// pullCursorTime 解析拉模式返回的"创建时间_帖子ID"游标或旧版纯RFC3339时间游标（ID为uuid.Nil），其他格式返回false
+func pullCursorTime(cursor string) (time.Time, uuid.UUID, bool) {
+	if createdAt, id, err := parseTimeIDCursor(cursor); err == nil {
+		return createdAt, id, true
 	}
+	createdAt, err := time.Parse(time.RFC3339Nano, cursor)
+	return createdAt, uuid.Nil, err == nil
+}
 
-	// 限制Timeline大小，删除最旧的条目
-	if err := s.cache.ZRemRangeByRank(ctx, key, 0, -MaxTimelineSize-1); err != nil {
-		s.logger.WithError(err).Error("Failed to trim timeline")
+// postsBeforePullCursor 游标来自拉模式时，只保留(创建时间, 帖子ID)排在游标之后的帖子（之前的拉模式上一页已返回），其他游标原样返回。
+// Timeline的score只精确到秒，GetTimeline对这类游标包含了同一秒的条目，需要按帖子的创建时间再过滤一次
+func postsBeforePullCursor(posts []*models.Post, cursor string) []*models.Post {
+	beforeCreatedAt, beforeID, ok := pullCursorTime(cursor)
+	if !ok {
+		return posts
 	}
+	bound := &models.Post{ID: beforeID, CreatedAt: beforeCreatedAt}
+	kept := posts[:0]
+	for _, post := range posts {
+		if postNewer(bound, post) {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
 
-	// 设置过期时间
-	if err := s.cache.Expire(ctx, key, TimelineCacheTTL); err != nil {
-		s.logger.WithError(err).Error("Failed to set timeline expiration")
+// clampTimelineLimit 将分页大小限制在[1, maxLimit]内，非正数使用defaultLimit
+func clampTimelineLimit(limit, defaultLimit, maxLimit int) int {
+	if limit <= 0 {
+		return defaultLimit
 	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
 
-	return nil
+// TimelineItem Timeline条目
+type TimelineItem struct {
+	PostID    string    `json:"post_id"`
+	Score     float64   `json:"score"`      // 所在ZSet中的score（时间线为时间戳，排序时间线为帖子分数）
+	RankScore float64   `json:"rank_score"` // 帖子排序分数，从排序时间线读取，不存在时为0
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AddToTimeline 添加帖子到用户Timeline
+func (s *TimelineCacheService) AddToTimeline(ctx context.Context, userID uuid.UUID, postID uuid.UUID, score float64, timestamp time.Time) error {
+	// 时间线使用时间戳作为score确保时间顺序，排序时间线使用帖子分数
+	return s.addChunkToTimeline(ctx, []uuid.UUID{userID}, postID, float64(timestamp.Unix()), score, false)
 }
 
 // GetTimeline 获取用户Timeline (基于游标分页)
 func (s *TimelineCacheService) GetTimeline(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]TimelineItem, string, bool, error) {
 	key := s.getTimelineKey(userID)
+	limit = clampTimelineLimit(limit, s.defaultPageLimit, s.maxPageLimit)
 
-	// 解析游标
+	// 解析游标，格式错误直接返回，避免静默返回错误的分页
 	var maxScore float64 = float64(time.Now().Unix()) // 默认从当前时间开始
+	var afterMember string
 	if cursor != "" {
-		if score, err := strconv.ParseFloat(cursor, 64); err == nil {
-			maxScore = score
+		score, member, err := parseTimelineCursor(cursor)
+		if err != nil {
+			return nil, "", false, err
 		}
+		maxScore, afterMember = score, member
 	}
 
-	// 使用ZRevRangeByScore获取数据，按时间倒序
+	// 游标带帖子ID时包含同分的条目，再跳过上一页已返回的部分，避免同一秒内的帖子在翻页边界被漏掉；
+	// 拉模式的时间游标同样包含这一秒，由调用方用postsBeforePullCursor按创建时间过滤
+	upper := fmt.Sprintf("(%f", maxScore) // 不包含cursor本身
+	count := int64(limit + 1)             // 多获取一个判断是否还有更多
+	_, _, fromPull := pullCursorTime(cursor)
+	if afterMember != "" || fromPull {
+		upper = fmt.Sprintf("%f", maxScore)
+		ties, err := s.cache.ZCount(ctx, key, upper, upper)
+		if err != nil {
+			return nil, "", false, fmt.Errorf("failed to count timeline ties: %w", err)
+		}
+		count += ties
+	}
+
+	// 使用ZRevRangeByScore获取数据，按时间倒序，同分按帖子ID倒序
 	results, err := s.cache.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
 		Min:   "-inf",
-		Max:   fmt.Sprintf("(%f", maxScore), // 不包含cursor本身
-		Count: int64(limit + 1),             // 多获取一个判断是否还有更多
+		Max:   upper,
+		Count: count,
 	})
 	if err != nil {
 		return nil, "", false, fmt.Errorf("failed to get timeline: %w", err)
 	}
+	if afterMember != "" {
+		results = skipTimelineResultsThrough(results, maxScore, afterMember)
+	}
+
+	items, nextCursor, hasMore := pageTimelineResults(results, limit)
+
+	s.fillRankScores(ctx, userID, items)
+
+	return items, nextCursor, hasMore, nil
+}
+
+// skipTimelineResultsThrough 跳过与游标同分且排在游标帖子之前（含）的条目
+// ZRevRangeByScore对同分成员按字典序倒序返回，上一页已返回的同分成员都不小于游标帖子ID
+func skipTimelineResultsThrough(results []redis.Z, score float64, member string) []redis.Z {
+	kept := results[:0]
+	for _, result := range results {
+		if result.Score == score && result.Member.(string) >= member {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept
+}
+
+// pageTimelineResults 将多取一条的查询结果截断为一页：超过limit条时hasMore为true，
+// 游标取截断后最后一条返回条目的score和帖子ID，下一页从它之后开始（不含）
+func pageTimelineResults(results []redis.Z, limit int) ([]TimelineItem, string, bool) {
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+
+	items := make([]TimelineItem, 0, len(results))
+	for _, result := range results {
+		items = append(items, TimelineItem{
+			PostID:    result.Member.(string),
+			Score:     result.Score,
+			Timestamp: time.Unix(int64(result.Score), 0),
+		})
+	}
 
-	var items []TimelineItem
 	var nextCursor string
-	hasMore := false
+	if len(items) > 0 {
+		last := items[len(items)-1]
+		nextCursor = fmt.Sprintf("%.0f_%s", last.Score, last.PostID)
+	}
+	return items, nextCursor, hasMore
+}
+
+// PostFetcher 按ID批量获取帖子，返回顺序不限
+type PostFetcher func(ctx context.Context, postIDs []uuid.UUID) ([]*models.Post, error)
+
+// GetTimelineHydrated 获取用户Timeline并直接返回完整帖子，顺序与Timeline一致，跳过不存在或已删除的帖子
+// nextCursor为空表示Timeline中没有条目（未缓存或已翻到底）
+func (s *TimelineCacheService) GetTimelineHydrated(ctx context.Context, userID uuid.UUID, cursor string, limit int, fetch PostFetcher) ([]*models.Post, string, bool, error) {
+	items, nextCursor, hasMore, err := s.GetTimeline(ctx, userID, cursor, limit)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if len(items) == 0 {
+		return []*models.Post{}, "", false, nil
+	}
+
+	posts, err := hydrateTimelineItems(ctx, items, fetch)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return postsBeforePullCursor(posts, cursor), nextCursor, hasMore, nil
+}
+
+// hydrateTimelineItems 根据Timeline项获取完整的Post信息，按Timeline顺序返回
+func hydrateTimelineItems(ctx context.Context, items []TimelineItem, fetch PostFetcher) ([]*models.Post, error) {
+	var postIDs []uuid.UUID
+	for _, item := range items {
+		if postID, err := uuid.Parse(item.PostID); err == nil {
+			postIDs = append(postIDs, postID)
+		}
+	}
+
+	if len(postIDs) == 0 {
+		return []*models.Post{}, nil
+	}
+
+	posts, err := fetch(ctx, postIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+	}
+
+	postMap := make(map[uuid.UUID]*models.Post, len(posts))
+	for _, post := range posts {
+		postMap[post.ID] = post
+	}
+
+	orderedPosts := make([]*models.Post, 0, len(postIDs))
+	for _, postID := range postIDs {
+		if post, exists := postMap[postID]; exists && !post.IsDeleted {
+			orderedPosts = append(orderedPosts, post)
+		}
+	}
+
+	return orderedPosts, nil
+}
+
+// GetTimelineSince 获取比since更新的Timeline条目（用于增量轮询），返回条目（时间倒序）、新条目总数和下一次轮询的since
+// since与分页游标格式相同（"时间戳_帖子ID"），同一秒内的条目按帖子ID区分，不会因为秒级精度被漏掉；
+// 新条目超过limit时返回紧挨since的最旧limit条，客户端用返回的since继续轮询即可无缝衔接
+func (s *TimelineCacheService) GetTimelineSince(ctx context.Context, userID uuid.UUID, since string, limit int) ([]TimelineItem, int64, string, error) {
+	key := s.getTimelineKey(userID)
+
+	sinceScore, afterMember, err := parseTimelineCursor(since)
+	if err != nil {
+		return nil, 0, since, err
+	}
 
-	// 处理结果
+	// 带帖子ID时包含同分条目，再跳过since及之前的同分条目；旧版纯时间戳since不包含该秒
+	min := fmt.Sprintf("(%f", sinceScore)
+	total, err := s.cache.ZCount(ctx, key, min, "+inf")
+	if err != nil {
+		return nil, 0, since, fmt.Errorf("failed to count new timeline items: %w", err)
+	}
+	var skipped int64
+	if afterMember != "" {
+		min = fmt.Sprintf("%f", sinceScore)
+		ties, err := s.cache.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: min})
+		if err != nil {
+			return nil, 0, since, fmt.Errorf("failed to get timeline ties: %w", err)
+		}
+		for _, tie := range ties {
+			if tie.Member.(string) > afterMember {
+				total++
+			} else {
+				skipped++
+			}
+		}
+	}
+	if total == 0 {
+		return []TimelineItem{}, 0, since, nil
+	}
+
+	results, err := s.cache.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   min,
+		Max:   "+inf",
+		Count: int64(limit) + skipped,
+	})
+	if err != nil {
+		return nil, 0, since, fmt.Errorf("failed to get new timeline items: %w", err)
+	}
+	if afterMember != "" {
+		results = skipTimelineResultsUpTo(results, sinceScore, afterMember)
+	}
 	if len(results) > limit {
-		hasMore = true
 		results = results[:limit]
 	}
 
-	for _, result := range results {
-		item := TimelineItem{
+	items := make([]TimelineItem, len(results))
+	nextSince := since
+	for i, result := range results {
+		// 结果按时间正序，倒序放入以便和普通分页展示顺序一致
+		items[len(results)-1-i] = TimelineItem{
 			PostID:    result.Member.(string),
 			Score:     result.Score,
 			Timestamp: time.Unix(int64(result.Score), 0),
 		}
-		items = append(items, item)
-		nextCursor = fmt.Sprintf("%.0f", result.Score)
+		nextSince = fmt.Sprintf("%.0f_%s", result.Score, result.Member.(string))
 	}
+	s.fillRankScores(ctx, userID, items)
 
-	return items, nextCursor, hasMore, nil
+	return items, total, nextSince, nil
+}
+
+// skipTimelineResultsUpTo 跳过与since同分且不晚于since帖子的条目
+// ZRangeByScore对同分成员按字典序正序返回，上一次轮询已返回的同分成员都不大于since帖子ID
+func skipTimelineResultsUpTo(results []redis.Z, score float64, member string) []redis.Z {
+	kept := results[:0]
+	for _, result := range results {
+		if result.Score == score && result.Member.(string) <= member {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept
 }
 
 // RemoveFromTimeline 从Timeline移除帖子
 func (s *TimelineCacheService) RemoveFromTimeline(ctx context.Context, userID uuid.UUID, postID uuid.UUID) error {
-	key := s.getTimelineKey(userID)
-
-	if err := s.cache.ZRem(ctx, key, postID.String()); err != nil {
+	pipe := s.cache.Pipeline()
+	pipe.ZRem(ctx, s.getTimelineKey(userID), postID.String())
+	pipe.ZRem(ctx, RankedTimelineKey(userID), postID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to remove from timeline: %w", err)
 	}
 
 	return nil
 }
 
+// RemovePostsFromTimeline 从用户Timeline中批量删除帖子（例如取消关注后清理作者的帖子）
+func (s *TimelineCacheService) RemovePostsFromTimeline(ctx context.Context, userID uuid.UUID, postIDs []uuid.UUID) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(postIDs))
+	for i, postID := range postIDs {
+		members[i] = postID.String()
+	}
+
+	pipe := s.cache.Pipeline()
+	pipe.ZRem(ctx, s.getTimelineKey(userID), members...)
+	pipe.ZRem(ctx, RankedTimelineKey(userID), members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove posts from timeline: %w", err)
+	}
+
+	return nil
+}
+
 // BatchAddToTimeline 批量添加到多个用户的Timeline
+// 关注者较多时拆分为多个分块，由有界worker池并发执行，避免单个超大Pipeline
 func (s *TimelineCacheService) BatchAddToTimeline(ctx context.Context, userIDs []uuid.UUID, postID uuid.UUID, score float64, timestamp time.Time) error {
+	return s.batchAddToTimeline(ctx, userIDs, postID, score, timestamp, false)
+}
+
+// BatchAddToTimelineIfAbsent 与BatchAddToTimeline相同，但已在Timeline中的帖子保持原有score（用于恢复时的重复推送）
+func (s *TimelineCacheService) BatchAddToTimelineIfAbsent(ctx context.Context, userIDs []uuid.UUID, postID uuid.UUID, score float64, timestamp time.Time) error {
+	return s.batchAddToTimeline(ctx, userIDs, postID, score, timestamp, true)
+}
+
+func (s *TimelineCacheService) batchAddToTimeline(ctx context.Context, userIDs []uuid.UUID, postID uuid.UUID, score float64, timestamp time.Time, nx bool) error {
 	scoreValue := float64(timestamp.Unix())
+	chunkSize, workers := s.fanoutSettings()
+
+	if len(userIDs) <= chunkSize {
+		return s.addChunkToTimeline(ctx, userIDs, postID, scoreValue, score, nx)
+	}
+
+	// 无缓冲channel提供背压：所有worker忙碌时生产者阻塞
+	chunks := make(chan []uuid.UUID)
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				if err := s.addChunkToTimeline(ctx, chunk, postID, scoreValue, score, nx); err != nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for start := 0; start < len(userIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		select {
+		case chunks <- userIDs[start:end]:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// addChunkToTimeline 使用单个Pipeline将帖子写入一批用户的时间线和排序时间线
+// nx为true时使用ZADD NX，不覆盖已存在条目的score
+func (s *TimelineCacheService) addChunkToTimeline(ctx context.Context, userIDs []uuid.UUID, postID uuid.UUID, scoreValue, rankScore float64, nx bool) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	var caps map[uuid.UUID]int
+	if s.capResolver != nil {
+		caps = s.capResolver(ctx, userIDs)
+	}
 
-	// 使用Pipeline批量操作
 	pipe := s.cache.Pipeline()
+	zadd := pipe.ZAdd
+	if nx {
+		zadd = pipe.ZAddNX
+	}
+
+	evicted := make([]*redis.StringSliceCmd, len(userIDs))
+	for i, userID := range userIDs {
+		maxItems := int64(MaxTimelineSize)
+		if limit, ok := caps[userID]; ok && limit > 0 {
+			maxItems = int64(limit)
+		}
 
-	for _, userID := range userIDs {
 		key := s.getTimelineKey(userID)
-		pipe.ZAdd(ctx, key, &redis.Z{
+		rankedKey := RankedTimelineKey(userID)
+		zadd(ctx, key, &redis.Z{
 			Score:  scoreValue,
 			Member: postID.String(),
 		})
-		// 限制大小
-		pipe.ZRemRangeByRank(ctx, key, 0, -MaxTimelineSize-1)
-		// 设置过期时间
+		zadd(ctx, rankedKey, &redis.Z{
+			Score:  rankScore,
+			Member: postID.String(),
+		})
+		// 两个key使用相同的过期时间
 		pipe.Expire(ctx, key, TimelineCacheTTL)
+		pipe.Expire(ctx, rankedKey, TimelineCacheTTL)
+		// 按用户档位限制大小，查出时间线中将被淘汰的最旧条目
+		evicted[i] = pipe.ZRange(ctx, key, 0, -maxItems-1)
 	}
 
 	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to batch add to timelines: %w", err)
 	}
 
+	return s.evictFromTimelines(ctx, userIDs, evicted)
+}
+
+// TrimTimeline 将时间线裁剪到最新的maxItems条，排序时间线删除同样的条目
+func (s *TimelineCacheService) TrimTimeline(ctx context.Context, userID uuid.UUID, maxItems int) error {
+	evicted, err := s.timelineEvictions(ctx, []uuid.UUID{userID}, int64(maxItems))
+	if err != nil {
+		return err
+	}
+	return s.evictFromTimelines(ctx, []uuid.UUID{userID}, evicted)
+}
+
+// timelineEvictions 查询每个时间线中超出maxItems、将被淘汰的最旧条目
+func (s *TimelineCacheService) timelineEvictions(ctx context.Context, userIDs []uuid.UUID, maxItems int64) ([]*redis.StringSliceCmd, error) {
+	pipe := s.cache.Pipeline()
+	evicted := make([]*redis.StringSliceCmd, len(userIDs))
+	for i, userID := range userIDs {
+		evicted[i] = pipe.ZRange(ctx, s.getTimelineKey(userID), 0, -maxItems-1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get evicted timeline entries: %w", err)
+	}
+	return evicted, nil
+}
+
+// evictFromTimelines 从时间线和排序时间线中删除同一批被淘汰的条目，使两个集合保持相同的成员。
+// 排序时间线按分数排序，单独按排名裁剪会淘汰不同的帖子
+func (s *TimelineCacheService) evictFromTimelines(ctx context.Context, userIDs []uuid.UUID, evicted []*redis.StringSliceCmd) error {
+	pipe := s.cache.Pipeline()
+	queued := 0
+	for i, userID := range userIDs {
+		posts := evicted[i].Val()
+		if len(posts) == 0 {
+			continue
+		}
+		members := make([]interface{}, len(posts))
+		for j, post := range posts {
+			members[j] = post
+		}
+		pipe.ZRem(ctx, s.getTimelineKey(userID), members...)
+		pipe.ZRem(ctx, RankedTimelineKey(userID), members...)
+		queued++
+	}
+	if queued == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to trim timelines: %w", err)
+	}
 	return nil
 }
 
+// fanoutSettings 读取扇出分块大小和并发数
+func (s *TimelineCacheService) fanoutSettings() (int, int) {
+	chunkSize, workers := DefaultFanoutChunk, DefaultFanoutWorkers
+	if s.config != nil {
+		timelineCfg := s.config.Feed().Optimization.Timeline
+		if timelineCfg.FanoutChunkSize > 0 {
+			chunkSize = timelineCfg.FanoutChunkSize
+		}
+		if timelineCfg.FanoutWorkers > 0 {
+			workers = timelineCfg.FanoutWorkers
+		}
+	}
+	return chunkSize, workers
+}
+
 // ClearUserTimeline 清空用户Timeline
 func (s *TimelineCacheService) ClearUserTimeline(ctx context.Context, userID uuid.UUID) error {
-	key := s.getTimelineKey(userID)
-	return s.cache.Delete(ctx, key)
+	return s.cache.Delete(ctx, s.getTimelineKey(userID), RankedTimelineKey(userID))
 }
 
 // IsTimelineCached 检查用户Timeline是否已缓存
@@ -175,8 +597,6 @@ func (s *TimelineCacheService) GetTimelineSize(ctx context.Context, userID uuid.
 
 // SetTimelineExpiration 设置Timeline过期时间（根据用户活跃度）
 func (s *TimelineCacheService) SetTimelineExpiration(ctx context.Context, userID uuid.UUID, isActiveUser bool) error {
-	key := s.getTimelineKey(userID)
-
 	var ttl time.Duration
 	if isActiveUser {
 		ttl = ActiveUserCacheTTL
@@ -184,15 +604,19 @@ func (s *TimelineCacheService) SetTimelineExpiration(ctx context.Context, userID
 		ttl = InactiveUserCacheTTL
 	}
 
-	return s.cache.Expire(ctx, key, ttl)
+	if err := s.cache.Expire(ctx, s.getTimelineKey(userID), ttl); err != nil {
+		return err
+	}
+	return s.cache.Expire(ctx, RankedTimelineKey(userID), ttl)
 }
 
 // RebuildTimelineFromDB 从数据库重建Timeline缓存
 func (s *TimelineCacheService) RebuildTimelineFromDB(ctx context.Context, userID uuid.UUID, timelines []*models.Timeline) error {
 	key := s.getTimelineKey(userID)
+	rankedKey := RankedTimelineKey(userID)
 
 	// 先清空现有缓存
-	if err := s.cache.Delete(ctx, key); err != nil {
+	if err := s.cache.Delete(ctx, key, rankedKey); err != nil {
 		s.logger.WithError(err).Error("Failed to clear timeline cache")
 	}
 
@@ -208,10 +632,15 @@ func (s *TimelineCacheService) RebuildTimelineFromDB(ctx context.Context, userID
 			Score:  scoreValue,
 			Member: timeline.PostID.String(),
 		})
+		pipe.ZAdd(ctx, rankedKey, &redis.Z{
+			Score:  timeline.Score,
+			Member: timeline.PostID.String(),
+		})
 	}
 
 	// 设置过期时间
 	pipe.Expire(ctx, key, TimelineCacheTTL)
+	pipe.Expire(ctx, rankedKey, TimelineCacheTTL)
 
 	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to rebuild timeline cache: %w", err)
@@ -225,6 +654,69 @@ func (s *TimelineCacheService) getTimelineKey(userID uuid.UUID) string {
 	return fmt.Sprintf("timeline:%s", userID.String())
 }
 
+// RankedTimelineKey 获取按帖子分数排序的Timeline的Redis key，与时间线同步维护
+// 不使用timeline:前缀，避免被按timeline:*扫描的清理任务当作时间线处理
+func RankedTimelineKey(userID uuid.UUID) string {
+	return fmt.Sprintf("timeline_ranked:%s", userID.String())
+}
+
+// GetTimelineItemScore 获取Timeline中某个帖子的排序分数，不需要回查数据库
+func (s *TimelineCacheService) GetTimelineItemScore(ctx context.Context, userID, postID uuid.UUID) (float64, error) {
+	score, err := s.cache.ZScore(ctx, RankedTimelineKey(userID), postID.String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get timeline item score: %w", err)
+	}
+	return score, nil
+}
+
+// fillRankScores 为时间线条目批量填充排序分数，读取失败时保持为0
+func (s *TimelineCacheService) fillRankScores(ctx context.Context, userID uuid.UUID, items []TimelineItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	key := RankedTimelineKey(userID)
+	pipe := s.cache.Pipeline()
+	cmds := make([]*redis.FloatCmd, len(items))
+	for i, item := range items {
+		cmds[i] = pipe.ZScore(ctx, key, item.PostID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		s.logger.WithError(err).Error("Failed to get timeline rank scores")
+		return
+	}
+
+	for i, cmd := range cmds {
+		if score, err := cmd.Result(); err == nil {
+			items[i].RankScore = score
+		}
+	}
+}
+
+// GetRankedTimeline 按帖子分数从高到低获取Timeline（基于偏移量分页）
+func (s *TimelineCacheService) GetRankedTimeline(ctx context.Context, userID uuid.UUID, offset, limit int) ([]TimelineItem, bool, error) {
+	results, err := s.cache.ZRevRangeWithScores(ctx, RankedTimelineKey(userID), int64(offset), int64(offset+limit)) // 多获取一个判断是否还有更多
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get ranked timeline: %w", err)
+	}
+
+	hasMore := false
+	if len(results) > limit {
+		hasMore = true
+		results = results[:limit]
+	}
+
+	items := make([]TimelineItem, 0, len(results))
+	for _, result := range results {
+		items = append(items, TimelineItem{
+			PostID:    result.Member.(string),
+			Score:     result.Score,
+			RankScore: result.Score,
+		})
+	}
+	return items, hasMore, nil
+}
+
 // GetOldestPostScore 获取Timeline中最旧帖子的分数
 func (s *TimelineCacheService) GetOldestPostScore(ctx context.Context, userID uuid.UUID) (float64, error) {
 	key := s.getTimelineKey(userID)
@@ -242,16 +734,77 @@ func (s *TimelineCacheService) GetOldestPostScore(ctx context.Context, userID uu
 	return results[0].Score, nil
 }
 
-// CleanupExpiredTimelines 清理过期的Timeline缓存
+// CleanupExpiredTimelines 清理Timeline缓存：补上缺失的过期时间并裁剪超长的Timeline
+// 每次最多检查max_per_run个Timeline，游标保存在Redis中，下一次从中断处继续
 func (s *TimelineCacheService) CleanupExpiredTimelines(ctx context.Context) error {
-	// 这个方法可以通过定时任务调用，清理过期的Timeline
-	// 实际实现可能需要扫描所有timeline:*的key并检查其TTL
-	// 这里只是一个示例框架
 	s.logger.Info("Timeline cleanup job started")
 
-	// TODO: 实现具体的清理逻辑
-	// 可以使用SCAN命令扫描timeline:*模式的key
-	// 然后检查其TTL，删除过期的缓存
+	fixed := 0
+	result, err := scanResumable(ctx, s.cache, "cleanup_cursor:expired_timelines", "timeline:*",
+		s.config.Feed().Optimization.CacheCleanup,
+		func(ctx context.Context, keys []string) {
+			fixed += s.cleanupTimelineKeys(ctx, keys)
+		})
+	if err != nil {
+		return fmt.Errorf("failed to cleanup timelines: %w", err)
+	}
 
+	s.logger.WithFields(map[string]interface{}{
+		"inspected": result.Inspected,
+		"completed": result.Completed,
+		"fixed":     fixed,
+	}).Info("Timeline cleanup job completed")
 	return nil
 }
+
+// cleanupTimelineKeys 为没有过期时间的Timeline设置TTL并裁剪到MaxTimelineSize，返回处理的数量
+func (s *TimelineCacheService) cleanupTimelineKeys(ctx context.Context, keys []string) int {
+	pipe := s.cache.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.TTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		s.logger.WithError(err).Error("Failed to get timeline TTLs")
+		return 0
+	}
+
+	var userIDs []uuid.UUID
+	for i, key := range keys {
+		ttl, err := ttls[i].Result()
+		// -1表示永不过期（-2表示key已不存在）
+		if err != nil || ttl != -1 {
+			continue
+		}
+		userID, err := uuid.Parse(strings.TrimPrefix(key, "timeline:"))
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if len(userIDs) == 0 {
+		return 0
+	}
+
+	evicted, err := s.timelineEvictions(ctx, userIDs, MaxTimelineSize)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to fix timelines without expiration")
+		return 0
+	}
+	if err := s.evictFromTimelines(ctx, userIDs, evicted); err != nil {
+		s.logger.WithError(err).Error("Failed to fix timelines without expiration")
+		return 0
+	}
+
+	// 两个key使用相同的过期时间
+	pipe = s.cache.Pipeline()
+	for _, userID := range userIDs {
+		pipe.Expire(ctx, s.getTimelineKey(userID), TimelineCacheTTL)
+		pipe.Expire(ctx, RankedTimelineKey(userID), TimelineCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.WithError(err).Error("Failed to fix timelines without expiration")
+		return 0
+	}
+	return len(userIDs)
+}
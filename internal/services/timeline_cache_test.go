@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestGetTimelineSinceSameSecond(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// 同一秒内的三条帖子，按ZSet同分时的字典序排列
+	now := time.Unix(time.Now().Unix(), 0)
+	postIDs := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	sort.Strings(postIDs)
+	for _, id := range postIDs {
+		if err := timelineCache.AddToTimeline(ctx, userID, uuid.MustParse(id), 1, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("cursor excludes only the seen post", func(t *testing.T) {
+		since := fmt.Sprintf("%d_%s", now.Unix(), postIDs[0])
+		items, total, _, err := timelineCache.GetTimelineSince(ctx, userID, since, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 2 || len(items) != 2 {
+			t.Fatalf("got %d items, total %d, want 2", len(items), total)
+		}
+		if items[0].PostID != postIDs[2] || items[1].PostID != postIDs[1] {
+			t.Errorf("unexpected items %v", items)
+		}
+	})
+
+	t.Run("polling one at a time returns each post once", func(t *testing.T) {
+		since := fmt.Sprintf("%d", now.Add(-time.Second).Unix())
+		var seen []string
+		for i := 0; i < len(postIDs)+1; i++ {
+			items, _, next, err := timelineCache.GetTimelineSince(ctx, userID, since, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range items {
+				seen = append(seen, item.PostID)
+			}
+			since = next
+		}
+		if fmt.Sprint(seen) != fmt.Sprint(postIDs) {
+			t.Errorf("polled %v, want %v", seen, postIDs)
+		}
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		if _, _, _, err := timelineCache.GetTimelineSince(ctx, userID, "abc_def", 10); err != ErrInvalidCursor {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+}
+
+func TestBatchAddToTimelineChunked(t *testing.T) {
+	redisClient, mr := newTestRedis(t)
+	cfg := newTestConfig(func(feed *config.FeedConfig) {
+		feed.Optimization.Timeline.FanoutChunkSize = 3
+		feed.Optimization.Timeline.FanoutWorkers = 2
+	})
+	timelineCache := NewTimelineCacheService(redisClient, cfg, logger.NewLogger())
+	ctx := context.Background()
+
+	// 10个关注者拆成4个分块，由2个worker并发写入
+	userIDs := make([]uuid.UUID, 10)
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+	}
+	postID := uuid.New()
+	if err := timelineCache.BatchAddToTimeline(ctx, userIDs, postID, 2.5, time.Now()); err != nil {
+		t.Fatalf("BatchAddToTimeline: %v", err)
+	}
+
+	for _, userID := range userIDs {
+		for _, key := range []string{timelineCache.getTimelineKey(userID), RankedTimelineKey(userID)} {
+			members, err := mr.ZMembers(key)
+			if err != nil || len(members) != 1 || members[0] != postID.String() {
+				t.Errorf("%s members = %v, %v; want [%s]", key, members, err, postID)
+			}
+		}
+	}
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := timelineCache.BatchAddToTimeline(cancelled, userIDs, uuid.New(), 1, time.Now()); err == nil {
+			t.Error("expected error for cancelled context")
+		}
+	})
+}
+
+func TestTimelineTrimKeepsRankedInSync(t *testing.T) {
+	redisClient, mr := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	timelineCache.SetCapResolver(func(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]int {
+		caps := make(map[uuid.UUID]int, len(userIDs))
+		for _, userID := range userIDs {
+			caps[userID] = 3
+		}
+		return caps
+	})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// 越旧的帖子分数越高，按排名单独裁剪排序时间线会保留被时间线淘汰的帖子
+	start := time.Now().Add(-time.Hour)
+	var postIDs []string
+	for i := 0; i < 5; i++ {
+		postID := uuid.New()
+		postIDs = append(postIDs, postID.String())
+		if err := timelineCache.AddToTimeline(ctx, userID, postID, float64(100-i), start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("AddToTimeline: %v", err)
+		}
+	}
+	assertTimelinesInSync(t, mr, userID, postIDs[2:])
+
+	if err := timelineCache.TrimTimeline(ctx, userID, 2); err != nil {
+		t.Fatalf("TrimTimeline: %v", err)
+	}
+	assertTimelinesInSync(t, mr, userID, postIDs[3:])
+
+	t.Run("cleanup trims and expires both keys", func(t *testing.T) {
+		userID := uuid.New()
+		key, rankedKey := timelineCache.getTimelineKey(userID), RankedTimelineKey(userID)
+		var kept []string
+		for i := 0; i < MaxTimelineSize+2; i++ {
+			postID := uuid.NewString()
+			mr.ZAdd(key, float64(i), postID)
+			mr.ZAdd(rankedKey, float64(-i), postID)
+			if i >= 2 {
+				kept = append(kept, postID)
+			}
+		}
+
+		if fixed := timelineCache.cleanupTimelineKeys(ctx, []string{key}); fixed != 1 {
+			t.Fatalf("cleanupTimelineKeys() = %d, want 1", fixed)
+		}
+		assertTimelinesInSync(t, mr, userID, kept)
+		if ttl, rankedTTL := mr.TTL(key), mr.TTL(rankedKey); ttl != TimelineCacheTTL || rankedTTL != TimelineCacheTTL {
+			t.Errorf("TTLs = %v / %v, want %v for both", ttl, rankedTTL, TimelineCacheTTL)
+		}
+	})
+}
+
+// assertTimelinesInSync 检查时间线和排序时间线都恰好包含want中的帖子
+func assertTimelinesInSync(t *testing.T, mr *miniredis.Miniredis, userID uuid.UUID, want []string) {
+	t.Helper()
+
+	sorted := append([]string(nil), want...)
+	sort.Strings(sorted)
+	for _, key := range []string{"timeline:" + userID.String(), RankedTimelineKey(userID)} {
+		members, err := mr.ZMembers(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(members)
+		if fmt.Sprint(members) != fmt.Sprint(sorted) {
+			t.Errorf("%s members = %v, want %v", key, members, sorted)
+		}
+	}
+}
+
+func TestTimelineStoresRankScorePerItem(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID, follower := uuid.New(), uuid.New()
+
+	// 新帖分数低、旧帖分数高，时间线按时间排序，排序时间线按分数排序
+	now := time.Unix(time.Now().Unix()-1, 0)
+	older, newer := uuid.New(), uuid.New()
+	if err := timelineCache.AddToTimeline(ctx, userID, older, 42.5, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := timelineCache.AddToTimeline(ctx, userID, newer, 7.25, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := timelineCache.BatchAddToTimeline(ctx, []uuid.UUID{userID, follower}, uuid.New(), 13, now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	for postID, want := range map[uuid.UUID]float64{older: 42.5, newer: 7.25} {
+		if score, err := timelineCache.GetTimelineItemScore(ctx, userID, postID); err != nil || score != want {
+			t.Errorf("GetTimelineItemScore(%s) = %v, %v; want %v", postID, score, err, want)
+		}
+	}
+
+	items, _, _, err := timelineCache.GetTimeline(ctx, userID, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRank := []float64{7.25, 13, 42.5}
+	if len(items) != len(wantRank) {
+		t.Fatalf("got %d items, want %d", len(items), len(wantRank))
+	}
+	for i, item := range items {
+		if item.RankScore != wantRank[i] {
+			t.Errorf("item %d rank score = %v, want %v", i, item.RankScore, wantRank[i])
+		}
+	}
+	if items[0].Score != float64(now.Unix()) {
+		t.Errorf("timeline score = %v, want the post timestamp %d", items[0].Score, now.Unix())
+	}
+
+	ranked, _, err := timelineCache.GetRankedTimeline(ctx, userID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 3 || ranked[0].PostID != older.String() || ranked[0].RankScore != 42.5 || ranked[2].PostID != newer.String() {
+		t.Errorf("ranked timeline = %+v, want highest score first", ranked)
+	}
+
+	// 批量写入的分数对每个关注者都可读取
+	followerItems, _, _, err := timelineCache.GetTimeline(ctx, follower, "", 10)
+	if err != nil || len(followerItems) != 1 || followerItems[0].RankScore != 13 {
+		t.Errorf("follower timeline = %+v, %v; want one item with rank score 13", followerItems, err)
+	}
+}
+
+func TestGetTimelineHydratedKeepsOrderAndSkipsDeleted(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// 五条帖子从新到旧：第二条已删除，第四条已不存在
+	base := time.Now().Add(-time.Hour)
+	postIDs := make([]uuid.UUID, 5)
+	for i := range postIDs {
+		postIDs[i] = uuid.New()
+		if err := timelineCache.AddToTimeline(ctx, userID, postIDs[i], 1, base.Add(-time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var fetched [][]uuid.UUID
+	fetch := func(ctx context.Context, ids []uuid.UUID) ([]*models.Post, error) {
+		fetched = append(fetched, ids)
+		// 返回顺序与请求顺序无关
+		return []*models.Post{
+			{ID: postIDs[4]},
+			{ID: postIDs[1], IsDeleted: true},
+			{ID: postIDs[0]},
+			{ID: postIDs[2]},
+		}, nil
+	}
+
+	posts, nextCursor, hasMore, err := timelineCache.GetTimelineHydrated(ctx, userID, "", 10, fetch)
+	if err != nil {
+		t.Fatalf("GetTimelineHydrated: %v", err)
+	}
+	if len(fetched) != 1 || len(fetched[0]) != 5 {
+		t.Errorf("fetch calls = %v, want one batch of 5 IDs", fetched)
+	}
+	want := []uuid.UUID{postIDs[0], postIDs[2], postIDs[4]}
+	if len(posts) != len(want) {
+		t.Fatalf("got %d posts, want %d", len(posts), len(want))
+	}
+	for i, post := range posts {
+		if post.ID != want[i] {
+			t.Errorf("post %d = %s, want %s", i, post.ID, want[i])
+		}
+	}
+	if hasMore || nextCursor == "" {
+		t.Errorf("nextCursor = %q, hasMore = %v", nextCursor, hasMore)
+	}
+
+	// 分页游标基于Timeline条目，被跳过的帖子不影响翻页
+	posts, _, hasMore, err = timelineCache.GetTimelineHydrated(ctx, userID, "", 2, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != postIDs[0] || !hasMore {
+		t.Errorf("first page = %v, hasMore = %v; want only %s with more", posts, hasMore, postIDs[0])
+	}
+
+	// 空Timeline不调用fetch
+	fetched = nil
+	posts, nextCursor, _, err = timelineCache.GetTimelineHydrated(ctx, uuid.New(), "", 10, fetch)
+	if err != nil || len(posts) != 0 || nextCursor != "" || len(fetched) != 0 {
+		t.Errorf("empty timeline = %v, %q, %v; fetch calls %v", posts, nextCursor, err, fetched)
+	}
+
+	// fetch失败时返回错误
+	failing := func(ctx context.Context, ids []uuid.UUID) ([]*models.Post, error) {
+		return nil, fmt.Errorf("db down")
+	}
+	if _, _, _, err := timelineCache.GetTimelineHydrated(ctx, userID, "", 10, failing); err == nil {
+		t.Error("expected error when fetch fails")
+	}
+}
+
+func TestGetTimelinePageBoundary(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// limit+1条帖子，各相差一秒，从新到旧
+	const limit = 3
+	base := time.Unix(time.Now().Unix()-60, 0)
+	postIDs := make([]uuid.UUID, limit+1)
+	for i := range postIDs {
+		postIDs[i] = uuid.New()
+		if err := timelineCache.AddToTimeline(ctx, userID, postIDs[i], 1, base.Add(-time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, nextCursor, hasMore, err := timelineCache.GetTimeline(ctx, userID, "", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != limit || !hasMore {
+		t.Fatalf("got %d items, hasMore = %v; want %d with more", len(items), hasMore, limit)
+	}
+	last := items[limit-1]
+	if last.PostID != postIDs[limit-1].String() {
+		t.Errorf("last item = %s, want %s", last.PostID, postIDs[limit-1])
+	}
+	if want := fmt.Sprintf("%.0f_%s", last.Score, last.PostID); nextCursor != want {
+		t.Errorf("nextCursor = %q, want the last returned item %q", nextCursor, want)
+	}
+
+	// 下一页从第N+1条开始，恰好取完后没有更多
+	items, nextCursor, hasMore, err = timelineCache.GetTimeline(ctx, userID, nextCursor, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].PostID != postIDs[limit].String() || hasMore {
+		t.Fatalf("second page = %v, hasMore = %v; want only %s", items, hasMore, postIDs[limit])
+	}
+
+	// 恰好limit条时不多报hasMore
+	items, _, hasMore, err = timelineCache.GetTimeline(ctx, userID, "", limit+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != limit+1 || hasMore {
+		t.Errorf("exact page: got %d items, hasMore = %v; want %d and no more", len(items), hasMore, limit+1)
+	}
+
+	// 翻到底后的游标返回空页
+	items, _, hasMore, err = timelineCache.GetTimeline(ctx, userID, nextCursor, limit)
+	if err != nil || len(items) != 0 || hasMore {
+		t.Errorf("past the end = %v, %v, %v; want empty", items, hasMore, err)
+	}
+}
+
+func TestGetTimelinePaginatesSameSecondPosts(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// 三条同一秒的帖子和一条更早的帖子，同分按帖子ID倒序
+	now := time.Unix(time.Now().Unix()-60, 0)
+	sameSecond := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	sort.Sort(sort.Reverse(sort.StringSlice(sameSecond)))
+	for _, id := range sameSecond {
+		if err := timelineCache.AddToTimeline(ctx, userID, uuid.MustParse(id), 1, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	older := uuid.New()
+	if err := timelineCache.AddToTimeline(ctx, userID, older, 1, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]string{}, sameSecond...), older.String())
+
+	for _, limit := range []int{1, 2} {
+		var seen []string
+		cursor := ""
+		for page := 0; page < len(want)+1; page++ {
+			items, next, hasMore, err := timelineCache.GetTimeline(ctx, userID, cursor, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range items {
+				seen = append(seen, item.PostID)
+			}
+			if !hasMore {
+				break
+			}
+			// 同分条目的游标带上帖子ID
+			if last := items[len(items)-1]; next != fmt.Sprintf("%d_%s", int64(last.Score), last.PostID) {
+				t.Errorf("cursor %q does not identify the last item %s", next, last.PostID)
+			}
+			cursor = next
+		}
+		if fmt.Sprint(seen) != fmt.Sprint(want) {
+			t.Errorf("limit %d: paged %v, want %v", limit, seen, want)
+		}
+	}
+}
+
+func TestBatchAddToTimelineIfAbsentKeepsExistingScores(t *testing.T) {
+	redisClient, mr := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+
+	existing, missing := uuid.New(), uuid.New()
+	postID := uuid.New()
+	original := time.Unix(time.Now().Unix()-3600, 0)
+	if err := timelineCache.AddToTimeline(ctx, existing, postID, 5, original); err != nil {
+		t.Fatal(err)
+	}
+
+	// 恢复时的重复推送带着新的时间和分数
+	repushed := original.Add(30 * time.Minute)
+	if err := timelineCache.BatchAddToTimelineIfAbsent(ctx, []uuid.UUID{existing, missing}, postID, 9, repushed); err != nil {
+		t.Fatalf("BatchAddToTimelineIfAbsent: %v", err)
+	}
+
+	for _, tt := range []struct {
+		userID    uuid.UUID
+		timestamp time.Time
+		rank      float64
+	}{
+		{existing, original, 5}, // 已存在的条目不变
+		{missing, repushed, 9},  // 缺失的条目补上
+	} {
+		if score, err := mr.ZScore(timelineCache.getTimelineKey(tt.userID), postID.String()); err != nil || score != float64(tt.timestamp.Unix()) {
+			t.Errorf("timeline score for %s = %v, %v; want %d", tt.userID, score, err, tt.timestamp.Unix())
+		}
+		if score, err := mr.ZScore(RankedTimelineKey(tt.userID), postID.String()); err != nil || score != tt.rank {
+			t.Errorf("ranked score for %s = %v, %v; want %v", tt.userID, score, err, tt.rank)
+		}
+	}
+
+	// 普通推送会覆盖score
+	if err := timelineCache.BatchAddToTimeline(ctx, []uuid.UUID{existing}, postID, 9, repushed); err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := mr.ZScore(timelineCache.getTimelineKey(existing), postID.String()); score != float64(repushed.Unix()) {
+		t.Errorf("plain push score = %v, want %d", score, repushed.Unix())
+	}
+}
+
+// 配置的单页上限高于包内默认值时不被截断到MaxTimelinePage
+func TestGetTimelineUsesConfiguredPageLimits(t *testing.T) {
+	redisClient, _ := newTestRedis(t)
+	timelineCache := NewTimelineCacheService(redisClient, newTestConfig(nil), logger.NewLogger())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < MaxTimelinePage+50; i++ {
+		if err := timelineCache.AddToTimeline(ctx, userID, uuid.New(), 1, base.Add(-time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, _, _, err := timelineCache.GetTimeline(ctx, userID, "", MaxTimelinePage+50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != MaxTimelinePage {
+		t.Errorf("default limits: got %d items, want %d", len(items), MaxTimelinePage)
+	}
+
+	timelineCache.SetPageLimits(10, MaxTimelinePage+20)
+	items, _, hasMore, err := timelineCache.GetTimeline(ctx, userID, "", MaxTimelinePage+50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != MaxTimelinePage+20 || !hasMore {
+		t.Errorf("configured max: got %d items, hasMore = %v; want %d with more", len(items), hasMore, MaxTimelinePage+20)
+	}
+
+	items, _, _, err = timelineCache.GetTimeline(ctx, userID, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 10 {
+		t.Errorf("configured default: got %d items, want 10", len(items))
+	}
+}
+
+// BenchmarkBatchAddToTimeline 对比一次写入全部关注者的单个Pipeline和按块并发的Pipeline。
+// miniredis没有网络往返，结果主要反映客户端开销，真实Redis上按块并发的收益更大
+func BenchmarkBatchAddToTimeline(b *testing.B) {
+	const followers = 10000
+	userIDs := make([]uuid.UUID, followers)
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+	}
+
+	for _, bc := range []struct {
+		name      string
+		chunkSize int
+		workers   int
+	}{
+		{"single pipeline", followers, 1},
+		{"chunked", DefaultFanoutChunk, DefaultFanoutWorkers},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			redisClient, mr := newTestRedis(b)
+			cfg := newTestConfig(func(feed *config.FeedConfig) {
+				feed.Optimization.Timeline.FanoutChunkSize = bc.chunkSize
+				feed.Optimization.Timeline.FanoutWorkers = bc.workers
+			})
+			timelineCache := NewTimelineCacheService(redisClient, cfg, logger.NewLogger())
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := timelineCache.BatchAddToTimeline(ctx, userIDs, uuid.New(), 1, time.Now()); err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				mr.FlushAll()
+				b.StartTimer()
+			}
+		})
+	}
+}
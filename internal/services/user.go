@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	apperrors "github.com/feed-system/feed-system/internal/errors"
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/cache"
 	"github.com/feed-system/feed-system/pkg/logger"
 	"github.com/feed-system/feed-system/pkg/queue"
 	"github.com/google/uuid"
@@ -14,18 +18,22 @@ import (
 )
 
 type UserService struct {
-	userRepo   *repository.UserRepository
-	followRepo *repository.FollowRepository
-	producer   *queue.KafkaProducer
-	logger     *logger.Logger
+	userRepo        *repository.UserRepository
+	followRepo      *repository.FollowRepository
+	cache           *cache.RedisClient
+	producer        queue.Publisher
+	profileCacheTTL time.Duration
+	logger          *logger.Logger
 }
 
-func NewUserService(userRepo *repository.UserRepository, followRepo *repository.FollowRepository, producer *queue.KafkaProducer, logger *logger.Logger) *UserService {
+func NewUserService(userRepo *repository.UserRepository, followRepo *repository.FollowRepository, cache *cache.RedisClient, producer *queue.KafkaProducer, profileCacheTTL time.Duration, logger *logger.Logger) *UserService {
 	return &UserService{
-		userRepo:   userRepo,
-		followRepo: followRepo,
-		producer:   producer,
-		logger:     logger,
+		userRepo:        userRepo,
+		followRepo:      followRepo,
+		cache:           cache,
+		producer:        producer,
+		profileCacheTTL: profileCacheTTL,
+		logger:          logger,
 	}
 }
 
@@ -45,6 +53,9 @@ type UpdateUserRequest struct {
 	DisplayName *string `json:"display_name" binding:"max=50"`
 	Avatar      *string `json:"avatar"`
 	Bio         *string `json:"bio" binding:"max=500"`
+
+	ShowProfileViews *bool `json:"show_profile_views"`
+	ShowLikes        *bool `json:"show_likes"`
 }
 
 type FollowRequest struct {
@@ -59,7 +70,7 @@ func (s *UserService) Register(ctx context.Context, req *RegisterRequest) (*mode
 		return nil, fmt.Errorf("failed to check username: %w", err)
 	}
 	if existingUser != nil {
-		return nil, errors.New("username already exists")
+		return nil, apperrors.AlreadyExists("username already exists")
 	}
 
 	// 检查邮箱是否已存在
@@ -68,7 +79,7 @@ func (s *UserService) Register(ctx context.Context, req *RegisterRequest) (*mode
 		return nil, fmt.Errorf("failed to check email: %w", err)
 	}
 	if existingUser != nil {
-		return nil, errors.New("email already exists")
+		return nil, apperrors.AlreadyExists("email already exists")
 	}
 
 	// 加密密码
@@ -130,23 +141,56 @@ func (s *UserService) Login(ctx context.Context, req *LoginRequest) (*models.Use
 	return user, nil
 }
 
+// ProfileCacheKey 用户资料缓存key
+func ProfileCacheKey(userID string) string {
+	return fmt.Sprintf("profile:%s", userID)
+}
+
 func (s *UserService) GetByID(ctx context.Context, userID string) (*models.User, error) {
 	id, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID: %w", err)
 	}
 
+	// 优先读取资料缓存
+	var cached models.User
+	if err := s.cache.GetJSON(ctx, ProfileCacheKey(userID), &cached); err == nil {
+		return &cached, nil
+	}
+
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found")
+	}
+
+	if err := s.cache.SetJSON(ctx, ProfileCacheKey(userID), user, s.profileCacheTTL); err != nil {
+		s.logger.WithError(err).Error("Failed to cache user profile")
 	}
 
 	return user, nil
 }
 
+// invalidateProfiles 删除用户资料缓存，user_events消费者也会做同样的失效作为兜底
+func (s *UserService) invalidateProfiles(ctx context.Context, userIDs ...string) {
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = ProfileCacheKey(userID)
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		s.logger.WithError(err).Error("Failed to invalidate profile cache")
+	}
+}
+
+// removeActiveFollower 取消关注后把关注者移出被关注者的活跃关注者集合，避免头部用户继续向其推送
+func (s *UserService) removeActiveFollower(ctx context.Context, followingID, followerID string) {
+	if err := s.cache.ZRem(ctx, activeFollowersKey(followingID), followerID); err != nil {
+		s.logger.WithError(err).Error("Failed to remove active follower")
+	}
+}
+
 func (s *UserService) Update(ctx context.Context, userID string, req *UpdateUserRequest) (*models.User, error) {
 	id, err := uuid.Parse(userID)
 	if err != nil {
@@ -158,7 +202,7 @@ func (s *UserService) Update(ctx context.Context, userID string, req *UpdateUser
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, apperrors.NotFound("user not found")
 	}
 
 	// 更新字段
@@ -171,11 +215,19 @@ func (s *UserService) Update(ctx context.Context, userID string, req *UpdateUser
 	if req.Bio != nil {
 		user.Bio = *req.Bio
 	}
+	if req.ShowProfileViews != nil {
+		user.ShowProfileViews = *req.ShowProfileViews
+	}
+	if req.ShowLikes != nil {
+		user.ShowLikes = *req.ShowLikes
+	}
 
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err := s.userRepo.UpdateProfile(ctx, user); err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	s.invalidateProfiles(ctx, user.ID.String())
+
 	// 发送用户更新事件
 	event := queue.Event{
 		Type:      queue.EventUserUpdated,
@@ -212,7 +264,7 @@ func (s *UserService) Follow(ctx context.Context, followerID, followingID string
 		return fmt.Errorf("failed to get follower: %w", err)
 	}
 	if follower == nil {
-		return errors.New("follower not found")
+		return apperrors.NotFound("follower not found")
 	}
 
 	following, err := s.userRepo.GetByID(ctx, followingUUID)
@@ -220,7 +272,7 @@ func (s *UserService) Follow(ctx context.Context, followerID, followingID string
 		return fmt.Errorf("failed to get following: %w", err)
 	}
 	if following == nil {
-		return errors.New("following user not found")
+		return apperrors.NotFound("following user not found")
 	}
 
 	// 检查是否已经关注
@@ -229,7 +281,7 @@ func (s *UserService) Follow(ctx context.Context, followerID, followingID string
 		return fmt.Errorf("failed to check follow status: %w", err)
 	}
 	if existingFollow != nil {
-		return errors.New("already following")
+		return apperrors.AlreadyExists("already following")
 	}
 
 	// 创建关注关系
@@ -250,6 +302,8 @@ func (s *UserService) Follow(ctx context.Context, followerID, followingID string
 		s.logger.WithError(err).Error("Failed to update followers count")
 	}
 
+	s.invalidateProfiles(ctx, followerID, followingID)
+
 	// 发送关注事件
 	event := queue.Event{
 		Type:      queue.EventFollowCreated,
@@ -272,6 +326,28 @@ func (s *UserService) Follow(ctx context.Context, followerID, followingID string
 	return nil
 }
 
+func (s *UserService) FollowBack(ctx context.Context, userID, followerID string) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	followerUUID, err := uuid.Parse(followerID)
+	if err != nil {
+		return fmt.Errorf("invalid follower ID: %w", err)
+	}
+
+	isFollower, err := s.followRepo.IsFollowing(ctx, followerUUID, userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to check follow status: %w", err)
+	}
+	if !isFollower {
+		return apperrors.InvalidInput("user is not following you")
+	}
+
+	return s.Follow(ctx, userID, followerID)
+}
+
 func (s *UserService) Unfollow(ctx context.Context, followerID, followingID string) error {
 	followerUUID, err := uuid.Parse(followerID)
 	if err != nil {
@@ -289,7 +365,7 @@ func (s *UserService) Unfollow(ctx context.Context, followerID, followingID stri
 		return fmt.Errorf("failed to check follow status: %w", err)
 	}
 	if existingFollow == nil {
-		return errors.New("not following")
+		return apperrors.NotFound("not following")
 	}
 
 	// 删除关注关系
@@ -305,6 +381,9 @@ func (s *UserService) Unfollow(ctx context.Context, followerID, followingID stri
 		s.logger.WithError(err).Error("Failed to update followers count")
 	}
 
+	s.invalidateProfiles(ctx, followerID, followingID)
+	s.removeActiveFollower(ctx, followingID, followerID)
+
 	// 发送取消关注事件
 	event := queue.Event{
 		Type:      queue.EventFollowDeleted,
@@ -326,6 +405,361 @@ func (s *UserService) Unfollow(ctx context.Context, followerID, followingID stri
 	return nil
 }
 
+// 批量关注/取消关注单次最多处理的用户数
+const MaxBatchFollowSize = 100
+
+type BatchFollowRequest struct {
+	FollowingIDs []string `json:"following_ids" binding:"required,min=1"`
+}
+
+// BatchFollowResult 批量操作中单个目标用户的结果
+type BatchFollowResult struct {
+	UserID  string `json:"user_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BatchFollow 批量关注，校验失败的目标单独返回错误，其余目标在一个事务中创建关注关系并更新计数
+func (s *UserService) BatchFollow(ctx context.Context, followerID string, followingIDs []string) ([]*BatchFollowResult, error) {
+	followerUUID, results, candidates, err := s.prepareBatchFollow(followerID, followingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 过滤不存在的用户和已关注的用户
+	var targets []uuid.UUID
+	if len(candidates) > 0 {
+		users, err := s.userRepo.GetByIDs(ctx, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+		exists := make(map[uuid.UUID]bool, len(users))
+		for _, u := range users {
+			exists[u.ID] = true
+		}
+		following, err := s.followRepo.AreFollowing(ctx, followerUUID, candidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range candidates {
+			switch {
+			case !exists[id]:
+				results[id.String()].Error = "user not found"
+			case following[id]:
+				results[id.String()].Error = "already following"
+			default:
+				targets = append(targets, id)
+			}
+		}
+	}
+
+	follows, err := s.followRepo.CreateBatch(ctx, followerUUID, targets)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to batch follow")
+		for _, id := range targets {
+			results[id.String()].Error = "failed to follow"
+		}
+		return s.orderBatchResults(followingIDs, results), nil
+	}
+
+	for _, follow := range follows {
+		results[follow.FollowingID.String()].Success = true
+	}
+	s.publishFollowsCreated(ctx, followerID, follows)
+
+	s.logger.WithFields(map[string]interface{}{
+		"follower_id": followerID,
+		"requested":   len(followingIDs),
+		"followed":    len(follows),
+	}).Info("Batch follow completed")
+
+	return s.orderBatchResults(followingIDs, results), nil
+}
+
+// publishFollowsCreated 批量创建关注后失效相关资料缓存，并为每个目标发送一个关注事件，与单个关注保持一致
+func (s *UserService) publishFollowsCreated(ctx context.Context, followerID string, follows []*models.Follow) {
+	for _, follow := range follows {
+		followingID := follow.FollowingID.String()
+		s.invalidateProfiles(ctx, followingID)
+
+		event := queue.Event{
+			Type:      queue.EventFollowCreated,
+			Timestamp: follow.CreatedAt,
+			Data: queue.FollowEventData{
+				FollowerID:  followerID,
+				FollowingID: followingID,
+				CreatedAt:   follow.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			},
+		}
+		if err := s.producer.Publish(ctx, followerID, event); err != nil {
+			s.logger.WithError(err).Error("Failed to publish follow created event")
+		}
+	}
+	s.invalidateProfiles(ctx, followerID)
+}
+
+// 导入关注单次最多处理的用户名/ID数，按MaxBatchFollowSize分批写入
+const MaxImportFollowingSize = 1000
+
+type ImportFollowingRequest struct {
+	Users []string `json:"users" binding:"required,min=1"` // 用户名或用户ID
+}
+
+// ImportFollowingResult 导入关注的汇总结果
+type ImportFollowingResult struct {
+	Followed int `json:"followed"`
+	Skipped  int `json:"skipped"`   // 已关注、重复或是自己
+	NotFound int `json:"not_found"` // 用户名或ID不存在
+}
+
+// ImportFollowing 从其他平台迁移时批量导入关注，条目可以是用户名或用户ID
+// 先统一解析为用户，跳过已关注和不存在的，其余按批次各在一个事务中创建关注关系
+func (s *UserService) ImportFollowing(ctx context.Context, followerID string, identifiers []string) (*ImportFollowingResult, error) {
+	followerUUID, err := uuid.Parse(followerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid follower ID: %w", err)
+	}
+	if len(identifiers) == 0 {
+		return nil, apperrors.InvalidInput("users is empty")
+	}
+	if len(identifiers) > MaxImportFollowingSize {
+		return nil, apperrors.InvalidInput(fmt.Sprintf("too many users to import: %d (max %d)", len(identifiers), MaxImportFollowingSize))
+	}
+
+	result := &ImportFollowingResult{}
+	resolved, err := s.resolveImportUsers(ctx, identifiers, result)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(resolved); start += MaxBatchFollowSize {
+		end := start + MaxBatchFollowSize
+		if end > len(resolved) {
+			end = len(resolved)
+		}
+		chunk := resolved[start:end]
+
+		following, err := s.followRepo.AreFollowing(ctx, followerUUID, chunk)
+		if err != nil {
+			return nil, err
+		}
+		var targets []uuid.UUID
+		for _, id := range chunk {
+			if id == followerUUID || following[id] {
+				result.Skipped++
+				continue
+			}
+			targets = append(targets, id)
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		follows, err := s.followRepo.CreateBatch(ctx, followerUUID, targets)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import following: %w", err)
+		}
+		result.Followed += len(follows)
+		result.Skipped += len(targets) - len(follows)
+		s.publishFollowsCreated(ctx, followerID, follows)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"follower_id": followerID,
+		"requested":   len(identifiers),
+		"followed":    result.Followed,
+		"skipped":     result.Skipped,
+		"not_found":   result.NotFound,
+	}).Info("Following import completed")
+
+	return result, nil
+}
+
+// resolveImportUsers 将用户名或ID解析为去重后的用户ID（保持请求顺序），同时统计不存在和重复的条目
+func (s *UserService) resolveImportUsers(ctx context.Context, identifiers []string, result *ImportFollowingResult) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	var usernames []string
+	for _, raw := range identifiers {
+		if id, err := uuid.Parse(raw); err == nil {
+			ids = append(ids, id)
+		} else if name := strings.TrimPrefix(strings.TrimSpace(raw), "@"); name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+
+	existing := make(map[uuid.UUID]bool, len(identifiers))
+	byName := make(map[string]uuid.UUID, len(usernames))
+	for start := 0; start < len(ids); start += MaxBatchFollowSize {
+		end := start + MaxBatchFollowSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		users, err := s.userRepo.GetByIDs(ctx, ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+		for _, u := range users {
+			existing[u.ID] = true
+		}
+	}
+	for start := 0; start < len(usernames); start += MaxBatchFollowSize {
+		end := start + MaxBatchFollowSize
+		if end > len(usernames) {
+			end = len(usernames)
+		}
+		users, err := s.userRepo.GetByUsernames(ctx, usernames[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+		for _, u := range users {
+			byName[u.Username] = u.ID
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(identifiers))
+	resolved := make([]uuid.UUID, 0, len(identifiers))
+	for _, raw := range identifiers {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			var ok bool
+			if id, ok = byName[strings.TrimPrefix(strings.TrimSpace(raw), "@")]; !ok {
+				result.NotFound++
+				continue
+			}
+		} else if !existing[id] {
+			result.NotFound++
+			continue
+		}
+		if seen[id] {
+			result.Skipped++
+			continue
+		}
+		seen[id] = true
+		resolved = append(resolved, id)
+	}
+	return resolved, nil
+}
+
+// BatchUnfollow 批量取消关注，未关注的目标单独返回错误，其余目标在一个事务中删除关注关系并更新计数
+func (s *UserService) BatchUnfollow(ctx context.Context, followerID string, followingIDs []string) ([]*BatchFollowResult, error) {
+	followerUUID, results, candidates, err := s.prepareBatchFollow(followerID, followingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.followRepo.DeleteBatch(ctx, followerUUID, candidates)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to batch unfollow")
+		for _, id := range candidates {
+			results[id.String()].Error = "failed to unfollow"
+		}
+		return s.orderBatchResults(followingIDs, results), nil
+	}
+
+	removed := make(map[uuid.UUID]bool, len(deleted))
+	for _, follow := range deleted {
+		removed[follow.FollowingID] = true
+	}
+
+	for _, id := range candidates {
+		followingID := id.String()
+		if !removed[id] {
+			results[followingID].Error = "not following"
+			continue
+		}
+		results[followingID].Success = true
+		s.invalidateProfiles(ctx, followingID)
+		s.removeActiveFollower(ctx, followingID, followerID)
+
+		event := queue.Event{
+			Type:      queue.EventFollowDeleted,
+			Timestamp: time.Now(),
+			Data: queue.FollowEventData{
+				FollowerID:  followerID,
+				FollowingID: followingID,
+			},
+		}
+		if err := s.producer.Publish(ctx, followerID, event); err != nil {
+			s.logger.WithError(err).Error("Failed to publish follow deleted event")
+		}
+	}
+	s.invalidateProfiles(ctx, followerID)
+
+	s.logger.WithFields(map[string]interface{}{
+		"follower_id": followerID,
+		"requested":   len(followingIDs),
+		"unfollowed":  len(deleted),
+	}).Info("Batch unfollow completed")
+
+	return s.orderBatchResults(followingIDs, results), nil
+}
+
+// prepareBatchFollow 校验批量请求：数量上限、ID格式、不能是自己、去重，返回待处理的目标ID
+func (s *UserService) prepareBatchFollow(followerID string, followingIDs []string) (uuid.UUID, map[string]*BatchFollowResult, []uuid.UUID, error) {
+	followerUUID, err := uuid.Parse(followerID)
+	if err != nil {
+		return uuid.Nil, nil, nil, fmt.Errorf("invalid follower ID: %w", err)
+	}
+	if len(followingIDs) == 0 {
+		return uuid.Nil, nil, nil, apperrors.InvalidInput("following_ids is empty")
+	}
+	if len(followingIDs) > MaxBatchFollowSize {
+		return uuid.Nil, nil, nil, apperrors.InvalidInput(fmt.Sprintf("too many users in batch: %d (max %d)", len(followingIDs), MaxBatchFollowSize))
+	}
+
+	results := make(map[string]*BatchFollowResult, len(followingIDs))
+	var candidates []uuid.UUID
+	for _, rawID := range followingIDs {
+		if _, seen := results[rawID]; seen {
+			continue
+		}
+		result := &BatchFollowResult{UserID: rawID}
+		results[rawID] = result
+
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			result.Error = "invalid user ID"
+			continue
+		}
+		// 以规范化后的ID为key，保证后续按uuid.String()查找一致
+		if canonical := id.String(); canonical != rawID {
+			delete(results, rawID)
+			if _, seen := results[canonical]; seen {
+				continue
+			}
+			result.UserID = canonical
+			results[canonical] = result
+		}
+		if id == followerUUID {
+			result.Error = "cannot follow yourself"
+			continue
+		}
+		candidates = append(candidates, id)
+	}
+
+	return followerUUID, results, candidates, nil
+}
+
+// orderBatchResults 按请求顺序返回结果，重复的ID只返回一次
+func (s *UserService) orderBatchResults(followingIDs []string, results map[string]*BatchFollowResult) []*BatchFollowResult {
+	ordered := make([]*BatchFollowResult, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	for _, rawID := range followingIDs {
+		key := rawID
+		if id, err := uuid.Parse(rawID); err == nil {
+			key = id.String()
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if result, ok := results[key]; ok {
+			ordered = append(ordered, result)
+		}
+	}
+	return ordered
+}
+
 func (s *UserService) GetFollowers(ctx context.Context, userID string, offset, limit int) ([]*models.User, error) {
 	uuid, err := uuid.Parse(userID)
 	if err != nil {
@@ -354,6 +788,80 @@ func (s *UserService) GetFollowing(ctx context.Context, userID string, offset, l
 	return following, nil
 }
 
+func (s *UserService) GetMutuals(ctx context.Context, userID, otherID string, offset, limit int) ([]*models.User, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	otherUUID, err := uuid.Parse(otherID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid other user ID: %w", err)
+	}
+
+	return s.followRepo.GetMutuals(ctx, userUUID, otherUUID, offset, limit)
+}
+
+func (s *UserService) IsMutualFollow(ctx context.Context, userID, otherID string) (bool, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	otherUUID, err := uuid.Parse(otherID)
+	if err != nil {
+		return false, fmt.Errorf("invalid other user ID: %w", err)
+	}
+
+	return s.followRepo.IsMutualFollow(ctx, userUUID, otherUUID)
+}
+
+// 关注推荐时最多从多少个关注对象展开二度关系
+const maxSuggestionSeedFollowing = 500
+
+type FollowSuggestion struct {
+	User        *models.User `json:"user"`
+	MutualCount int64        `json:"mutual_count"`
+}
+
+// GetFollowSuggestions 推荐用户关注的人所关注、但用户尚未关注的用户，按共同关注人数排序
+// 排除自己、已关注和屏蔽过的用户，屏蔽的关注对象也不参与展开
+func (s *UserService) GetFollowSuggestions(ctx context.Context, userID string, limit int) ([]*FollowSuggestion, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	candidates, err := s.followRepo.GetSecondDegreeFollowing(ctx, userUUID, maxSuggestionSeedFollowing, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return []*FollowSuggestion{}, nil
+	}
+
+	ids := make([]uuid.UUID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.UserID
+	}
+	users, err := s.userRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	suggestions := make([]*FollowSuggestion, 0, len(candidates))
+	for _, c := range candidates {
+		if u, ok := byID[c.UserID]; ok {
+			suggestions = append(suggestions, &FollowSuggestion{User: u, MutualCount: c.MutualCount})
+		}
+	}
+	return suggestions, nil
+}
+
 func (s *UserService) IsFollowing(ctx context.Context, followerID, followingID string) (bool, error) {
 	followerUUID, err := uuid.Parse(followerID)
 	if err != nil {
@@ -368,6 +876,15 @@ func (s *UserService) IsFollowing(ctx context.Context, followerID, followingID s
 	return s.followRepo.IsFollowing(ctx, followerUUID, followingUUID)
 }
 
+func (s *UserService) AreFollowing(ctx context.Context, followerID string, followingIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	followerUUID, err := uuid.Parse(followerID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid follower ID: %w", err)
+	}
+
+	return s.followRepo.AreFollowing(ctx, followerUUID, followingIDs)
+}
+
 func (s *UserService) Search(ctx context.Context, query string, offset, limit int) ([]*models.User, error) {
 	users, err := s.userRepo.Search(ctx, query, offset, limit)
 	if err != nil {
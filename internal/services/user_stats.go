@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// 创作者统计的缓存时间
+	UserStatsCacheTTL = 5 * time.Minute
+	// 关注者增长统计的最大窗口
+	MaxUserStatsWindowDays = 365
+)
+
+// UserStats 创作者统计
+type UserStats struct {
+	UserID          uuid.UUID    `json:"user_id"`
+	TotalPosts      int64        `json:"total_posts"`
+	TotalLikes      int64        `json:"total_likes_received"`
+	TotalComments   int64        `json:"total_comments_received"`
+	TotalFollowers  int64        `json:"total_followers"`
+	FollowerGrowth  int64        `json:"follower_growth"`
+	WindowDays      int          `json:"window_days"`
+	MostEngagedPost *models.Post `json:"most_engaged_post,omitempty"`
+	GeneratedAt     time.Time    `json:"generated_at"`
+}
+
+// GetUserStats 获取用户的发帖和互动统计，结果缓存几分钟
+func (s *FeedService) GetUserStats(ctx context.Context, userID string, windowDays int) (*UserStats, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	cacheKey := fmt.Sprintf("user_stats:%s:%d", userID, windowDays)
+	var cached UserStats
+	if err := s.cache.GetJSON(ctx, cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	postStats, err := s.postRepo.AggregateUserStats(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	totalFollowers, err := s.followRepo.CountFollowers(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	growth, err := s.followRepo.CountFollowersSince(ctx, userUUID, time.Now().AddDate(0, 0, -windowDays))
+	if err != nil {
+		return nil, err
+	}
+
+	topPost, err := s.postRepo.GetMostEngagedPost(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &UserStats{
+		UserID:          userUUID,
+		TotalPosts:      postStats.TotalPosts,
+		TotalLikes:      postStats.TotalLikes,
+		TotalComments:   postStats.TotalComments,
+		TotalFollowers:  totalFollowers,
+		FollowerGrowth:  growth,
+		WindowDays:      windowDays,
+		MostEngagedPost: topPost,
+		GeneratedAt:     time.Now(),
+	}
+
+	if err := s.cache.SetJSON(ctx, cacheKey, stats, UserStatsCacheTTL); err != nil {
+		s.logger.WithError(err).Error("Failed to cache user stats")
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+func TestGetUserStatsAggregatesAndCaches(t *testing.T) {
+	db, mock := newTestDB(t)
+	redisClient, mr := newTestRedis(t)
+	service := NewFeedService(
+		repository.NewPostRepository(db), repository.NewTimelineRepository(db), repository.NewUserRepository(db),
+		repository.NewFollowRepository(db), nil, nil, redisClient, nil, newTestConfig(nil), logger.NewLogger(), nil, nil,
+	)
+	ctx := context.Background()
+	userID, topPost := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) AS total_posts, COALESCE\(SUM\(like_count\), 0\) AS total_likes`).
+		WithArgs(userID, false).
+		WillReturnRows(sqlmock.NewRows([]string{"total_posts", "total_likes", "total_comments", "total_shares"}).AddRow(2, 7, 5, 3))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "follows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(40))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "follows" WHERE \(following_id = \$1 AND created_at >= \$2\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+	mock.ExpectQuery(`ORDER BY like_count \+ comment_count \+ share_count DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "like_count"}).AddRow(topPost, userID, 5))
+
+	stats, err := service.GetUserStats(ctx, userID.String(), 30)
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if stats.TotalPosts != 2 || stats.TotalLikes != 7 || stats.TotalComments != 5 ||
+		stats.TotalFollowers != 40 || stats.FollowerGrowth != 6 || stats.WindowDays != 30 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.MostEngagedPost == nil || stats.MostEngagedPost.ID != topPost {
+		t.Errorf("most engaged post = %v, want %s", stats.MostEngagedPost, topPost)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存期内不再查询数据库
+	key := "user_stats:" + userID.String() + ":30"
+	if ttl := mr.TTL(key); ttl != UserStatsCacheTTL {
+		t.Errorf("stats cache TTL = %s, want %s", ttl, UserStatsCacheTTL)
+	}
+	cached, err := service.GetUserStats(ctx, userID.String(), 30)
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if cached.TotalLikes != 7 || cached.FollowerGrowth != 6 {
+		t.Errorf("cached stats = %+v", cached)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestGetByIDProfileCache(t *testing.T) {
+	service, mock, mr, _ := newUserTestService(t)
+	service.profileCacheTTL = 10 * time.Minute
+	ctx := context.Background()
+	userID := uuid.New()
+	cacheKey := ProfileCacheKey(userID.String())
+
+	expectProfile := func(displayName string, followers int64) {
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "display_name", "followers", "following"}).
+				AddRow(userID, displayName, followers, 3))
+	}
+
+	// 未命中缓存时回源数据库并写入缓存
+	expectProfile("Alice", 42)
+	user, err := service.GetByID(ctx, userID.String())
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.DisplayName != "Alice" || user.Followers != 42 {
+		t.Errorf("user = %+v", user)
+	}
+	if !mr.Exists(cacheKey) {
+		t.Fatal("profile not cached after miss")
+	}
+	if ttl := mr.TTL(cacheKey); ttl != service.profileCacheTTL {
+		t.Errorf("profile cache TTL = %s, want %s", ttl, service.profileCacheTTL)
+	}
+
+	// 命中缓存时不访问数据库，返回的资料包含关注数和粉丝数
+	cached, err := service.GetByID(ctx, userID.String())
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if cached.DisplayName != "Alice" || cached.Followers != 42 || cached.Following != 3 {
+		t.Errorf("cached user = %+v", cached)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 更新资料后缓存失效，下次读取拿到新数据
+	displayName := "Alice B."
+	expectProfile("Alice", 42)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if _, err := service.Update(ctx, userID.String(), &UpdateUserRequest{DisplayName: &displayName}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if mr.Exists(cacheKey) {
+		t.Error("profile cache not invalidated after update")
+	}
+
+	expectProfile(displayName, 42)
+	user, err = service.GetByID(ctx, userID.String())
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.DisplayName != displayName {
+		t.Errorf("display name after update = %q, want %q", user.DisplayName, displayName)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+)
+
+// rawEvent 把事件编码为消费时收到的原始消息
+func rawEvent(t *testing.T, eventType queue.EventType, data interface{}) queue.Message {
+	t.Helper()
+
+	raw, err := json.Marshal(queue.Event{Type: eventType, Timestamp: time.Now(), Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queue.Message{Raw: raw}
+}
+
+// metricCounts 按(worker, 事件类型, 结果)汇总快照中的计数
+func metricCounts(metrics *services.EventMetrics) map[[3]string]int64 {
+	counts := make(map[[3]string]int64)
+	for _, metric := range metrics.Snapshot() {
+		counts[[3]string{metric.Worker, metric.EventType, metric.Outcome}] = metric.Count
+	}
+	return counts
+}
+
+func TestWorkersRecordPerEventMetrics(t *testing.T) {
+	log := logger.NewLogger()
+	metrics := services.NewEventMetrics(log)
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+	userWorker := NewUserEventWorker(redisClient, nil, nil, nil, log, metrics)
+
+	feedWorker, _ := newTestFeedWorker(t)
+	feedWorker.metrics = metrics
+
+	userID, postID := uuid.NewString(), uuid.NewString()
+	for _, tt := range []struct {
+		name    string
+		handle  func(context.Context, queue.Message) error
+		msg     queue.Message
+		wantErr bool
+	}{
+		{"user updated", userWorker.handleMessage, rawEvent(t, queue.EventUserUpdated, queue.UserEventData{UserID: userID}), false},
+		{"user updated again", userWorker.handleMessage, rawEvent(t, queue.EventUserUpdated, queue.UserEventData{UserID: userID}), false},
+		{"user updated without id", userWorker.handleMessage, rawEvent(t, queue.EventUserUpdated, queue.UserEventData{}), true},
+		{"unknown user event", userWorker.handleMessage, rawEvent(t, "user_renamed", queue.UserEventData{UserID: userID}), false},
+		{"malformed user event", userWorker.handleMessage, queue.Message{Raw: []byte("{not json")}, true},
+		{"post created", feedWorker.handleMessage, rawEvent(t, queue.EventPostCreated, queue.PostEventData{PostID: postID, UserID: userID}), false},
+		{"post created without ids", feedWorker.handleMessage, rawEvent(t, queue.EventPostCreated, queue.PostEventData{}), true},
+	} {
+		if err := tt.handle(ctx, tt.msg); (err != nil) != tt.wantErr {
+			t.Errorf("%s: handleMessage() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// 每个(worker, 事件类型, 结果)组合单独计数，互不混淆
+	want := map[[3]string]int64{
+		{userEventWorkerName, string(queue.EventUserUpdated), services.EventOutcomeSuccess}: 2,
+		{userEventWorkerName, string(queue.EventUserUpdated), services.EventOutcomeError}:   1,
+		{userEventWorkerName, "user_renamed", services.EventOutcomeSuccess}:                 1,
+		{userEventWorkerName, eventTypeMalformed, services.EventOutcomeError}:               1,
+		{feedWorkerName, string(queue.EventPostCreated), services.EventOutcomeSuccess}:      1,
+		{feedWorkerName, string(queue.EventPostCreated), services.EventOutcomeError}:        1,
+	}
+	got := metricCounts(metrics)
+	if len(got) != len(want) {
+		t.Errorf("metrics = %v, want %v", got, want)
+	}
+	for key, count := range want {
+		if got[key] != count {
+			t.Errorf("count%v = %d, want %d", key, got[key], count)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package workers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/feed-system/feed-system/internal/models"
 	"github.com/feed-system/feed-system/internal/repository"
@@ -14,6 +14,16 @@ import (
 	"github.com/google/uuid"
 )
 
+// 事件统计中的worker标签
+const (
+	feedWorkerName          = "feed_worker"
+	optimizedFeedWorkerName = "optimized_feed_worker"
+	userEventWorkerName     = "user_event_worker"
+
+	// 无法解析的消息在统计中使用的事件类型
+	eventTypeMalformed = "malformed"
+)
+
 type FeedWorker struct {
 	feedService  *services.FeedService
 	userService  *services.UserService
@@ -24,6 +34,7 @@ type FeedWorker struct {
 	cache        *cache.RedisClient
 	consumer     *queue.KafkaConsumer
 	logger       *logger.Logger
+	metrics      *services.EventMetrics
 }
 
 func NewFeedWorker(
@@ -36,6 +47,7 @@ func NewFeedWorker(
 	cache *cache.RedisClient,
 	consumer *queue.KafkaConsumer,
 	logger *logger.Logger,
+	metrics *services.EventMetrics,
 ) *FeedWorker {
 	return &FeedWorker{
 		feedService:  feedService,
@@ -47,65 +59,75 @@ func NewFeedWorker(
 		cache:        cache,
 		consumer:     consumer,
 		logger:       logger,
+		metrics:      metrics,
 	}
 }
 
 func (w *FeedWorker) Start(ctx context.Context) error {
 	w.logger.Info("Starting feed worker...")
 
-	return w.consumer.Subscribe(ctx, func(msg queue.Message) error {
-		var event queue.Event
-		data, err := json.Marshal(msg.Value)
-		if err != nil {
-			return fmt.Errorf("failed to marshal message value: %w", err)
-		}
+	// 定期根据互动重算帖子分数，保持Timeline排序新鲜
+	go w.feedService.StartScoreRecomputeJob(ctx)
 
-		if err := json.Unmarshal(data, &event); err != nil {
-			return fmt.Errorf("failed to unmarshal event: %w", err)
-		}
+	// 定期将Redis中累加的曝光数刷入数据库
+	go w.feedService.StartImpressionFlushJob(ctx)
 
-		w.logger.WithFields(map[string]interface{}{
-			"event_type": event.Type,
-			"timestamp":  event.Timestamp,
-		}).Info("Processing event")
-
-		switch event.Type {
-		case queue.EventPostCreated:
-			return w.handlePostCreated(ctx, event)
-		case queue.EventPostDeleted:
-			return w.handlePostDeleted(ctx, event)
-		case queue.EventFollowCreated:
-			return w.handleFollowCreated(ctx, event)
-		case queue.EventFollowDeleted:
-			return w.handleFollowDeleted(ctx, event)
-		case queue.EventLikeCreated:
-			return w.handleLikeCreated(ctx, event)
-		case queue.EventLikeDeleted:
-			return w.handleLikeDeleted(ctx, event)
-		case queue.EventCommentCreated:
-			return w.handleCommentCreated(ctx, event)
-		default:
-			w.logger.WithField("event_type", event.Type).Warn("Unknown event type")
-			return nil
-		}
+	return w.consumer.Subscribe(ctx, func(msg queue.Message) error {
+		return w.handleMessage(ctx, msg)
 	})
 }
 
-func (w *FeedWorker) handlePostCreated(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid post created event data")
+// handleMessage 解码并处理一条消息，按事件类型和处理结果记录统计
+func (w *FeedWorker) handleMessage(ctx context.Context, msg queue.Message) error {
+	event, _, err := queue.DecodeEvent(msg.Raw)
+	if err != nil {
+		w.metrics.Observe(feedWorkerName, eventTypeMalformed, 0, err)
+		return err
 	}
 
-	postID, ok := data["post_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing post_id in event data")
+	w.logger.WithFields(map[string]interface{}{
+		"event_type": event.Type,
+		"timestamp":  event.Timestamp,
+	}).Info("Processing event")
+
+	start := time.Now()
+	err = w.handleEvent(ctx, event)
+	w.metrics.Observe(feedWorkerName, string(event.Type), time.Since(start), err)
+	return err
+}
+
+// handleEvent 按事件类型分发处理
+func (w *FeedWorker) handleEvent(ctx context.Context, event queue.Event) error {
+	switch event.Type {
+	case queue.EventPostCreated:
+		return w.handlePostCreated(ctx, event)
+	case queue.EventPostDeleted:
+		return w.handlePostDeleted(ctx, event)
+	case queue.EventFollowCreated:
+		return w.handleFollowCreated(ctx, event)
+	case queue.EventFollowDeleted:
+		return w.handleFollowDeleted(ctx, event)
+	case queue.EventLikeCreated:
+		return w.handleLikeCreated(ctx, event)
+	case queue.EventLikeDeleted:
+		return w.handleLikeDeleted(ctx, event)
+	case queue.EventCommentCreated:
+		return w.handleCommentCreated(ctx, event)
+	default:
+		w.logger.WithField("event_type", event.Type).Warn("Unknown event type")
+		return nil
 	}
+}
 
-	userID, ok := data["user_id"].(string)
+func (w *FeedWorker) handlePostCreated(ctx context.Context, event queue.Event) error {
+	data, ok := event.Data.(queue.PostEventData)
 	if !ok {
-		return fmt.Errorf("missing user_id in event data")
+		return fmt.Errorf("invalid post created event data")
 	}
+	if data.PostID == "" || data.UserID == "" {
+		return fmt.Errorf("missing post_id or user_id in event data")
+	}
+	postID, userID := data.PostID, data.UserID
 
 	w.logger.WithFields(map[string]interface{}{
 		"post_id": postID,
@@ -121,20 +143,14 @@ func (w *FeedWorker) handlePostCreated(ctx context.Context, event queue.Event) e
 }
 
 func (w *FeedWorker) handlePostDeleted(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.PostEventData)
 	if !ok {
 		return fmt.Errorf("invalid post deleted event data")
 	}
-
-	postID, ok := data["post_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing post_id in event data")
-	}
-
-	userID, ok := data["user_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing user_id in event data")
+	if data.PostID == "" || data.UserID == "" {
+		return fmt.Errorf("missing post_id or user_id in event data")
 	}
+	postID, userID := data.PostID, data.UserID
 
 	postUUID, err := uuid.Parse(postID)
 	if err != nil {
@@ -162,22 +178,10 @@ func (w *FeedWorker) handlePostDeleted(ctx context.Context, event queue.Event) e
 func (w *FeedWorker) handleFollowCreated(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.FollowEventData)
 	if !ok {
-		// 尝试解析为map
-		mapData, mapOk := event.Data.(map[string]interface{})
-		if !mapOk {
-			return fmt.Errorf("invalid follow created event data")
-		}
-
-		followerID, ok1 := mapData["follower_id"].(string)
-		followingID, ok2 := mapData["following_id"].(string)
-		if !ok1 || !ok2 {
-			return fmt.Errorf("missing follower_id or following_id in event data")
-		}
-
-		data = queue.FollowEventData{
-			FollowerID:  followerID,
-			FollowingID: followingID,
-		}
+		return fmt.Errorf("invalid follow created event data")
+	}
+	if data.FollowerID == "" || data.FollowingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -195,8 +199,33 @@ func (w *FeedWorker) handleFollowCreated(ctx context.Context, event queue.Event)
 		return fmt.Errorf("invalid following ID: %w", err)
 	}
 
-	// 获取被关注者的最新帖子
-	posts, err := w.postRepo.GetByUserID(ctx, followingUUID, 0, 10)
+	// 短时间内反复取消/重新关注时只回填一次
+	if !w.feedService.AcquireBackfillSlot(ctx, data.FollowerID, data.FollowingID) {
+		w.logger.WithFields(map[string]interface{}{
+			"follower_id":  data.FollowerID,
+			"following_id": data.FollowingID,
+		}).Info("Skipping follow backfill within cooldown")
+		return nil
+	}
+
+	if err := w.backfillFollowing(ctx, followerUUID, followingUUID); err != nil {
+		// 回填失败时释放冷却，事件重试时可以再次回填
+		w.feedService.ReleaseBackfillSlot(ctx, data.FollowerID, data.FollowingID)
+		return err
+	}
+
+	// 清除关注者的feed缓存
+	if err := w.clearUserFeedCache(ctx, data.FollowerID); err != nil {
+		w.logger.WithError(err).Error("Failed to clear follower feed cache")
+	}
+
+	return nil
+}
+
+// backfillFollowing 将被关注者的最新帖子回填到关注者的Timeline
+func (w *FeedWorker) backfillFollowing(ctx context.Context, followerUUID, followingUUID uuid.UUID) error {
+	// 获取被关注者的最新帖子，新关注者还不是密友，不回填仅密友可见的帖子
+	posts, err := w.postRepo.GetByUserID(ctx, followingUUID, 0, 10, false)
 	if err != nil {
 		return fmt.Errorf("failed to get following's posts: %w", err)
 	}
@@ -204,6 +233,10 @@ func (w *FeedWorker) handleFollowCreated(ctx context.Context, event queue.Event)
 	// 将帖子添加到关注者的timeline
 	var timelines []*models.Timeline
 	for _, post := range posts {
+		// 只回填推送时间窗口内的帖子
+		if !w.feedService.IsPushEligible(post) {
+			continue
+		}
 		timeline := &models.Timeline{
 			UserID:    followerUUID,
 			PostID:    post.ID,
@@ -218,34 +251,16 @@ func (w *FeedWorker) handleFollowCreated(ctx context.Context, event queue.Event)
 			return fmt.Errorf("failed to create timelines: %w", err)
 		}
 	}
-
-	// 清除关注者的feed缓存
-	if err := w.clearUserFeedCache(ctx, data.FollowerID); err != nil {
-		w.logger.WithError(err).Error("Failed to clear follower feed cache")
-	}
-
 	return nil
 }
 
 func (w *FeedWorker) handleFollowDeleted(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.FollowEventData)
 	if !ok {
-		// 尝试解析为map
-		mapData, mapOk := event.Data.(map[string]interface{})
-		if !mapOk {
-			return fmt.Errorf("invalid follow deleted event data")
-		}
-
-		followerID, ok1 := mapData["follower_id"].(string)
-		followingID, ok2 := mapData["following_id"].(string)
-		if !ok1 || !ok2 {
-			return fmt.Errorf("missing follower_id or following_id in event data")
-		}
-
-		data = queue.FollowEventData{
-			FollowerID:  followerID,
-			FollowingID: followingID,
-		}
+		return fmt.Errorf("invalid follow deleted event data")
+	}
+	if data.FollowerID == "" || data.FollowingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -264,7 +279,7 @@ func (w *FeedWorker) handleFollowDeleted(ctx context.Context, event queue.Event)
 	}
 
 	// 获取被关注者的帖子
-	posts, err := w.postRepo.GetByUserID(ctx, followingUUID, 0, 100)
+	posts, err := w.postRepo.GetByUserID(ctx, followingUUID, 0, 100, true)
 	if err != nil {
 		return fmt.Errorf("failed to get following's posts: %w", err)
 	}
@@ -279,6 +294,9 @@ func (w *FeedWorker) handleFollowDeleted(ctx context.Context, event queue.Event)
 		}
 	}
 
+	// 回填的帖子已被删除，冷却期内重新关注时需要再次回填
+	w.feedService.ReleaseBackfillSlot(ctx, data.FollowerID, data.FollowingID)
+
 	// 清除关注者的feed缓存
 	if err := w.clearUserFeedCache(ctx, data.FollowerID); err != nil {
 		w.logger.WithError(err).Error("Failed to clear follower feed cache")
@@ -290,22 +308,10 @@ func (w *FeedWorker) handleFollowDeleted(ctx context.Context, event queue.Event)
 func (w *FeedWorker) handleLikeCreated(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.LikeEventData)
 	if !ok {
-		// 尝试解析为map
-		mapData, mapOk := event.Data.(map[string]interface{})
-		if !mapOk {
-			return fmt.Errorf("invalid like created event data")
-		}
-
-		userID, ok1 := mapData["user_id"].(string)
-		postID, ok2 := mapData["post_id"].(string)
-		if !ok1 || !ok2 {
-			return fmt.Errorf("missing user_id or post_id in event data")
-		}
-
-		data = queue.LikeEventData{
-			UserID: userID,
-			PostID: postID,
-		}
+		return fmt.Errorf("invalid like created event data")
+	}
+	if data.UserID == "" || data.PostID == "" {
+		return fmt.Errorf("missing user_id or post_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -325,22 +331,10 @@ func (w *FeedWorker) handleLikeCreated(ctx context.Context, event queue.Event) e
 func (w *FeedWorker) handleLikeDeleted(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.LikeEventData)
 	if !ok {
-		// 尝试解析为map
-		mapData, mapOk := event.Data.(map[string]interface{})
-		if !mapOk {
-			return fmt.Errorf("invalid like deleted event data")
-		}
-
-		userID, ok1 := mapData["user_id"].(string)
-		postID, ok2 := mapData["post_id"].(string)
-		if !ok1 || !ok2 {
-			return fmt.Errorf("missing user_id or post_id in event data")
-		}
-
-		data = queue.LikeEventData{
-			UserID: userID,
-			PostID: postID,
-		}
+		return fmt.Errorf("invalid like deleted event data")
+	}
+	if data.UserID == "" || data.PostID == "" {
+		return fmt.Errorf("missing user_id or post_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -359,22 +353,10 @@ func (w *FeedWorker) handleLikeDeleted(ctx context.Context, event queue.Event) e
 func (w *FeedWorker) handleCommentCreated(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.CommentEventData)
 	if !ok {
-		// 尝试解析为map
-		mapData, mapOk := event.Data.(map[string]interface{})
-		if !mapOk {
-			return fmt.Errorf("invalid comment created event data")
-		}
-
-		userID, ok1 := mapData["user_id"].(string)
-		postID, ok2 := mapData["post_id"].(string)
-		if !ok1 || !ok2 {
-			return fmt.Errorf("missing user_id or post_id in event data")
-		}
-
-		data = queue.CommentEventData{
-			UserID: userID,
-			PostID: postID,
-		}
+		return fmt.Errorf("invalid comment created event data")
+	}
+	if data.UserID == "" || data.PostID == "" {
+		return fmt.Errorf("missing user_id or post_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -400,14 +382,25 @@ func (w *FeedWorker) clearUserFeedCache(ctx context.Context, userID string) erro
 
 func (w *FeedWorker) clearPostCache(ctx context.Context, postID string) error {
 	// 清除帖子相关的缓存
-	cacheKey := fmt.Sprintf("post:%s", postID)
-	if err := w.cache.Delete(ctx, cacheKey); err != nil {
+	if err := w.cache.Delete(ctx, services.PostCacheKey(postID)); err != nil {
 		return fmt.Errorf("failed to delete post cache: %w", err)
 	}
 	return nil
 }
 
-func (w *FeedWorker) Stop() error {
+func (w *FeedWorker) Stop(ctx context.Context) error {
 	w.logger.Info("Stopping feed worker...")
-	return w.consumer.Close()
+
+	// 关闭消费者可能阻塞在提交offset上，使用ctx限制等待时间
+	done := make(chan error, 1)
+	go func() {
+		done <- w.consumer.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out stopping feed worker: %w", ctx.Err())
+	}
 }
\ No newline at end of file
@@ -2,7 +2,6 @@ package workers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -24,6 +23,7 @@ type OptimizedFeedWorker struct {
 	cacheStrategyService *services.CacheStrategyService
 	recoveryService      *services.RecoveryService
 	optimizedFeedService *services.OptimizedFeedService
+	metrics              *services.EventMetrics
 }
 
 func NewOptimizedFeedWorker(
@@ -35,6 +35,7 @@ func NewOptimizedFeedWorker(
 	cacheStrategyService *services.CacheStrategyService,
 	recoveryService *services.RecoveryService,
 	optimizedFeedService *services.OptimizedFeedService,
+	metrics *services.EventMetrics,
 ) *OptimizedFeedWorker {
 	return &OptimizedFeedWorker{
 		consumer:             consumer,
@@ -45,6 +46,7 @@ func NewOptimizedFeedWorker(
 		cacheStrategyService: cacheStrategyService,
 		recoveryService:      recoveryService,
 		optimizedFeedService: optimizedFeedService,
+		metrics:              metrics,
 	}
 }
 
@@ -73,20 +75,22 @@ func (w *OptimizedFeedWorker) startBackgroundJobs(ctx context.Context) {
 	// 启动用户活跃度衰减任务（每天执行一次）
 	go w.startActivityDecayJob(ctx)
 
+	// 启动延迟扇出任务（每分钟检查一次，仅在低峰时段处理）
+	go w.optimizedFeedService.StartDelayedFanoutJob(ctx, 1*time.Minute)
+
+	// 启动消费延迟上报任务（每分钟执行一次）
+	go w.startConsumerLagReportJob(ctx, 1*time.Minute)
+
 	w.logger.Info("Background jobs started")
 }
 
 // handleMessage 处理消息
 func (w *OptimizedFeedWorker) handleMessage(message queue.Message) error {
 	ctx := context.Background()
-	var event queue.Event
-	if messageBytes, ok := message.Value.([]byte); ok {
-		if err := json.Unmarshal(messageBytes, &event); err != nil {
-			w.logger.WithError(err).Error("Failed to unmarshal event")
-			return err
-		}
-	} else if err := json.Unmarshal([]byte(fmt.Sprintf("%v", message.Value)), &event); err != nil {
-		w.logger.WithError(err).Error("Failed to unmarshal event")
+	event, _, err := queue.DecodeEvent(message.Raw)
+	if err != nil {
+		w.logger.WithError(err).Error("Failed to decode event")
+		w.metrics.Observe(optimizedFeedWorkerName, eventTypeMalformed, 0, err)
 		return err
 	}
 
@@ -95,6 +99,14 @@ func (w *OptimizedFeedWorker) handleMessage(message queue.Message) error {
 		"topic":      message.Topic,
 	}).Info("Processing event")
 
+	start := time.Now()
+	err = w.handleEvent(ctx, event)
+	w.metrics.Observe(optimizedFeedWorkerName, string(event.Type), time.Since(start), err)
+	return err
+}
+
+// handleEvent 按事件类型分发处理
+func (w *OptimizedFeedWorker) handleEvent(ctx context.Context, event queue.Event) error {
 	switch event.Type {
 	case queue.EventPostCreated:
 		return w.handlePostCreated(ctx, event)
@@ -104,9 +116,9 @@ func (w *OptimizedFeedWorker) handleMessage(message queue.Message) error {
 		return w.handleUserFollowed(ctx, event)
 	case queue.EventFollowDeleted:
 		return w.handleUserUnfollowed(ctx, event)
-	case "post_distribution_completed":
+	case queue.EventPostDistributionCompleted:
 		return w.handlePostDistributionCompleted(ctx, event)
-	case "user_activity_updated":
+	case queue.EventUserActivityUpdated:
 		return w.handleUserActivityUpdated(ctx, event)
 	default:
 		w.logger.WithField("event_type", event.Type).Warn("Unknown event type")
@@ -118,7 +130,10 @@ func (w *OptimizedFeedWorker) handleMessage(message queue.Message) error {
 func (w *OptimizedFeedWorker) handlePostCreated(ctx context.Context, event queue.Event) error {
 	data, ok := event.Data.(queue.PostEventData)
 	if !ok {
-		return fmt.Errorf("invalid post event data")
+		return fmt.Errorf("invalid post created event data")
+	}
+	if data.PostID == "" || data.UserID == "" {
+		return fmt.Errorf("missing post_id or user_id in event data")
 	}
 
 	w.logger.WithFields(map[string]interface{}{
@@ -136,15 +151,14 @@ func (w *OptimizedFeedWorker) handlePostCreated(ctx context.Context, event queue
 
 // handlePostDeleted 处理帖子删除事件
 func (w *OptimizedFeedWorker) handlePostDeleted(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.PostEventData)
 	if !ok {
 		return fmt.Errorf("invalid post deleted event data")
 	}
-
-	postID, ok := data["post_id"].(string)
-	if !ok {
+	if data.PostID == "" {
 		return fmt.Errorf("missing post_id in event data")
 	}
+	postID := data.PostID
 
 	w.logger.WithField("post_id", postID).Info("Handling post deleted event")
 
@@ -157,20 +171,14 @@ func (w *OptimizedFeedWorker) handlePostDeleted(ctx context.Context, event queue
 
 // handleUserFollowed 处理用户关注事件
 func (w *OptimizedFeedWorker) handleUserFollowed(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.FollowEventData)
 	if !ok {
 		return fmt.Errorf("invalid user followed event data")
 	}
-
-	followerID, ok := data["follower_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing follower_id in event data")
-	}
-
-	followingID, ok := data["following_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing following_id in event data")
+	if data.FollowerID == "" || data.FollowingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
 	}
+	followerID, followingID := data.FollowerID, data.FollowingID
 
 	w.logger.WithFields(map[string]interface{}{
 		"follower_id":  followerID,
@@ -187,20 +195,14 @@ func (w *OptimizedFeedWorker) handleUserFollowed(ctx context.Context, event queu
 
 // handleUserUnfollowed 处理用户取消关注事件
 func (w *OptimizedFeedWorker) handleUserUnfollowed(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.FollowEventData)
 	if !ok {
 		return fmt.Errorf("invalid user unfollowed event data")
 	}
-
-	followerID, ok := data["follower_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing follower_id in event data")
-	}
-
-	followingID, ok := data["following_id"].(string)
-	if !ok {
-		return fmt.Errorf("missing following_id in event data")
+	if data.FollowerID == "" || data.FollowingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
 	}
+	followerID, followingID := data.FollowerID, data.FollowingID
 
 	w.logger.WithFields(map[string]interface{}{
 		"follower_id":  followerID,
@@ -216,45 +218,42 @@ func (w *OptimizedFeedWorker) handleUserUnfollowed(ctx context.Context, event qu
 
 // handlePostDistributionCompleted 处理帖子分发完成事件
 func (w *OptimizedFeedWorker) handlePostDistributionCompleted(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.DistributionCompletedEventData)
 	if !ok {
 		return fmt.Errorf("invalid distribution completed event data")
 	}
-
-	postID, ok := data["post_id"].(string)
-	if !ok {
+	if data.PostID == "" {
 		return fmt.Errorf("missing post_id in event data")
 	}
+	postID := data.PostID
 
-	distributionType, ok := data["distribution_type"].(string)
-	if !ok {
+	distributionType := data.DistributionType
+	if distributionType == "" {
 		distributionType = "unknown"
 	}
 
 	w.logger.WithFields(map[string]interface{}{
 		"post_id":           postID,
 		"distribution_type": distributionType,
+		"active_followers":  data.ActiveFollowers,
+		"duration_ms":       data.DurationMs,
 	}).Info("Handling post distribution completed event")
 
-	// 分发完成后的处理：
-	// 1. 更新分发状态
-	// 2. 记录统计信息
-	// 3. 清理临时数据
-
-	return nil
+	// 更新分发状态为已完成，并累计分发统计供GetWorkerStats和管理接口展示
+	data.DistributionType = distributionType
+	return w.recoveryService.RecordDistributionCompleted(ctx, data)
 }
 
 // handleUserActivityUpdated 处理用户活跃度更新事件
 func (w *OptimizedFeedWorker) handleUserActivityUpdated(ctx context.Context, event queue.Event) error {
-	data, ok := event.Data.(map[string]interface{})
+	data, ok := event.Data.(queue.UserActivityEventData)
 	if !ok {
 		return fmt.Errorf("invalid user activity event data")
 	}
-
-	userID, ok := data["user_id"].(string)
-	if !ok {
+	if data.UserID == "" {
 		return fmt.Errorf("missing user_id in event data")
 	}
+	userID := data.UserID
 
 	w.logger.WithField("user_id", userID).Info("Handling user activity updated event")
 
@@ -309,12 +308,40 @@ func (w *OptimizedFeedWorker) runActivityDecayJob(ctx context.Context) {
 	w.logger.Info("Activity decay job completed")
 }
 
+// startConsumerLagReportJob 定期上报消费延迟指标
+func (w *OptimizedFeedWorker) startConsumerLagReportJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Consumer lag report job stopped")
+			return
+		case <-ticker.C:
+			lag, err := w.consumer.Lag(ctx)
+			if err != nil {
+				w.logger.WithError(err).Warn("Failed to get consumer lag")
+				continue
+			}
+
+			w.logger.WithFields(map[string]interface{}{
+				"metric":     "kafka.consumer.lag",
+				"topic":      lag.Topic,
+				"group_id":   lag.GroupID,
+				"total_lag":  lag.TotalLag,
+				"partitions": len(lag.Partitions),
+			}).Info("Consumer lag")
+		}
+	}
+}
+
 // GetWorkerStats 获取Worker统计信息
 func (w *OptimizedFeedWorker) GetWorkerStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := map[string]interface{}{
-		"worker_type":        "optimized_feed_worker",
-		"start_time":         time.Now().Format(time.RFC3339),
-		"processed_messages": 0, // 这里需要实际统计
+		"worker_type":   "optimized_feed_worker",
+		"start_time":    time.Now().Format(time.RFC3339),
+		"event_metrics": w.metrics.Snapshot(),
 	}
 
 	// 获取各种服务的统计信息
@@ -326,11 +353,35 @@ func (w *OptimizedFeedWorker) GetWorkerStats(ctx context.Context) (map[string]in
 		stats["distribution_stats"] = distributionStats
 	}
 
+	if distributionMetrics, err := w.recoveryService.GetDistributionMetrics(ctx); err == nil {
+		stats["distribution_metrics"] = distributionMetrics
+	}
+
+	if pending, err := w.optimizedFeedService.PendingDelayedFanouts(ctx); err == nil {
+		stats["pending_delayed_fanouts"] = pending
+	}
+
+	if lag, err := w.consumer.Lag(ctx); err == nil {
+		stats["consumer_lag"] = lag
+	}
+
 	return stats, nil
 }
 
 // Stop 停止Worker
 func (w *OptimizedFeedWorker) Stop(ctx context.Context) error {
 	w.logger.Info("Stopping optimized feed worker")
-	return w.consumer.Close()
+
+	// 关闭消费者可能阻塞在提交offset上，使用ctx限制等待时间
+	done := make(chan error, 1)
+	go func() {
+		done <- w.consumer.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out stopping optimized feed worker: %w", ctx.Err())
+	}
 }
@@ -0,0 +1,117 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+)
+
+// newTestOptimizedFeedWorker 基于miniredis的OptimizedFeedWorker，只初始化消息处理用到的依赖
+func newTestOptimizedFeedWorker(t *testing.T) (*OptimizedFeedWorker, *services.RecoveryService, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+
+	log := logger.NewLogger()
+	cfg := config.NewConfigWatcher(&config.FeedConfig{MaxFeedSize: 1000}, log)
+	recoveryService := services.NewRecoveryService(nil, nil, nil, redisClient, cfg, log, nil, nil)
+	worker := NewOptimizedFeedWorker(nil, log, nil, nil, nil, nil, recoveryService, nil, services.NewEventMetrics(log))
+	return worker, recoveryService, mr
+}
+
+func TestOptimizedWorkerHandlesSerializedPostCreated(t *testing.T) {
+	worker, _, _ := newTestOptimizedFeedWorker(t)
+	userID, postID := uuid.NewString(), uuid.NewString()
+
+	// 经过Kafka序列化后Data是按事件类型解析出的值类型，类型断言能成功
+	if err := worker.handleMessage(rawEvent(t, queue.EventPostCreated, queue.PostEventData{PostID: postID, UserID: userID})); err != nil {
+		t.Errorf("valid post_created: handleMessage() error = %v", err)
+	}
+
+	if err := worker.handleMessage(queue.Message{Raw: []byte(`{"type":"post_created","data":`)}); !errors.Is(err, queue.ErrMalformedEvent) {
+		t.Errorf("malformed payload: handleMessage() error = %v, want ErrMalformedEvent", err)
+	}
+	if err := worker.handleMessage(queue.Message{Raw: []byte(`{"type":"post_created","data":{"post_id":7}}`)}); !errors.Is(err, queue.ErrMalformedEvent) {
+		t.Errorf("mistyped data: handleMessage() error = %v, want ErrMalformedEvent", err)
+	}
+	if err := worker.handleMessage(rawEvent(t, queue.EventPostCreated, queue.PostEventData{PostID: postID})); err == nil {
+		t.Error("missing user_id: expected error")
+	}
+
+	want := map[[3]string]int64{
+		{optimizedFeedWorkerName, string(queue.EventPostCreated), services.EventOutcomeSuccess}: 1,
+		{optimizedFeedWorkerName, string(queue.EventPostCreated), services.EventOutcomeError}:   1,
+		{optimizedFeedWorkerName, eventTypeMalformed, services.EventOutcomeError}:               2,
+	}
+	got := metricCounts(worker.metrics)
+	if len(got) != len(want) {
+		t.Errorf("metrics = %v, want %v", got, want)
+	}
+	for key, count := range want {
+		if got[key] != count {
+			t.Errorf("count%v = %d, want %d", key, got[key], count)
+		}
+	}
+}
+
+func TestOptimizedWorkerRecordsDistributionCompleted(t *testing.T) {
+	worker, recoveryService, mr := newTestOptimizedFeedWorker(t)
+	ctx := context.Background()
+	authorID, postID := uuid.NewString(), uuid.NewString()
+
+	// 分发开始时写入的pending状态
+	status, err := json.Marshal(services.DistributionStatus{PostID: postID, AuthorID: authorID, Status: "pending"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("distribution_status:"+postID, string(status))
+
+	for _, data := range []queue.DistributionCompletedEventData{
+		{PostID: postID, AuthorID: authorID, ActiveFollowers: 30, DistributionType: "push", DurationMs: 100},
+		{PostID: uuid.NewString(), AuthorID: authorID, ActiveFollowers: 10, DistributionType: "push", DurationMs: 300},
+		{PostID: uuid.NewString(), AuthorID: authorID, ActiveFollowers: 5, DurationMs: 50},
+	} {
+		if err := worker.handleMessage(rawEvent(t, queue.EventPostDistributionCompleted, data)); err != nil {
+			t.Fatalf("handleMessage() error = %v", err)
+		}
+	}
+
+	metrics, err := recoveryService.GetDistributionMetrics(ctx)
+	if err != nil {
+		t.Fatalf("GetDistributionMetrics() error = %v", err)
+	}
+	want := map[string]map[string]int64{
+		"push":    {"completed": 2, "active_followers": 40, "duration_ms": 400, "avg_duration_ms": 200},
+		"unknown": {"completed": 1, "active_followers": 5, "duration_ms": 50, "avg_duration_ms": 50},
+	}
+	for distributionType, counters := range want {
+		for name, value := range counters {
+			if got := metrics[distributionType][name]; got != value {
+				t.Errorf("metrics[%s][%s] = %d, want %d", distributionType, name, got, value)
+			}
+		}
+	}
+
+	var updated services.DistributionStatus
+	raw, err := mr.Get("distribution_status:" + postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(raw), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != "completed" {
+		t.Errorf("distribution status = %q, want completed", updated.Status)
+	}
+}
@@ -0,0 +1,159 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestFeedWorker 基于sqlmock和miniredis的FeedWorker，只初始化关注事件用到的依赖
+func newTestFeedWorker(t *testing.T) (*FeedWorker, sqlmock.Sqlmock) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+
+	log := logger.NewLogger()
+	cfg := config.NewConfigWatcher(&config.FeedConfig{
+		MaxFeedSize:      1000,
+		BackfillCooldown: 10 * time.Minute,
+	}, log)
+
+	postRepo := repository.NewPostRepository(db)
+	timelineRepo := repository.NewTimelineRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	feedService := services.NewFeedService(postRepo, timelineRepo, userRepo, followRepo, nil, nil, redisClient, nil, cfg, log, nil, nil)
+
+	return NewFeedWorker(feedService, nil, postRepo, timelineRepo, followRepo, userRepo, redisClient, nil, log, nil), mock
+}
+
+func followEvent(eventType queue.EventType, followerID, followingID uuid.UUID) queue.Event {
+	return queue.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data: queue.FollowEventData{
+			FollowerID:  followerID.String(),
+			FollowingID: followingID.String(),
+		},
+	}
+}
+
+func expectPostsQuery(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(user_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func TestFollowBackfillCooldown(t *testing.T) {
+	ctx := context.Background()
+	followerID, followingID := uuid.New(), uuid.New()
+
+	t.Run("duplicate follow event skips backfill", func(t *testing.T) {
+		worker, mock := newTestFeedWorker(t)
+		expectPostsQuery(mock)
+
+		for i := 0; i < 2; i++ {
+			if err := worker.handleFollowCreated(ctx, followEvent(queue.EventFollowCreated, followerID, followingID)); err != nil {
+				t.Fatalf("handleFollowCreated: %v", err)
+			}
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("refollow after unfollow backfills again", func(t *testing.T) {
+		worker, mock := newTestFeedWorker(t)
+		expectPostsQuery(mock) // 关注时回填
+		expectPostsQuery(mock) // 取消关注时删除回填的帖子
+		expectPostsQuery(mock) // 冷却期内重新关注仍然回填
+
+		if err := worker.handleFollowCreated(ctx, followEvent(queue.EventFollowCreated, followerID, followingID)); err != nil {
+			t.Fatalf("handleFollowCreated: %v", err)
+		}
+		if err := worker.handleFollowDeleted(ctx, followEvent(queue.EventFollowDeleted, followerID, followingID)); err != nil {
+			t.Fatalf("handleFollowDeleted: %v", err)
+		}
+		if err := worker.handleFollowCreated(ctx, followEvent(queue.EventFollowCreated, followerID, followingID)); err != nil {
+			t.Fatalf("handleFollowCreated: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("failed backfill releases cooldown", func(t *testing.T) {
+		worker, mock := newTestFeedWorker(t)
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(user_id`).WillReturnError(errors.New("connection reset"))
+		expectPostsQuery(mock)
+
+		if err := worker.handleFollowCreated(ctx, followEvent(queue.EventFollowCreated, followerID, followingID)); err == nil {
+			t.Fatal("expected backfill error")
+		}
+		if err := worker.handleFollowCreated(ctx, followEvent(queue.EventFollowCreated, followerID, followingID)); err != nil {
+			t.Fatalf("handleFollowCreated retry: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestEngagementEventsInvalidatePostCache(t *testing.T) {
+	ctx := context.Background()
+	userID, postID := uuid.NewString(), uuid.NewString()
+
+	for _, event := range []queue.Event{
+		{Type: queue.EventLikeCreated, Data: queue.LikeEventData{UserID: userID, PostID: postID}},
+		{Type: queue.EventLikeDeleted, Data: queue.LikeEventData{UserID: userID, PostID: postID}},
+		{Type: queue.EventCommentCreated, Data: queue.CommentEventData{CommentID: uuid.NewString(), UserID: userID, PostID: postID}},
+	} {
+		t.Run(string(event.Type), func(t *testing.T) {
+			worker, _ := newTestFeedWorker(t)
+			other := uuid.NewString()
+			for _, id := range []string{postID, other} {
+				if err := worker.cache.Set(ctx, services.PostCacheKey(id), "{}", time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := worker.handleEvent(ctx, event); err != nil {
+				t.Fatalf("handleEvent() error = %v", err)
+			}
+			// 只失效事件涉及的帖子
+			if n, err := worker.cache.Exists(ctx, services.PostCacheKey(postID)); err != nil || n != 0 {
+				t.Errorf("post cache still present after %s (exists=%d, err=%v)", event.Type, n, err)
+			}
+			if n, err := worker.cache.Exists(ctx, services.PostCacheKey(other)); err != nil || n != 1 {
+				t.Errorf("unrelated post cache dropped (exists=%d, err=%v)", n, err)
+			}
+		})
+	}
+}
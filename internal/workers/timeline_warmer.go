@@ -0,0 +1,60 @@
+package workers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/models"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// TimelineWarmer worker启动时为最活跃的用户预热Timeline缓存，避免Redis清空/重启后大量请求同时走拉模式
+type TimelineWarmer struct {
+	cacheStrategy *services.CacheStrategyService
+	userRepo      *repository.UserRepository
+	timelineRepo  *repository.TimelineRepository
+	config        *config.ConfigWatcher
+	logger        *logger.Logger
+}
+
+func NewTimelineWarmer(cacheStrategy *services.CacheStrategyService, userRepo *repository.UserRepository, timelineRepo *repository.TimelineRepository, config *config.ConfigWatcher, logger *logger.Logger) *TimelineWarmer {
+	return &TimelineWarmer{
+		cacheStrategy: cacheStrategy,
+		userRepo:      userRepo,
+		timelineRepo:  timelineRepo,
+		config:        config,
+		logger:        logger,
+	}
+}
+
+// Run 执行一次预热，未开启或max_users为0时直接返回
+func (w *TimelineWarmer) Run(ctx context.Context) error {
+	prewarmCfg := w.config.Feed().Optimization.Prewarm
+	if !prewarmCfg.Enabled || prewarmCfg.MaxUsers <= 0 {
+		return nil
+	}
+
+	userIDs, err := w.userRepo.GetMostActive(ctx, prewarmCfg.MaxUsers)
+	if err != nil {
+		return err
+	}
+
+	warmed, err := w.cacheStrategy.PrewarmCache(ctx, userIDs, w.loadTimeline)
+	w.logger.WithFields(map[string]interface{}{
+		"candidates": len(userIDs),
+		"warmed":     warmed,
+	}).Info("Startup timeline prewarm finished")
+	if errors.Is(err, context.Canceled) {
+		// worker关闭时中断预热属于正常情况
+		return nil
+	}
+	return err
+}
+
+func (w *TimelineWarmer) loadTimeline(ctx context.Context, userID uuid.UUID) ([]*models.Timeline, error) {
+	return w.timelineRepo.GetByUserID(ctx, userID, 0, services.MaxTimelineItemsActive)
+}
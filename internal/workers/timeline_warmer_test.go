@@ -0,0 +1,139 @@
+package workers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func newTestTimelineWarmer(t *testing.T, prewarm config.PrewarmConfig) (*TimelineWarmer, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	t.Cleanup(func() { redisClient.Close() })
+
+	log := logger.NewLogger()
+	feedCfg := &config.FeedConfig{MaxFeedSize: 1000, CacheTTL: time.Hour}
+	feedCfg.Optimization.Prewarm = prewarm
+	cfg := config.NewConfigWatcher(feedCfg, log)
+
+	userRepo := repository.NewUserRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	activityService := services.NewActivityService(userRepo, followRepo, redisClient, cfg, log)
+	timelineCache := services.NewTimelineCacheService(redisClient, cfg, log)
+	cacheStrategy := services.NewCacheStrategyService(redisClient, cfg, log, activityService, timelineCache)
+
+	return NewTimelineWarmer(cacheStrategy, userRepo, repository.NewTimelineRepository(db), cfg, log), mock, mr
+}
+
+func TestTimelineWarmerPrewarmsTopActiveUsers(t *testing.T) {
+	warmer, mock, mr := newTestTimelineWarmer(t, config.PrewarmConfig{Enabled: true, MaxUsers: 3})
+	ctx := context.Background()
+
+	// 前三名活跃用户：第一个需要预热，第二个已缓存，第三个已不活跃
+	cold, cached, inactive := uuid.New(), uuid.New(), uuid.New()
+	mr.Set("user_active:"+cold.String(), "1")
+	mr.Set("user_active:"+cached.String(), "1")
+	mr.Set("user_active:"+inactive.String(), "0")
+	mr.ZAdd("timeline:"+cached.String(), 1, uuid.NewString())
+
+	mock.ExpectQuery(`SELECT "id" FROM "users" WHERE last_active_at IS NOT NULL .* ORDER BY activity_score DESC LIMIT 3`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(cold).AddRow(cached).AddRow(inactive))
+	postA, postB := uuid.New(), uuid.New()
+	createdAt := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`SELECT \* FROM "timelines" WHERE user_id = \$1`).
+		WithArgs(cold).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "post_id", "score", "created_at"}).
+			AddRow(uuid.New(), cold, postA, 12.5, createdAt).
+			AddRow(uuid.New(), cold, postB, 3.0, createdAt.Add(-time.Minute)))
+	mock.ExpectQuery(`SELECT \* FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := warmer.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := mr.ZMembers("timeline:" + cold.String())
+	if err != nil || len(members) != 2 {
+		t.Fatalf("warmed timeline = %v, %v; want both posts", members, err)
+	}
+	if score, _ := mr.ZScore(services.RankedTimelineKey(cold), postA.String()); score != 12.5 {
+		t.Errorf("ranked score = %v, want 12.5", score)
+	}
+	if members, _ := mr.ZMembers("timeline:" + cached.String()); len(members) != 1 {
+		t.Errorf("already cached timeline rebuilt: %v", members)
+	}
+	if mr.Exists("timeline:" + inactive.String()) {
+		t.Error("inactive user's timeline prewarmed")
+	}
+}
+
+func TestTimelineWarmerDisabled(t *testing.T) {
+	for name, prewarm := range map[string]config.PrewarmConfig{
+		"disabled":     {Enabled: false, MaxUsers: 10},
+		"no max users": {Enabled: true, MaxUsers: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			warmer, mock, _ := newTestTimelineWarmer(t, prewarm)
+
+			// 未开启时不查询数据库
+			if err := warmer.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTimelineWarmerStopsOnShutdown(t *testing.T) {
+	warmer, mock, mr := newTestTimelineWarmer(t, config.PrewarmConfig{Enabled: true, MaxUsers: 2, Delay: time.Hour})
+	first, second := uuid.New(), uuid.New()
+	mr.Set("user_active:"+first.String(), "1")
+	mr.Set("user_active:"+second.String(), "1")
+
+	mock.ExpectQuery(`SELECT "id" FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first).AddRow(second))
+	mock.ExpectQuery(`SELECT \* FROM "timelines" WHERE user_id = \$1`).
+		WithArgs(first).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "post_id", "created_at"}))
+
+	// 每个用户之间间隔一小时，关闭worker时中断预热且不报错
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := warmer.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v, want nil on shutdown", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,198 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+)
+
+// unfollowCleanupPostLimit 取消关注时从Timeline缓存清理的作者帖子数，覆盖Timeline缓存的最大条目数
+const unfollowCleanupPostLimit = services.MaxTimelineItemsActive * 2
+
+// UserEventWorker 消费user_events，保持用户资料缓存和Timeline缓存与数据库一致
+type UserEventWorker struct {
+	cache         *cache.RedisClient
+	postRepo      *repository.PostRepository
+	timelineCache *services.TimelineCacheService
+	consumer      *queue.KafkaConsumer
+	logger        *logger.Logger
+	metrics       *services.EventMetrics
+}
+
+func NewUserEventWorker(cache *cache.RedisClient, postRepo *repository.PostRepository, timelineCache *services.TimelineCacheService, consumer *queue.KafkaConsumer, logger *logger.Logger, metrics *services.EventMetrics) *UserEventWorker {
+	return &UserEventWorker{
+		cache:         cache,
+		postRepo:      postRepo,
+		timelineCache: timelineCache,
+		consumer:      consumer,
+		logger:        logger,
+		metrics:       metrics,
+	}
+}
+
+func (w *UserEventWorker) Start(ctx context.Context) error {
+	w.logger.Info("Starting user event worker...")
+
+	return w.consumer.Subscribe(ctx, func(msg queue.Message) error {
+		return w.handleMessage(ctx, msg)
+	})
+}
+
+// handleMessage 解码并处理一条消息，按事件类型和处理结果记录统计
+func (w *UserEventWorker) handleMessage(ctx context.Context, msg queue.Message) error {
+	event, _, err := queue.DecodeEvent(msg.Raw)
+	if err != nil {
+		w.metrics.Observe(userEventWorkerName, eventTypeMalformed, 0, err)
+		return err
+	}
+
+	w.logger.WithFields(map[string]interface{}{
+		"event_type": event.Type,
+		"timestamp":  event.Timestamp,
+	}).Info("Processing user event")
+
+	start := time.Now()
+	err = w.handleEvent(ctx, event)
+	w.metrics.Observe(userEventWorkerName, string(event.Type), time.Since(start), err)
+	return err
+}
+
+// handleEvent 按事件类型分发处理
+func (w *UserEventWorker) handleEvent(ctx context.Context, event queue.Event) error {
+	switch event.Type {
+	case queue.EventUserCreated:
+		return w.handleUserCreated(ctx, event)
+	case queue.EventUserUpdated:
+		return w.handleUserUpdated(ctx, event)
+	case queue.EventFollowCreated:
+		return w.handleFollowChanged(ctx, event)
+	case queue.EventFollowDeleted:
+		if err := w.handleFollowChanged(ctx, event); err != nil {
+			return err
+		}
+		return w.handleFollowDeleted(ctx, event)
+	default:
+		w.logger.WithField("event_type", event.Type).Warn("Unknown event type")
+		return nil
+	}
+}
+
+func (w *UserEventWorker) handleUserCreated(ctx context.Context, event queue.Event) error {
+	data, ok := event.Data.(queue.UserEventData)
+	if !ok {
+		return fmt.Errorf("invalid user created event data")
+	}
+
+	// 新用户还没有关注任何人，Timeline为空，无需预热；首次读取Feed时会按拉模式构建
+	w.logger.WithField("user_id", data.UserID).Info("Handling user created event")
+	return nil
+}
+
+func (w *UserEventWorker) handleUserUpdated(ctx context.Context, event queue.Event) error {
+	data, ok := event.Data.(queue.UserEventData)
+	if !ok {
+		return fmt.Errorf("invalid user updated event data")
+	}
+	if data.UserID == "" {
+		return fmt.Errorf("missing user_id in event data")
+	}
+	userID := data.UserID
+
+	w.logger.WithField("user_id", userID).Info("Handling user updated event")
+
+	return w.invalidateProfiles(ctx, userID)
+}
+
+func (w *UserEventWorker) handleFollowChanged(ctx context.Context, event queue.Event) error {
+	data, ok := event.Data.(queue.FollowEventData)
+	if !ok {
+		return fmt.Errorf("invalid follow event data")
+	}
+
+	followerID, followingID := data.FollowerID, data.FollowingID
+	if followerID == "" || followingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
+	}
+
+	// 关注数和粉丝数发生变化，双方资料缓存都需要失效
+	return w.invalidateProfiles(ctx, followerID, followingID)
+}
+
+// handleFollowDeleted 从关注者的Timeline缓存中删除被取消关注用户的帖子
+func (w *UserEventWorker) handleFollowDeleted(ctx context.Context, event queue.Event) error {
+	data, ok := event.Data.(queue.FollowEventData)
+	if !ok {
+		return fmt.Errorf("invalid follow deleted event data")
+	}
+
+	followerID, followingID := data.FollowerID, data.FollowingID
+	if followerID == "" || followingID == "" {
+		return fmt.Errorf("missing follower_id or following_id in event data")
+	}
+
+	followerUUID, err := uuid.Parse(followerID)
+	if err != nil {
+		return fmt.Errorf("invalid follower ID: %w", err)
+	}
+	followingUUID, err := uuid.Parse(followingID)
+	if err != nil {
+		return fmt.Errorf("invalid following ID: %w", err)
+	}
+
+	posts, err := w.postRepo.GetByUserID(ctx, followingUUID, 0, unfollowCleanupPostLimit, true)
+	if err != nil {
+		return fmt.Errorf("failed to get following's posts: %w", err)
+	}
+
+	postIDs := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID
+	}
+	if err := w.timelineCache.RemovePostsFromTimeline(ctx, followerUUID, postIDs); err != nil {
+		return err
+	}
+
+	w.logger.WithFields(map[string]interface{}{
+		"follower_id":  followerID,
+		"following_id": followingID,
+		"removed":      len(postIDs),
+	}).Info("Removed unfollowed user's posts from timeline cache")
+	return nil
+}
+
+// invalidateProfiles 删除用户资料缓存
+func (w *UserEventWorker) invalidateProfiles(ctx context.Context, userIDs ...string) error {
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = services.ProfileCacheKey(userID)
+	}
+
+	if err := w.cache.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to invalidate profile cache: %w", err)
+	}
+	return nil
+}
+
+func (w *UserEventWorker) Stop(ctx context.Context) error {
+	w.logger.Info("Stopping user event worker...")
+
+	// 关闭消费者可能阻塞在提交offset上，使用ctx限制等待时间
+	done := make(chan error, 1)
+	go func() {
+		done <- w.consumer.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out stopping user event worker: %w", ctx.Err())
+	}
+}
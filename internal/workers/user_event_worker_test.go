@@ -0,0 +1,121 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/feed-system/feed-system/internal/config"
+	"github.com/feed-system/feed-system/internal/repository"
+	"github.com/feed-system/feed-system/internal/services"
+	"github.com/feed-system/feed-system/pkg/cache"
+	"github.com/feed-system/feed-system/pkg/logger"
+	"github.com/feed-system/feed-system/pkg/queue"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestFollowDeletedCleansTimelines(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	defer redisClient.Close()
+
+	log := logger.NewLogger()
+	cfg := config.NewConfigWatcher(&config.FeedConfig{MaxFeedSize: 1000, CacheTTL: time.Hour}, log)
+	timelineCache := services.NewTimelineCacheService(redisClient, cfg, log)
+	worker := NewUserEventWorker(redisClient, repository.NewPostRepository(db), timelineCache, nil, log, nil)
+
+	ctx := context.Background()
+	followerID, unfollowedID := uuid.New(), uuid.New()
+	unfollowedPost, otherPost := uuid.New(), uuid.New()
+	for _, postID := range []uuid.UUID{unfollowedPost, otherPost} {
+		if err := timelineCache.AddToTimeline(ctx, followerID, postID, 1, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := redisClient.Set(ctx, services.ProfileCacheKey(unfollowedID.String()), "cached", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE \(user_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(unfollowedPost, unfollowedID))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(unfollowedID))
+
+	if err := worker.handleEvent(ctx, followEvent(queue.EventFollowDeleted, followerID, unfollowedID)); err != nil {
+		t.Fatalf("handleEvent: %v", err)
+	}
+
+	members, err := mr.ZMembers("timeline:" + followerID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || members[0] != otherPost.String() {
+		t.Errorf("timeline members = %v, want only %s", members, otherPost)
+	}
+	if mr.Exists(services.ProfileCacheKey(unfollowedID.String())) {
+		t.Errorf("profile cache of unfollowed user not invalidated")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserUpdatedInvalidatesProfile(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := cache.NewRedisClient(mr.Addr(), "", 0, 10, 0)
+	defer redisClient.Close()
+	worker := NewUserEventWorker(redisClient, nil, nil, nil, logger.NewLogger(), nil)
+	ctx := context.Background()
+
+	updatedID, otherID := uuid.NewString(), uuid.NewString()
+	for _, userID := range []string{updatedID, otherID} {
+		mr.Set(services.ProfileCacheKey(userID), "cached")
+	}
+
+	// 按消费者收到的原始消息解码
+	raw, err := json.Marshal(queue.Event{
+		Type:      queue.EventUserUpdated,
+		Timestamp: time.Now(),
+		Data:      queue.UserEventData{UserID: updatedID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, _, err := queue.DecodeEvent(raw)
+	if err != nil {
+		t.Fatalf("DecodeEvent: %v", err)
+	}
+	if err := worker.handleEvent(ctx, event); err != nil {
+		t.Fatalf("handleEvent: %v", err)
+	}
+
+	if mr.Exists(services.ProfileCacheKey(updatedID)) {
+		t.Error("profile cache of updated user not invalidated")
+	}
+	if !mr.Exists(services.ProfileCacheKey(otherID)) {
+		t.Error("profile cache of other user invalidated")
+	}
+
+	missing := queue.Event{Type: queue.EventUserUpdated, Data: queue.UserEventData{}}
+	if err := worker.handleEvent(ctx, missing); err == nil {
+		t.Error("handleEvent accepted a user updated event without user_id")
+	}
+}
@@ -4,13 +4,54 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
 type RedisClient struct {
-	client *redis.Client
+	client redis.UniversalClient
+}
+
+// Redis部署模式
+const (
+	ModeSingle   = "single"
+	ModeCluster  = "cluster"
+	ModeSentinel = "sentinel"
+)
+
+// Options Redis连接配置。Mode为single时使用Addrs[0]；cluster时Addrs为集群节点；
+// sentinel时Addrs为哨兵地址，MasterName为主节点名
+type Options struct {
+	Mode         string
+	Addrs        []string
+	MasterName   string
+	Password     string
+	DB           int
+	PoolSize     int
+	MinIdleConns int
+}
+
+// New 按部署模式创建Redis客户端，各模式对外暴露相同的方法
+func New(opts Options) (*RedisClient, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("redis addrs must not be empty")
+	}
+
+	switch opts.Mode {
+	case "", ModeSingle:
+		return NewRedisClient(opts.Addrs[0], opts.Password, opts.DB, opts.PoolSize, opts.MinIdleConns), nil
+	case ModeCluster:
+		return NewRedisClusterClient(opts.Addrs, opts.Password, opts.PoolSize, opts.MinIdleConns), nil
+	case ModeSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("redis master name is required in sentinel mode")
+		}
+		return NewRedisSentinelClient(opts.MasterName, opts.Addrs, opts.Password, opts.DB, opts.PoolSize, opts.MinIdleConns), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", opts.Mode)
+	}
 }
 
 func NewRedisClient(addr, password string, db, poolSize, minIdleConns int) *RedisClient {
@@ -25,6 +66,39 @@ func NewRedisClient(addr, password string, db, poolSize, minIdleConns int) *Redi
 	return &RedisClient{client: client}
 }
 
+// NewRedisClusterClient 连接Redis Cluster，PoolSize和MinIdleConns作用于每个节点。
+// 集群不支持多DB，跨slot的多key命令由客户端拆分为单key命令执行
+func NewRedisClusterClient(addrs []string, password string, poolSize, minIdleConns int) *RedisClient {
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:        addrs,
+		Password:     password,
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+	})
+
+	return &RedisClient{client: client}
+}
+
+// NewRedisSentinelClient 通过哨兵连接主节点，主从切换后自动重连到新的主节点
+func NewRedisSentinelClient(masterName string, sentinelAddrs []string, password string, db, poolSize, minIdleConns int) *RedisClient {
+	client := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    masterName,
+		SentinelAddrs: sentinelAddrs,
+		Password:      password,
+		DB:            db,
+		PoolSize:      poolSize,
+		MinIdleConns:  minIdleConns,
+	})
+
+	return &RedisClient{client: client}
+}
+
+// cluster 集群模式下返回集群客户端
+func (r *RedisClient) cluster() (*redis.ClusterClient, bool) {
+	cc, ok := r.client.(*redis.ClusterClient)
+	return cc, ok
+}
+
 func (r *RedisClient) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
@@ -54,10 +128,38 @@ func (r *RedisClient) GetJSON(ctx context.Context, key string, dest interface{})
 }
 
 func (r *RedisClient) Delete(ctx context.Context, keys ...string) error {
+	if _, ok := r.cluster(); ok && len(keys) > 1 {
+		// 集群中多个key可能位于不同slot，逐个删除
+		pipe := r.client.Pipeline()
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	}
 	return r.client.Del(ctx, keys...).Err()
 }
 
+func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
 func (r *RedisClient) Exists(ctx context.Context, keys ...string) (int64, error) {
+	if _, ok := r.cluster(); ok && len(keys) > 1 {
+		pipe := r.client.Pipeline()
+		cmds := make([]*redis.IntCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Exists(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+		var total int64
+		for _, cmd := range cmds {
+			total += cmd.Val()
+		}
+		return total, nil
+	}
 	return r.client.Exists(ctx, keys...).Result()
 }
 
@@ -65,6 +167,11 @@ func (r *RedisClient) ZAdd(ctx context.Context, key string, members ...*redis.Z)
 	return r.client.ZAdd(ctx, key, members...).Err()
 }
 
+// ZAddNX 只添加不存在的成员，已存在成员的score保持不变
+func (r *RedisClient) ZAddNX(ctx context.Context, key string, members ...*redis.Z) error {
+	return r.client.ZAddNX(ctx, key, members...).Err()
+}
+
 func (r *RedisClient) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
 	return r.client.ZRange(ctx, key, start, stop).Result()
 }
@@ -109,6 +216,14 @@ func (r *RedisClient) HDel(ctx context.Context, key string, fields ...string) er
 	return r.client.HDel(ctx, key, fields...).Err()
 }
 
+func (r *RedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
+func (r *RedisClient) Rename(ctx context.Context, key, newKey string) error {
+	return r.client.Rename(ctx, key, newKey).Err()
+}
+
 func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
 	return r.client.Expire(ctx, key, expiration).Err()
 }
@@ -121,10 +236,98 @@ func (r *RedisClient) ZRevRangeByScoreWithScores(ctx context.Context, key string
 	return r.client.ZRevRangeByScoreWithScores(ctx, key, opt).Result()
 }
 
+func (r *RedisClient) ZRangeByScoreWithScores(ctx context.Context, key string, opt *redis.ZRangeBy) ([]redis.Z, error) {
+	return r.client.ZRangeByScoreWithScores(ctx, key, opt).Result()
+}
+
+func (r *RedisClient) ZCount(ctx context.Context, key, min, max string) (int64, error) {
+	return r.client.ZCount(ctx, key, min, max).Result()
+}
+
+// Scan 使用SCAN遍历匹配pattern的所有key，避免KEYS阻塞Redis。集群模式下遍历所有主节点
+func (r *RedisClient) Scan(ctx context.Context, pattern string, count int64) ([]string, error) {
+	cc, ok := r.cluster()
+	if !ok {
+		return scanAll(ctx, r.client, pattern, count)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cc.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scanAll(ctx, node, pattern, count)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// scanAll 在单个节点上完整执行一轮SCAN
+func scanAll(ctx context.Context, client redis.Cmdable, pattern string, count int64) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := client.Scan(ctx, cursor, pattern, count).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		cursor = next
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// ScanPage 执行一次SCAN，返回本页keys和下一次的游标（游标为0表示扫描结束）。
+// 集群模式下各节点的游标无法合并，一次返回所有节点的全部匹配key，游标为0
+func (r *RedisClient) ScanPage(ctx context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	if _, ok := r.cluster(); ok {
+		keys, err := r.Scan(ctx, pattern, count)
+		return keys, 0, err
+	}
+	return r.client.Scan(ctx, cursor, pattern, count).Result()
+}
+
+// MGet 一次读取多个key，不存在的key对应nil
+func (r *RedisClient) MGet(ctx context.Context, keys ...string) ([]interface{}, error) {
+	if _, ok := r.cluster(); ok && len(keys) > 1 {
+		// 集群中多个key可能位于不同slot，用pipeline逐个GET
+		pipe := r.client.Pipeline()
+		cmds := make([]*redis.StringCmd, len(keys))
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(keys))
+		for i, cmd := range cmds {
+			if val, err := cmd.Result(); err == nil {
+				values[i] = val
+			}
+		}
+		return values, nil
+	}
+	return r.client.MGet(ctx, keys...).Result()
+}
+
 func (r *RedisClient) Pipeline() redis.Pipeliner {
 	return r.client.Pipeline()
 }
 
+// PoolStats 连接池统计，集群模式下为所有节点之和
+func (r *RedisClient) PoolStats() *redis.PoolStats {
+	return r.client.PoolStats()
+}
+
 func (r *RedisClient) Close() error {
 	return r.client.Close()
 }
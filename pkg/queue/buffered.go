@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Publisher 事件发布接口，KafkaProducer和BufferedProducer都实现了该接口
+type Publisher interface {
+	Publish(ctx context.Context, key string, value interface{}) error
+}
+
+// batchPublisher 批量发送消息，由KafkaProducer实现
+type batchPublisher interface {
+	PublishBatch(ctx context.Context, messages []Message) error
+}
+
+// maxBufferedBatches 发送失败时缓冲区最多保留的批数，超出后丢弃最旧的事件
+const maxBufferedBatches = 10
+
+// BufferedProducer 缓冲发布器：合并事件后通过PublishBatch批量发送
+// 达到flushSize或每隔flushInterval刷新一次。同一key的事件在批内保持顺序，
+// 并由Hash分区器写入同一分区，因此按post ID作为key即可保证单帖事件有序
+type BufferedProducer struct {
+	producer      batchPublisher
+	flushInterval time.Duration
+	flushSize     int
+
+	mu      sync.Mutex
+	buffer  []Message
+	dropped uint64 // 因发送失败且缓冲区已满被丢弃的事件数
+	closed  bool
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewBufferedProducer(producer *KafkaProducer, flushInterval time.Duration, flushSize int) *BufferedProducer {
+	if flushInterval <= 0 {
+		flushInterval = 100 * time.Millisecond
+	}
+	if flushSize <= 0 {
+		flushSize = 100
+	}
+
+	p := &BufferedProducer{
+		producer:      producer,
+		flushInterval: flushInterval,
+		flushSize:     flushSize,
+		flushCh:       make(chan struct{}, 1),
+		stopCh:        make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.run()
+
+	return p
+}
+
+// Publish 将事件放入缓冲区，不等待写入Kafka
+func (p *BufferedProducer) Publish(ctx context.Context, key string, value interface{}) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return fmt.Errorf("buffered producer is closed")
+	}
+	p.buffer = append(p.buffer, Message{Key: key, Value: value})
+	full := len(p.buffer) >= p.flushSize
+	p.mu.Unlock()
+
+	if full {
+		select {
+		case p.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush 立即发送缓冲区中的所有事件
+func (p *BufferedProducer) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	batch := p.buffer
+	p.buffer = nil
+	p.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := p.producer.PublishBatch(ctx, batch); err != nil {
+		requeued, dropped := p.requeue(batch)
+		if dropped > 0 {
+			return fmt.Errorf("failed to publish %d buffered messages (%d requeued, %d dropped): %w", len(batch), requeued, dropped, err)
+		}
+		return fmt.Errorf("failed to publish %d buffered messages (requeued): %w", len(batch), err)
+	}
+	return nil
+}
+
+// requeue 将发送失败的批次放回缓冲区头部，保持与之后写入的事件的先后顺序，下次刷新时重试。
+// 缓冲区最多保留maxBufferedBatches批，超出部分丢弃最旧的事件并计数；批次可能已部分写入，重试时会重复投递
+func (p *BufferedProducer) requeue(batch []Message) (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	buffer := append(batch, p.buffer...)
+	dropped := 0
+	if limit := p.flushSize * maxBufferedBatches; len(buffer) > limit {
+		dropped = len(buffer) - limit
+		buffer = buffer[dropped:]
+	}
+	p.buffer = buffer
+	p.dropped += uint64(dropped)
+
+	requeued := len(batch) - dropped
+	if requeued < 0 {
+		requeued = 0
+	}
+	return requeued, dropped
+}
+
+// Dropped 返回累计丢弃的事件数
+func (p *BufferedProducer) Dropped() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped
+}
+
+// Close 停止定时刷新并发送剩余事件，不关闭底层的KafkaProducer
+func (p *BufferedProducer) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.stopCh)
+	p.wg.Wait()
+
+	return p.Flush(ctx)
+}
+
+func (p *BufferedProducer) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+		case <-p.flushCh:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := p.Flush(ctx); err != nil {
+			fmt.Printf("Failed to flush buffered messages: %v\n", err)
+		}
+		cancel()
+	}
+}
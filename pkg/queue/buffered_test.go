@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeBatchPublisher 记录成功发送的消息，fail为true时发送失败
+type fakeBatchPublisher struct {
+	fail bool
+	sent []Message
+}
+
+func (f *fakeBatchPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	if f.fail {
+		return errors.New("broker unavailable")
+	}
+	f.sent = append(f.sent, messages...)
+	return nil
+}
+
+func newTestBufferedProducer(publisher batchPublisher, flushSize int) *BufferedProducer {
+	return &BufferedProducer{producer: publisher, flushSize: flushSize}
+}
+
+func TestBufferedProducerRequeuesFailedBatch(t *testing.T) {
+	ctx := context.Background()
+	publisher := &fakeBatchPublisher{fail: true}
+	p := newTestBufferedProducer(publisher, 10)
+
+	p.Publish(ctx, "post-1", "a")
+	p.Publish(ctx, "post-1", "b")
+	if err := p.Flush(ctx); err == nil {
+		t.Fatal("expected flush error")
+	}
+	p.Publish(ctx, "post-1", "c")
+
+	publisher.fail = false
+	if err := p.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	var values []interface{}
+	for _, msg := range publisher.sent {
+		values = append(values, msg.Value)
+	}
+	if len(values) != 3 || values[0] != "a" || values[1] != "b" || values[2] != "c" {
+		t.Errorf("sent %v, want [a b c] in order", values)
+	}
+	if p.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", p.Dropped())
+	}
+}
+
+func TestBufferedProducerDropsOldestBeyondCap(t *testing.T) {
+	ctx := context.Background()
+	publisher := &fakeBatchPublisher{fail: true}
+	p := newTestBufferedProducer(publisher, 1)
+
+	total := maxBufferedBatches + 3
+	for i := 0; i < total; i++ {
+		p.Publish(ctx, "post-1", i)
+		p.Flush(ctx)
+	}
+
+	if p.Dropped() != 3 {
+		t.Errorf("Dropped() = %d, want 3", p.Dropped())
+	}
+
+	publisher.fail = false
+	if err := p.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(publisher.sent) != maxBufferedBatches || publisher.sent[0].Value != 3 {
+		t.Errorf("sent %d messages starting at %v, want %d starting at 3", len(publisher.sent), publisher.sent[0].Value, maxBufferedBatches)
+	}
+}
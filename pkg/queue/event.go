@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CurrentEventVersion 当前发布的事件结构版本。新增字段无需升级版本，
+// 字段含义或类型发生不兼容变化时递增，消费者可据此区分处理
+const CurrentEventVersion = 1
+
+// ErrMalformedEvent 消息无法解析为事件
+var ErrMalformedEvent = errors.New("malformed event")
+
+// MarshalJSON 发布时未设置版本的事件按当前版本写入
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	if e.Version == 0 {
+		e.Version = CurrentEventVersion
+	}
+	return json.Marshal(event(e))
+}
+
+type UserEventData struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	Bio         string `json:"bio,omitempty"`
+}
+
+type DistributionCompletedEventData struct {
+	PostID           string `json:"post_id"`
+	AuthorID         string `json:"author_id"`
+	ActiveFollowers  int    `json:"active_followers"`
+	DistributionType string `json:"distribution_type"`
+	DurationMs       int64  `json:"duration_ms"`
+}
+
+type UserActivityEventData struct {
+	UserID string `json:"user_id"`
+}
+
+// decodeEventData 按事件类型解析Data，未知类型返回nil
+func decodeEventData(eventType EventType, data json.RawMessage) (interface{}, error) {
+	switch eventType {
+	case EventUserCreated, EventUserUpdated:
+		return decodeData[UserEventData](data)
+	case EventPostCreated, EventPostDeleted:
+		return decodeData[PostEventData](data)
+	case EventFollowCreated, EventFollowDeleted:
+		return decodeData[FollowEventData](data)
+	case EventLikeCreated, EventLikeDeleted:
+		return decodeData[LikeEventData](data)
+	case EventCommentCreated:
+		return decodeData[CommentEventData](data)
+	case EventPostDistributionCompleted:
+		return decodeData[DistributionCompletedEventData](data)
+	case EventUserActivityUpdated:
+		return decodeData[UserActivityEventData](data)
+	default:
+		return nil, nil
+	}
+}
+
+func decodeData[T any](data json.RawMessage) (interface{}, error) {
+	var v T
+	if len(data) == 0 || string(data) == "null" {
+		return nil, errors.New("missing data")
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DecodeEvent 解析消息中的事件，并按事件类型将Data解析为对应的结构体（值类型，如PostEventData）。
+// 返回的Event.Data与typed相同；未知事件类型不报错，typed为nil，由调用方决定是否忽略。
+// 旧版本发布的消息没有version字段，按版本1处理
+func DecodeEvent(raw []byte) (Event, interface{}, error) {
+	var envelope struct {
+		Type      EventType       `json:"type"`
+		Version   int             `json:"version"`
+		Timestamp time.Time       `json:"timestamp"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return Event{}, nil, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+	if envelope.Version == 0 {
+		envelope.Version = 1
+	}
+
+	event := Event{
+		Type:      envelope.Type,
+		Version:   envelope.Version,
+		Timestamp: envelope.Timestamp,
+	}
+	typed, err := decodeEventData(envelope.Type, envelope.Data)
+	if err != nil {
+		return event, nil, fmt.Errorf("%w: invalid %s data: %v", ErrMalformedEvent, envelope.Type, err)
+	}
+	event.Data = typed
+	return event, typed, nil
+}
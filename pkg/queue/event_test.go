@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 每种事件发布后都能解析回同样的类型化数据
+func TestDecodeEventRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		eventType EventType
+		data      interface{}
+	}{
+		{EventUserCreated, UserEventData{UserID: "u1", Username: "alice", DisplayName: "Alice"}},
+		{EventUserUpdated, UserEventData{UserID: "u1", Avatar: "a.png", Bio: "hi"}},
+		{EventPostCreated, PostEventData{PostID: "p1", UserID: "u1", Content: "hello", CreatedAt: ts.Format(time.RFC3339)}},
+		{EventPostDeleted, PostEventData{PostID: "p1", UserID: "u1"}},
+		{EventFollowCreated, FollowEventData{FollowerID: "u1", FollowingID: "u2", CreatedAt: ts.Format(time.RFC3339)}},
+		{EventFollowDeleted, FollowEventData{FollowerID: "u1", FollowingID: "u2"}},
+		{EventLikeCreated, LikeEventData{UserID: "u1", PostID: "p1", ReactionType: "love"}},
+		{EventLikeDeleted, LikeEventData{UserID: "u1", PostID: "p1"}},
+		{EventCommentCreated, CommentEventData{CommentID: "c1", UserID: "u1", PostID: "p1", Content: "nice"}},
+		{EventPostDistributionCompleted, DistributionCompletedEventData{PostID: "p1", AuthorID: "u1", ActiveFollowers: 12, DistributionType: "push", DurationMs: 40}},
+		{EventUserActivityUpdated, UserActivityEventData{UserID: "u1"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.eventType), func(t *testing.T) {
+			raw, err := json.Marshal(Event{Type: tt.eventType, Timestamp: ts, Data: tt.data})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			event, typed, err := DecodeEvent(raw)
+			if err != nil {
+				t.Fatalf("DecodeEvent() error = %v", err)
+			}
+			if event.Type != tt.eventType || event.Version != CurrentEventVersion || !event.Timestamp.Equal(ts) {
+				t.Errorf("event = %+v, want type %s version %d at %s", event, tt.eventType, CurrentEventVersion, ts)
+			}
+			if !reflect.DeepEqual(typed, tt.data) || !reflect.DeepEqual(event.Data, tt.data) {
+				t.Errorf("typed = %#v, event.Data = %#v, want %#v", typed, event.Data, tt.data)
+			}
+		})
+	}
+}
+
+func TestDecodeEventEdgeCases(t *testing.T) {
+	t.Run("unknown type is not an error", func(t *testing.T) {
+		event, typed, err := DecodeEvent([]byte(`{"type":"something_new","version":3,"data":{"x":1}}`))
+		if err != nil {
+			t.Fatalf("DecodeEvent() error = %v", err)
+		}
+		if event.Type != "something_new" || event.Version != 3 || typed != nil || event.Data != nil {
+			t.Errorf("event = %+v, typed = %v; want unknown type with nil data", event, typed)
+		}
+	})
+
+	t.Run("missing version defaults to 1", func(t *testing.T) {
+		event, typed, err := DecodeEvent([]byte(`{"type":"like_created","data":{"user_id":"u1","post_id":"p1"}}`))
+		if err != nil {
+			t.Fatalf("DecodeEvent() error = %v", err)
+		}
+		if event.Version != 1 {
+			t.Errorf("version = %d, want 1", event.Version)
+		}
+		if want := (LikeEventData{UserID: "u1", PostID: "p1"}); typed != want {
+			t.Errorf("typed = %#v, want %#v", typed, want)
+		}
+	})
+
+	t.Run("explicit version is kept", func(t *testing.T) {
+		raw, err := json.Marshal(Event{Type: EventPostDeleted, Version: 2, Data: PostEventData{PostID: "p1"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		event, _, err := DecodeEvent(raw)
+		if err != nil || event.Version != 2 {
+			t.Errorf("DecodeEvent() = %+v, %v; want version 2", event, err)
+		}
+	})
+
+	for name, raw := range map[string]string{
+		"missing data":   `{"type":"post_created","version":1}`,
+		"null data":      `{"type":"post_created","data":null}`,
+		"mistyped data":  `{"type":"follow_created","data":{"follower_id":42}}`,
+		"invalid json":   `{"type":`,
+		"non-object raw": `"post_created"`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, typed, err := DecodeEvent([]byte(raw)); !errors.Is(err, ErrMalformedEvent) || typed != nil {
+				t.Errorf("DecodeEvent(%s) = %v, %v; want ErrMalformedEvent", raw, typed, err)
+			}
+		})
+	}
+}
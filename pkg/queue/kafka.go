@@ -3,7 +3,9 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -14,9 +16,18 @@ type KafkaProducer struct {
 }
 
 type KafkaConsumer struct {
-	reader *kafka.Reader
+	reader  *kafka.Reader
+	brokers []string
+	topic   string
+	groupID string
+
+	// 一个Reader只能有一个读取者，多个worker共用同一个consumer会互相抢消息
+	subscribed atomic.Bool
 }
 
+// ErrAlreadySubscribed 同一个consumer被重复订阅
+var ErrAlreadySubscribed = errors.New("kafka consumer already has a subscriber")
+
 func NewKafkaProducer(brokers []string, topic string) *KafkaProducer {
 	writer := &kafka.Writer{
 		Addr:     kafka.TCP(brokers...),
@@ -39,7 +50,12 @@ func NewKafkaConsumer(brokers []string, topic, groupID string) *KafkaConsumer {
 		StartOffset:    kafka.FirstOffset,
 	})
 
-	return &KafkaConsumer{reader: reader}
+	return &KafkaConsumer{
+		reader:  reader,
+		brokers: brokers,
+		topic:   topic,
+		groupID: groupID,
+	}
 }
 
 func (p *KafkaProducer) Publish(ctx context.Context, key string, value interface{}) error {
@@ -74,6 +90,11 @@ func (p *KafkaProducer) PublishBatch(ctx context.Context, messages []Message) er
 }
 
 func (c *KafkaConsumer) Subscribe(ctx context.Context, handler func(Message) error) error {
+	if !c.subscribed.CompareAndSwap(false, true) {
+		return fmt.Errorf("%w: topic=%s group=%s", ErrAlreadySubscribed, c.topic, c.groupID)
+	}
+	defer c.subscribed.Store(false)
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -94,6 +115,7 @@ func (c *KafkaConsumer) Subscribe(ctx context.Context, handler func(Message) err
 				Key:   string(message.Key),
 				Value: value,
 				Topic: message.Topic,
+				Raw:   message.Value,
 			}
 
 			if err := handler(msg); err != nil {
@@ -116,6 +138,8 @@ type Message struct {
 	Key   string
 	Value interface{}
 	Topic string
+	// Raw 消息原始内容，仅消费时填充，用于DecodeEvent
+	Raw []byte
 }
 
 type EventType string
@@ -130,10 +154,14 @@ const (
 	EventLikeCreated      EventType = "like_created"
 	EventLikeDeleted      EventType = "like_deleted"
 	EventCommentCreated   EventType = "comment_created"
+
+	EventPostDistributionCompleted EventType = "post_distribution_completed"
+	EventUserActivityUpdated       EventType = "user_activity_updated"
 )
 
 type Event struct {
 	Type      EventType   `json:"type"`
+	Version   int         `json:"version,omitempty"`
 	Timestamp time.Time   `json:"timestamp"`
 	Data      interface{} `json:"data"`
 }
@@ -152,8 +180,9 @@ type FollowEventData struct {
 }
 
 type LikeEventData struct {
-	UserID string `json:"user_id"`
-	PostID string `json:"post_id"`
+	UserID       string `json:"user_id"`
+	PostID       string `json:"post_id"`
+	ReactionType string `json:"reaction_type,omitempty"`
 }
 
 type CommentEventData struct {
@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// startSubscriber 在后台订阅，直到consumer被标记为已订阅后返回；读取会一直阻塞到ctx取消
+func startSubscriber(t *testing.T, ctx context.Context, c *KafkaConsumer) <-chan error {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Subscribe(ctx, func(Message) error { return nil })
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !c.subscribed.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func TestKafkaConsumerSingleSubscriber(t *testing.T) {
+	// 不可达的broker：ReadMessage一直阻塞，直到ctx取消
+	brokers := []string{"127.0.0.1:1"}
+	feedConsumer := NewKafkaConsumer(brokers, "feed_events", "feed-worker")
+	optimizedConsumer := NewKafkaConsumer(brokers, "feed_events", "optimized-feed-worker")
+	t.Cleanup(func() {
+		feedConsumer.Close()
+		optimizedConsumer.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first := startSubscriber(t, ctx, feedConsumer)
+
+	t.Run("second subscriber on the same consumer is rejected", func(t *testing.T) {
+		err := feedConsumer.Subscribe(ctx, func(Message) error { return nil })
+		if !errors.Is(err, ErrAlreadySubscribed) {
+			t.Fatalf("expected ErrAlreadySubscribed, got %v", err)
+		}
+	})
+
+	t.Run("consumer groups are independent", func(t *testing.T) {
+		if feedConsumer.reader == optimizedConsumer.reader {
+			t.Fatal("consumers share a reader")
+		}
+		if got := optimizedConsumer.reader.Config().GroupID; got != "optimized-feed-worker" {
+			t.Errorf("group ID = %q, want optimized-feed-worker", got)
+		}
+		second := startSubscriber(t, ctx, optimizedConsumer)
+		cancel()
+		for _, done := range []<-chan error{first, second} {
+			if err := <-done; errors.Is(err, ErrAlreadySubscribed) {
+				t.Errorf("subscriber rejected: %v", err)
+			}
+		}
+	})
+
+	t.Run("consumer can be subscribed again after the subscriber exits", func(t *testing.T) {
+		if feedConsumer.subscribed.Load() {
+			t.Error("consumer still marked as subscribed")
+		}
+	})
+}
+
+// 两个Feed Worker使用不同的消费组订阅同一个topic，发布的每条事件两边都能收到。
+// 需要FEEDSYSTEM_TEST_KAFKA_BROKERS指定的Kafka，未设置时跳过
+func TestSeparateConsumerGroupsBothReceiveEventIntegration(t *testing.T) {
+	brokersEnv := os.Getenv("FEEDSYSTEM_TEST_KAFKA_BROKERS")
+	if brokersEnv == "" {
+		t.Skip("FEEDSYSTEM_TEST_KAFKA_BROKERS not set, skipping integration test")
+	}
+	brokers := strings.Split(brokersEnv, ",")
+	suffix := uuid.NewString()[:8]
+	topic := "feed-events-it-" + suffix
+
+	conn, err := kafka.Dial("tcp", brokers[0])
+	if err != nil {
+		t.Fatalf("failed to dial kafka: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.CreateTopics(kafka.TopicConfig{Topic: topic, NumPartitions: 1, ReplicationFactor: 1}); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	producer := NewKafkaProducer(brokers, topic)
+	defer producer.Close()
+	postID := uuid.NewString()
+	if err := producer.Publish(ctx, postID, Event{Type: EventPostCreated, Timestamp: time.Now(), Data: PostEventData{PostID: postID, UserID: uuid.NewString()}}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	received := make(chan string, 2)
+	for _, group := range []string{"feed-worker-group-" + suffix, "optimized-feed-worker-group-" + suffix} {
+		group := group
+		consumer := NewKafkaConsumer(brokers, topic, group)
+		t.Cleanup(func() { consumer.Close() })
+		go consumer.Subscribe(ctx, func(msg Message) error {
+			_, typed, err := DecodeEvent(msg.Raw)
+			if data, ok := typed.(PostEventData); err == nil && ok && data.PostID == postID {
+				received <- group
+			}
+			return nil
+		})
+	}
+
+	groups := map[string]bool{}
+	for len(groups) < 2 {
+		select {
+		case group := <-received:
+			groups[group] = true
+		case <-ctx.Done():
+			t.Fatalf("only %v received the event", groups)
+		}
+	}
+}
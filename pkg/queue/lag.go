@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// PartitionLag 单个分区的消费延迟
+type PartitionLag struct {
+	Partition       int   `json:"partition"`
+	HighWaterMark   int64 `json:"high_water_mark"`
+	CommittedOffset int64 `json:"committed_offset"`
+	Lag             int64 `json:"lag"`
+}
+
+// ConsumerLag 消费者组在某个topic上的消费延迟
+type ConsumerLag struct {
+	Topic      string         `json:"topic"`
+	GroupID    string         `json:"group_id"`
+	TotalLag   int64          `json:"total_lag"`
+	Partitions []PartitionLag `json:"partitions"`
+	// 当前reader最近一次观测到的延迟（仅覆盖本实例分配到的分区）
+	ReaderLag  int64     `json:"reader_lag"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// Lag 查询每个分区的high-water mark与消费者组已提交offset之差
+func (c *KafkaConsumer) Lag(ctx context.Context) (*ConsumerLag, error) {
+	client := &kafka.Client{Addr: kafka.TCP(c.brokers...), Timeout: 10 * time.Second}
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{c.topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch topic metadata: %w", err)
+	}
+
+	var partitions []int
+	for _, t := range metadata.Topics {
+		if t.Name != c.topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, fmt.Errorf("failed to fetch metadata for topic %s: %w", c.topic, t.Error)
+		}
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+	sort.Ints(partitions)
+
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, p := range partitions {
+		requests[i] = kafka.LastOffsetOf(p)
+	}
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{c.topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partition offsets: %w", err)
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: c.groupID,
+		Topics:  map[string][]int{c.topic: partitions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("failed to fetch committed offsets: %w", committed.Error)
+	}
+
+	committedByPartition := make(map[int]int64, len(partitions))
+	for _, p := range committed.Topics[c.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to fetch committed offset for partition %d: %w", p.Partition, p.Error)
+		}
+		committedByPartition[p.Partition] = p.CommittedOffset
+	}
+
+	result := &ConsumerLag{
+		Topic:      c.topic,
+		GroupID:    c.groupID,
+		ReaderLag:  c.reader.Stats().Lag,
+		ObservedAt: time.Now(),
+	}
+	for _, p := range offsets.Topics[c.topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offset for partition %d: %w", p.Partition, p.Error)
+		}
+		result.Partitions = append(result.Partitions, partitionLag(p.Partition, p.LastOffset, committedByPartition[p.Partition], p.FirstOffset))
+	}
+	sort.Slice(result.Partitions, func(i, j int) bool {
+		return result.Partitions[i].Partition < result.Partitions[j].Partition
+	})
+	for _, p := range result.Partitions {
+		result.TotalLag += p.Lag
+	}
+
+	return result, nil
+}
+
+// partitionLag 计算分区延迟，尚未提交过offset（-1）时从分区起始offset算起
+func partitionLag(partition int, highWaterMark, committedOffset, firstOffset int64) PartitionLag {
+	from := committedOffset
+	if from < 0 {
+		from = firstOffset
+	}
+
+	lag := highWaterMark - from
+	if lag < 0 {
+		lag = 0
+	}
+
+	return PartitionLag{
+		Partition:       partition,
+		HighWaterMark:   highWaterMark,
+		CommittedOffset: committedOffset,
+		Lag:             lag,
+	}
+}
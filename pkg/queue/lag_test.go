@@ -0,0 +1,36 @@
+package queue
+
+import "testing"
+
+func TestPartitionLag(t *testing.T) {
+	t.Run("grows while consumption is paused and shrinks as it catches up", func(t *testing.T) {
+		committed := int64(100)
+		var lags []int64
+		for _, highWaterMark := range []int64{100, 150, 200} {
+			lags = append(lags, partitionLag(0, highWaterMark, committed, 0).Lag)
+		}
+		for _, c := range []int64{180, 200} {
+			committed = c
+			lags = append(lags, partitionLag(0, 200, committed, 0).Lag)
+		}
+
+		want := []int64{0, 50, 100, 20, 0}
+		for i := range want {
+			if lags[i] != want[i] {
+				t.Fatalf("lag sequence = %v, want %v", lags, want)
+			}
+		}
+	})
+
+	t.Run("no committed offset counts from the first offset", func(t *testing.T) {
+		if got := partitionLag(1, 500, -1, 120); got.Lag != 380 || got.CommittedOffset != -1 {
+			t.Errorf("partitionLag() = %+v, want lag 380", got)
+		}
+	})
+
+	t.Run("committed past the high-water mark is not negative", func(t *testing.T) {
+		if got := partitionLag(2, 10, 12, 0); got.Lag != 0 {
+			t.Errorf("partitionLag() = %+v, want lag 0", got)
+		}
+	})
+}
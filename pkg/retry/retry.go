@@ -0,0 +1,49 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backoff 重试配置：最多尝试Attempts次，间隔从Interval开始每次翻倍，不超过MaxInterval
+type Backoff struct {
+	Attempts    int
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// Do 执行fn直到成功或用完尝试次数，每次失败后调用onRetry（可为nil）再等待
+// 返回最后一次的错误；ctx取消时立即返回
+func Do(ctx context.Context, backoff Backoff, fn func(ctx context.Context) error, onRetry func(attempt int, wait time.Duration, err error)) error {
+	attempts := backoff.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := backoff.Interval
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("retry canceled after %d attempts: %w", attempt, err)
+		}
+
+		wait *= 2
+		if backoff.MaxInterval > 0 && wait > backoff.MaxInterval {
+			wait = backoff.MaxInterval
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
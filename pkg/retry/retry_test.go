@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDoSucceedsAfterFailures(t *testing.T) {
+	calls := 0
+	var waits []time.Duration
+	err := Do(context.Background(), Backoff{Attempts: 5, Interval: time.Millisecond, MaxInterval: 3 * time.Millisecond}, func(context.Context) error {
+		calls++
+		if calls <= 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(attempt int, wait time.Duration, err error) {
+		waits = append(waits, wait)
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+	// 间隔每次翻倍，不超过MaxInterval
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	if !reflect.DeepEqual(waits, want) {
+		t.Errorf("waits = %v, want %v", waits, want)
+	}
+}
+
+func TestDoGivesUpAfterAttempts(t *testing.T) {
+	refused := errors.New("connection refused")
+	calls := 0
+	err := Do(context.Background(), Backoff{Attempts: 3, Interval: time.Millisecond}, func(context.Context) error {
+		calls++
+		return refused
+	}, nil)
+	if !errors.Is(err, refused) {
+		t.Errorf("Do() error = %v, want wrapped %v", err, refused)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+
+	// 未配置尝试次数时至少尝试一次
+	calls = 0
+	Do(context.Background(), Backoff{}, func(context.Context) error {
+		calls++
+		return refused
+	}, nil)
+	if calls != 1 {
+		t.Errorf("calls with zero attempts = %d, want 1", calls)
+	}
+}
+
+func TestDoStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Do(ctx, Backoff{Attempts: 5, Interval: time.Hour}, func(context.Context) error {
+		calls++
+		return errors.New("connection refused")
+	}, func(int, time.Duration, error) {
+		cancel()
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want an error after 1 call", err, calls)
+	}
+}
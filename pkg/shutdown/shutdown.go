@@ -0,0 +1,52 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+)
+
+// Step 关闭流程中的一步
+type Step struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Close 将不接受ctx的Close方法包装为Step
+func Close(name string, close func() error) Step {
+	return Step{Name: name, Stop: func(context.Context) error { return close() }}
+}
+
+// Run 按顺序执行关闭步骤，所有步骤共用ctx的超时
+// 某一步在ctx到期后仍未返回时不再等待它，继续执行后续步骤（仍要关闭连接）；
+// 每一步的错误交给onError（可为nil），返回第一个错误
+func Run(ctx context.Context, steps []Step, onError func(step string, err error)) error {
+	var firstErr error
+	for _, step := range steps {
+		done := make(chan error, 1)
+		go func(step Step) {
+			done <- step.Stop(ctx)
+		}(step)
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			// 已到期时仍优先采用已完成步骤的结果
+			select {
+			case err = <-done:
+			default:
+				err = fmt.Errorf("timed out: %w", ctx.Err())
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if onError != nil {
+			onError(step.Name, err)
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", step.Name, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,82 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunStopsInOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Step {
+		return Step{Name: name, Stop: func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}}
+	}
+
+	steps := []Step{record("http"), record("workers"), record("buffer"), record("kafka"), record("redis"), record("db")}
+	if err := Run(context.Background(), steps, nil); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"http", "workers", "buffer", "kafka", "redis", "db"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("teardown order = %v, want %v", order, want)
+	}
+}
+
+func TestRunBoundsHungStep(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	hung := make(chan struct{})
+	defer close(hung)
+	closed := make(chan struct{})
+	failures := map[string]error{}
+	steps := []Step{
+		{Name: "workers", Stop: func(context.Context) error {
+			<-hung // 忽略ctx，一直不返回
+			return nil
+		}},
+		Close("db", func() error {
+			close(closed)
+			return nil
+		}),
+	}
+
+	start := time.Now()
+	err := Run(ctx, steps, func(step string, err error) { failures[step] = err })
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %v, want it bounded by the timeout", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %v, want deadline exceeded", err)
+	}
+	if !errors.Is(failures["workers"], context.DeadlineExceeded) {
+		t.Errorf("hung step error = %v", failures["workers"])
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("steps after the hung one were skipped")
+	}
+}
+
+func TestRunReportsStepErrors(t *testing.T) {
+	closeErr := errors.New("connection reset")
+	var reported []string
+	steps := []Step{
+		Close("redis", func() error { return closeErr }),
+		Close("db", func() error { return nil }),
+	}
+
+	err := Run(context.Background(), steps, func(step string, err error) { reported = append(reported, step) })
+	if !errors.Is(err, closeErr) {
+		t.Errorf("Run() = %v, want %v", err, closeErr)
+	}
+	if !reflect.DeepEqual(reported, []string{"redis"}) {
+		t.Errorf("reported steps = %v, want [redis]", reported)
+	}
+}